@@ -7,12 +7,15 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
+	"syscall"
 
 	"github.com/eclipse-symphony/symphony/api/constants"
 	mu "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
@@ -57,7 +60,8 @@ var RootCmd = &cobra.Command{
 			fmt.Println(err)
 			return
 		}
-		starHost := host.APIHost{}
+		starHost := host.APIHost{ConfigFile: configFile}
+		go shutdownOnSignal(&starHost)
 		err = starHost.Launch(config, []vf.IVendorFactory{
 			svf.SymphonyVendorFactory{},
 		}, []mf.IManagerFactroy{
@@ -72,6 +76,22 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// shutdownOnSignal shuts the host down gracefully on SIGINT or SIGTERM, which lets Launch return once
+// the work in flight is finished. A second signal exits right away.
+func shutdownOnSignal(starHost *host.APIHost) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	fmt.Println("CLEANING UP")
+	go func() {
+		<-signals
+		os.Exit(1)
+	}()
+	if err := starHost.Shutdown(context.Background()); err != nil {
+		fmt.Println(err)
+	}
+}
+
 func Execute(versiong string) {
 	fmt.Println(constants.EulaMessage)
 	fmt.Println()
@@ -10,8 +10,10 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
@@ -21,11 +23,19 @@ import (
 const (
 	// DefaultRetentionInMinutes is the default time to cleanup completed activations
 	DefaultRetentionInMinutes = 1440
+	// DefaultHistoryMaxEntries is the default number of history entries kept for each activation
+	DefaultHistoryMaxEntries = 100
 )
 
 type ActivationsCleanupManager struct {
 	ActivationsManager
 	RetentionInMinutes int
+	// HistoryMaxEntries is the number of history entries kept for each activation, 0 keeps all entries
+	HistoryMaxEntries int
+	// HistoryRetentionInMinutes is how long finished history entries are kept, 0 keeps them until the activation is deleted
+	HistoryRetentionInMinutes int
+	// settingsLock keeps the settings from changing while the activations are polled
+	settingsLock sync.RWMutex
 }
 
 func (s *ActivationsCleanupManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -34,16 +44,51 @@ func (s *ActivationsCleanupManager) Init(context *contexts.VendorContext, config
 		return err
 	}
 
+	return s.readSettings(config.Properties)
+}
+
+// Reconfigure applies new retention settings, which are used from the next poll on
+func (s *ActivationsCleanupManager) Reconfigure(config managers.ManagerConfig) error {
+	if err := s.CheckProviderProperties(config.Properties); err != nil {
+		return err
+	}
+	return s.readSettings(config.Properties)
+}
+
+// readSettings reads the retention settings from the manager properties. The settings and the properties
+// are only changed when all of the settings are valid.
+func (s *ActivationsCleanupManager) readSettings(properties map[string]string) error {
+	var err error
 	// Set activation cleanup interval after they are done. If not set, use default 60 minutes.
-	if val, ok := config.Properties["RetentionInMinutes"]; ok {
-		s.RetentionInMinutes, err = strconv.Atoi(val)
+	retentionInMinutes := DefaultRetentionInMinutes
+	if val, ok := properties["RetentionInMinutes"]; ok {
+		retentionInMinutes, err = strconv.Atoi(val)
 		if err != nil {
-			s.RetentionInMinutes = DefaultRetentionInMinutes
+			retentionInMinutes = DefaultRetentionInMinutes
+		}
+	}
+	log.Info("M (Activation Cleanup): Initialize RetentionInMinutes as " + fmt.Sprint(retentionInMinutes))
+
+	historyMaxEntries := DefaultHistoryMaxEntries
+	if val, ok := properties["HistoryMaxEntries"]; ok {
+		historyMaxEntries, err = strconv.Atoi(val)
+		if err != nil || historyMaxEntries < 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid HistoryMaxEntries '%s'", val), v1alpha2.BadConfig)
+		}
+	}
+	historyRetentionInMinutes := 0
+	if val, ok := properties["HistoryRetentionInMinutes"]; ok {
+		historyRetentionInMinutes, err = strconv.Atoi(val)
+		if err != nil || historyRetentionInMinutes < 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid HistoryRetentionInMinutes '%s'", val), v1alpha2.BadConfig)
 		}
-	} else {
-		s.RetentionInMinutes = DefaultRetentionInMinutes
 	}
-	log.Info("M (Activation Cleanup): Initialize RetentionInMinutes as " + fmt.Sprint(s.RetentionInMinutes))
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.RetentionInMinutes = retentionInMinutes
+	s.HistoryMaxEntries = historyMaxEntries
+	s.HistoryRetentionInMinutes = historyRetentionInMinutes
+	s.SetProperties(properties)
 	return nil
 }
 
@@ -53,15 +98,36 @@ func (s *ActivationsCleanupManager) Enabled() bool {
 
 func (s *ActivationsCleanupManager) Poll() []error {
 	log.Info("M (Activation Cleanup): Polling activations")
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
 	activations, err := s.ActivationsManager.ListSpec(context.Background())
 	if err != nil {
 		return []error{err}
 	}
+	names := make(map[string]bool)
+	for _, activation := range activations {
+		names[activation.Id] = true
+	}
 	ret := []error{}
 	for _, activation := range activations {
+		if len(activation.Status.History) > 0 {
+			ret = append(ret, s.trimHistory(activation)...)
+		}
+		if activation.Status.Status == v1alpha2.Paused {
+			// approvals are otherwise only found to be expired when someone tries to decide on them
+			if _, err := s.ActivationsManager.ExpireApproval(context.Background(), activation.Id, time.Now()); err != nil {
+				log.Errorf("M (Activation Cleanup): Cannot expire approval of activation %s: %+v", activation.Id, err)
+				ret = append(ret, err)
+			}
+			continue
+		}
 		if activation.Status.Status != v1alpha2.Done {
 			continue
 		}
+		if names[activation.Status.ParentActivation] {
+			// child activations are deleted together with their parent
+			continue
+		}
 		if activation.Status.UpdateTime == "" {
 			// Ugrade scenario: update time is not set for activations created before. Set it to now and the activation will be deleted later.
 			// UpdateTime will be set in ReportStatus function
@@ -85,12 +151,48 @@ func (s *ActivationsCleanupManager) Poll() []error {
 		duration := time.Since(updateTime)
 		if duration > time.Duration(s.RetentionInMinutes)*time.Minute {
 			log.Info("M (Activation Cleanup): Deleting activation " + activation.Id + " since it has completed for " + duration.String())
-			err = s.ActivationsManager.DeleteSpec(context.Background(), activation.Id)
-			if err != nil {
-				ret = append(ret, err)
+			ret = append(ret, s.deleteActivation(activation, activations)...)
+		}
+	}
+	return ret
+}
+
+// trimHistory applies the history retention to an activation
+func (s *ActivationsCleanupManager) trimHistory(activation model.ActivationState) []error {
+	if s.HistoryMaxEntries == 0 && s.HistoryRetentionInMinutes == 0 {
+		return nil
+	}
+	cutoff := time.Time{}
+	if s.HistoryRetentionInMinutes > 0 {
+		cutoff = time.Now().Add(-time.Duration(s.HistoryRetentionInMinutes) * time.Minute)
+	}
+	removed, err := s.ActivationsManager.TrimHistory(context.Background(), activation.Id, s.HistoryMaxEntries, cutoff)
+	if err != nil {
+		log.Errorf("M (Activation Cleanup): Cannot trim history of activation %s: %+v", activation.Id, err)
+		return []error{err}
+	}
+	if removed > 0 {
+		log.Infof("M (Activation Cleanup): Removed %d history entries of activation %s", removed, activation.Id)
+	}
+	return nil
+}
+
+// deleteActivation deletes an activation and, recursively, the child activations it started
+func (s *ActivationsCleanupManager) deleteActivation(activation model.ActivationState, activations []model.ActivationState) []error {
+	ret := []error{}
+	for _, child := range activation.Status.ChildActivations {
+		for _, a := range activations {
+			if a.Id == child {
+				log.Info("M (Activation Cleanup): Deleting child activation " + child + " of " + activation.Id)
+				ret = append(ret, s.deleteActivation(a, activations)...)
+				break
 			}
 		}
 	}
+	err := s.ActivationsManager.DeleteSpec(context.Background(), activation.Id)
+	if err != nil {
+		ret = append(ret, err)
+	}
 	return ret
 }
 
@@ -14,6 +14,8 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -46,6 +48,11 @@ func (s *ActivationsManager) Init(context *contexts.VendorContext, config manage
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *ActivationsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (m *ActivationsManager) GetSpec(ctx context.Context, name string) (model.ActivationState, error) {
 	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
 		"method": "GetSpec",
@@ -130,7 +137,39 @@ func (m *ActivationsManager) UpsertSpec(ctx context.Context, name string, spec m
 	if err != nil {
 		return err
 	}
-	return nil
+	if spec.Parent != "" {
+		err = m.addChildActivation(ctx, spec.Parent, name)
+	}
+	return err
+}
+
+// addChildActivation records a child activation in the status of its parent activation
+func (m *ActivationsManager) addChildActivation(ctx context.Context, parent string, child string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	entry, err := m.StateProvider.Get(ctx, states.GetRequest{
+		ID: parent,
+		Metadata: map[string]string{
+			"version":  "v1",
+			"group":    model.WorkflowGroup,
+			"resource": "activations",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	state, err := getActivationState(parent, entry.Body, entry.ETag)
+	if err != nil {
+		return err
+	}
+	for _, c := range state.Status.ChildActivations {
+		if c == child {
+			return nil
+		}
+	}
+	status := *state.Status
+	status.ChildActivations = append(status.ChildActivations, child)
+	return m.upsertStatus(ctx, entry, status)
 }
 
 func (m *ActivationsManager) DeleteSpec(ctx context.Context, name string) error {
@@ -201,9 +240,198 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, curr
 	if err != nil {
 		return err
 	}
-	dict := entry.Body.(map[string]interface{})
-	delete(dict, "spec")
-	current.UpdateTime = time.Now().Format(time.RFC3339)
+	err = t.upsertStatus(ctx, entry, current)
+	return err
+}
+
+// ResolveApproval approves or rejects the pending approval of a paused activation and returns the
+// updated status. An approved activation is left for the caller to resume; a rejected or expired
+// one is marked as failed.
+func (t *ActivationsManager) ResolveApproval(ctx context.Context, name string, approve bool, approver approval.Approver, comment string) (model.ActivationStatus, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "ResolveApproval",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	lock.Lock()
+	defer lock.Unlock()
+	getRequest := states.GetRequest{
+		ID: name,
+		Metadata: map[string]string{
+			"version":  "v1",
+			"group":    model.WorkflowGroup,
+			"resource": "activations",
+		},
+	}
+	entry, err := t.StateProvider.Get(ctx, getRequest)
+	if err != nil {
+		return model.ActivationStatus{}, err
+	}
+	state, err := getActivationState(name, entry.Body, entry.ETag)
+	if err != nil {
+		return model.ActivationStatus{}, err
+	}
+	if state.Status == nil || state.Status.Status != v1alpha2.Paused {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is not paused", name), v1alpha2.BadRequest)
+		return model.ActivationStatus{}, err
+	}
+	status := *state.Status
+	err = approval.Resolve(status.Outputs, approve, approver, comment, time.Now())
+	if err != nil {
+		if status.Outputs != nil && status.Outputs[approval.DecisionOutput] == approval.DecisionExpired {
+			status = expiredStatus(status)
+			if uErr := t.upsertStatus(ctx, entry, status); uErr != nil {
+				log.Errorf(" M (Activations): failed to report expired approval: %v", uErr)
+			}
+		}
+		return status, err
+	}
+	if approve {
+		status.Status = v1alpha2.Done
+		log.Infof(" M (Activations): stage %s of activation %s is approved by %s", status.Stage, name, approver.Name)
+	} else {
+		status.Status = v1alpha2.InternalError
+		status.ErrorMessage = fmt.Sprintf("stage %s is rejected by %s", status.Stage, approver.Name)
+		if comment != "" {
+			status.ErrorMessage = fmt.Sprintf("%s: %s", status.ErrorMessage, comment)
+		}
+		log.Infof(" M (Activations): stage %s of activation %s is rejected by %s", status.Stage, name, approver.Name)
+	}
+	status.IsActive = false
+	err = t.upsertStatus(ctx, entry, status)
+	return status, err
+}
+
+// ExpireApproval fails a paused activation whose pending approval is past its expiry, and reports
+// whether it did
+func (t *ActivationsManager) ExpireApproval(ctx context.Context, name string, now time.Time) (bool, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "ExpireApproval",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	lock.Lock()
+	defer lock.Unlock()
+	getRequest := states.GetRequest{
+		ID: name,
+		Metadata: map[string]string{
+			"version":  "v1",
+			"group":    model.WorkflowGroup,
+			"resource": "activations",
+		},
+	}
+	entry, err := t.StateProvider.Get(ctx, getRequest)
+	if err != nil {
+		return false, err
+	}
+	state, err := getActivationState(name, entry.Body, entry.ETag)
+	if err != nil {
+		return false, err
+	}
+	if state.Status == nil || state.Status.Status != v1alpha2.Paused || !approval.Expire(state.Status.Outputs, now) {
+		return false, nil
+	}
+	log.Infof(" M (Activations): approval of stage %s of activation %s expired", state.Status.Stage, name)
+	err = t.upsertStatus(ctx, entry, expiredStatus(*state.Status))
+	return err == nil, err
+}
+
+// expiredStatus marks the status of an activation whose approval expired as failed
+func expiredStatus(status model.ActivationStatus) model.ActivationStatus {
+	status.Status = v1alpha2.InternalError
+	status.ErrorMessage = fmt.Sprintf("approval of stage %s expired", status.Stage)
+	status.IsActive = false
+	return status
+}
+
+// CancelActivation marks an activation that hasn't finished as cancelled and returns its state
+func (t *ActivationsManager) CancelActivation(ctx context.Context, name string) (model.ActivationState, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "CancelActivation",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	lock.Lock()
+	defer lock.Unlock()
+	getRequest := states.GetRequest{
+		ID: name,
+		Metadata: map[string]string{
+			"version":  "v1",
+			"group":    model.WorkflowGroup,
+			"resource": "activations",
+		},
+	}
+	entry, err := t.StateProvider.Get(ctx, getRequest)
+	if err != nil {
+		return model.ActivationState{}, err
+	}
+	state, err := getActivationState(name, entry.Body, entry.ETag)
+	if err != nil {
+		return model.ActivationState{}, err
+	}
+	status := model.ActivationStatus{}
+	if state.Status != nil {
+		status = *state.Status
+	}
+	switch status.Status {
+	case 0, v1alpha2.Untouched, v1alpha2.Running, v1alpha2.Paused, v1alpha2.Delayed:
+	default:
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s has already finished", name), v1alpha2.BadRequest)
+		return state, err
+	}
+	status.Status = v1alpha2.Cancelled
+	status.ErrorMessage = fmt.Sprintf("activation %s is cancelled", name)
+	status.IsActive = false
+	if status.ActivationGeneration == "" {
+		status.ActivationGeneration = state.Spec.Generation
+	}
+	err = t.upsertStatus(ctx, entry, status)
+	if err != nil {
+		return state, err
+	}
+	state.Status = &status
+	log.Infof(" M (Activations): activation %s is cancelled", name)
+	return state, nil
+}
+
+func (t *ActivationsManager) upsertStatus(ctx context.Context, entry states.StateEntry, current model.ActivationStatus) error {
+	// copy the body so that the stored entry isn't changed before the upsert
+	dict := make(map[string]interface{})
+	for k, v := range entry.Body.(map[string]interface{}) {
+		if k != "spec" {
+			dict[k] = v
+		}
+	}
+	previous := false
+	campaign := ""
+	// statuses reported by stages don't carry the links between activations, keep the stored ones
+	if state, err := getActivationState("", entry.Body, entry.ETag); err == nil {
+		if state.Spec != nil {
+			campaign = state.Spec.Campaign
+		}
+		if current.ParentActivation == "" {
+			current.ParentActivation = state.Spec.Parent
+		}
+		if current.ParentActivation == "" {
+			current.ParentActivation = state.Status.ParentActivation
+		}
+		if len(current.ChildActivations) == 0 {
+			current.ChildActivations = state.Status.ChildActivations
+		}
+		if current.History == nil {
+			current.History = state.Status.History
+		}
+		// a status written again without changes, such as when a child activation is linked, isn't a transition
+		if current.Stage == state.Status.Stage && current.Status == state.Status.Status &&
+			current.NextStage == state.Status.NextStage && current.ErrorMessage == state.Status.ErrorMessage {
+			previous = true
+		}
+	}
+	now := time.Now().Format(time.RFC3339)
+	if !previous {
+		current.History = appendHistory(current.History, current, now)
+	}
+	current.UpdateTime = now
 	dict["status"] = current
 	entry.Body = dict
 	upsertRequest := states.UpsertRequest{
@@ -214,9 +442,168 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, curr
 			"resource": "activations",
 		},
 	}
-	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
+	_, err := t.StateProvider.Upsert(ctx, upsertRequest)
+	if err == nil && !previous && t.Context != nil {
+		if pErr := t.Context.Publish(model.ActivationEventTopic, model.NewActivationStageEvent(entry.ID, campaign, current)); pErr != nil {
+			log.Errorf(" M (Activations): failed to publish stage change of activation %s: %v", entry.ID, pErr)
+		}
+	}
+	return err
+}
+
+// outputs that identify the activation and the stage, which are kept in the history entry itself
+var historyIgnoredOutputs = map[string]bool{
+	"__campaign":             true,
+	"__activation":           true,
+	"__activationGeneration": true,
+	"__stage":                true,
+	"__site":                 true,
+}
+
+// appendHistory records a reported status in the activation history. A stage run stays open while the
+// stage is running, paused or delayed, so that all the reports of the same run update a single entry.
+func appendHistory(history []model.StageHistory, current model.ActivationStatus, now string) []model.StageHistory {
+	if current.Stage == "" {
+		return history
+	}
+	// copy the history so that the stored status isn't changed
+	history = append([]model.StageHistory{}, history...)
+	if n := len(history); n == 0 || history[n-1].Stage != current.Stage || history[n-1].EndTime != "" {
+		history = append(history, model.StageHistory{
+			Stage:     current.Stage,
+			StartTime: now,
+		})
+	}
+	entry := &history[len(history)-1]
+	entry.Status = current.Status
+	entry.NextStage = current.NextStage
+	entry.ErrorMessage = current.ErrorMessage
+	if site, ok := current.Outputs["__site"].(string); ok {
+		entry.Site = site
+	}
+	entry.Outputs = nil
+	for k, v := range current.Outputs {
+		if historyIgnoredOutputs[k] {
+			continue
+		}
+		if entry.Outputs == nil {
+			entry.Outputs = make(map[string]interface{})
+		}
+		entry.Outputs[k] = v
+	}
+	switch current.Status {
+	case v1alpha2.Untouched, v1alpha2.Paused, v1alpha2.Delayed:
+	case v1alpha2.Running:
+		// a running stage that has selected its next stage is finished
+		if current.NextStage != "" {
+			entry.EndTime = now
+		}
+	default:
+		entry.EndTime = now
+	}
+	return history
+}
+
+// GetHistory returns up to limit entries of the history of an activation, starting from offset. A limit
+// of 0 returns all the entries from offset.
+func (t *ActivationsManager) GetHistory(ctx context.Context, name string, offset int, limit int) (model.ActivationHistory, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "GetHistory",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	if offset < 0 || limit < 0 {
+		err = v1alpha2.NewCOAError(nil, "offset and limit can't be negative", v1alpha2.BadRequest)
+		return model.ActivationHistory{}, err
+	}
+	var state model.ActivationState
+	state, err = t.GetSpec(ctx, name)
 	if err != nil {
-		return err
+		return model.ActivationHistory{}, err
 	}
-	return nil
+	ret := model.ActivationHistory{
+		Activation: name,
+		Offset:     offset,
+		Items:      []model.StageHistory{},
+	}
+	if state.Status == nil {
+		return ret, nil
+	}
+	history := state.Status.History
+	ret.Total = len(history)
+	if offset >= len(history) {
+		return ret, nil
+	}
+	end := len(history)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	ret.Items = history[offset:end]
+	return ret, nil
+}
+
+// TrimHistory removes the history entries of an activation that ended before cutoff, and the oldest
+// entries beyond maxEntries. A zero cutoff or maxEntries disables the respective limit. It returns the
+// number of removed entries.
+func (t *ActivationsManager) TrimHistory(ctx context.Context, name string, maxEntries int, cutoff time.Time) (int, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "TrimHistory",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	lock.Lock()
+	defer lock.Unlock()
+	metadata := map[string]string{
+		"version":  "v1",
+		"group":    model.WorkflowGroup,
+		"resource": "activations",
+	}
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID:       name,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return 0, err
+	}
+	state, err := getActivationState(name, entry.Body, entry.ETag)
+	if err != nil {
+		return 0, err
+	}
+	history := make([]model.StageHistory, 0, len(state.Status.History))
+	for _, h := range state.Status.History {
+		if !cutoff.IsZero() && h.EndTime != "" {
+			if endTime, pErr := time.Parse(time.RFC3339, h.EndTime); pErr == nil && endTime.Before(cutoff) {
+				continue
+			}
+		}
+		history = append(history, h)
+	}
+	if maxEntries > 0 && len(history) > maxEntries {
+		history = history[len(history)-maxEntries:]
+	}
+	removed := len(state.Status.History) - len(history)
+	if removed == 0 {
+		return 0, nil
+	}
+	// the status is written as is, without going through upsertStatus, so that the update time, which
+	// decides when a finished activation is deleted, doesn't change
+	status := *state.Status
+	status.History = history
+	dict := make(map[string]interface{})
+	for k, v := range entry.Body.(map[string]interface{}) {
+		if k != "spec" {
+			dict[k] = v
+		}
+	}
+	dict["status"] = status
+	entry.Body = dict
+	_, err = t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value:    entry,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
 }
@@ -9,8 +9,12 @@ package activations
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -53,3 +57,380 @@ func TestCleanupOldActivationSpec(t *testing.T) {
 	_, err = manager.GetSpec(context.Background(), "test")
 	assert.NotNil(t, err)
 }
+
+func pausedForApproval(t *testing.T, approvers []string) ActivationsManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{
+		Stage:  "approval",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			approval.DecisionOutput:  approval.DecisionPending,
+			approval.ApproversOutput: approvers,
+			"__campaign":             "campaign",
+			"__activation":           "test",
+		},
+	})
+	assert.Nil(t, err)
+	return manager
+}
+
+func TestResolveApprovalApprove(t *testing.T) {
+	manager := pausedForApproval(t, []string{"alice"})
+	status, err := manager.ResolveApproval(context.Background(), "test", true, approval.Approver{Name: "alice", Authenticated: true}, "ship it")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, "alice", status.Outputs[approval.ApproverOutput])
+	assert.Equal(t, "campaign", status.Outputs["__campaign"])
+
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, state.Status.Status)
+	assert.Equal(t, approval.DecisionApproved, state.Status.Outputs[approval.DecisionOutput])
+	assert.Equal(t, "ship it", state.Status.Outputs[approval.CommentOutput])
+
+	// the activation is no longer waiting for a decision
+	_, err = manager.ResolveApproval(context.Background(), "test", false, approval.Approver{Name: "alice", Authenticated: true}, "")
+	assert.NotNil(t, err)
+}
+
+func TestResolveApprovalReject(t *testing.T) {
+	manager := pausedForApproval(t, nil)
+	status, err := manager.ResolveApproval(context.Background(), "test", false, approval.Approver{Name: "bob"}, "not during the freeze")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.Equal(t, "stage approval is rejected by bob: not during the freeze", status.ErrorMessage)
+	assert.False(t, status.IsActive)
+}
+
+func TestResolveApprovalNotAllowed(t *testing.T) {
+	manager := pausedForApproval(t, []string{"alice"})
+	_, err := manager.ResolveApproval(context.Background(), "test", true, approval.Approver{Name: "mallory", Authenticated: true}, "")
+	assert.NotNil(t, err)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Paused, state.Status.Status)
+}
+
+func TestResolveApprovalNotAuthenticated(t *testing.T) {
+	manager := pausedForApproval(t, []string{"alice"})
+	_, err := manager.ResolveApproval(context.Background(), "test", true, approval.Approver{Name: "alice"}, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Paused, state.Status.Status)
+}
+
+func TestCleanupExpiresApprovals(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	cleanupmanager := ActivationsCleanupManager{
+		ActivationsManager: manager,
+		RetentionInMinutes: DefaultRetentionInMinutes,
+	}
+	for name, expiry := range map[string]time.Duration{"expired": -time.Minute, "pending": time.Hour} {
+		err := manager.UpsertSpec(context.Background(), name, model.ActivationSpec{})
+		assert.Nil(t, err)
+		err = manager.ReportStatus(context.Background(), name, model.ActivationStatus{
+			Stage:  "approval",
+			Status: v1alpha2.Paused,
+			Outputs: map[string]interface{}{
+				approval.DecisionOutput: approval.DecisionPending,
+				approval.ExpiryOutput:   time.Now().Add(expiry).Format(time.RFC3339),
+			},
+		})
+		assert.Nil(t, err)
+	}
+	errList := cleanupmanager.Poll()
+	assert.Empty(t, errList)
+
+	state, err := manager.GetSpec(context.Background(), "expired")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, state.Status.Status)
+	assert.Equal(t, "approval of stage approval expired", state.Status.ErrorMessage)
+	assert.Equal(t, approval.DecisionExpired, state.Status.Outputs[approval.DecisionOutput])
+	assert.False(t, state.Status.IsActive)
+
+	state, err = manager.GetSpec(context.Background(), "pending")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Paused, state.Status.Status)
+}
+
+func TestResolveApprovalNotPaused(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{})
+	assert.Nil(t, err)
+	_, err = manager.ResolveApproval(context.Background(), "test", true, approval.Approver{Name: "alice", Authenticated: true}, "")
+	assert.NotNil(t, err)
+	_, err = manager.ResolveApproval(context.Background(), "missing", true, approval.Approver{Name: "alice", Authenticated: true}, "")
+	assert.NotNil(t, err)
+}
+
+func TestCancelActivation(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{
+		Stage:                "deploy",
+		Status:               v1alpha2.Running,
+		IsActive:             true,
+		ActivationGeneration: "1",
+	})
+	assert.Nil(t, err)
+	state, err := manager.CancelActivation(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, "campaign", state.Spec.Campaign)
+	assert.Equal(t, "1", state.Status.ActivationGeneration)
+
+	state, err = manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+	assert.False(t, state.Status.IsActive)
+
+	// a finished activation can't be cancelled
+	_, err = manager.CancelActivation(context.Background(), "test")
+	assert.NotNil(t, err)
+}
+
+func TestCancelActivationNotStarted(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	state, err := manager.CancelActivation(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+	assert.Equal(t, state.Spec.Generation, state.Status.ActivationGeneration)
+}
+
+func TestChildActivationLinks(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "parent", model.ActivationSpec{Campaign: "parent-campaign"})
+	assert.Nil(t, err)
+	err = manager.UpsertSpec(context.Background(), "child", model.ActivationSpec{Campaign: "child-campaign", Parent: "parent"})
+	assert.Nil(t, err)
+
+	parent, err := manager.GetSpec(context.Background(), "parent")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"child"}, parent.Status.ChildActivations)
+
+	// statuses reported by stages keep the links
+	err = manager.ReportStatus(context.Background(), "parent", model.ActivationStatus{Stage: "rollout", Status: v1alpha2.Paused})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "child", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running})
+	assert.Nil(t, err)
+	parent, err = manager.GetSpec(context.Background(), "parent")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Paused, parent.Status.Status)
+	assert.Equal(t, []string{"child"}, parent.Status.ChildActivations)
+	child, err := manager.GetSpec(context.Background(), "child")
+	assert.Nil(t, err)
+	assert.Equal(t, "parent", child.Status.ParentActivation)
+}
+
+func TestCleanupChildActivations(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	cleanupmanager := ActivationsCleanupManager{
+		ActivationsManager: manager,
+		RetentionInMinutes: 0,
+	}
+	err := manager.UpsertSpec(context.Background(), "parent", model.ActivationSpec{})
+	assert.Nil(t, err)
+	err = manager.UpsertSpec(context.Background(), "child", model.ActivationSpec{Parent: "parent"})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "child", model.ActivationStatus{Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "parent", model.ActivationStatus{Status: v1alpha2.Running, IsActive: true})
+	assert.Nil(t, err)
+
+	// the child is kept while the parent is around
+	errList := cleanupmanager.Poll()
+	assert.Empty(t, errList)
+	_, err = manager.GetSpec(context.Background(), "child")
+	assert.Nil(t, err)
+
+	err = manager.ReportStatus(context.Background(), "parent", model.ActivationStatus{Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	errList = cleanupmanager.Poll()
+	assert.Empty(t, errList)
+	_, err = manager.GetSpec(context.Background(), "parent")
+	assert.NotNil(t, err)
+	_, err = manager.GetSpec(context.Background(), "child")
+	assert.NotNil(t, err)
+}
+
+func TestActivationHistory(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	for _, status := range []model.ActivationStatus{
+		{Stage: "deploy", Status: v1alpha2.Running},
+		{Stage: "deploy", NextStage: "check", Status: v1alpha2.Running, Outputs: map[string]interface{}{
+			"__stage": "deploy",
+			"__site":  "hq",
+			"result":  "ok",
+		}},
+		{Stage: "check", Status: v1alpha2.Running},
+		{Stage: "check", Status: v1alpha2.Paused},
+		{Stage: "check", Status: v1alpha2.InternalError, ErrorMessage: "check failed"},
+	} {
+		err = manager.ReportStatus(context.Background(), "test", status)
+		assert.Nil(t, err)
+	}
+
+	history, err := manager.GetHistory(context.Background(), "test", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", history.Activation)
+	assert.Equal(t, 2, history.Total)
+	assert.Equal(t, 2, len(history.Items))
+
+	deploy := history.Items[0]
+	assert.Equal(t, "deploy", deploy.Stage)
+	assert.Equal(t, "check", deploy.NextStage)
+	assert.Equal(t, "hq", deploy.Site)
+	assert.Equal(t, v1alpha2.Running, deploy.Status)
+	assert.Equal(t, map[string]interface{}{"result": "ok"}, deploy.Outputs)
+	assert.NotEmpty(t, deploy.StartTime)
+	assert.NotEmpty(t, deploy.EndTime)
+
+	check := history.Items[1]
+	assert.Equal(t, "check", check.Stage)
+	assert.Equal(t, v1alpha2.InternalError, check.Status)
+	assert.Equal(t, "check failed", check.ErrorMessage)
+	assert.NotEmpty(t, check.EndTime)
+
+	history, err = manager.GetHistory(context.Background(), "test", 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, history.Total)
+	assert.Equal(t, 1, history.Offset)
+	assert.Equal(t, []model.StageHistory{check}, history.Items)
+
+	history, err = manager.GetHistory(context.Background(), "test", 5, 1)
+	assert.Nil(t, err)
+	assert.Empty(t, history.Items)
+
+	_, err = manager.GetHistory(context.Background(), "test", -1, 0)
+	assert.NotNil(t, err)
+}
+
+func TestActivationHistoryLoop(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "counter", Status: v1alpha2.Running})
+		assert.Nil(t, err)
+		err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "counter", NextStage: "counter", Status: v1alpha2.Running})
+		assert.Nil(t, err)
+	}
+	history, err := manager.GetHistory(context.Background(), "test", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, history.Total)
+
+	// cancelling closes the running stage
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "counter", Status: v1alpha2.Running})
+	assert.Nil(t, err)
+	_, err = manager.CancelActivation(context.Background(), "test")
+	assert.Nil(t, err)
+	history, err = manager.GetHistory(context.Background(), "test", 3, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(history.Items))
+	assert.Equal(t, v1alpha2.Cancelled, history.Items[0].Status)
+	assert.NotEmpty(t, history.Items[0].EndTime)
+}
+
+func TestCleanupActivationHistory(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	cleanupmanager := ActivationsCleanupManager{
+		ActivationsManager: manager,
+		RetentionInMinutes: 60,
+		HistoryMaxEntries:  2,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	for _, stage := range []string{"a", "b", "c"} {
+		err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: stage, NextStage: "next", Status: v1alpha2.Running})
+		assert.Nil(t, err)
+	}
+	before, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+
+	errList := cleanupmanager.Poll()
+	assert.Empty(t, errList)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(state.Status.History))
+	assert.Equal(t, "b", state.Status.History[0].Stage)
+	assert.Equal(t, before.Status.UpdateTime, state.Status.UpdateTime)
+
+	// entries that ended before the cutoff are removed
+	removed, err := manager.TrimHistory(context.Background(), "test", 0, time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, removed)
+	state, err = manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Empty(t, state.Status.History)
+	assert.Equal(t, "c", state.Status.Stage)
+}
+
+func TestReconfigureCleanupManager(t *testing.T) {
+	cleanupmanager := ActivationsCleanupManager{
+		RetentionInMinutes: 60,
+		HistoryMaxEntries:  2,
+	}
+	err := cleanupmanager.Reconfigure(managers.ManagerConfig{
+		Properties: map[string]string{"RetentionInMinutes": "30", "HistoryMaxEntries": "-1"},
+	})
+	assert.NotNil(t, err)
+	// invalid settings don't change any of the settings
+	assert.Equal(t, 60, cleanupmanager.RetentionInMinutes)
+	assert.Equal(t, 2, cleanupmanager.HistoryMaxEntries)
+
+	err = cleanupmanager.Reconfigure(managers.ManagerConfig{
+		Properties: map[string]string{"RetentionInMinutes": "30", "HistoryRetentionInMinutes": "120"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 30, cleanupmanager.RetentionInMinutes)
+	assert.Equal(t, DefaultHistoryMaxEntries, cleanupmanager.HistoryMaxEntries)
+	assert.Equal(t, 120, cleanupmanager.HistoryRetentionInMinutes)
+}
@@ -12,6 +12,9 @@ import (
 	"fmt"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	symproviders "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -39,6 +42,11 @@ func (s *CampaignsManager) Init(context *contexts.VendorContext, config managers
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *CampaignsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 // GetCampaign retrieves a CampaignSpec object by name
 func (m *CampaignsManager) GetSpec(ctx context.Context, name string) (model.CampaignState, error) {
 	ctx, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
@@ -84,6 +92,17 @@ func getCampaignState(id string, body interface{}) (model.CampaignState, error)
 	return state, nil
 }
 
+// ValidateSpec checks the stages, stage selectors and expressions of a campaign, and that its stages use
+// stage providers the provider factory creates
+func (m *CampaignsManager) ValidateSpec(ctx context.Context, spec model.CampaignSpec) utils.CampaignValidationResult {
+	_, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
+		"method": "ValidateSpec",
+	})
+	defer span.End()
+
+	return utils.ValidateCampaign(spec, symproviders.StageProviderTypes())
+}
+
 func (m *CampaignsManager) UpsertSpec(ctx context.Context, name string, spec model.CampaignSpec) error {
 	ctx, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
 		"method": "UpsertSpec",
@@ -91,6 +110,12 @@ func (m *CampaignsManager) UpsertSpec(ctx context.Context, name string, spec mod
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
 
+	result := m.ValidateSpec(ctx, spec)
+	if !result.Valid {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("campaign %s is invalid: %s", name, result.String()), v1alpha2.BadRequest)
+		return err
+	}
+
 	upsertRequest := states.UpsertRequest{
 		Value: states.StateEntry{
 			ID: name,
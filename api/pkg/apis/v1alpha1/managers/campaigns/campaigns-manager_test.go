@@ -11,6 +11,9 @@ import (
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	symproviders "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +37,43 @@ func TestCreateGetDeleteCampaignSpec(t *testing.T) {
 	err = manager.DeleteSpec(context.Background(), "test")
 	assert.Nil(t, err)
 }
+
+func TestUpsertInvalidCampaignSpec(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := CampaignsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.CampaignSpec{
+		FirstStage: "mock",
+		Stages: map[string]model.StageSpec{
+			"mock": {
+				Name:          "mock",
+				Provider:      "providers.stage.mock",
+				StageSelector: "${{$if($lt($output(mock,foo), 5), mock, done)}}",
+			},
+		},
+	})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadRequest, coaErr.State)
+	assert.Contains(t, err.Error(), "stage 'done' is not found")
+	_, err = manager.GetSpec(context.Background(), "test")
+	assert.NotNil(t, err)
+}
+
+func TestValidateSpecStageProviders(t *testing.T) {
+	manager := CampaignsManager{}
+	stages := map[string]model.StageSpec{}
+	// every stage provider the factory creates can be used
+	for _, provider := range symproviders.StageProviderTypes() {
+		stages[provider] = model.StageSpec{Name: provider, Provider: provider}
+	}
+	stages["unknown"] = model.StageSpec{Name: "unknown", Provider: "providers.stage.unknown"}
+	result := manager.ValidateSpec(context.Background(), model.CampaignSpec{Stages: stages})
+	assert.False(t, result.Valid)
+	assert.Equal(t, []utils.CampaignValidationError{
+		{Field: "stages.unknown.provider", Message: "unknown stage provider 'providers.stage.unknown'"},
+	}, result.Errors)
+}
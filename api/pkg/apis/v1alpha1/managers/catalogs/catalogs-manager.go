@@ -52,6 +52,11 @@ func (s *CatalogsManager) Init(context *contexts.VendorContext, config managers.
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *CatalogsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (s *CatalogsManager) GetSpec(ctx context.Context, name string) (model.CatalogState, error) {
 	ctx, span := observability.StartSpan("Catalogs Manager", ctx, &map[string]string{
 		"method": "GetSpec",
@@ -40,6 +40,11 @@ func (s *DevicesManager) Init(context *contexts.VendorContext, config managers.M
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *DevicesManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (t *DevicesManager) DeleteSpec(ctx context.Context, name string) error {
 	ctx, span := observability.StartSpan("Devices Manager", ctx, &map[string]string{
 		"method": "DeleteSpec",
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package events
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/sink"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+// maxPendingEvents bounds the number of partially delivered events the manager remembers
+const maxPendingEvents = 1000
+
+// EventSinkManager forwards the events published to the selected topics as CloudEvents to its sink
+// providers. The events can be filtered by their CloudEvents type, with glob patterns such as
+// org.eclipse.symphony.deployment.*, and by their scope.
+type EventSinkManager struct {
+	managers.Manager
+	SinkProviders []sink.ISinkProvider
+	topics        []string
+	types         []string
+	scopes        map[string]bool
+	source        string
+	// pending holds, by event ID, the sinks that are done with an event that failed on other sinks, so
+	// that a redelivered event only goes to the sinks that haven't got it yet
+	pendingLock  sync.Mutex
+	pending      map[string]map[int]bool
+	pendingOrder []string
+}
+
+func (s *EventSinkManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	err := s.Manager.Init(context, config, providers)
+	if err != nil {
+		return err
+	}
+	s.SinkProviders = make([]sink.ISinkProvider, 0)
+	for _, provider := range providers {
+		if p, ok := provider.(sink.ISinkProvider); ok {
+			s.SinkProviders = append(s.SinkProviders, p)
+		}
+	}
+	if len(s.SinkProviders) == 0 {
+		return v1alpha2.NewCOAError(nil, "event sink manager has no sink providers", v1alpha2.MissingConfig)
+	}
+	s.topics = splitList(config.Properties["topics"])
+	if len(s.topics) == 0 {
+		s.topics = []string{model.DeploymentEventTopic, model.ActivationEventTopic}
+	}
+	s.types = splitList(config.Properties["types"])
+	for _, t := range s.types {
+		if _, err := path.Match(t, ""); err != nil {
+			return v1alpha2.NewCOAError(err, "invalid pattern in the 'types' setting of event sink manager", v1alpha2.BadConfig)
+		}
+	}
+	s.scopes = make(map[string]bool)
+	for _, scope := range splitList(config.Properties["scopes"]) {
+		s.scopes[scope] = true
+	}
+	s.source = config.Properties["source"]
+	s.pending = make(map[string]map[int]bool)
+	s.pendingOrder = make([]string, 0)
+	return nil
+}
+
+// Topics returns the topics of the events the manager forwards
+func (s *EventSinkManager) Topics() []string {
+	return s.topics
+}
+
+// Matches returns whether an event published to a topic passes the type and scope filters
+func (s *EventSinkManager) Matches(topic string, event v1alpha2.Event) bool {
+	if len(s.scopes) > 0 && !s.scopes[event.Metadata["scope"]] {
+		return false
+	}
+	if len(s.types) == 0 {
+		return true
+	}
+	eventType := event.Metadata[v1alpha2.CloudEventTypeMetadata]
+	if eventType == "" {
+		eventType = v1alpha2.DefaultCloudEventTypePrefix + topic
+	}
+	for _, t := range s.types {
+		if ok, _ := path.Match(t, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleEvent sends an event that passes the filters to all the sinks. When some sinks fail, the
+// returned error gets the event delivered again, and then it's only sent to the sinks that failed.
+// This needs a stable event ID, so events without the ce-id metadata are sent to all the sinks again.
+func (s *EventSinkManager) HandleEvent(ctx context.Context, topic string, event v1alpha2.Event) error {
+	ctx, span := observability.StartSpan("Event Sink Manager", ctx, &map[string]string{
+		"method": "HandleEvent",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	if !s.Matches(topic, event) {
+		return nil
+	}
+	cloudEvent := event.ToCloudEvent(topic, s.source)
+	eventId := event.Metadata[v1alpha2.CloudEventIdMetadata]
+	done := s.doneSinks(eventId)
+	errMessage := ""
+	retry := false
+	for i, p := range s.SinkProviders {
+		if done[i] {
+			continue
+		}
+		sErr := p.Send(ctx, cloudEvent)
+		if sErr != nil {
+			log.Errorf(" M (Event Sink): failed to send event %s of topic %s: %v", cloudEvent.Id, topic, sErr)
+			errMessage += sErr.Error() + ";"
+		}
+		// a sink that rejected the event won't accept it when it's delivered again
+		if sErr == nil || v1alpha2.IsBadRequest(sErr) {
+			done[i] = true
+		} else {
+			retry = true
+		}
+	}
+	if retry {
+		s.setPending(eventId, done)
+	} else {
+		s.setPending(eventId, nil)
+	}
+	if errMessage != "" {
+		state := v1alpha2.InternalError
+		if !retry {
+			state = v1alpha2.BadRequest
+		}
+		err = v1alpha2.NewCOAError(nil, errMessage, state)
+		return err
+	}
+	return nil
+}
+
+// doneSinks returns the indexes of the sinks that are done with an event delivered before
+func (s *EventSinkManager) doneSinks(eventId string) map[int]bool {
+	ret := make(map[int]bool)
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+	for i := range s.pending[eventId] {
+		ret[i] = true
+	}
+	return ret
+}
+
+// setPending remembers the sinks that are done with an event to be delivered again, or forgets the
+// event when done is nil. The oldest events are forgotten when there are too many of them.
+func (s *EventSinkManager) setPending(eventId string, done map[int]bool) {
+	if eventId == "" {
+		return
+	}
+	s.pendingLock.Lock()
+	defer s.pendingLock.Unlock()
+	_, known := s.pending[eventId]
+	if done == nil {
+		if known {
+			delete(s.pending, eventId)
+			for i, id := range s.pendingOrder {
+				if id == eventId {
+					s.pendingOrder = append(s.pendingOrder[:i], s.pendingOrder[i+1:]...)
+					break
+				}
+			}
+		}
+		return
+	}
+	if !known {
+		s.pendingOrder = append(s.pendingOrder, eventId)
+		if len(s.pendingOrder) > maxPendingEvents {
+			delete(s.pending, s.pendingOrder[0])
+			s.pendingOrder = s.pendingOrder[1:]
+		}
+	}
+	s.pending[eventId] = done
+}
+
+func splitList(value string) []string {
+	ret := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package events
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSinkProvider struct {
+	events []v1alpha2.CloudEvent
+	err    error
+}
+
+func (f *fakeSinkProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+
+func (f *fakeSinkProvider) Send(ctx context.Context, event v1alpha2.CloudEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, event)
+	return nil
+}
+
+func newTestManager(t *testing.T, properties map[string]string, sinks ...*fakeSinkProvider) *EventSinkManager {
+	providerMap := make(map[string]providers.IProvider)
+	for i, s := range sinks {
+		providerMap[string(rune('a'+i))] = s
+	}
+	manager := &EventSinkManager{}
+	err := manager.Init(nil, managers.ManagerConfig{Properties: properties}, providerMap)
+	assert.Nil(t, err)
+	return manager
+}
+
+func TestInitWithoutSinks(t *testing.T) {
+	manager := EventSinkManager{}
+	err := manager.Init(nil, managers.ManagerConfig{}, map[string]providers.IProvider{})
+	assert.NotNil(t, err)
+}
+
+func TestInitWithBadPattern(t *testing.T) {
+	manager := EventSinkManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{"types": "org.eclipse.symphony.[deployment"},
+	}, map[string]providers.IProvider{"sink": &fakeSinkProvider{}})
+	assert.NotNil(t, err)
+}
+
+func TestDefaultTopics(t *testing.T) {
+	manager := newTestManager(t, map[string]string{}, &fakeSinkProvider{})
+	assert.Equal(t, []string{model.DeploymentEventTopic, model.ActivationEventTopic}, manager.Topics())
+	manager = newTestManager(t, map[string]string{"topics": "job, trail"}, &fakeSinkProvider{})
+	assert.Equal(t, []string{"job", "trail"}, manager.Topics())
+}
+
+func TestFilterByType(t *testing.T) {
+	sink := &fakeSinkProvider{}
+	manager := newTestManager(t, map[string]string{
+		"types":  "org.eclipse.symphony.deployment.failed,org.eclipse.symphony.activation.*",
+		"source": "https://symphony.example.com",
+	}, sink)
+	deployment := model.DeploymentSpec{Instance: model.InstanceSpec{Name: "instance1"}}
+	for _, event := range model.NewDeploymentEvents(deployment, model.SummarySpec{}, "default", nil) {
+		assert.Nil(t, manager.HandleEvent(context.Background(), model.DeploymentEventTopic, event))
+	}
+	assert.Empty(t, sink.events)
+	for _, event := range model.NewDeploymentEvents(deployment, model.SummarySpec{}, "default", v1alpha2.NewCOAError(nil, "failed", v1alpha2.InternalError)) {
+		assert.Nil(t, manager.HandleEvent(context.Background(), model.DeploymentEventTopic, event))
+	}
+	event := model.NewActivationStageEvent("activation1", "campaign1", model.ActivationStatus{Stage: "test"})
+	assert.Nil(t, manager.HandleEvent(context.Background(), model.ActivationEventTopic, event))
+	// events without a type are matched by the type derived from their topic
+	assert.Nil(t, manager.HandleEvent(context.Background(), "activation.other", v1alpha2.Event{Body: "TEST"}))
+
+	assert.Equal(t, 3, len(sink.events))
+	assert.Equal(t, model.DeploymentFailedEventType, sink.events[0].Type)
+	assert.Equal(t, "instance1", sink.events[0].Subject)
+	assert.Equal(t, "https://symphony.example.com", sink.events[0].Source)
+	assert.Equal(t, model.ActivationStageChangedEventType, sink.events[1].Type)
+	assert.Equal(t, "activation1", sink.events[1].Subject)
+	assert.Equal(t, "org.eclipse.symphony.activation.other", sink.events[2].Type)
+}
+
+func TestFilterByScope(t *testing.T) {
+	sink := &fakeSinkProvider{}
+	manager := newTestManager(t, map[string]string{"scopes": "production"}, sink)
+	deployment := model.DeploymentSpec{Instance: model.InstanceSpec{Name: "instance1"}}
+	for _, scope := range []string{"default", "production"} {
+		for _, event := range model.NewDeploymentEvents(deployment, model.SummarySpec{}, scope, nil) {
+			assert.Nil(t, manager.HandleEvent(context.Background(), model.DeploymentEventTopic, event))
+		}
+	}
+	assert.Equal(t, 1, len(sink.events))
+	assert.Equal(t, "production", sink.events[0].Extensions["scope"])
+}
+
+func TestSinkErrors(t *testing.T) {
+	good := &fakeSinkProvider{}
+	rejecting := &fakeSinkProvider{err: v1alpha2.NewCOAError(nil, "rejected", v1alpha2.BadRequest)}
+	manager := newTestManager(t, map[string]string{}, good, rejecting)
+	err := manager.HandleEvent(context.Background(), "job", v1alpha2.Event{Body: "TEST"})
+	assert.True(t, v1alpha2.IsBadRequest(err))
+	assert.Equal(t, 1, len(good.events))
+
+	failing := &fakeSinkProvider{err: v1alpha2.NewCOAError(nil, "unavailable", v1alpha2.InternalError)}
+	manager = newTestManager(t, map[string]string{}, rejecting, failing)
+	err = manager.HandleEvent(context.Background(), "job", v1alpha2.Event{Body: "TEST"})
+	assert.NotNil(t, err)
+	assert.False(t, v1alpha2.IsBadRequest(err))
+}
+
+func TestRedeliveryOnlyGoesToFailedSinks(t *testing.T) {
+	good := &fakeSinkProvider{}
+	rejecting := &fakeSinkProvider{err: v1alpha2.NewCOAError(nil, "rejected", v1alpha2.BadRequest)}
+	failing := &fakeSinkProvider{err: v1alpha2.NewCOAError(nil, "unavailable", v1alpha2.InternalError)}
+	manager := newTestManager(t, map[string]string{}, good, rejecting, failing)
+	event := model.NewActivationStageEvent("activation1", "campaign1", model.ActivationStatus{Stage: "test"})
+
+	err := manager.HandleEvent(context.Background(), model.ActivationEventTopic, event)
+	assert.NotNil(t, err)
+	assert.False(t, v1alpha2.IsBadRequest(err))
+	err = manager.HandleEvent(context.Background(), model.ActivationEventTopic, event)
+	assert.NotNil(t, err)
+	assert.False(t, v1alpha2.IsBadRequest(err))
+
+	failing.err = nil
+	rejecting.err = nil
+	err = manager.HandleEvent(context.Background(), model.ActivationEventTopic, event)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(good.events))
+	assert.Empty(t, rejecting.events)
+	assert.Equal(t, 1, len(failing.events))
+	assert.Equal(t, good.events[0].Id, failing.events[0].Id)
+	assert.Empty(t, manager.pending)
+
+	// an event that is delivered in full is forgotten, so it's sent to all the sinks again
+	err = manager.HandleEvent(context.Background(), model.ActivationEventTopic, event)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(good.events))
+}
+
+func TestPendingEventsAreBounded(t *testing.T) {
+	manager := newTestManager(t, map[string]string{}, &fakeSinkProvider{})
+	for i := 0; i < maxPendingEvents+10; i++ {
+		manager.setPending(strconv.Itoa(i), map[int]bool{0: true})
+	}
+	assert.Equal(t, maxPendingEvents, len(manager.pending))
+	assert.Equal(t, maxPendingEvents, len(manager.pendingOrder))
+	assert.Empty(t, manager.doneSinks("0"))
+	assert.True(t, manager.doneSinks(strconv.Itoa(maxPendingEvents + 9))[0])
+}
@@ -40,6 +40,11 @@ func (s *InstancesManager) Init(context *contexts.VendorContext, config managers
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *InstancesManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (t *InstancesManager) DeleteSpec(ctx context.Context, name string, scope string) error {
 	ctx, span := observability.StartSpan("Instances Manager", ctx, &map[string]string{
 		"method": "DeleteSpec",
@@ -51,8 +51,24 @@ func (s *JobsManager) Init(context *contexts.VendorContext, config managers.Mana
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *JobsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
+// Reconfigure applies new properties, such as the polling interval or the credentials used to call the
+// API, which are read when they're used
+func (s *JobsManager) Reconfigure(config managers.ManagerConfig) error {
+	if err := s.CheckProviderProperties(config.Properties); err != nil {
+		return err
+	}
+	s.SetProperties(config.Properties)
+	return nil
+}
+
 func (s *JobsManager) Enabled() bool {
-	return s.Config.Properties["poll.enabled"] == "true" || s.Config.Properties["schedule.enabled"] == "true"
+	properties := s.Properties()
+	return properties["poll.enabled"] == "true" || properties["schedule.enabled"] == "true"
 }
 
 func (s *JobsManager) pollObjects() []error {
@@ -62,19 +78,20 @@ func (s *JobsManager) pollObjects() []error {
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
 
-	baseUrl, err := utils.GetString(s.Manager.Config.Properties, "baseUrl")
+	properties := s.Properties()
+	baseUrl, err := utils.GetString(properties, "baseUrl")
 	if err != nil {
 		return []error{err}
 	}
-	user, err := utils.GetString(s.Manager.Config.Properties, "user")
+	user, err := utils.GetString(properties, "user")
 	if err != nil {
 		return []error{err}
 	}
-	password, err := utils.GetString(s.Manager.Config.Properties, "password")
+	password, err := utils.GetString(properties, "password")
 	if err != nil {
 		return []error{err}
 	}
-	interval := utils.ReadInt32(s.Manager.Config.Properties, "interval", 0)
+	interval := utils.ReadInt32(properties, "interval", 0)
 	if interval == 0 {
 		return nil
 	}
@@ -150,13 +167,14 @@ func (s *JobsManager) pollObjects() []error {
 }
 func (s *JobsManager) Poll() []error {
 	// TODO: do these in parallel?
-	if s.Config.Properties["poll.enabled"] == "true" {
+	properties := s.Properties()
+	if properties["poll.enabled"] == "true" {
 		errors := s.pollObjects()
 		if len(errors) > 0 {
 			return errors
 		}
 	}
-	if s.Config.Properties["schedule.enabled"] == "true" {
+	if properties["schedule.enabled"] == "true" {
 		errors := s.pollSchedules()
 		if len(errors) > 0 {
 			return errors
@@ -324,15 +342,16 @@ func (s *JobsManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event)
 			return err
 		}
 
-		baseUrl, err = utils.GetString(s.Manager.Config.Properties, "baseUrl")
+		properties := s.Properties()
+		baseUrl, err = utils.GetString(properties, "baseUrl")
 		if err != nil {
 			return err
 		}
-		user, err = utils.GetString(s.Manager.Config.Properties, "user")
+		user, err = utils.GetString(properties, "user")
 		if err != nil {
 			return err
 		}
-		password, err = utils.GetString(s.Manager.Config.Properties, "password")
+		password, err = utils.GetString(properties, "password")
 		if err != nil {
 			return err
 		}
@@ -36,6 +36,11 @@ func (s *ModelsManager) Init(context *contexts.VendorContext, config managers.Ma
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *ModelsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (t *ModelsManager) DeleteSpec(ctx context.Context, name string) error {
 	ctx, span := observability.StartSpan("Models Manager", ctx, &map[string]string{
 		"method": "DeleteSpec",
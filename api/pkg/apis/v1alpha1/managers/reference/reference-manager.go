@@ -87,6 +87,11 @@ func (s *ReferenceManager) Init(context *contexts.VendorContext, config managers
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *ReferenceManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (s *ReferenceManager) GetExt(refType string, namespace string, id1 string, group1 string, kind1 string, version1 string, id2 string, group2 string, kind2 string, version2 string, iteration string, alias string) ([]byte, error) {
 	if group2 != "download" {
 		data1, err := s.Get(refType, id1, namespace, group1, kind1, version1, "", "")
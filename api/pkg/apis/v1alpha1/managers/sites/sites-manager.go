@@ -42,6 +42,11 @@ func (s *SitesManager) Init(context *contexts.VendorContext, config managers.Man
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *SitesManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 // GetCampaign retrieves a CampaignSpec object by name
 func (m *SitesManager) GetSpec(ctx context.Context, name string) (model.SiteState, error) {
 	ctx, span := observability.StartSpan("Sites Manager", ctx, &map[string]string{
@@ -36,6 +36,11 @@ func (s *SkillsManager) Init(context *contexts.VendorContext, config managers.Ma
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *SkillsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (t *SkillsManager) DeleteSpec(ctx context.Context, name string) error {
 	ctx, span := observability.StartSpan("Skills Manager", ctx, &map[string]string{
 		"method": "DeleteSpec",
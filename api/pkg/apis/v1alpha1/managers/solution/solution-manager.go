@@ -89,6 +89,11 @@ func (s *SolutionManager) Init(context *contexts.VendorContext, config managers.
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *SolutionManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (s *SolutionManager) getPreviousState(ctx context.Context, instance string, scope string) *SolutionManagerDeploymentState {
 	state, err := s.StateProvider.Get(ctx, states.GetRequest{
 		ID: instance,
@@ -167,6 +172,12 @@ func (s *SolutionManager) sendHeartbeat(id string, remove bool, stopCh chan stru
 }
 
 func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.DeploymentSpec, remove bool, scope string) (model.SummarySpec, error) {
+	// a shutdown waits for the reconciles in flight
+	if err := s.BeginWork(); err != nil {
+		return model.SummarySpec{}, err
+	}
+	defer s.EndWork()
+
 	lock.Lock()
 	defer lock.Unlock()
 
@@ -215,7 +226,7 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 		s.saveSummary(iCtx, deployment, summary, scope)
 		return summary, err
 	}
-	currentState, _, err := s.Get(iCtx, deployment)
+	currentState, currentComponents, err := s.Get(iCtx, deployment)
 	if err != nil {
 		summary.SummaryMessage = "failed to get current state: " + err.Error()
 		log.Errorf(" M (Solution): failed to get current state: %+v", err)
@@ -270,7 +281,7 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 
 		if previousDesiredState != nil {
 			testState := MergeDeploymentStates(&previousDesiredState.State, currentState)
-			if s.canSkipStep(iCtx, step, step.Target, provider.(tgt.ITargetProvider), previousDesiredState.State.Components, testState) {
+			if s.canSkipStep(iCtx, step, step.Target, provider.(tgt.ITargetProvider), previousDesiredState.State.Components, currentComponents, testState) {
 				continue
 			}
 		}
@@ -341,6 +352,9 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 		summary.SuccessCount = summary.TargetCount
 	}
 	summary.IsRemoval = remove
+	// a deployment reconciled again without changes only runs steps when the targets drifted
+	summary.IsDrift = someStepsRan && !remove && previousDesiredState != nil &&
+		deployment.Generation != "" && previousDesiredState.Spec.Generation == deployment.Generation
 	s.saveSummary(iCtx, deployment, summary, scope)
 	return summary, nil
 }
@@ -360,7 +374,11 @@ func (s *SolutionManager) saveSummary(ctx context.Context, deployment model.Depl
 		},
 	})
 }
-func (s *SolutionManager) canSkipStep(ctx context.Context, step model.DeploymentStep, target string, provider tgt.ITargetProvider, currentComponents []model.ComponentSpec, state model.DeploymentState) bool {
+
+// canSkipStep checks if a step can be skipped because none of its components changed. A component changes
+// when its desired spec differs from the previous desired spec, or when the spec read back from the target
+// has drifted from the desired spec.
+func (s *SolutionManager) canSkipStep(ctx context.Context, step model.DeploymentStep, target string, provider tgt.ITargetProvider, currentComponents []model.ComponentSpec, readbackComponents []model.ComponentSpec, state model.DeploymentState) bool {
 
 	for _, newCom := range step.Components {
 		key := fmt.Sprintf("%s::%s", newCom.Component.Name, target)
@@ -380,6 +398,11 @@ func (s *SolutionManager) canSkipStep(ctx context.Context, step model.Deployment
 					if rule.IsComponentChanged(c, newCom.Component) {
 						return false // component has changed, can't skip the step
 					}
+					for _, r := range readbackComponents {
+						if r.Name == newCom.Component.Name && rule.IsComponentChanged(r, newCom.Component) {
+							return false // component has drifted on the target, can't skip the step
+						}
+					}
 					break
 				}
 			}
@@ -8,10 +8,14 @@ package solution
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
+	httptarget "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/http"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/google/uuid"
@@ -569,3 +573,80 @@ func TestMockApplyWithError(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, 0, summary.SuccessCount)
 }
+func TestHttpApplyOnReadBackDrift(t *testing.T) {
+	var posts int32
+	var color atomic.Value
+	color.Store("blue")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&posts, 1)
+			return
+		}
+		w.Write([]byte(`{"color": "` + color.Load().(string) + `"}`))
+	}))
+	defer ts.Close()
+
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{
+			Name: "hooks",
+		},
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{
+				{
+					Name: "hook",
+					Type: "http",
+					Properties: map[string]interface{}{
+						"http.url":        ts.URL + "/hooks",
+						"http.getUrl":     ts.URL + "/hooks/1",
+						"http.getMapping": `{"color": "$.color"}`,
+						"color":           "blue",
+					},
+				},
+			},
+		},
+		Assignments: map[string]string{
+			"T1": "{hook}",
+		},
+		Targets: map[string]model.TargetSpec{
+			"T1": {
+				Topologies: []model.TopologySpec{
+					{
+						Bindings: []model.BindingSpec{
+							{
+								Role:     "http",
+								Provider: "providers.target.http",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	targetProvider := &httptarget.HttpTargetProvider{}
+	targetProvider.Init(httptarget.HttpTargetProviderConfig{})
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := SolutionManager{
+		TargetProviders: map[string]target.ITargetProvider{
+			"T1": targetProvider,
+		},
+		StateProvider: stateProvider,
+	}
+
+	_, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&posts))
+
+	// the read back matches the desired state, so the step is skipped
+	summary, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+	assert.True(t, summary.Skipped)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&posts))
+
+	// the target drifted, so the component is applied again
+	color.Store("red")
+	summary, err = manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+	assert.False(t, summary.Skipped)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&posts))
+}
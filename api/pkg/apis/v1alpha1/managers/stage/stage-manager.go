@@ -10,9 +10,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	symproviders "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
@@ -34,12 +38,27 @@ var log = logger.NewLogger("coa.runtime")
 type StageManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
+	runLock       sync.Mutex
+	running       map[runKey]context.CancelFunc
 }
 
+// runKey identifies a running stage. Activations aren't namespaced, so the campaign qualifies the
+// activation name.
+type runKey struct {
+	campaign   string
+	activation string
+	stage      string
+}
+
+// ForEachResultsOutput is the output of a foreach stage holding the outputs of each item
+const ForEachResultsOutput = "results"
+
 type TaskResult struct {
 	Outputs map[string]interface{}
 	Site    string
 	Error   error
+	// Pause is set when the stage provider asked for the activation to be paused
+	Pause bool
 }
 
 func (t *TaskResult) GetError() error {
@@ -101,8 +120,23 @@ func (s *StageManager) Init(context *contexts.VendorContext, config managers.Man
 	}
 	return nil
 }
+
+// CheckHealth checks that the state provider is reachable
+func (s *StageManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
+// Reconfigure applies new properties. poll.enabled is read on every loop, while the state provider
+// can't be changed.
+func (s *StageManager) Reconfigure(config managers.ManagerConfig) error {
+	if err := s.CheckProviderProperties(config.Properties); err != nil {
+		return err
+	}
+	s.SetProperties(config.Properties)
+	return nil
+}
 func (s *StageManager) Enabled() bool {
-	return s.Config.Properties["poll.enabled"] == "true"
+	return s.Properties()["poll.enabled"] == "true"
 }
 func (s *StageManager) Poll() []error {
 	return nil
@@ -345,6 +379,19 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 	}
 	var activationData *v1alpha2.ActivationData
 	if currentStage, ok := campaign.Stages[triggerData.Stage]; ok {
+		var runCtx context.Context
+		var cancel context.CancelFunc
+		key := runKey{campaign: triggerData.Campaign, activation: triggerData.Activation, stage: triggerData.Stage}
+		runCtx, cancel, err = s.startRun(ctx, key, currentStage)
+		if err != nil {
+			status.Status = v1alpha2.BadRequest
+			status.ErrorMessage = err.Error()
+			status.IsActive = false
+			log.Errorf(" M (Stage): invalid stage timeout or retry: %v", err)
+			return status, activationData
+		}
+		defer s.endRun(key, cancel)
+
 		sites := make([]string, 0)
 		if currentStage.Contexts != "" {
 			parser := utils.NewParser(currentStage.Contexts)
@@ -386,6 +433,18 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 			sites = append(sites, s.VendorContext.SiteInfo.SiteId)
 		}
 
+		var items []interface{}
+		if currentStage.ForEach != nil {
+			items, err = s.evaluateItems(currentStage.ForEach.Items, triggerData)
+			if err != nil {
+				status.Status = v1alpha2.InternalError
+				status.ErrorMessage = err.Error()
+				status.IsActive = false
+				log.Errorf(" M (Stage): failed to evaluate foreach items: %v", err)
+				return status, activationData
+			}
+		}
+
 		inputs := triggerData.Inputs
 		if inputs == nil {
 			inputs = make(map[string]interface{})
@@ -410,7 +469,11 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 			jSchedule, _ := json.Marshal(triggerData.Schedule)
 			inputs["__schedule"] = string(jSchedule)
 		}
+		// inputs of foreach stages refer to the current item, so they're evaluated for each item
 		for k, v := range inputs {
+			if currentStage.ForEach != nil {
+				break
+			}
 			var val interface{}
 			val, err = s.traceValue(v, inputs, triggerData.Outputs)
 			if err != nil {
@@ -450,108 +513,58 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 			log.Errorf(" M (Stage): provider %s does not implement IWithManagerContext", triggerData.Provider)
 		}
 
-		numTasks := len(sites)
-		waitGroup := sync.WaitGroup{}
-		results := make(chan TaskResult, numTasks)
-		pauseRequested := false
-
-		for _, site := range sites {
-			waitGroup.Add(1)
-			go func(wg *sync.WaitGroup, site string, results chan<- TaskResult) {
-				defer wg.Done()
-				inputCopy := make(map[string]interface{})
-				for k, v := range inputs {
-					inputCopy[k] = v
-				}
-				inputCopy["__site"] = site
-
-				for k, v := range inputCopy {
-					var val interface{}
-					val, err = s.traceValue(v, inputCopy, triggerData.Outputs)
-					if err != nil {
-						status.Status = v1alpha2.InternalError
-						status.ErrorMessage = err.Error()
-						status.IsActive = false
-						log.Errorf(" M (Stage): failed to evaluate input: %v", err)
-						results <- TaskResult{
-							Outputs: nil,
-							Error:   err,
-							Site:    site,
-						}
-						return
-					}
-					inputCopy[k] = val
-				}
-
-				if _, ok := provider.(*remote.RemoteStageProvider); ok {
-					provider.(*remote.RemoteStageProvider).SetOutputsContext(triggerData.Outputs)
-				}
+		var required int
+		required, err = requiredSuccesses(currentStage.SuccessThreshold, len(sites))
+		if err != nil {
+			status.Status = v1alpha2.BadRequest
+			status.ErrorMessage = err.Error()
+			status.IsActive = false
+			log.Errorf(" M (Stage): invalid success threshold: %v", err)
+			return status, activationData
+		}
 
-				if triggerData.Schedule != nil {
-					s.Context.Publish("schedule", v1alpha2.Event{
-						Body: triggerData,
-					})
-					pauseRequested = true
-					results <- TaskResult{
-						Outputs: nil,
-						Error:   nil,
-						Site:    site,
-					}
-				} else {
-					var outputs map[string]interface{}
-					var pause bool
-					outputs, pause, err = provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, inputCopy)
+		if _, ok := provider.(*remote.RemoteStageProvider); ok {
+			provider.(*remote.RemoteStageProvider).SetOutputsContext(triggerData.Outputs)
+		}
 
-					if pause {
-						pauseRequested = true
-					}
-					results <- TaskResult{
-						Outputs: outputs,
-						Error:   err,
-						Site:    site,
-					}
-				}
-			}(&waitGroup, site, results)
+		var results []TaskResult
+		if triggerData.Schedule != nil {
+			results = s.scheduleStage(triggerData, sites)
+		} else {
+			results = s.runSites(runCtx, provider.(stage.IStageProvider), currentStage, triggerData, inputs, sites, items)
 		}
 
-		waitGroup.Wait()
-		close(results)
+		if runCtx.Err() == context.Canceled {
+			status.Status = v1alpha2.Cancelled
+			status.ErrorMessage = fmt.Sprintf("activation %s is cancelled", triggerData.Activation)
+			status.IsActive = false
+			log.Infof(" M (Stage): stage %s is cancelled", triggerData.Stage)
+			return status, activationData
+		}
+		timedOut := runCtx.Err() == context.DeadlineExceeded
 
-		outputs := make(map[string]interface{})
-		delayedExit := false
-		for result := range results {
-			err = result.GetError()
-			if err != nil {
-				status.Status = v1alpha2.InternalError
-				status.ErrorMessage = fmt.Sprintf("%s: %s", result.Site, err.Error())
-				status.IsActive = false
-				site := result.Site
-				if result.Site == s.Context.SiteInfo.SiteId {
-					site = ""
-				}
-				status.Outputs = carryOutPutsToErrorStatus(nil, err, site)
-				result.Outputs = carryOutPutsToErrorStatus(nil, err, site)
-				log.Errorf(" M (Stage): failed to process stage outputs: %v", err)
-				delayedExit = true
+		outputs, pauseRequested := s.collectSiteResults(results)
+		succeeded := outputs["__succeeded"].(int)
+		errorMessages := make([]string, 0)
+		err = nil
+		for _, result := range results {
+			if rErr := result.GetError(); rErr != nil {
+				errorMessages = append(errorMessages, fmt.Sprintf("%s: %s", result.Site, rErr.Error()))
+				log.Errorf(" M (Stage): site %s failed to process stage %s: %v", result.Site, triggerData.Stage, rErr)
 			}
-			for k, v := range result.Outputs {
-				if result.Site == s.Context.SiteInfo.SiteId {
-					outputs[k] = v
-				} else {
-					outputs[fmt.Sprintf("%s.%s", result.Site, k)] = v
-				}
-			}
-			if result.Site == s.Context.SiteInfo.SiteId {
-				if _, ok := result.Outputs["__status"]; !ok {
-					outputs["__status"] = v1alpha2.OK
-				}
-			} else {
-				key := fmt.Sprintf("%s.__status", result.Site)
-				if _, ok := result.Outputs[key]; !ok {
-					outputs[fmt.Sprintf("%s.__status", result.Site)] = v1alpha2.OK
-				}
+		}
+		if succeeded < required {
+			status.Status = v1alpha2.InternalError
+			status.ErrorMessage = strings.Join(errorMessages, "; ")
+			if currentStage.SuccessThreshold != "" {
+				status.ErrorMessage = fmt.Sprintf("%d of %d sites succeeded, %d required: %s", succeeded, len(sites), required, status.ErrorMessage)
 			}
+			status.IsActive = false
+			err = v1alpha2.NewCOAError(nil, status.ErrorMessage, v1alpha2.InternalError)
+		} else if len(errorMessages) > 0 {
+			log.Infof(" M (Stage): stage %s succeeded on %d of %d sites", triggerData.Stage, succeeded, len(sites))
 		}
+		delayedExit := succeeded < required
 		outputs["__campaign"] = triggerData.Campaign
 		outputs["__activation"] = triggerData.Activation
 		outputs["__activationGeneration"] = triggerData.ActivationGeneration
@@ -586,31 +599,43 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 				return status, activationData
 			}
 
-			parser := utils.NewParser(currentStage.StageSelector)
-			eCtx := s.VendorContext.EvaluationContext.Clone()
-			eCtx.Inputs = triggerData.Inputs
-			if eCtx.Inputs != nil {
-				if v, ok := eCtx.Inputs["context"]; ok {
-					eCtx.Value = v
+			sVal := ""
+			handler := ""
+			if delayedExit {
+				handler = currentStage.OnFailure
+				if timedOut && currentStage.OnTimeout != "" {
+					handler = currentStage.OnTimeout
 				}
 			}
-			eCtx.Outputs = triggerData.Outputs
-			var val interface{}
-			val, err = parser.Eval(*eCtx)
-			if err != nil {
-				status.Status = v1alpha2.InternalError
-				status.ErrorMessage = err.Error()
-				status.IsActive = false
-				log.Errorf(" M (Stage): failed to evaluate stage selector: %v", err)
-				return status, activationData
-			}
-			sVal := ""
-			if val != nil {
-				sVal = val.(string)
+			if handler != "" {
+				log.Infof(" M (Stage): stage %s failed, running stage %s", triggerData.Stage, handler)
+				sVal = handler
+			} else {
+				parser := utils.NewParser(currentStage.StageSelector)
+				eCtx := s.VendorContext.EvaluationContext.Clone()
+				eCtx.Inputs = triggerData.Inputs
+				if eCtx.Inputs != nil {
+					if v, ok := eCtx.Inputs["context"]; ok {
+						eCtx.Value = v
+					}
+				}
+				eCtx.Outputs = triggerData.Outputs
+				var val interface{}
+				val, err = parser.Eval(*eCtx)
+				if err != nil {
+					status.Status = v1alpha2.InternalError
+					status.ErrorMessage = err.Error()
+					status.IsActive = false
+					log.Errorf(" M (Stage): failed to evaluate stage selector: %v", err)
+					return status, activationData
+				}
+				if val != nil {
+					sVal = val.(string)
+				}
 			}
 			if sVal != "" {
 				if nextStage, ok := campaign.Stages[sVal]; ok {
-					if !delayedExit || nextStage.HandleErrors {
+					if !delayedExit || handler != "" || nextStage.HandleErrors {
 						status.NextStage = sVal
 						activationData = &v1alpha2.ActivationData{
 							Campaign:             triggerData.Campaign,
@@ -626,7 +651,7 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 						}
 					} else {
 						status.Status = v1alpha2.InternalError
-						status.ErrorMessage = fmt.Sprintf("stage %s failed", triggerData.Stage)
+						status.ErrorMessage = fmt.Sprintf("stage %s failed: %s", triggerData.Stage, status.ErrorMessage)
 						status.IsActive = false
 						log.Errorf(" M (Stage): failed to process stage outputs: %v", status.ErrorMessage)
 						return status, activationData
@@ -671,6 +696,392 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 	return status, activationData
 }
 
+// scheduleStage publishes a single schedule event for a scheduled stage, which runs on all of its sites
+// and items once the schedule fires, and pauses the stage on each site until then
+func (s *StageManager) scheduleStage(triggerData v1alpha2.ActivationData, sites []string) []TaskResult {
+	s.Context.Publish("schedule", v1alpha2.Event{
+		Body: triggerData,
+	})
+	results := make([]TaskResult, len(sites))
+	for i, site := range sites {
+		results[i] = TaskResult{
+			Site:  site,
+			Pause: true,
+		}
+	}
+	return results
+}
+
+// runSites runs the stage provider for each of the sites, with at most stageSpec.MaxParallelism sites
+// processed at the same time. Sites that haven't started when ctx is done fail with the context error.
+func (s *StageManager) runSites(ctx context.Context, provider stage.IStageProvider, stageSpec model.StageSpec, triggerData v1alpha2.ActivationData, inputs map[string]interface{}, sites []string, items []interface{}) []TaskResult {
+	parallelism := stageSpec.MaxParallelism
+	if parallelism <= 0 || parallelism > len(sites) {
+		parallelism = len(sites)
+	}
+	results := make([]TaskResult, len(sites))
+	slots := make(chan struct{}, parallelism)
+	waitGroup := sync.WaitGroup{}
+	for i, site := range sites {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = TaskResult{
+				Error: contextError(ctx),
+				Site:  site,
+			}
+			continue
+		}
+		waitGroup.Add(1)
+		go func(i int, site string) {
+			defer waitGroup.Done()
+			defer func() { <-slots }()
+			// each goroutine only writes its own slot of results
+			if stageSpec.ForEach != nil {
+				results[i] = s.runForEach(ctx, provider, stageSpec, triggerData, inputs, site, items)
+			} else {
+				results[i] = s.runSiteTask(ctx, provider, stageSpec, triggerData, inputs, site)
+			}
+		}(i, site)
+	}
+	waitGroup.Wait()
+	return results
+}
+
+// collectSiteResults merges the outputs of all sites into the stage outputs. Outputs of remote sites
+// are prefixed with the site name, such as "site1.__status". The "__total", "__succeeded", "__failed"
+// and "__failedSites" outputs summarize the results so that stage selectors can act on partial success.
+func (s *StageManager) collectSiteResults(results []TaskResult) (map[string]interface{}, bool) {
+	outputs := make(map[string]interface{})
+	pauseRequested := false
+	succeeded := 0
+	failedSites := make([]string, 0)
+	for _, result := range results {
+		if result.Pause {
+			pauseRequested = true
+		}
+		siteOutputs := result.Outputs
+		if err := result.GetError(); err != nil {
+			failedSites = append(failedSites, result.Site)
+			siteOutputs = carryOutPutsToErrorStatus(nil, err, "")
+			// keep the results of the items that succeeded
+			if v, ok := result.Outputs[ForEachResultsOutput]; ok {
+				siteOutputs[ForEachResultsOutput] = v
+			}
+		} else {
+			succeeded++
+		}
+		prefix := ""
+		if result.Site != s.Context.SiteInfo.SiteId {
+			prefix = result.Site + "."
+		}
+		for k, v := range siteOutputs {
+			outputs[prefix+k] = v
+		}
+		if _, ok := siteOutputs["__status"]; !ok {
+			outputs[prefix+"__status"] = v1alpha2.OK
+		}
+	}
+	sort.Strings(failedSites)
+	outputs["__total"] = len(results)
+	outputs["__succeeded"] = succeeded
+	outputs["__failed"] = len(failedSites)
+	outputs["__failedSites"] = failedSites
+	return outputs, pauseRequested
+}
+
+// runSiteTask evaluates the stage inputs for a site and runs the stage provider for it
+func (s *StageManager) runSiteTask(ctx context.Context, provider stage.IStageProvider, stageSpec model.StageSpec, triggerData v1alpha2.ActivationData, inputs map[string]interface{}, site string) TaskResult {
+	inputCopy := make(map[string]interface{})
+	for k, v := range inputs {
+		inputCopy[k] = v
+	}
+	inputCopy["__site"] = site
+
+	for k, v := range inputCopy {
+		val, err := s.traceValue(v, inputCopy, triggerData.Outputs)
+		if err != nil {
+			log.Errorf(" M (Stage): failed to evaluate input: %v", err)
+			return TaskResult{
+				Error: err,
+				Site:  site,
+			}
+		}
+		inputCopy[k] = val
+	}
+
+	outputs, pause, err := s.processWithRetry(ctx, provider, stageSpec.Retry, inputCopy)
+	return TaskResult{
+		Outputs: outputs,
+		Error:   err,
+		Site:    site,
+		Pause:   pause,
+	}
+}
+
+// evaluateItems evaluates the list a foreach stage iterates over. The expression can yield a list of
+// any type, or a string holding a JSON array.
+func (s *StageManager) evaluateItems(expression string, triggerData v1alpha2.ActivationData) ([]interface{}, error) {
+	parser := utils.NewParser(expression)
+	eCtx := s.VendorContext.EvaluationContext.Clone()
+	eCtx.Inputs = triggerData.Inputs
+	if eCtx.Inputs != nil {
+		if v, ok := eCtx.Inputs["context"]; ok {
+			eCtx.Value = v
+		}
+	}
+	eCtx.Outputs = triggerData.Outputs
+	val, err := parser.Eval(*eCtx)
+	if err != nil {
+		return nil, err
+	}
+	if str, ok := val.(string); ok {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(str), &items); err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("foreach items '%s' is not a list", expression), v1alpha2.BadRequest)
+		}
+		return items, nil
+	}
+	rVal := reflect.ValueOf(val)
+	if rVal.Kind() != reflect.Slice && rVal.Kind() != reflect.Array {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("foreach items '%s' is not a list", expression), v1alpha2.BadRequest)
+	}
+	items := make([]interface{}, rVal.Len())
+	for i := 0; i < rVal.Len(); i++ {
+		items[i] = rVal.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// runForEach runs the stage provider for each item of a foreach stage on a site, one after the other
+// or in parallel. The outputs of the items are collected, in order, into the ForEachResultsOutput
+// output. The site fails if any of the items fails.
+func (s *StageManager) runForEach(ctx context.Context, provider stage.IStageProvider, stageSpec model.StageSpec, triggerData v1alpha2.ActivationData, inputs map[string]interface{}, site string, items []interface{}) TaskResult {
+	itemName := stageSpec.ForEach.ItemName
+	if itemName == "" {
+		itemName = "item"
+	}
+	parallelism := 1
+	if stageSpec.ForEach.Parallel {
+		parallelism = stageSpec.ForEach.MaxParallelism
+		if parallelism <= 0 || parallelism > len(items) {
+			parallelism = len(items)
+		}
+	}
+	itemResults := make([]TaskResult, len(items))
+	slots := make(chan struct{}, parallelism)
+	waitGroup := sync.WaitGroup{}
+	for i, item := range items {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			itemResults[i] = TaskResult{
+				Error: contextError(ctx),
+				Site:  site,
+			}
+			continue
+		}
+		itemInputs := make(map[string]interface{})
+		for k, v := range inputs {
+			itemInputs[k] = v
+		}
+		itemInputs[itemName] = item
+		itemInputs["__index"] = i
+		waitGroup.Add(1)
+		go func(i int, itemInputs map[string]interface{}) {
+			defer waitGroup.Done()
+			defer func() { <-slots }()
+			itemResults[i] = s.runSiteTask(ctx, provider, stageSpec, triggerData, itemInputs, site)
+		}(i, itemInputs)
+	}
+	waitGroup.Wait()
+
+	results := make([]interface{}, len(items))
+	errorMessages := make([]string, 0)
+	pause := false
+	for i, result := range itemResults {
+		if result.Pause {
+			pause = true
+		}
+		if result.Error != nil {
+			results[i] = carryOutPutsToErrorStatus(nil, result.Error, "")
+			errorMessages = append(errorMessages, fmt.Sprintf("item %d: %s", i, result.Error.Error()))
+			continue
+		}
+		results[i] = result.Outputs
+	}
+	ret := TaskResult{
+		Outputs: map[string]interface{}{
+			ForEachResultsOutput: results,
+		},
+		Site:  site,
+		Pause: pause,
+	}
+	if len(errorMessages) > 0 {
+		ret.Error = v1alpha2.NewCOAError(nil, fmt.Sprintf("%d of %d items failed: %s", len(errorMessages), len(items), strings.Join(errorMessages, "; ")), v1alpha2.InternalError)
+	}
+	return ret
+}
+
+// requiredSuccesses returns the number of sites that need to succeed for a stage to succeed. The
+// threshold is either a count or a percentage, such as "80%"; all sites need to succeed by default.
+func requiredSuccesses(threshold string, total int) (int, error) {
+	if threshold == "" {
+		return total, nil
+	}
+	if strings.HasSuffix(threshold, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil || percentage < 0 || percentage > 100 {
+			return 0, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid success threshold '%s'", threshold), v1alpha2.BadRequest)
+		}
+		return int(math.Ceil(float64(total) * percentage / 100)), nil
+	}
+	count, err := strconv.Atoi(threshold)
+	if err != nil || count < 0 {
+		return 0, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid success threshold '%s'", threshold), v1alpha2.BadRequest)
+	}
+	if count > total {
+		return total, nil
+	}
+	return count, nil
+}
+
+// startRun creates the context stage providers of an activation run under, bounded by the stage
+// timeout, and registers it so that the activation can be cancelled. The retry settings are checked
+// too, so that a stage with invalid settings fails before any provider runs.
+func (s *StageManager) startRun(ctx context.Context, key runKey, stageSpec model.StageSpec) (context.Context, context.CancelFunc, error) {
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if _, _, err := retryBackoff(stageSpec.Retry); err != nil {
+		return nil, nil, err
+	}
+	if stageSpec.Timeout != "" {
+		timeout, err := time.ParseDuration(stageSpec.Timeout)
+		if err != nil {
+			return nil, nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid timeout '%s'", stageSpec.Timeout), v1alpha2.BadRequest)
+		}
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+	if s.running == nil {
+		s.running = make(map[runKey]context.CancelFunc)
+	}
+	s.running[key] = cancel
+	return runCtx, cancel, nil
+}
+func (s *StageManager) endRun(key runKey, cancel context.CancelFunc) {
+	cancel()
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+	delete(s.running, key)
+}
+
+// CancelActivation cancels the stages that are running for an activation, if any, and removes its
+// pending task so that a paused activation isn't resumed
+func (s *StageManager) CancelActivation(ctx context.Context, campaign string, activation string, activationGeneration string) error {
+	log.Infof(" M (Stage): cancelling activation %s", activation)
+	s.runLock.Lock()
+	for key, cancel := range s.running {
+		if key.campaign == campaign && key.activation == activation {
+			cancel()
+		}
+	}
+	s.runLock.Unlock()
+	err := s.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID: fmt.Sprintf("%s-%s-%s", campaign, activation, activationGeneration),
+	})
+	if err != nil && !v1alpha2.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// processWithRetry runs a stage provider, retrying failed attempts as described by the retry spec
+func (s *StageManager) processWithRetry(ctx context.Context, provider stage.IStageProvider, retry *model.RetrySpec, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	attempts := 1
+	if retry != nil && retry.Attempts > 1 {
+		attempts = retry.Attempts
+	}
+	backoff, maxBackoff, err := retryBackoff(retry)
+	if err != nil {
+		return nil, false, err
+	}
+	for attempt := 1; ; attempt++ {
+		outputs, pause, err := s.process(ctx, provider, inputs)
+		result := TaskResult{Outputs: outputs, Error: err}
+		rErr := result.GetError()
+		if rErr == nil || attempt >= attempts || ctx.Err() != nil {
+			return outputs, pause, err
+		}
+		log.Infof(" M (Stage): attempt %d of %d failed, retrying in %v: %v", attempt, attempts, backoff, rErr)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return outputs, pause, contextError(ctx)
+		}
+		backoff *= 2
+		if maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// retryBackoff parses the delay before the first retry and the cap on the delay, which are 0 when not set
+func retryBackoff(retry *model.RetrySpec) (time.Duration, time.Duration, error) {
+	if retry == nil {
+		return 0, 0, nil
+	}
+	var backoff, maxBackoff time.Duration
+	var err error
+	if retry.Backoff != "" {
+		backoff, err = time.ParseDuration(retry.Backoff)
+		if err != nil || backoff < 0 {
+			return 0, 0, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid retry backoff '%s'", retry.Backoff), v1alpha2.BadRequest)
+		}
+	}
+	if retry.MaxBackoff != "" {
+		maxBackoff, err = time.ParseDuration(retry.MaxBackoff)
+		if err != nil || maxBackoff < 0 {
+			return 0, 0, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid retry maxBackoff '%s'", retry.MaxBackoff), v1alpha2.BadRequest)
+		}
+	}
+	return backoff, maxBackoff, nil
+}
+
+// process runs a stage provider and returns as soon as the context is done, even if the provider
+// doesn't observe the context itself. The provider's context is cancelled when process returns, and
+// the result channel is buffered, so a provider that finishes late doesn't block its goroutine.
+func (s *StageManager) process(ctx context.Context, provider stage.IStageProvider, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	type processResult struct {
+		outputs map[string]interface{}
+		pause   bool
+		err     error
+	}
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan processResult, 1)
+	go func() {
+		outputs, pause, err := provider.Process(pctx, *s.Manager.Context, inputs)
+		done <- processResult{outputs: outputs, pause: pause, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.outputs, r.pause, r.err
+	case <-ctx.Done():
+		return nil, false, contextError(ctx)
+	}
+}
+
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return v1alpha2.NewCOAError(ctx.Err(), "stage timed out", v1alpha2.TimedOut)
+	}
+	return v1alpha2.NewCOAError(ctx.Err(), "stage is cancelled", v1alpha2.Cancelled)
+}
+
 func (s *StageManager) traceValue(v interface{}, inputs map[string]interface{}, outputs map[string]map[string]interface{}) (interface{}, error) {
 	switch val := v.(type) {
 	case string:
@@ -9,13 +9,21 @@ package stage
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/stretchr/testify/assert"
@@ -1117,3 +1125,741 @@ func TestTriggerEventWithSchedule(t *testing.T) {
 	assert.Equal(t, v1alpha2.Paused, status.Status)
 	assert.Equal(t, false, status.IsActive)
 }
+func TestTriggerEventWithSchedulePublishesOnce(t *testing.T) {
+	manager := newTestStageManager()
+	pubSub := &memory.InMemoryPubSubProvider{}
+	pubSub.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager.Context.PubsubProvider = pubSub
+	scheduled := make(chan v1alpha2.Event, 10)
+	pubSub.Subscribe("schedule", func(topic string, event v1alpha2.Event) error {
+		scheduled <- event
+		return nil
+	})
+
+	status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider: "providers.stage.mock",
+				Contexts: "${{$input(sites)}}",
+				ForEach: &model.ForEachSpec{
+					Items: "${{$input(instances)}}",
+				},
+			},
+		},
+	}, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "test",
+		Provider:   "providers.stage.mock",
+		Inputs: map[string]interface{}{
+			"sites":     []interface{}{"fake", "site1", "site2"},
+			"instances": []interface{}{"a", "b", "c"},
+		},
+		Schedule: &v1alpha2.ScheduleSpec{
+			Date: "2020-01-01",
+			Time: "12:00:00PM",
+			Zone: "PST",
+		},
+	})
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	// one schedule for the stage, not one for each site and item
+	select {
+	case <-scheduled:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stage isn't scheduled")
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, len(scheduled))
+}
+func TestCampaignWithApprovalStage(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "approval",
+		Stages: map[string]model.StageSpec{
+			"approval": {
+				Name:     "approval",
+				Provider: "providers.stage.approval",
+				Inputs: map[string]interface{}{
+					"approvers": "alice",
+					"message":   "Deploy to production?",
+				},
+				StageSelector: "deploy",
+			},
+			"deploy": {
+				Name:     "deploy",
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "approval",
+		Provider:             "providers.stage.approval",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	assert.Equal(t, approval.DecisionPending, status.Outputs[approval.DecisionOutput])
+	assert.Equal(t, "Deploy to production?", status.Outputs[approval.MessageOutput])
+
+	err := approval.Resolve(status.Outputs, true, approval.Approver{Name: "alice", Authenticated: true}, "ship it", time.Now())
+	assert.Nil(t, err)
+	status.Status = v1alpha2.Done
+	activation, err = manager.ResumeStage(status, campaign)
+	assert.Nil(t, err)
+	assert.NotNil(t, activation)
+	assert.Equal(t, "deploy", activation.Stage)
+	assert.Equal(t, "alice", activation.Outputs["approval"][approval.ApproverOutput])
+	assert.Equal(t, "ship it", activation.Outputs["approval"][approval.CommentOutput])
+}
+
+func newTestStageManager() *StageManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := &StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	return manager
+}
+
+func delayCampaign(stage model.StageSpec) model.CampaignSpec {
+	stage.Name = "slow"
+	stage.Provider = "providers.stage.delay"
+	stage.Inputs = map[string]interface{}{
+		"delay": "5s",
+	}
+	return model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "slow",
+		Stages: map[string]model.StageSpec{
+			"slow": stage,
+			"next": {
+				Name:     "next",
+				Provider: "providers.stage.mock",
+			},
+			"cleanup": {
+				Name:     "cleanup",
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+}
+
+func slowActivation() v1alpha2.ActivationData {
+	return v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "slow",
+		Provider:             "providers.stage.delay",
+	}
+}
+
+func TestStageTimeoutRunsOnTimeoutStage(t *testing.T) {
+	manager := newTestStageManager()
+	start := time.Now()
+	status, activation := manager.HandleTriggerEvent(context.Background(), delayCampaign(model.StageSpec{
+		Timeout:       "100ms",
+		StageSelector: "next",
+		OnTimeout:     "cleanup",
+		OnFailure:     "next",
+	}), slowActivation())
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.NotNil(t, activation)
+	assert.Equal(t, "cleanup", activation.Stage)
+	assert.Equal(t, "cleanup", status.NextStage)
+	assert.Equal(t, v1alpha2.TimedOut, activation.Outputs["slow"]["__status"])
+}
+
+func TestStageTimeoutRunsOnFailureStage(t *testing.T) {
+	manager := newTestStageManager()
+	_, activation := manager.HandleTriggerEvent(context.Background(), delayCampaign(model.StageSpec{
+		Timeout:       "100ms",
+		StageSelector: "next",
+		OnFailure:     "cleanup",
+	}), slowActivation())
+	assert.NotNil(t, activation)
+	assert.Equal(t, "cleanup", activation.Stage)
+}
+
+func TestStageTimeoutWithoutHandler(t *testing.T) {
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), delayCampaign(model.StageSpec{
+		Timeout:       "100ms",
+		StageSelector: "next",
+	}), slowActivation())
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.False(t, status.IsActive)
+}
+
+func TestStageInvalidTimeout(t *testing.T) {
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), delayCampaign(model.StageSpec{
+		Timeout: "soon",
+	}), slowActivation())
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+}
+
+func TestStageInvalidRetry(t *testing.T) {
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), delayCampaign(model.StageSpec{
+		Retry: &model.RetrySpec{Attempts: 2, Backoff: "soon"},
+	}), slowActivation())
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+}
+
+func TestOnFailureStage(t *testing.T) {
+	manager := newTestStageManager()
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider:      "providers.stage.http",
+				StageSelector: "next",
+				OnFailure:     "cleanup",
+				Inputs: map[string]interface{}{
+					"method": "GET",
+					"url":    "bad url",
+				},
+			},
+			"next": {
+				Provider: "providers.stage.mock",
+			},
+			"cleanup": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	_, activation := manager.HandleTriggerEvent(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "test",
+		Provider:   "providers.stage.http",
+	})
+	assert.NotNil(t, activation)
+	assert.Equal(t, "cleanup", activation.Stage)
+}
+
+func TestCancelRunningActivation(t *testing.T) {
+	manager := newTestStageManager()
+	done := make(chan model.ActivationStatus)
+	go func() {
+		status, _ := manager.HandleTriggerEvent(context.Background(), delayCampaign(model.StageSpec{
+			StageSelector: "next",
+		}), slowActivation())
+		done <- status
+	}()
+	// wait for the stage to start
+	for i := 0; i < 100; i++ {
+		manager.runLock.Lock()
+		_, ok := manager.running[runKey{campaign: "test-campaign", activation: "test-activation", stage: "slow"}]
+		manager.runLock.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	err := manager.CancelActivation(context.Background(), "test-campaign", "test-activation", "1")
+	assert.Nil(t, err)
+	select {
+	case status := <-done:
+		assert.Equal(t, v1alpha2.Cancelled, status.Status)
+		assert.False(t, status.IsActive)
+	case <-time.After(2 * time.Second):
+		t.Fatal("activation wasn't cancelled")
+	}
+}
+
+func TestRunsAreKeyedByStage(t *testing.T) {
+	manager := newTestStageManager()
+	first := runKey{campaign: "test-campaign", activation: "test-activation", stage: "first"}
+	second := runKey{campaign: "test-campaign", activation: "test-activation", stage: "second"}
+	other := runKey{campaign: "other-campaign", activation: "test-activation", stage: "first"}
+	_, firstCancel, err := manager.startRun(context.Background(), first, model.StageSpec{})
+	assert.Nil(t, err)
+	secondCtx, secondCancel, err := manager.startRun(context.Background(), second, model.StageSpec{})
+	assert.Nil(t, err)
+	defer secondCancel()
+	otherCtx, otherCancel, err := manager.startRun(context.Background(), other, model.StageSpec{})
+	assert.Nil(t, err)
+	defer otherCancel()
+
+	// ending one stage keeps the other stages of the activation cancellable
+	manager.endRun(first, firstCancel)
+	err = manager.CancelActivation(context.Background(), "test-campaign", "test-activation", "1")
+	assert.Nil(t, err)
+	assert.NotNil(t, secondCtx.Err())
+	assert.Nil(t, otherCtx.Err())
+}
+
+func TestCancelPausedActivation(t *testing.T) {
+	manager := newTestStageManager()
+	_, err := manager.StateProvider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "test-campaign-test-activation-1",
+			Body: PendingTask{Sites: []string{"fake"}},
+		},
+	})
+	assert.Nil(t, err)
+	err = manager.CancelActivation(context.Background(), "test-campaign", "test-activation", "1")
+	assert.Nil(t, err)
+	_, err = manager.StateProvider.Get(context.Background(), states.GetRequest{ID: "test-campaign-test-activation-1"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	// cancelling an activation that isn't running or paused is a no-op
+	err = manager.CancelActivation(context.Background(), "test-campaign", "test-activation", "1")
+	assert.Nil(t, err)
+}
+
+type flakyStageProvider struct {
+	failures int
+	calls    int
+}
+
+func (f *flakyStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, false, fmt.Errorf("attempt %d failed", f.calls)
+	}
+	return map[string]interface{}{"calls": f.calls}, false, nil
+}
+
+func TestProcessWithRetry(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &flakyStageProvider{failures: 2}
+	outputs, _, err := manager.processWithRetry(context.Background(), provider, &model.RetrySpec{
+		Attempts: 3,
+		Backoff:  "1ms",
+	}, map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, outputs["calls"])
+}
+
+func TestProcessWithRetryExhausted(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &flakyStageProvider{failures: 5}
+	_, _, err := manager.processWithRetry(context.Background(), provider, &model.RetrySpec{
+		Attempts: 2,
+	}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, provider.calls)
+
+	// no retry spec means a single attempt
+	provider = &flakyStageProvider{failures: 5}
+	_, _, err = manager.processWithRetry(context.Background(), provider, nil, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestProcessWithRetryInvalidBackoff(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &flakyStageProvider{failures: 5}
+	_, _, err := manager.processWithRetry(context.Background(), provider, &model.RetrySpec{
+		Attempts:   2,
+		Backoff:    "1s",
+		MaxBackoff: "a minute",
+	}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	assert.Equal(t, 0, provider.calls)
+}
+
+func TestProcessWithRetryStopsOnTimeout(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &flakyStageProvider{failures: 5}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := manager.processWithRetry(ctx, provider, &model.RetrySpec{
+		Attempts: 5,
+		Backoff:  "10s",
+	}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.TimedOut, err.(v1alpha2.COAError).State)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, 1, provider.calls)
+}
+
+type concurrencyStageProvider struct {
+	lock    sync.Mutex
+	active  int
+	maxSeen int
+	failing map[string]bool
+}
+
+func (c *concurrencyStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	c.lock.Lock()
+	c.active++
+	if c.active > c.maxSeen {
+		c.maxSeen = c.active
+	}
+	c.lock.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	c.lock.Lock()
+	c.active--
+	c.lock.Unlock()
+	site := inputs["__site"].(string)
+	if c.failing[site] {
+		return nil, false, fmt.Errorf("site %s is down", site)
+	}
+	return map[string]interface{}{"site": site}, false, nil
+}
+
+func TestRunSitesMaxParallelism(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &concurrencyStageProvider{}
+	sites := []string{"s1", "s2", "s3", "s4", "s5", "s6", "s7", "s8", "s9", "s10"}
+	results := manager.runSites(context.Background(), provider, model.StageSpec{MaxParallelism: 3}, v1alpha2.ActivationData{}, map[string]interface{}{}, sites, nil)
+	assert.Equal(t, 10, len(results))
+	assert.LessOrEqual(t, provider.maxSeen, 3)
+	for i, result := range results {
+		assert.Nil(t, result.Error)
+		assert.Equal(t, sites[i], result.Site)
+		assert.Equal(t, sites[i], result.Outputs["site"])
+	}
+}
+
+func TestRunSitesStopsStartingOnCancel(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &concurrencyStageProvider{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := manager.runSites(ctx, provider, model.StageSpec{MaxParallelism: 1}, v1alpha2.ActivationData{}, map[string]interface{}{}, []string{"s1", "s2", "s3"}, nil)
+	assert.Equal(t, 3, len(results))
+	for _, result := range results {
+		assert.NotNil(t, result.Error)
+	}
+}
+
+func TestCollectSiteResults(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &concurrencyStageProvider{failing: map[string]bool{"site1": true}}
+	results := manager.runSites(context.Background(), provider, model.StageSpec{}, v1alpha2.ActivationData{}, map[string]interface{}{}, []string{"fake", "site1", "site2"}, nil)
+	outputs, pause := manager.collectSiteResults(results)
+	assert.False(t, pause)
+	assert.Equal(t, v1alpha2.OK, outputs["__status"])
+	assert.Equal(t, "fake", outputs["site"])
+	assert.Equal(t, v1alpha2.InternalError, outputs["site1.__status"])
+	assert.Equal(t, "site site1 is down", outputs["site1.__error"])
+	assert.Equal(t, v1alpha2.OK, outputs["site2.__status"])
+	assert.Equal(t, "site2", outputs["site2.site"])
+	assert.Equal(t, 3, outputs["__total"])
+	assert.Equal(t, 2, outputs["__succeeded"])
+	assert.Equal(t, 1, outputs["__failed"])
+	assert.Equal(t, []string{"site1"}, outputs["__failedSites"])
+}
+
+func TestRequiredSuccesses(t *testing.T) {
+	tests := []struct {
+		threshold string
+		total     int
+		expected  int
+	}{
+		{"", 10, 10},
+		{"3", 10, 3},
+		{"30", 10, 10},
+		{"80%", 10, 8},
+		{"75%", 10, 8},
+		{"0%", 10, 0},
+		{"100%", 7, 7},
+	}
+	for _, test := range tests {
+		required, err := requiredSuccesses(test.threshold, test.total)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, required, test.threshold)
+	}
+	for _, threshold := range []string{"most", "-1", "120%", "x%"} {
+		_, err := requiredSuccesses(threshold, 10)
+		assert.NotNil(t, err, threshold)
+	}
+}
+
+func siteCampaign(threshold string) model.CampaignSpec {
+	return model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "rollout",
+		Stages: map[string]model.StageSpec{
+			"rollout": {
+				Name:             "rollout",
+				Provider:         "providers.stage.http",
+				Config:           map[string]interface{}{"successCodes": []int{200}},
+				Contexts:         "${{$input(sites)}}",
+				MaxParallelism:   2,
+				SuccessThreshold: threshold,
+				StageSelector:    "${{$if($gt($output(rollout,__failed), 0), partial, complete)}}",
+			},
+			"partial": {
+				Name:     "partial",
+				Provider: "providers.stage.mock",
+			},
+			"complete": {
+				Name:     "complete",
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+}
+
+func siteActivation(url string) v1alpha2.ActivationData {
+	return v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "rollout",
+		Provider:   "providers.stage.http",
+		Config:     map[string]interface{}{"successCodes": []int{200}},
+		Inputs: map[string]interface{}{
+			"sites":  []interface{}{"fake", "site1", "site2", "site3"},
+			"method": "GET",
+			"url":    url,
+		},
+	}
+}
+
+// failingServer fails the first failures requests it receives
+func failingServer(failures int32) *httptest.Server {
+	var count int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestSuccessThresholdPartialSuccess(t *testing.T) {
+	server := failingServer(1)
+	defer server.Close()
+
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), siteCampaign("75%"), siteActivation(server.URL))
+	assert.NotNil(t, activation)
+	assert.Equal(t, "partial", status.NextStage)
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	outputs := activation.Outputs["rollout"]
+	assert.Equal(t, 3, outputs["__succeeded"])
+	assert.Equal(t, 1, outputs["__failed"])
+	failedSites := outputs["__failedSites"].([]string)
+	assert.Equal(t, 1, len(failedSites))
+	key := failedSites[0] + ".__status"
+	if failedSites[0] == "fake" {
+		key = "__status"
+	}
+	assert.Equal(t, v1alpha2.BadConfig, outputs[key])
+}
+
+func TestSuccessThresholdAllSucceeded(t *testing.T) {
+	server := failingServer(0)
+	defer server.Close()
+
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), siteCampaign(""), siteActivation(server.URL))
+	assert.NotNil(t, activation)
+	assert.Equal(t, "complete", status.NextStage)
+	assert.Equal(t, 4, activation.Outputs["rollout"]["__succeeded"])
+}
+
+func TestSuccessThresholdNotMet(t *testing.T) {
+	server := failingServer(2)
+	defer server.Close()
+
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), siteCampaign("3"), siteActivation(server.URL))
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.Contains(t, status.ErrorMessage, "2 of 4 sites succeeded, 3 required")
+}
+
+func TestInvalidSuccessThreshold(t *testing.T) {
+	manager := newTestStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), siteCampaign("most"), siteActivation("http://localhost"))
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+}
+
+type itemStageProvider struct {
+	concurrencyStageProvider
+	failing string
+}
+
+func (p *itemStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	p.concurrencyStageProvider.Process(ctx, mgrContext, inputs)
+	if inputs["name"] == p.failing {
+		return nil, false, fmt.Errorf("%v failed", inputs["name"])
+	}
+	return map[string]interface{}{"name": inputs["name"], "index": inputs["__index"]}, false, nil
+}
+
+func foreachCampaign(foreach *model.ForEachSpec) model.CampaignSpec {
+	return model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "patch",
+		Stages: map[string]model.StageSpec{
+			"patch": {
+				Name:     "patch",
+				Provider: "providers.stage.mock",
+				ForEach:  foreach,
+				Inputs: map[string]interface{}{
+					"name": "${{$input(instance)}}",
+				},
+			},
+		},
+	}
+}
+
+func foreachActivation() v1alpha2.ActivationData {
+	return v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "patch",
+		Provider:   "providers.stage.mock",
+		Inputs: map[string]interface{}{
+			"instances": []interface{}{"a", "b", "c"},
+		},
+	}
+}
+
+func TestForEachStage(t *testing.T) {
+	manager := newTestStageManager()
+	status, _ := manager.HandleTriggerEvent(context.Background(), foreachCampaign(&model.ForEachSpec{
+		Items:    "${{$input(instances)}}",
+		ItemName: "instance",
+	}), foreachActivation())
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	results := status.Outputs[ForEachResultsOutput].([]interface{})
+	assert.Equal(t, 3, len(results))
+	for i, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, name, results[i].(map[string]interface{})["name"])
+		assert.Equal(t, i, results[i].(map[string]interface{})["__index"])
+	}
+}
+
+func TestForEachStageInvalidItems(t *testing.T) {
+	manager := newTestStageManager()
+	status, _ := manager.HandleTriggerEvent(context.Background(), foreachCampaign(&model.ForEachSpec{
+		Items: "not a list",
+	}), foreachActivation())
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+}
+
+func TestEvaluateItemsFromJson(t *testing.T) {
+	manager := newTestStageManager()
+	items, err := manager.evaluateItems(`["a", "b"]`, v1alpha2.ActivationData{})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, items)
+
+	items, err = manager.evaluateItems("${{$input(names)}}", v1alpha2.ActivationData{
+		Inputs: map[string]interface{}{"names": []string{"x", "y"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"x", "y"}, items)
+}
+
+func TestRunForEachSequential(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &itemStageProvider{}
+	result := manager.runForEach(context.Background(), provider, model.StageSpec{
+		ForEach: &model.ForEachSpec{},
+	}, v1alpha2.ActivationData{}, map[string]interface{}{"name": "${{$input(item)}}"}, "fake", []interface{}{"a", "b", "c", "d"})
+	assert.Nil(t, result.Error)
+	assert.Equal(t, 1, provider.maxSeen)
+	results := result.Outputs[ForEachResultsOutput].([]interface{})
+	assert.Equal(t, "d", results[3].(map[string]interface{})["name"])
+	assert.Equal(t, 3, results[3].(map[string]interface{})["index"])
+}
+
+func TestRunForEachParallel(t *testing.T) {
+	manager := newTestStageManager()
+	provider := &itemStageProvider{failing: "c"}
+	items := make([]interface{}, 10)
+	for i := range items {
+		items[i] = string(rune('a' + i))
+	}
+	result := manager.runForEach(context.Background(), provider, model.StageSpec{
+		ForEach: &model.ForEachSpec{Parallel: true, MaxParallelism: 3},
+	}, v1alpha2.ActivationData{}, map[string]interface{}{"name": "${{$input(item)}}"}, "fake", items)
+	assert.LessOrEqual(t, provider.maxSeen, 3)
+	assert.NotNil(t, result.Error)
+	assert.Contains(t, result.Error.Error(), "1 of 10 items failed: item 2: c failed")
+	results := result.Outputs[ForEachResultsOutput].([]interface{})
+	assert.Equal(t, 10, len(results))
+	assert.Equal(t, "c failed", results[2].(map[string]interface{})["__error"])
+	assert.Equal(t, "d", results[3].(map[string]interface{})["name"])
+
+	// the results of the items that succeeded are kept in the stage outputs
+	outputs, _ := manager.collectSiteResults([]TaskResult{result})
+	assert.Equal(t, v1alpha2.InternalError, outputs["__status"])
+	assert.Equal(t, 10, len(outputs[ForEachResultsOutput].([]interface{})))
+}
+
+func TestReconfigureRejectsProviderChanges(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{"providers.state": "mem-state"},
+	}, map[string]providers.IProvider{"mem-state": stateProvider})
+	assert.Nil(t, err)
+
+	err = manager.Reconfigure(managers.ManagerConfig{
+		Properties: map[string]string{"providers.state": "k8s-state", "poll.enabled": "true"},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "providers.state")
+	// nothing is applied when the change is rejected
+	assert.False(t, manager.Enabled())
+	assert.Equal(t, "mem-state", manager.Properties()["providers.state"])
+
+	err = manager.Reconfigure(managers.ManagerConfig{
+		Properties: map[string]string{"poll.enabled": "true"},
+	})
+	assert.NotNil(t, err)
+
+	err = manager.Reconfigure(managers.ManagerConfig{
+		Properties: map[string]string{"providers.state": "mem-state", "poll.enabled": "true"},
+	})
+	assert.Nil(t, err)
+	assert.True(t, manager.Enabled())
+}
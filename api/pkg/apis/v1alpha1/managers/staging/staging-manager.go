@@ -53,8 +53,22 @@ func (s *StagingManager) Init(context *contexts.VendorContext, config managers.M
 	}
 	return nil
 }
+
+// CheckHealth checks that the queue and state providers are reachable
+func (s *StagingManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.QueueProvider, s.StateProvider)
+}
+
+// Reconfigure applies new properties, which are read on every loop. The providers can't be changed.
+func (s *StagingManager) Reconfigure(config managers.ManagerConfig) error {
+	if err := s.CheckProviderProperties(config.Properties); err != nil {
+		return err
+	}
+	s.SetProperties(config.Properties)
+	return nil
+}
 func (s *StagingManager) Enabled() bool {
-	return s.Config.Properties["poll.enabled"] == "true"
+	return s.Properties()["poll.enabled"] == "true"
 }
 func (s *StagingManager) Poll() []error {
 	ctx, span := observability.StartSpan("Staging Manager", context.Background(), &map[string]string{
@@ -128,7 +142,7 @@ func (s *StagingManager) Reconcil() []error {
 }
 
 func (s *StagingManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event) error {
-	_, span := observability.StartSpan("Staging Manager", ctx, &map[string]string{
+	ctx, span := observability.StartSpan("Staging Manager", ctx, &map[string]string{
 		"method": "HandleJobEvent",
 	})
 	var err error = nil
@@ -141,6 +155,10 @@ func (s *StagingManager) HandleJobEvent(ctx context.Context, event v1alpha2.Even
 		err = v1alpha2.NewCOAError(nil, "event body is not a job", v1alpha2.BadRequest)
 		return err
 	}
+	if job.Metadata == nil {
+		job.Metadata = make(map[string]string)
+	}
+	observ_utils.PropagateSpanContextToMetadata(ctx, job.Metadata)
 	s.QueueProvider.Enqueue(Site_Job_Queue, event.Metadata["site"])
 	return s.QueueProvider.Enqueue(event.Metadata["site"], job)
 }
@@ -9,13 +9,17 @@ package staging
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	memoryqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
@@ -104,6 +108,33 @@ func TestHandleJobEvent(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, "fake", site.(string))
 }
+func TestHandleJobEventWithTraceContext(t *testing.T) {
+	queueProvider := &memoryqueue.MemoryQueueProvider{}
+	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
+
+	manager := StagingManager{
+		QueueProvider: queueProvider,
+	}
+	ctx := observ_utils.SpanContextFromMetadata(context.Background(), map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	err := manager.HandleJobEvent(ctx, v1alpha2.Event{
+		Metadata: map[string]string{
+			"site": "child",
+		},
+		Body: v1alpha2.JobData{
+			Id:     "task",
+			Action: "RUN",
+		},
+	})
+	assert.Nil(t, err)
+
+	jobs, err := manager.GetABatchForSite("child", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+	// the job carries the trace to the child site
+	assert.True(t, strings.HasPrefix(jobs[0].Metadata["traceparent"], "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+}
 func TestGetABatchForSite(t *testing.T) {
 	queueProvider := &memoryqueue.MemoryQueueProvider{}
 	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
@@ -175,3 +206,46 @@ func InitializeMockSymphonyAPI() *httptest.Server {
 	}))
 	return ts
 }
+
+func TestReconfigureWhileEnabledIsRead(t *testing.T) {
+	manager := StagingManager{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			manager.Enabled()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		manager.Reconfigure(managers.ManagerConfig{Properties: map[string]string{"poll.enabled": "true"}})
+	}
+	<-done
+	assert.True(t, manager.Enabled())
+	manager.Reconfigure(managers.ManagerConfig{Properties: map[string]string{"poll.enabled": "false"}})
+	assert.False(t, manager.Enabled())
+}
+
+type unreachableStateProvider struct {
+	memorystate.MemoryStateProvider
+}
+
+func (p *unreachableStateProvider) CheckHealth(ctx context.Context) error {
+	return errors.New("state store is unreachable")
+}
+
+func TestCheckHealth(t *testing.T) {
+	queueProvider := &memoryqueue.MemoryQueueProvider{}
+	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StagingManager{
+		StateProvider: stateProvider,
+		QueueProvider: queueProvider,
+	}
+	assert.Nil(t, manager.CheckHealth(context.Background()))
+
+	manager.StateProvider = &unreachableStateProvider{}
+	err := manager.CheckHealth(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, "state store is unreachable", err.Error())
+}
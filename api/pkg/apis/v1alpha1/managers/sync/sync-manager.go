@@ -32,8 +32,17 @@ func (s *SyncManager) Init(context *contexts.VendorContext, config managers.Mana
 	}
 	return nil
 }
+
+// Reconfigure lets sync.enabled be turned on and off without a restart
+func (s *SyncManager) Reconfigure(config managers.ManagerConfig) error {
+	if err := s.CheckProviderProperties(config.Properties); err != nil {
+		return err
+	}
+	s.SetProperties(config.Properties)
+	return nil
+}
 func (s *SyncManager) Enabled() bool {
-	return s.Config.Properties["sync.enabled"] == "true"
+	return s.Properties()["sync.enabled"] == "true"
 }
 func (s *SyncManager) Poll() []error {
 	ctx, span := observability.StartSpan("Sync Manager", context.Background(), &map[string]string{
@@ -69,11 +78,14 @@ func (s *SyncManager) Poll() []error {
 	}
 	if batch.Jobs != nil {
 		for _, job := range batch.Jobs {
+			metadata := map[string]string{
+				"origin": batch.Origin,
+			}
+			// jobs continue the traces started on the parent site
+			observ_utils.PropagateSpanContextToMetadata(observ_utils.SpanContextFromMetadata(ctx, job.Metadata), metadata)
 			s.Context.Publish("remote-job", v1alpha2.Event{
-				Metadata: map[string]string{
-					"origin": batch.Origin,
-				},
-				Body: job,
+				Metadata: metadata,
+				Body:     job,
 			})
 		}
 	}
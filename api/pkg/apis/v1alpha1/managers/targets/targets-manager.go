@@ -43,6 +43,11 @@ func (s *TargetsManager) Init(context *contexts.VendorContext, config managers.M
 	return nil
 }
 
+// CheckHealth checks that the state provider is reachable
+func (s *TargetsManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
+
 func (t *TargetsManager) DeleteSpec(ctx context.Context, name string, scope string) error {
 	ctx, span := observability.StartSpan("Targets Manager", ctx, &map[string]string{
 		"method": "DeleteSpec",
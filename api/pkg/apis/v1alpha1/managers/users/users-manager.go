@@ -43,6 +43,11 @@ func (s *UsersManager) Init(context *contexts.VendorContext, config managers.Man
 
 	return nil
 }
+
+// CheckHealth checks that the state provider is reachable
+func (s *UsersManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, s.StateProvider)
+}
 func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "DeleteUser",
@@ -33,6 +33,43 @@ type StageSpec struct {
 	Inputs        map[string]interface{} `json:"inputs,omitempty"`
 	HandleErrors  bool                   `json:"handleErrors,omitempty"`
 	Schedule      *v1alpha2.ScheduleSpec `json:"schedule,omitempty"`
+	// Timeout bounds the time a stage (including retries) can run, such as "10m"
+	Timeout string     `json:"timeout,omitempty"`
+	Retry   *RetrySpec `json:"retry,omitempty"`
+	// OnTimeout and OnFailure name the stage to run when this stage times out or fails, instead
+	// of the stage picked by StageSelector
+	OnTimeout string `json:"onTimeout,omitempty"`
+	OnFailure string `json:"onFailure,omitempty"`
+	// MaxParallelism limits the number of sites from Contexts that are processed at the same time
+	MaxParallelism int `json:"maxParallelism,omitempty"`
+	// SuccessThreshold is the number (such as "3") or percentage (such as "80%") of sites from
+	// Contexts that need to succeed for the stage to succeed. All sites need to succeed by default.
+	SuccessThreshold string `json:"successThreshold,omitempty"`
+	// ForEach runs the stage provider once for each item of a list
+	ForEach *ForEachSpec `json:"foreach,omitempty"`
+}
+
+// ForEachSpec describes how a stage iterates over a list
+type ForEachSpec struct {
+	// Items is an expression that yields the list, such as "${{$output(list,items)}}"
+	Items string `json:"items"`
+	// ItemName is the input that holds the current item, "item" by default. The index of the
+	// item is available as the "__index" input.
+	ItemName string `json:"itemName,omitempty"`
+	// Parallel runs the items at the same time instead of one after the other
+	Parallel bool `json:"parallel,omitempty"`
+	// MaxParallelism limits the number of items processed at the same time when Parallel is set
+	MaxParallelism int `json:"maxParallelism,omitempty"`
+}
+
+// RetrySpec describes how a failed stage is retried
+type RetrySpec struct {
+	// Attempts is the total number of attempts, including the first one
+	Attempts int `json:"attempts,omitempty"`
+	// Backoff is the delay before the first retry, such as "5s". The delay doubles after each retry.
+	Backoff string `json:"backoff,omitempty"`
+	// MaxBackoff caps the delay between retries
+	MaxBackoff string `json:"maxBackoff,omitempty"`
 }
 
 func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -65,6 +102,38 @@ func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if s.HandleErrors != otherS.HandleErrors {
+		return false, nil
+	}
+
+	if s.Timeout != otherS.Timeout {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(s.Retry, otherS.Retry) {
+		return false, nil
+	}
+
+	if s.OnTimeout != otherS.OnTimeout {
+		return false, nil
+	}
+
+	if s.OnFailure != otherS.OnFailure {
+		return false, nil
+	}
+
+	if s.MaxParallelism != otherS.MaxParallelism {
+		return false, nil
+	}
+
+	if s.SuccessThreshold != otherS.SuccessThreshold {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(s.ForEach, otherS.ForEach) {
+		return false, nil
+	}
+
 	return true, nil
 }
 
@@ -78,6 +147,49 @@ type ActivationStatus struct {
 	IsActive             bool                   `json:"isActive,omitempty"`
 	ActivationGeneration string                 `json:"activationGeneration,omitempty"`
 	UpdateTime           string                 `json:"updateTime,omitempty"`
+	// ParentActivation and ChildActivations link activations started by a sub-campaign stage
+	// with the activation that started them
+	ParentActivation string   `json:"parentActivation,omitempty"`
+	ChildActivations []string `json:"childActivations,omitempty"`
+	// History records the stages the activation has run, oldest first
+	History []StageHistory `json:"history,omitempty"`
+}
+
+// IsFinished tells if an activation has reached a final state
+func (s ActivationStatus) IsFinished() bool {
+	switch s.Status {
+	case 0, v1alpha2.Untouched, v1alpha2.Running, v1alpha2.Paused, v1alpha2.Delayed:
+		return false
+	}
+	return !s.IsActive
+}
+
+// StageHistory records one run of a stage of an activation. EndTime is empty while the stage is
+// running, paused or waiting for its schedule.
+type StageHistory struct {
+	Stage        string                 `json:"stage"`
+	NextStage    string                 `json:"nextStage,omitempty"`
+	Site         string                 `json:"site,omitempty"`
+	StartTime    string                 `json:"startTime,omitempty"`
+	EndTime      string                 `json:"endTime,omitempty"`
+	Status       v1alpha2.State         `json:"status,omitempty"`
+	Outputs      map[string]interface{} `json:"outputs,omitempty"`
+	ErrorMessage string                 `json:"errorMessage,omitempty"`
+}
+
+// ActivationHistory is a page of the history of an activation
+type ActivationHistory struct {
+	Activation string         `json:"activation"`
+	Total      int            `json:"total"`
+	Offset     int            `json:"offset"`
+	Items      []StageHistory `json:"items"`
+}
+
+// ApprovalDecision is the body of a request that approves or rejects a paused approval stage
+type ApprovalDecision struct {
+	// Approver is used when the caller isn't identified by the HTTP binding
+	Approver string `json:"approver,omitempty"`
+	Comment  string `json:"comment,omitempty"`
 }
 
 type ActivationSpec struct {
@@ -86,6 +198,8 @@ type ActivationSpec struct {
 	Stage      string                 `json:"stage,omitempty"`
 	Inputs     map[string]interface{} `json:"inputs,omitempty"`
 	Generation string                 `json:"generation,omitempty"`
+	// Parent is the activation that started this activation, if any
+	Parent string `json:"parent,omitempty"`
 }
 
 func (c ActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -110,6 +224,10 @@ func (c ActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if c.Parent != otherC.Parent {
+		return false, nil
+	}
+
 	return true, nil
 }
 
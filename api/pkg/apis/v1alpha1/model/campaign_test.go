@@ -9,6 +9,7 @@ package model
 import (
 	"testing"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,3 +45,11 @@ func TestCampaignRoleNotMatch(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, equal)
 }
+
+func TestActivationStatusIsFinished(t *testing.T) {
+	assert.False(t, ActivationStatus{Status: v1alpha2.Running, IsActive: true}.IsFinished())
+	assert.False(t, ActivationStatus{Status: v1alpha2.Paused}.IsFinished())
+	assert.True(t, ActivationStatus{Status: v1alpha2.Done}.IsFinished())
+	assert.True(t, ActivationStatus{Status: v1alpha2.InternalError}.IsFinished())
+	assert.True(t, ActivationStatus{Status: v1alpha2.Cancelled}.IsFinished())
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// Topics of the events Symphony publishes for external systems
+const (
+	DeploymentEventTopic = "deployment"
+	ActivationEventTopic = "activation-stage"
+)
+
+// CloudEvents types of the events Symphony publishes for external systems
+const (
+	DeploymentFinishedEventType     = "org.eclipse.symphony.deployment.finished"
+	DeploymentFailedEventType       = "org.eclipse.symphony.deployment.failed"
+	DeploymentRemovedEventType      = "org.eclipse.symphony.deployment.removed"
+	DeploymentDriftEventType        = "org.eclipse.symphony.deployment.drift"
+	ActivationStageChangedEventType = "org.eclipse.symphony.activation.stagechanged"
+)
+
+// DeploymentEventData is the data of the deployment events
+type DeploymentEventData struct {
+	Instance   string      `json:"instance"`
+	Solution   string      `json:"solution,omitempty"`
+	Scope      string      `json:"scope,omitempty"`
+	Generation string      `json:"generation,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Summary    SummarySpec `json:"summary"`
+}
+
+// ActivationEventData is the data of the activation events
+type ActivationEventData struct {
+	Activation   string `json:"activation"`
+	Campaign     string `json:"campaign,omitempty"`
+	Stage        string `json:"stage"`
+	NextStage    string `json:"nextStage,omitempty"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	IsActive     bool   `json:"isActive"`
+}
+
+// NewDeploymentEvents returns the events of a reconciled deployment: the deployment finished, failed or
+// was removed, and the deployment drifted when the targets had to be changed although the deployment
+// didn't change
+func NewDeploymentEvents(deployment DeploymentSpec, summary SummarySpec, scope string, err error) []v1alpha2.Event {
+	data := DeploymentEventData{
+		Instance:   deployment.Instance.Name,
+		Solution:   deployment.SolutionName,
+		Scope:      scope,
+		Generation: deployment.Generation,
+		Summary:    summary,
+	}
+	eventType := DeploymentFinishedEventType
+	if err != nil {
+		eventType = DeploymentFailedEventType
+		data.Error = err.Error()
+	} else if summary.IsRemoval {
+		eventType = DeploymentRemovedEventType
+	}
+	ret := []v1alpha2.Event{newEvent(eventType, deployment.Instance.Name, scope, data)}
+	if summary.IsDrift {
+		ret = append(ret, newEvent(DeploymentDriftEventType, deployment.Instance.Name, scope, data))
+	}
+	return ret
+}
+
+// NewActivationStageEvent returns the event of an activation that moved to another stage or status
+func NewActivationStageEvent(activation string, campaign string, status ActivationStatus) v1alpha2.Event {
+	return newEvent(ActivationStageChangedEventType, activation, "", ActivationEventData{
+		Activation:   activation,
+		Campaign:     campaign,
+		Stage:        status.Stage,
+		NextStage:    status.NextStage,
+		Status:       status.Status.String(),
+		ErrorMessage: status.ErrorMessage,
+		IsActive:     status.IsActive,
+	})
+}
+
+func newEvent(eventType string, subject string, scope string, data interface{}) v1alpha2.Event {
+	metadata := v1alpha2.NewCloudEventMetadata(eventType, subject)
+	if scope != "" {
+		metadata["scope"] = scope
+	}
+	return v1alpha2.Event{
+		Metadata: metadata,
+		Body:     data,
+	}
+}
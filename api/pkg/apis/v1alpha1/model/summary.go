@@ -28,6 +28,8 @@ type SummarySpec struct {
 	SummaryMessage string                      `json:"message,omitempty"`
 	Skipped        bool                        `json:"skipped"`
 	IsRemoval      bool                        `json:"isRemoval"`
+	// IsDrift is set when the targets had to be changed although the deployment didn't change
+	IsDrift bool `json:"isDrift,omitempty"`
 }
 type SummaryResult struct {
 	Summary    SummarySpec `json:"summary"`
@@ -8,10 +8,12 @@ package providers
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	catalogconfig "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/config/catalog"
 	memorygraph "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph/memory"
+	approvalstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
 	counterstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/counter"
 	symphonystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/create"
 	delaystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/delay"
@@ -19,14 +21,18 @@ import (
 	liststage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/list"
 	materialize "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/materialize"
 	mockstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/mock"
+	notifystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/notify"
 	patchstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/patch"
 	remotestage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/remote"
 	scriptstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/script"
+	starlarkstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/starlark"
+	subcampaignstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/subcampaign"
 	waitstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/wait"
 	k8sstate "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/states/k8s"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/adb"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/azure/adu"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/azure/iotedge"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/compose"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/configmap"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/docker"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/helm"
@@ -39,6 +45,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/proxy"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/script"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/staging"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/systemd"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/win10/sideload"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -47,6 +54,7 @@ import (
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/probe/rtsp"
 	mempubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	natspubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/nats"
 	reidspubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/redis"
 	memoryqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/memory"
 	cvref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/customvision"
@@ -55,6 +63,8 @@ import (
 	httpreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/http"
 	k8sreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/k8s"
 	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
+	httpsink "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/sink/http"
+	mqttsink "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/sink/mqtt"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/httpstate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/uploader/azure/blob"
@@ -82,8 +92,46 @@ func (c SymphonyProviderFactory) CreateProviders(config vendors.VendorConfig) (m
 	return ret, nil
 }
 
+// stageProviders creates the stage providers by type. It's also the list of providers campaign stages
+// can use, which the campaign validator checks stages against.
+var stageProviders = map[string]func() cp.IProvider{
+	"providers.stage.approval":    func() cp.IProvider { return &approvalstage.ApprovalStageProvider{} },
+	"providers.stage.counter":     func() cp.IProvider { return &counterstage.CounterStageProvider{} },
+	"providers.stage.create":      func() cp.IProvider { return &symphonystage.CreateStageProvider{} },
+	"providers.stage.delay":       func() cp.IProvider { return &delaystage.DelayStageProvider{} },
+	"providers.stage.http":        func() cp.IProvider { return &httpstage.HttpStageProvider{} },
+	"providers.stage.list":        func() cp.IProvider { return &liststage.ListStageProvider{} },
+	"providers.stage.materialize": func() cp.IProvider { return &materialize.MaterializeStageProvider{} },
+	"providers.stage.mock":        func() cp.IProvider { return &mockstage.MockStageProvider{} },
+	"providers.stage.notify":      func() cp.IProvider { return &notifystage.NotifyStageProvider{} },
+	"providers.stage.patch":       func() cp.IProvider { return &patchstage.PatchStageProvider{} },
+	"providers.stage.remote":      func() cp.IProvider { return &remotestage.RemoteStageProvider{} },
+	"providers.stage.script":      func() cp.IProvider { return &scriptstage.ScriptStageProvider{} },
+	"providers.stage.starlark":    func() cp.IProvider { return &starlarkstage.StarlarkStageProvider{} },
+	"providers.stage.subcampaign": func() cp.IProvider { return &subcampaignstage.SubCampaignStageProvider{} },
+	"providers.stage.wait":        func() cp.IProvider { return &waitstage.WaitStageProvider{} },
+}
+
+// StageProviderTypes returns the types of the stage providers the factory creates, sorted by name
+func StageProviderTypes() []string {
+	ret := make([]string, 0, len(stageProviders))
+	for k := range stageProviders {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
 func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.IProviderConfig) (cp.IProvider, error) {
 	var err error
+	if create, ok := stageProviders[providerType]; ok {
+		mProvider := create()
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+		return nil, err
+	}
 	switch providerType {
 	case "providers.state.memory":
 		mProvider := &memorystate.MemoryStateProvider{}
@@ -157,12 +205,6 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
-	case "providers.stage.counter":
-		mProvider := &counterstage.CounterStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
 	case "providers.target.azure.iotedge":
 		mProvider := &iotedge.IoTEdgeTargetProvider{}
 		err = mProvider.Init(config)
@@ -187,6 +229,18 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
+	case "providers.target.compose":
+		mProvider := &compose.ComposeTargetProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.target.systemd":
+		mProvider := &systemd.SystemdTargetProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
 	case "providers.target.ingress":
 		mProvider := &ingress.IngressTargetProvider{}
 		err = mProvider.Init(config)
@@ -283,62 +337,20 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
-	case "providers.stage.mock":
-		mProvider := &mockstage.MockStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.http":
-		mProvider := &httpstage.HttpStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.create":
-		mProvider := &symphonystage.CreateStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.script":
-		mProvider := &scriptstage.ScriptStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.patch":
-		mProvider := &patchstage.PatchStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.list":
-		mProvider := &liststage.ListStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.remote":
-		mProvider := &remotestage.RemoteStageProvider{}
+	case "providers.pubsub.nats":
+		mProvider := &natspubsub.NatsPubSubProvider{}
 		err = mProvider.Init(config)
 		if err == nil {
 			return mProvider, nil
 		}
-	case "providers.stage.wait":
-		mProvider := &waitstage.WaitStageProvider{}
+	case "providers.sink.http":
+		mProvider := &httpsink.HttpSinkProvider{}
 		err = mProvider.Init(config)
 		if err == nil {
 			return mProvider, nil
 		}
-	case "providers.stage.delay":
-		mProvider := &delaystage.DelayStageProvider{}
-		err = mProvider.Init(config)
-		if err == nil {
-			return mProvider, nil
-		}
-	case "providers.stage.materialize":
-		mProvider := &materialize.MaterializeStageProvider{}
+	case "providers.sink.mqtt":
+		mProvider := &mqttsink.MqttSinkProvider{}
 		err = mProvider.Init(config)
 		if err == nil {
 			return mProvider, nil
@@ -400,6 +412,22 @@ func CreateProviderForTargetRole(context *contexts.ManagerContext, role string,
 					}
 					provider.Context = context
 					return provider, nil
+				case "providers.target.compose":
+					provider := &compose.ComposeTargetProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.target.systemd":
+					provider := &systemd.SystemdTargetProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
 				case "providers.target.ingress":
 					provider := &ingress.IngressTargetProvider{}
 					err := provider.InitWithMap(binding.Config)
@@ -693,6 +721,38 @@ func CreateProviderForTargetRole(context *contexts.ManagerContext, role string,
 					}
 					provider.Context = context
 					return provider, nil
+				case "providers.stage.approval":
+					provider := &approvalstage.ApprovalStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.stage.subcampaign":
+					provider := &subcampaignstage.SubCampaignStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.stage.starlark":
+					provider := &starlarkstage.StarlarkStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.stage.notify":
+					provider := &notifystage.NotifyStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
 				case "providers.queue.memory":
 					provider := &memoryqueue.MemoryQueueProvider{}
 					err := provider.InitWithMap(binding.Config)
@@ -725,6 +785,14 @@ func CreateProviderForTargetRole(context *contexts.ManagerContext, role string,
 					}
 					provider.Context = context
 					return provider, nil
+				case "providers.pubsub.nats":
+					provider := &natspubsub.NatsPubSubProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
 				}
 
 			}
@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var approvalLock sync.Mutex
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// Stage outputs that describe the approval. "decision" is "pending" until the approval is
+	// resolved, after which it's "approved", "rejected" or "expired".
+	DecisionOutput     = "decision"
+	ApproverOutput     = "approver"
+	CommentOutput      = "comment"
+	DecisionTimeOutput = "decisionTime"
+	MessageOutput      = "message"
+	ApproversOutput    = "approvers"
+	RolesOutput        = "roles"
+	RequestTimeOutput  = "requestTime"
+	ExpiryOutput       = "expiresAt"
+
+	DecisionPending  = "pending"
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+	DecisionExpired  = "expired"
+)
+
+type ApprovalStageProviderConfig struct {
+	// DefaultExpiry is used when the stage doesn't set an "expiry" input, such as "72h". Approvals
+	// don't expire when neither is set.
+	DefaultExpiry string `json:"defaultExpiry,omitempty"`
+}
+
+// ApprovalStageProvider pauses an activation until the pending approval it records in the stage
+// outputs is approved or rejected through the activations API
+type ApprovalStageProvider struct {
+	Config  ApprovalStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (m *ApprovalStageProvider) Init(config providers.IProviderConfig) error {
+	approvalLock.Lock()
+	defer approvalLock.Unlock()
+
+	approvalConfig, err := toApprovalStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	if approvalConfig.DefaultExpiry != "" {
+		if _, err := time.ParseDuration(approvalConfig.DefaultExpiry); err != nil {
+			return v1alpha2.NewCOAError(err, "'defaultExpiry' is not a valid duration in approval provider config", v1alpha2.BadConfig)
+		}
+	}
+	m.Config = approvalConfig
+	return nil
+}
+func (s *ApprovalStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toApprovalStageProviderConfig(config providers.IProviderConfig) (ApprovalStageProviderConfig, error) {
+	ret := ApprovalStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+func (i *ApprovalStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := ApprovalStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func ApprovalStageProviderConfigFromMap(properties map[string]string) (ApprovalStageProviderConfig, error) {
+	ret := ApprovalStageProviderConfig{}
+	ret.DefaultExpiry = properties["defaultExpiry"]
+	return ret, nil
+}
+
+// Process records a pending approval in the stage outputs and pauses the activation. Supported
+// inputs are "approvers" and "roles" (lists or comma-separated strings), "message" and "expiry".
+func (i *ApprovalStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	_, span := observability.StartSpan("[Stage] Approval provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Info("  P (Approval Stage): Process")
+
+	now := time.Now().UTC()
+	outputs := make(map[string]interface{})
+	outputs[DecisionOutput] = DecisionPending
+	outputs[ApproversOutput] = readList(inputs, "approvers")
+	outputs[RolesOutput] = readList(inputs, "roles")
+	outputs[MessageOutput] = readString(inputs, "message")
+	outputs[RequestTimeOutput] = now.Format(time.RFC3339)
+
+	expiry := readString(inputs, "expiry")
+	if expiry == "" {
+		expiry = i.Config.DefaultExpiry
+	}
+	if expiry != "" {
+		var duration time.Duration
+		duration, err = time.ParseDuration(expiry)
+		if err != nil {
+			err = v1alpha2.NewCOAError(err, fmt.Sprintf("invalid approval expiry '%s'", expiry), v1alpha2.BadRequest)
+			log.Errorf("  P (Approval Stage): %v", err)
+			return nil, false, err
+		}
+		outputs[ExpiryOutput] = now.Add(duration).Format(time.RFC3339)
+	}
+
+	log.Infof("  P (Approval Stage): waiting for approval of stage %v", inputs["__stage"])
+	return outputs, true, nil
+}
+
+// Approver is who decides on an approval. Authenticated is set when the name and roles are those of
+// the caller identified by the JWT middleware, rather than a name given in the request body.
+type Approver struct {
+	Name          string
+	Roles         []string
+	Authenticated bool
+}
+
+// Resolve records an approval decision in the outputs of a paused approval stage. When approvers or
+// roles are listed, the approver needs to be authenticated and be one of the listed approvers or hold
+// one of the listed roles; anyone can decide when neither is listed. Approvals past their expiry are
+// marked as expired and an error is returned.
+func Resolve(outputs map[string]interface{}, approve bool, approver Approver, comment string, now time.Time) error {
+	if outputs == nil || outputs[DecisionOutput] != DecisionPending {
+		return v1alpha2.NewCOAError(nil, "activation doesn't have a pending approval", v1alpha2.BadRequest)
+	}
+	if Expire(outputs, now) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("approval expired at %v", outputs[ExpiryOutput]), v1alpha2.BadRequest)
+	}
+	if isRestricted(outputs) && !approver.Authenticated {
+		return v1alpha2.NewCOAError(nil, "this stage can only be approved by an authenticated approver", v1alpha2.Unauthorized)
+	}
+	if !isAllowed(outputs, approver.Name, approver.Roles) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' is not allowed to approve this stage", approver.Name), v1alpha2.Unauthorized)
+	}
+	if approve {
+		outputs[DecisionOutput] = DecisionApproved
+	} else {
+		outputs[DecisionOutput] = DecisionRejected
+	}
+	outputs[ApproverOutput] = approver.Name
+	outputs[CommentOutput] = comment
+	outputs[DecisionTimeOutput] = now.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// Expire marks a pending approval past its expiry as expired, and reports whether it did
+func Expire(outputs map[string]interface{}, now time.Time) bool {
+	if outputs == nil || outputs[DecisionOutput] != DecisionPending {
+		return false
+	}
+	v, ok := outputs[ExpiryOutput].(string)
+	if !ok || v == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, v)
+	if err != nil || !now.After(expiresAt) {
+		return false
+	}
+	outputs[DecisionOutput] = DecisionExpired
+	outputs[DecisionTimeOutput] = now.UTC().Format(time.RFC3339)
+	return true
+}
+
+// isRestricted reports whether the approval lists who can decide on it
+func isRestricted(outputs map[string]interface{}) bool {
+	return len(readList(outputs, ApproversOutput)) > 0 || len(readList(outputs, RolesOutput)) > 0
+}
+
+func isAllowed(outputs map[string]interface{}, approver string, roles []string) bool {
+	if !isRestricted(outputs) {
+		return true
+	}
+	for _, a := range readList(outputs, ApproversOutput) {
+		if approver != "" && a == approver {
+			return true
+		}
+	}
+	for _, r := range readList(outputs, RolesOutput) {
+		for _, role := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func readString(inputs map[string]interface{}, key string) string {
+	if v, ok := inputs[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+func readList(inputs map[string]interface{}, key string) []string {
+	ret := make([]string, 0)
+	switch v := inputs[key].(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				ret = append(ret, s)
+			}
+		}
+	case []string:
+		ret = append(ret, v...)
+	case []interface{}:
+		for _, s := range v {
+			ret = append(ret, fmt.Sprintf("%v", s))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitWithMap(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.InitWithMap(map[string]string{"defaultExpiry": "72h"})
+	assert.Nil(t, err)
+	assert.Equal(t, "72h", provider.Config.DefaultExpiry)
+
+	err = provider.InitWithMap(map[string]string{"defaultExpiry": "soon"})
+	assert.NotNil(t, err)
+}
+
+func TestProcess(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.Init(ApprovalStageProviderConfig{})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approvers": "alice, bob",
+		"roles":     []interface{}{"release-manager"},
+		"message":   "Deploy to production?",
+		"expiry":    "1h",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	assert.Equal(t, DecisionPending, outputs[DecisionOutput])
+	assert.Equal(t, []string{"alice", "bob"}, outputs[ApproversOutput])
+	assert.Equal(t, []string{"release-manager"}, outputs[RolesOutput])
+	assert.Equal(t, "Deploy to production?", outputs[MessageOutput])
+	expiresAt, err := time.Parse(time.RFC3339, outputs[ExpiryOutput].(string))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+}
+
+func TestProcessDefaultExpiry(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.Init(ApprovalStageProviderConfig{DefaultExpiry: "2h"})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.Nil(t, err)
+	expiresAt, err := time.Parse(time.RFC3339, outputs[ExpiryOutput].(string))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), expiresAt, time.Minute)
+
+	provider.Init(ApprovalStageProviderConfig{})
+	outputs, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.Nil(t, err)
+	_, ok := outputs[ExpiryOutput]
+	assert.False(t, ok)
+}
+
+func TestProcessInvalidExpiry(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.Init(ApprovalStageProviderConfig{})
+	assert.Nil(t, err)
+	_, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"expiry": "tomorrow",
+	})
+	assert.NotNil(t, err)
+	assert.False(t, pause)
+}
+
+func pendingOutputs(approvers []string, roles []string) map[string]interface{} {
+	return map[string]interface{}{
+		DecisionOutput:  DecisionPending,
+		ApproversOutput: approvers,
+		RolesOutput:     roles,
+	}
+}
+
+func TestResolveApprove(t *testing.T) {
+	outputs := pendingOutputs([]string{"alice"}, nil)
+	err := Resolve(outputs, true, Approver{Name: "alice", Authenticated: true}, "looks good", time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, DecisionApproved, outputs[DecisionOutput])
+	assert.Equal(t, "alice", outputs[ApproverOutput])
+	assert.Equal(t, "looks good", outputs[CommentOutput])
+	assert.NotEmpty(t, outputs[DecisionTimeOutput])
+
+	// a decided approval can't be decided again
+	err = Resolve(outputs, false, Approver{Name: "alice", Authenticated: true}, "", time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, DecisionApproved, outputs[DecisionOutput])
+}
+
+func TestResolveReject(t *testing.T) {
+	outputs := pendingOutputs(nil, nil)
+	err := Resolve(outputs, false, Approver{Name: "carol"}, "not now", time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, DecisionRejected, outputs[DecisionOutput])
+	assert.Equal(t, "carol", outputs[ApproverOutput])
+}
+
+func TestResolveByRole(t *testing.T) {
+	// lists read back from a stored activation status are []interface{}
+	outputs := pendingOutputs(nil, nil)
+	outputs[ApproversOutput] = []interface{}{"alice"}
+	outputs[RolesOutput] = []interface{}{"release-manager"}
+	err := Resolve(outputs, true, Approver{Name: "dave", Roles: []string{"operator", "release-manager"}, Authenticated: true}, "", time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, DecisionApproved, outputs[DecisionOutput])
+}
+
+func TestResolveNotAllowed(t *testing.T) {
+	outputs := pendingOutputs([]string{"alice"}, []string{"release-manager"})
+	err := Resolve(outputs, true, Approver{Name: "mallory", Roles: []string{"operator"}, Authenticated: true}, "", time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	assert.Equal(t, DecisionPending, outputs[DecisionOutput])
+
+	// an unidentified caller isn't one of the approvers
+	err = Resolve(outputs, true, Approver{Authenticated: true}, "", time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestResolveNotAuthenticated(t *testing.T) {
+	// a name given in the request can't stand in for a listed approver
+	outputs := pendingOutputs([]string{"alice"}, nil)
+	err := Resolve(outputs, true, Approver{Name: "alice"}, "", time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	assert.Equal(t, DecisionPending, outputs[DecisionOutput])
+
+	outputs = pendingOutputs(nil, []string{"release-manager"})
+	err = Resolve(outputs, true, Approver{Roles: []string{"release-manager"}}, "", time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestExpire(t *testing.T) {
+	outputs := pendingOutputs(nil, nil)
+	assert.False(t, Expire(outputs, time.Now()))
+	outputs[ExpiryOutput] = time.Now().Add(time.Minute).Format(time.RFC3339)
+	assert.False(t, Expire(outputs, time.Now()))
+	assert.True(t, Expire(outputs, time.Now().Add(2*time.Minute)))
+	assert.Equal(t, DecisionExpired, outputs[DecisionOutput])
+	// a decided approval doesn't expire again
+	assert.False(t, Expire(outputs, time.Now().Add(2*time.Minute)))
+}
+
+func TestResolveExpired(t *testing.T) {
+	outputs := pendingOutputs(nil, nil)
+	outputs[ExpiryOutput] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	err := Resolve(outputs, true, Approver{Name: "alice"}, "", time.Now())
+	assert.NotNil(t, err)
+	assert.Equal(t, DecisionExpired, outputs[DecisionOutput])
+}
+
+func TestResolveNotPending(t *testing.T) {
+	err := Resolve(nil, true, Approver{Name: "alice"}, "", time.Now())
+	assert.NotNil(t, err)
+	err = Resolve(map[string]interface{}{"foo": "bar"}, true, Approver{Name: "alice"}, "", time.Now())
+	assert.NotNil(t, err)
+}
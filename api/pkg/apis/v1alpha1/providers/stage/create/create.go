@@ -159,7 +159,9 @@ func (i *CreateStageProvider) Process(ctx context.Context, mgrContext contexts.M
 				if summary.Summary.SuccessCount == summary.Summary.TargetCount {
 					break
 				}
-				time.Sleep(time.Duration(i.Config.WaitInterval) * time.Second)
+				if err = stage.Sleep(ctx, time.Duration(i.Config.WaitInterval)*time.Second); err != nil {
+					return nil, false, err
+				}
 			}
 			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("Instance creation failed: %s", lastSummaryMessage), v1alpha2.InternalError)
 			return nil, false, err
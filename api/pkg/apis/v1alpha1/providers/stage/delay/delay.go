@@ -14,6 +14,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -77,9 +79,9 @@ func (i *DelayStageProvider) Process(ctx context.Context, mgrContext contexts.Ma
 	outputs[v1alpha2.StatusOutput] = v1alpha2.OK
 
 	if v, ok := inputs["delay"]; ok {
+		var duration time.Duration
 		switch vs := v.(type) {
 		case string:
-			var duration time.Duration
 			duration, err = time.ParseDuration(vs)
 			if err != nil {
 				var vi int
@@ -90,13 +92,16 @@ func (i *DelayStageProvider) Process(ctx context.Context, mgrContext contexts.Ma
 					outputs[v1alpha2.ErrorOutput] = fmt.Sprintf("Failed to parse delay duration: %s", err.Error())
 				}
 			}
-			time.Sleep(duration)
 		case int:
-			time.Sleep(time.Duration(vs) * time.Second)
+			duration = time.Duration(vs) * time.Second
 		case int32:
-			time.Sleep(time.Duration(vs) * time.Second)
+			duration = time.Duration(vs) * time.Second
 		case int64:
-			time.Sleep(time.Duration(vs) * time.Second)
+			duration = time.Duration(vs) * time.Second
+		}
+		if sErr := stage.Sleep(ctx, duration); sErr != nil {
+			err = sErr
+			return nil, false, err
 		}
 	}
 
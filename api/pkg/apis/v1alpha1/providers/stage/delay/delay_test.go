@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package delay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	provider := DelayStageProvider{}
+	err := provider.Init(DelayStageProviderConfig{})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"delay": "10ms",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, outputs[v1alpha2.StatusOutput])
+}
+
+func TestDelayCancelled(t *testing.T) {
+	provider := DelayStageProvider{}
+	err := provider.Init(DelayStageProviderConfig{})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	_, _, err = provider.Process(ctx, contexts.ManagerContext{}, map[string]interface{}{
+		"delay": "1h",
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Less(t, time.Since(start), time.Minute)
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -173,7 +174,8 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 
 	sLog.Info("  P (Http Stage): start process request")
 
-	// Check all config fields for override in inputs
+	// Check all config fields for override in inputs. The overrides go to a copy of the config as
+	// the provider can process several sites at the same time.
 	var configMap map[string]interface{}
 	configJson, _ := json.Marshal(i.Config)
 	json.Unmarshal(configJson, &configMap)
@@ -188,15 +190,16 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 		sLog.Errorf("  P (Http Stage): failed to override config with input: %v", err)
 		return nil, false, err
 	}
-	err = json.Unmarshal(configJson, &i.Config)
+	config := HttpStageProviderConfig{}
+	err = json.Unmarshal(configJson, &config)
 	if err != nil {
 		sLog.Errorf("  P (Http Stage): failed to override config with input: %v", err)
 		return nil, false, err
 	}
 
-	sLog.Infof("  P (Http Stage): %v: %v", i.Config.Method, i.Config.Url)
+	sLog.Infof("  P (Http Stage): %v: %v", config.Method, config.Url)
 	webClient := &http.Client{}
-	req, err := http.NewRequest(fmt.Sprintf("%v", i.Config.Method), fmt.Sprintf("%v", i.Config.Url), nil)
+	req, err := http.NewRequestWithContext(ctx, fmt.Sprintf("%v", config.Method), fmt.Sprintf("%v", config.Url), nil)
 	if err != nil {
 		sLog.Errorf("  P (Http Stage): failed to create request: %v", err)
 		return nil, false, err
@@ -237,10 +240,10 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 	outputs["body"] = string(data) //TODO: probably not so good to assume string
 	outputs["status"] = resp.StatusCode
 
-	if i.Config.WaitUrl != "" {
+	if config.WaitUrl != "" {
 		okToWait := false
-		if len(i.Config.WaitStartCodes) > 0 {
-			for _, code := range i.Config.WaitStartCodes {
+		if len(config.WaitStartCodes) > 0 {
+			for _, code := range config.WaitStartCodes {
 				if code == resp.StatusCode {
 					okToWait = true
 					break
@@ -253,20 +256,25 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 		counter := 0
 		failed := false
 		succeeded := false
-		sLog.Debugf("  P (Http Stage): WaitCount: %d", i.Config.WaitCount)
-		for counter < i.Config.WaitCount || i.Config.WaitCount == 0 {
+		sLog.Debugf("  P (Http Stage): WaitCount: %d", config.WaitCount)
+		for counter < config.WaitCount || config.WaitCount == 0 {
+			// a wait without a count only ends with the stage
+			if err = ctx.Err(); err != nil {
+				sLog.Errorf("  P (Http Stage): stopped waiting: %v", err)
+				return nil, false, err
+			}
 			sLog.Infof("  P (Http Stage): start wait iteration %d", counter)
 			var waitReq *http.Request
-			waitReq, err = http.NewRequest("GET", i.Config.WaitUrl, nil)
+			waitReq, err = http.NewRequestWithContext(ctx, "GET", config.WaitUrl, nil)
+			if err != nil {
+				sLog.Errorf("  P (Http Stage): failed to create wait request: %v", err)
+				return nil, false, err
+			}
 			for key, input := range inputs {
 				if strings.HasPrefix(key, "header.") {
 					waitReq.Header.Add(key[7:], fmt.Sprintf("%v", input))
 				}
 			}
-			if err != nil {
-				sLog.Errorf("  P (Http Stage): failed to create wait request: %v", err)
-				return nil, false, err
-			}
 			var waitResp *http.Response
 			waitResp, err = webClient.Do(waitReq)
 			if err != nil {
@@ -274,16 +282,16 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 				return nil, false, err
 			}
 			defer waitResp.Body.Close()
-			if len(i.Config.WaitFailedCodes) > 0 {
-				for _, code := range i.Config.WaitFailedCodes {
+			if len(config.WaitFailedCodes) > 0 {
+				for _, code := range config.WaitFailedCodes {
 					if code == waitResp.StatusCode {
 						failed = true
 						break
 					}
 				}
 			}
-			if len(i.Config.WaitSuccessCodes) > 0 {
-				for _, code := range i.Config.WaitSuccessCodes {
+			if len(config.WaitSuccessCodes) > 0 {
+				for _, code := range config.WaitSuccessCodes {
 					if code == waitResp.StatusCode {
 						succeeded = true
 						break
@@ -297,24 +305,24 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 					sLog.Errorf("  P (Http Stage): failed to read wait request response: %v", err)
 					succeeded = false
 				} else {
-					if i.Config.WaitExpression != "" {
+					if config.WaitExpression != "" {
 						var obj interface{}
 						err = json.Unmarshal(data, &obj)
 						if err != nil {
 							sLog.Errorf("  P (Http Stage): wait response could not be decoded to json: %v", err)
 							succeeded = false
 						} else {
-							switch i.Config.WaitExpressionType {
+							switch config.WaitExpressionType {
 							case "jsonpath":
 								var result interface{}
-								result, err = utils.JsonPathQuery(obj, i.Config.WaitExpression)
+								result, err = utils.JsonPathQuery(obj, config.WaitExpression)
 								if err != nil {
 									sLog.Errorf("  P (Http Stage): failed to evaluate JsonPath: %v", err)
 								}
 								succeeded = err == nil
 								outputs["waitResult"] = result
 							default:
-								parser := utils.NewParser(i.Config.WaitExpression)
+								parser := utils.NewParser(config.WaitExpression)
 								var val interface{}
 								val, err = parser.Eval(coa_utils.EvaluationContext{
 									Value: obj,
@@ -334,9 +342,12 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 			}
 			if !failed && !succeeded {
 				counter++
-				if i.Config.WaitInterval > 0 {
+				if config.WaitInterval > 0 {
 					sLog.Debug("  P (Http Stage): sleep for wait interval")
-					time.Sleep(time.Duration(i.Config.WaitInterval) * time.Second)
+					if err = stage.Sleep(ctx, time.Duration(config.WaitInterval)*time.Second); err != nil {
+						sLog.Errorf("  P (Http Stage): stopped waiting: %v", err)
+						return nil, false, err
+					}
 				}
 			} else {
 				break
@@ -346,8 +357,8 @@ func (i *HttpStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 			return nil, false, v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to wait for operation %v", resp.StatusCode), v1alpha2.BadConfig)
 		}
 
-	} else if len(i.Config.SuccessCodes) > 0 {
-		for _, code := range i.Config.SuccessCodes {
+	} else if len(config.SuccessCodes) > 0 {
+		for _, code := range config.SuccessCodes {
 			if code == resp.StatusCode {
 				return outputs, false, nil
 			}
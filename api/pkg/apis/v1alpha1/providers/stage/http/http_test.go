@@ -8,8 +8,11 @@ package http
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/stretchr/testify/assert"
@@ -211,3 +214,33 @@ func TestFakeAPIWithSymphonyExpression(t *testing.T) {
 	assert.Equal(t, 200, outputs["status"])
 	assert.Equal(t, true, outputs["waitResult"])
 }
+
+func TestWaitStopsWhenCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	provider := HttpStageProvider{}
+	err := provider.Init(HttpStageProviderConfig{
+		Method:           "GET",
+		Url:              server.URL,
+		WaitStartCodes:   []int{http.StatusAccepted},
+		WaitUrl:          server.URL,
+		WaitSuccessCodes: []int{http.StatusOK},
+	})
+	assert.Nil(t, err)
+	// a wait without a count only ends when the stage does
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := provider.Process(ctx, contexts.ManagerContext{}, nil)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "wait didn't stop when the context was done")
+	}
+}
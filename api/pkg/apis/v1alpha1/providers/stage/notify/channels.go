@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+)
+
+// Notification is the message the notify stage provider sends
+type Notification struct {
+	Subject    string      `json:"subject,omitempty"`
+	Message    string      `json:"message"`
+	Severity   string      `json:"severity,omitempty"`
+	Campaign   string      `json:"campaign,omitempty"`
+	Activation string      `json:"activation,omitempty"`
+	Stage      string      `json:"stage,omitempty"`
+	Site       string      `json:"site,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// INotificationChannel delivers notifications. Send returns channel specific results, which are
+// added to the stage outputs with the channel name as prefix.
+type INotificationChannel interface {
+	Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error)
+}
+
+var channelLock sync.RWMutex
+var channels = map[string]INotificationChannel{
+	"webhook": &WebhookChannel{},
+	"teams":   &TeamsChannel{},
+	"slack":   &SlackChannel{},
+	"email":   &EmailChannel{},
+	"pubsub":  &PubSubChannel{},
+}
+
+// RegisterChannel adds a notification channel, or replaces the channel with the same name
+func RegisterChannel(name string, channel INotificationChannel) {
+	channelLock.Lock()
+	defer channelLock.Unlock()
+	channels[name] = channel
+}
+
+func getChannel(name string) (INotificationChannel, bool) {
+	channelLock.RLock()
+	defer channelLock.RUnlock()
+	channel, ok := channels[name]
+	return channel, ok
+}
+
+// WebhookChannel posts the notification as JSON to webhook.url
+type WebhookChannel struct{}
+
+func (c *WebhookChannel) Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error) {
+	return postJson(ctx, config.WebhookUrl, "webhook.url", notification)
+}
+
+// TeamsChannel posts the notification as a message card to a Microsoft Teams incoming webhook at teams.url
+type TeamsChannel struct{}
+
+func (c *TeamsChannel) Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error) {
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    summary(notification),
+		"themeColor": themeColor(notification.Severity),
+		"text":       notification.Message,
+	}
+	if notification.Subject != "" {
+		card["title"] = notification.Subject
+	}
+	return postJson(ctx, config.TeamsUrl, "teams.url", card)
+}
+
+// SlackChannel posts the notification to a Slack-compatible incoming webhook at slack.url
+type SlackChannel struct{}
+
+func (c *SlackChannel) Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error) {
+	text := notification.Message
+	if notification.Subject != "" {
+		text = fmt.Sprintf("*%s*\n%s", notification.Subject, notification.Message)
+	}
+	return postJson(ctx, config.SlackUrl, "slack.url", map[string]interface{}{
+		"text": text,
+	})
+}
+
+// EmailChannel sends the notification by email through the SMTP server at email.host. STARTTLS is used
+// when the server supports it.
+type EmailChannel struct{}
+
+func (c *EmailChannel) Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error) {
+	if config.SmtpHost == "" {
+		return nil, v1alpha2.NewCOAError(nil, "email.host is required", v1alpha2.BadConfig)
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, v1alpha2.NewCOAError(nil, "email.from and email.to are required", v1alpha2.BadConfig)
+	}
+	port := config.SmtpPort
+	if port == 0 {
+		port = 25
+	}
+	address := net.JoinHostPort(config.SmtpHost, strconv.Itoa(port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, config.SmtpHost)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: config.SmtpHost}); err != nil {
+			return nil, err
+		}
+	}
+	if config.SmtpUser != "" {
+		if err = client.Auth(smtp.PlainAuth("", config.SmtpUser, config.SmtpPassword, config.SmtpHost)); err != nil {
+			return nil, err
+		}
+	}
+	if err = client.Mail(config.From); err != nil {
+		return nil, err
+	}
+	for _, to := range config.To {
+		if err = client.Rcpt(to); err != nil {
+			return nil, err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", config.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(config.To, ", "))
+	// a subject with line breaks could add headers
+	fmt.Fprintf(&body, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(summary(notification)))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(notification.Message, "\n", "\r\n"))
+	body.WriteString("\r\n")
+	if _, err = writer.Write(body.Bytes()); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"recipients": len(config.To),
+	}, client.Quit()
+}
+
+// PubSubChannel publishes the notification to pubsub.topic
+type PubSubChannel struct{}
+
+func (c *PubSubChannel) Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error) {
+	if config.Topic == "" {
+		return nil, v1alpha2.NewCOAError(nil, "pubsub.topic is required", v1alpha2.BadConfig)
+	}
+	if mgrContext.PubsubProvider == nil {
+		return nil, v1alpha2.NewCOAError(nil, "pubsub provider is not configured", v1alpha2.BadConfig)
+	}
+	err := mgrContext.Publish(config.Topic, v1alpha2.Event{
+		Metadata: map[string]string{
+			"campaign":   notification.Campaign,
+			"activation": notification.Activation,
+		},
+		Body: notification,
+	})
+	return nil, err
+}
+
+func postJson(ctx context.Context, url string, property string, payload interface{}) (map[string]interface{}, error) {
+	if url == "" {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s is required", property), v1alpha2.BadConfig)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	result := map[string]interface{}{
+		"statusCode": resp.StatusCode,
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return result, nil
+}
+
+func summary(notification Notification) string {
+	if notification.Subject != "" {
+		return notification.Subject
+	}
+	if notification.Activation != "" {
+		return fmt.Sprintf("Activation %s", notification.Activation)
+	}
+	return "Symphony notification"
+}
+
+func themeColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "error":
+		return "D13438"
+	case "warning":
+		return "FFB900"
+	default:
+		return "0078D7"
+	}
+}
@@ -0,0 +1,352 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var msLock sync.Mutex
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// DeliveredOutput is true when the notification is delivered to all the channels
+	DeliveredOutput = "delivered"
+
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	defaultTimeout = "30s"
+)
+
+type NotifyStageProviderConfig struct {
+	// Channels are the channels a notification is sent to, such as webhook, email, teams, slack and pubsub
+	Channels []string `json:"channels,omitempty"`
+	// Subject and Message are the default templates of the notification
+	Subject  string `json:"subject,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	// FailOnError fails the stage when the notification can't be delivered to a channel
+	FailOnError bool   `json:"failOnError,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+
+	WebhookUrl   string   `json:"webhook.url,omitempty"`
+	TeamsUrl     string   `json:"teams.url,omitempty"`
+	SlackUrl     string   `json:"slack.url,omitempty"`
+	SmtpHost     string   `json:"email.host,omitempty"`
+	SmtpPort     int      `json:"email.port,omitempty"`
+	SmtpUser     string   `json:"email.user,omitempty"`
+	SmtpPassword string   `json:"email.password,omitempty"`
+	From         string   `json:"email.from,omitempty"`
+	To           []string `json:"email.to,omitempty"`
+	Topic        string   `json:"pubsub.topic,omitempty"`
+}
+
+// NotifyStageProvider sends a notification, built from templates evaluated with the Symphony
+// expression engine, to one or more channels. The delivery status of each channel is reported in
+// the stage outputs.
+type NotifyStageProvider struct {
+	Config  NotifyStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (s *NotifyStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+	mockConfig, err := toNotifyStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	if mockConfig.Timeout != "" {
+		if _, err := time.ParseDuration(mockConfig.Timeout); err != nil {
+			return v1alpha2.NewCOAError(err, "invalid timeout", v1alpha2.BadConfig)
+		}
+	}
+	for _, channel := range mockConfig.Channels {
+		if _, ok := getChannel(channel); !ok {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("unknown channel '%s'", channel), v1alpha2.BadConfig)
+		}
+	}
+	s.Config = mockConfig
+	return nil
+}
+func (s *NotifyStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toNotifyStageProviderConfig(config providers.IProviderConfig) (NotifyStageProviderConfig, error) {
+	// the config of a campaign stage can hold lists as comma-separated strings, so it's read like the inputs
+	properties := make(map[string]interface{})
+	data, err := json.Marshal(config)
+	if err != nil {
+		return NotifyStageProviderConfig{}, err
+	}
+	err = json.Unmarshal(data, &properties)
+	if err != nil {
+		return NotifyStageProviderConfig{}, err
+	}
+	return overrideConfig(NotifyStageProviderConfig{}, properties)
+}
+func (i *NotifyStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := NotifyStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func NotifyStageProviderConfigFromMap(properties map[string]string) (NotifyStageProviderConfig, error) {
+	ret := NotifyStageProviderConfig{}
+	ret.Channels = splitList(utils.ReadString(properties, "channels", ""))
+	ret.Subject = utils.ReadString(properties, "subject", "")
+	ret.Message = utils.ReadString(properties, "message", "")
+	ret.Severity = utils.ReadString(properties, "severity", "")
+	if v, ok := properties["failOnError"]; ok {
+		failOnError, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid failOnError", v1alpha2.BadConfig)
+		}
+		ret.FailOnError = failOnError
+	}
+	ret.Timeout = utils.ReadString(properties, "timeout", "")
+	ret.WebhookUrl = utils.ReadString(properties, "webhook.url", "")
+	ret.TeamsUrl = utils.ReadString(properties, "teams.url", "")
+	ret.SlackUrl = utils.ReadString(properties, "slack.url", "")
+	ret.SmtpHost = utils.ReadString(properties, "email.host", "")
+	if v, ok := properties["email.port"]; ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid email.port", v1alpha2.BadConfig)
+		}
+		ret.SmtpPort = port
+	}
+	ret.SmtpUser = utils.ReadString(properties, "email.user", "")
+	ret.SmtpPassword = utils.ReadString(properties, "email.password", "")
+	ret.From = utils.ReadString(properties, "email.from", "")
+	ret.To = splitList(utils.ReadString(properties, "email.to", ""))
+	ret.Topic = utils.ReadString(properties, "pubsub.topic", "")
+	return ret, nil
+}
+
+func (i *NotifyStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Notify Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Info("  P (Notify Stage): start process request")
+
+	// inputs override the config; the overrides go to a copy as the provider can process several
+	// sites at the same time
+	var config NotifyStageProviderConfig
+	config, err = overrideConfig(i.Config, inputs)
+	if err != nil {
+		log.Errorf("  P (Notify Stage): failed to override config with inputs: %v", err)
+		return nil, false, err
+	}
+	if len(config.Channels) == 0 {
+		err = v1alpha2.NewCOAError(nil, "channels is required", v1alpha2.BadRequest)
+		return nil, false, err
+	}
+
+	var notification Notification
+	notification, err = buildNotification(config, mgrContext, inputs)
+	if err != nil {
+		log.Errorf("  P (Notify Stage): failed to build notification: %v", err)
+		return nil, false, err
+	}
+
+	timeout := config.Timeout
+	if timeout == "" {
+		timeout = defaultTimeout
+	}
+	duration, _ := time.ParseDuration(timeout)
+	sendCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	outputs := make(map[string]interface{})
+	delivered := true
+	failed := make([]string, 0)
+	for _, name := range config.Channels {
+		channel, _ := getChannel(name)
+		result, sErr := channel.Send(sendCtx, config, mgrContext, notification)
+		for k, v := range result {
+			outputs[name+"."+k] = v
+		}
+		if sErr != nil {
+			log.Errorf("  P (Notify Stage): failed to send notification to %s: %v", name, sErr)
+			outputs[name+".status"] = StatusFailed
+			outputs[name+".error"] = sErr.Error()
+			delivered = false
+			failed = append(failed, name)
+			continue
+		}
+		log.Infof("  P (Notify Stage): notification sent to %s", name)
+		outputs[name+".status"] = StatusDelivered
+	}
+	outputs[DeliveredOutput] = delivered
+	if !delivered && config.FailOnError {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to deliver notification to %s", strings.Join(failed, ", ")), v1alpha2.InternalError)
+		return outputs, false, err
+	}
+	return outputs, false, nil
+}
+
+// buildNotification evaluates the subject and message templates. The templates can use the Symphony
+// expressions, such as $input(), on the stage inputs.
+func buildNotification(config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (Notification, error) {
+	ret := Notification{
+		Severity:   config.Severity,
+		Campaign:   readString(inputs, "__campaign"),
+		Activation: readString(inputs, "__activation"),
+		Stage:      readString(inputs, "__stage"),
+		Site:       readString(inputs, "__site"),
+	}
+	if ret.Severity == "" {
+		ret.Severity = "info"
+	}
+	if data, ok := inputs["data"]; ok {
+		ret.Data = data
+	}
+	if config.Message == "" {
+		return ret, v1alpha2.NewCOAError(nil, "message is required", v1alpha2.BadRequest)
+	}
+	eCtx := &coa_utils.EvaluationContext{}
+	if mgrContext.VencorContext != nil && mgrContext.VencorContext.EvaluationContext != nil {
+		eCtx = mgrContext.VencorContext.EvaluationContext.Clone()
+	}
+	eCtx.Inputs = inputs
+	var err error
+	ret.Subject, err = evaluateTemplate(config.Subject, *eCtx)
+	if err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to evaluate subject", v1alpha2.BadRequest)
+	}
+	ret.Message, err = evaluateTemplate(config.Message, *eCtx)
+	if err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to evaluate message", v1alpha2.BadRequest)
+	}
+	return ret, nil
+}
+
+func evaluateTemplate(template string, eCtx coa_utils.EvaluationContext) (string, error) {
+	if !strings.Contains(template, "${{") {
+		return template, nil
+	}
+	val, err := utils.NewParser(template).Eval(eCtx)
+	if err != nil {
+		return "", err
+	}
+	if val == nil {
+		return "", nil
+	}
+	if str, ok := val.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+// overrideConfig returns a copy of the config with the settings given in the stage inputs
+func overrideConfig(config NotifyStageProviderConfig, inputs map[string]interface{}) (NotifyStageProviderConfig, error) {
+	ret := config
+	for key, target := range map[string]*string{
+		"subject":        &ret.Subject,
+		"message":        &ret.Message,
+		"severity":       &ret.Severity,
+		"timeout":        &ret.Timeout,
+		"webhook.url":    &ret.WebhookUrl,
+		"teams.url":      &ret.TeamsUrl,
+		"slack.url":      &ret.SlackUrl,
+		"email.host":     &ret.SmtpHost,
+		"email.user":     &ret.SmtpUser,
+		"email.password": &ret.SmtpPassword,
+		"email.from":     &ret.From,
+		"pubsub.topic":   &ret.Topic,
+	} {
+		if v, ok := inputs[key]; ok {
+			*target = fmt.Sprintf("%v", v)
+		}
+	}
+	if v, ok := inputs["channels"]; ok {
+		ret.Channels = readList(v)
+		for _, channel := range ret.Channels {
+			if _, ok := getChannel(channel); !ok {
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("unknown channel '%s'", channel), v1alpha2.BadRequest)
+			}
+		}
+	}
+	if v, ok := inputs["email.to"]; ok {
+		ret.To = readList(v)
+	}
+	if v, ok := inputs["email.port"]; ok {
+		port, err := strconv.Atoi(fmt.Sprintf("%v", v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid email.port", v1alpha2.BadRequest)
+		}
+		ret.SmtpPort = port
+	}
+	if v, ok := inputs["failOnError"]; ok {
+		failOnError, err := strconv.ParseBool(fmt.Sprintf("%v", v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid failOnError", v1alpha2.BadRequest)
+		}
+		ret.FailOnError = failOnError
+	}
+	if ret.Timeout != "" {
+		if _, err := time.ParseDuration(ret.Timeout); err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid timeout", v1alpha2.BadRequest)
+		}
+	}
+	return ret, nil
+}
+
+// readList reads a list input, which can be a list or a comma-separated string
+func readList(v interface{}) []string {
+	switch tv := v.(type) {
+	case []interface{}:
+		ret := make([]string, 0, len(tv))
+		for _, item := range tv {
+			ret = append(ret, fmt.Sprintf("%v", item))
+		}
+		return ret
+	case []string:
+		return tv
+	default:
+		return splitList(fmt.Sprintf("%v", v))
+	}
+}
+
+func splitList(s string) []string {
+	ret := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func readString(inputs map[string]interface{}, key string) string {
+	if v, ok := inputs[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
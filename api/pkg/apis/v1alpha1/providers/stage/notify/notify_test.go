@@ -0,0 +1,288 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyInitWithMap(t *testing.T) {
+	provider := NotifyStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"channels":   "webhook, email",
+		"message":    "done",
+		"email.port": "2525",
+		"email.to":   "a@contoso.com,b@contoso.com",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"webhook", "email"}, provider.Config.Channels)
+	assert.Equal(t, 2525, provider.Config.SmtpPort)
+	assert.Equal(t, []string{"a@contoso.com", "b@contoso.com"}, provider.Config.To)
+
+	err = provider.InitWithMap(map[string]string{
+		"channels": "pager",
+	})
+	assert.NotNil(t, err)
+
+	err = provider.InitWithMap(map[string]string{
+		"email.port": "smtp",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestNotifyWebhooks(t *testing.T) {
+	received := make(map[string]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		received[r.URL.Path] = body
+	}))
+	defer server.Close()
+
+	provider := NotifyStageProvider{}
+	err := provider.Init(NotifyStageProviderConfig{
+		Channels:   []string{"webhook", "teams", "slack"},
+		Subject:    "Rollout of ${{$input(version)}}",
+		Message:    "Activation ${{$input(__activation)}} finished",
+		WebhookUrl: server.URL + "/webhook",
+		TeamsUrl:   server.URL + "/teams",
+		SlackUrl:   server.URL + "/slack",
+	})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"version":      "1.2",
+		"severity":     "error",
+		"__campaign":   "rollout",
+		"__activation": "rollout-1",
+	})
+	assert.Nil(t, err)
+	assert.False(t, pause)
+	assert.Equal(t, true, outputs[DeliveredOutput])
+	assert.Equal(t, StatusDelivered, outputs["webhook.status"])
+	assert.Equal(t, 200, outputs["webhook.statusCode"])
+	assert.Equal(t, StatusDelivered, outputs["teams.status"])
+	assert.Equal(t, StatusDelivered, outputs["slack.status"])
+
+	assert.Equal(t, "Rollout of 1.2", received["/webhook"]["subject"])
+	assert.Equal(t, "Activation rollout-1 finished", received["/webhook"]["message"])
+	assert.Equal(t, "rollout", received["/webhook"]["campaign"])
+	assert.Equal(t, "MessageCard", received["/teams"]["@type"])
+	assert.Equal(t, "Rollout of 1.2", received["/teams"]["title"])
+	assert.Equal(t, "D13438", received["/teams"]["themeColor"])
+	assert.Equal(t, "*Rollout of 1.2*\nActivation rollout-1 finished", received["/slack"]["text"])
+}
+
+func TestNotifyDeliveryFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	provider := NotifyStageProvider{}
+	err := provider.Init(NotifyStageProviderConfig{
+		Channels:   []string{"webhook"},
+		WebhookUrl: server.URL,
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"message": "done",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, false, outputs[DeliveredOutput])
+	assert.Equal(t, StatusFailed, outputs["webhook.status"])
+	assert.Equal(t, 500, outputs["webhook.statusCode"])
+	assert.Equal(t, "unexpected status code 500", outputs["webhook.error"])
+
+	outputs, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"message":     "done",
+		"failOnError": true,
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, false, outputs[DeliveredOutput])
+}
+
+func TestNotifyInvalidInputs(t *testing.T) {
+	provider := NotifyStageProvider{}
+	err := provider.Init(NotifyStageProviderConfig{})
+	assert.Nil(t, err)
+	for _, inputs := range []map[string]interface{}{
+		{"message": "done"},
+		{"message": "done", "channels": []interface{}{"pager"}},
+		{"channels": "webhook"},
+		{"channels": "webhook", "message": "${{$inptu(a)}}"},
+		{"channels": "webhook", "message": "done", "timeout": "soon"},
+	} {
+		_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+		assert.NotNil(t, err, inputs)
+	}
+}
+
+func TestNotifyEmail(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	messages := make(chan string, 1)
+	go serveSmtp(listener, messages)
+
+	provider := NotifyStageProvider{}
+	err = provider.Init(NotifyStageProviderConfig{
+		Channels: []string{"email"},
+		SmtpHost: "127.0.0.1",
+		SmtpPort: listener.Addr().(*net.TCPAddr).Port,
+		From:     "symphony@contoso.com",
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"subject":  "Rollout\r\nBcc: x@contoso.com",
+		"message":  "Waiting for approval",
+		"email.to": "ops@contoso.com",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, outputs[DeliveredOutput])
+	assert.Equal(t, 1, outputs["email.recipients"])
+	select {
+	case message := <-messages:
+		assert.Contains(t, message, "MAIL FROM:<symphony@contoso.com>")
+		assert.Contains(t, message, "RCPT TO:<ops@contoso.com>")
+		assert.Contains(t, message, "Subject: Rollout  Bcc: x@contoso.com\r\n")
+		assert.Contains(t, message, "\r\n\r\nWaiting for approval\r\n")
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no message received")
+	}
+}
+
+func TestNotifyPubSub(t *testing.T) {
+	pubSub := &memory.InMemoryPubSubProvider{}
+	pubSub.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	received := make(chan v1alpha2.Event, 1)
+	pubSub.Subscribe("notifications", func(topic string, event v1alpha2.Event) error {
+		received <- event
+		return nil
+	})
+
+	provider := NotifyStageProvider{}
+	err := provider.Init(NotifyStageProviderConfig{
+		Channels: []string{"pubsub"},
+		Topic:    "notifications",
+		Message:  "done",
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{PubsubProvider: pubSub}, map[string]interface{}{
+		"__activation": "rollout-1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, StatusDelivered, outputs["pubsub.status"])
+	select {
+	case event := <-received:
+		assert.Equal(t, "rollout-1", event.Metadata["activation"])
+		assert.Equal(t, "done", event.Body.(Notification).Message)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no event received")
+	}
+
+	// without a pubsub provider the notification can't be delivered
+	outputs, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, StatusFailed, outputs["pubsub.status"])
+}
+
+type recordingChannel struct {
+	notifications []Notification
+}
+
+func (c *recordingChannel) Send(ctx context.Context, config NotifyStageProviderConfig, mgrContext contexts.ManagerContext, notification Notification) (map[string]interface{}, error) {
+	c.notifications = append(c.notifications, notification)
+	return map[string]interface{}{"count": len(c.notifications)}, nil
+}
+
+func TestNotifyRegisterChannel(t *testing.T) {
+	channel := &recordingChannel{}
+	RegisterChannel("recording", channel)
+	provider := NotifyStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"channels": "recording",
+		"message":  "done",
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"data": map[string]interface{}{"sites": 3},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, outputs["recording.count"])
+	assert.Equal(t, map[string]interface{}{"sites": 3}, channel.notifications[0].Data)
+}
+
+// serveSmtp accepts a single SMTP session and sends the commands and data it received to messages
+func serveSmtp(listener net.Listener, messages chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	var session strings.Builder
+	fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		session.WriteString(line)
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				fmt.Fprint(conn, "250 OK\r\n")
+			}
+			continue
+		}
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"):
+			fmt.Fprint(conn, "250 localhost\r\n")
+		case command == "DATA":
+			inData = true
+			fmt.Fprint(conn, "354 Go ahead\r\n")
+		case command == "QUIT":
+			fmt.Fprint(conn, "221 Bye\r\n")
+			messages <- session.String()
+			return
+		default:
+			fmt.Fprint(conn, "250 OK\r\n")
+		}
+	}
+}
+
+func TestNotifyInitWithStageConfig(t *testing.T) {
+	provider := NotifyStageProvider{}
+	err := provider.Init(map[string]interface{}{
+		"channels":    "slack,email",
+		"email.to":    []interface{}{"ops@contoso.com"},
+		"email.port":  587,
+		"failOnError": "true",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"slack", "email"}, provider.Config.Channels)
+	assert.Equal(t, []string{"ops@contoso.com"}, provider.Config.To)
+	assert.Equal(t, 587, provider.Config.SmtpPort)
+	assert.True(t, provider.Config.FailOnError)
+}
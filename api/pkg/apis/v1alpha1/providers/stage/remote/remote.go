@@ -68,7 +68,7 @@ func (i *RemoteStageProvider) SetOutputsContext(outputs map[string]map[string]in
 	i.OutputContext = outputs
 }
 func (i *RemoteStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
-	_, span := observability.StartSpan("[Stage] Remote Process Provider", ctx, &map[string]string{
+	ctx, span := observability.StartSpan("[Stage] Remote Process Provider", ctx, &map[string]string{
 		"method": "Process",
 	})
 	var err error = nil
@@ -86,12 +86,15 @@ func (i *RemoteStageProvider) Process(ctx context.Context, mgrContext contexts.M
 		return nil, false, err
 	}
 
+	metadata := map[string]string{
+		"site":       v.(string),
+		"objectType": "task",
+		"origin":     mgrContext.SiteInfo.SiteId,
+	}
+	// the remote site continues the trace of this stage
+	observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 	err = mgrContext.Publish("remote", v1alpha2.Event{
-		Metadata: map[string]string{
-			"site":       v.(string),
-			"objectType": "task",
-			"origin":     mgrContext.SiteInfo.SiteId,
-		},
+		Metadata: metadata,
 		Body: v1alpha2.JobData{
 			Id:     "",
 			Action: "RUN",
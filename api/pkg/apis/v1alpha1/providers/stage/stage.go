@@ -8,6 +8,7 @@ package stage
 
 import (
 	"context"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 )
@@ -17,6 +18,19 @@ type IStageProvider interface {
 	Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error)
 }
 
+// Sleep waits for the duration, or returns the error of the context as soon as it's done, so that
+// providers stop waiting when the stage times out or the activation is cancelled
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func ReadInputString(inputs map[string]interface{}, key string) string {
 	if inputs == nil {
 		return ""
@@ -0,0 +1,479 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package starlark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/list"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/patch"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	sljson "go.starlark.net/lib/json"
+	sl "go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+var msLock sync.Mutex
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// ProcessFunction is the function a script defines to process the stage inputs
+	ProcessFunction = "process"
+
+	defaultMaxSteps = 1000000
+	defaultTimeout  = "30s"
+
+	contextKey    = "context"
+	mgrContextKey = "mgrContext"
+)
+
+type StarlarkStageProviderConfig struct {
+	BaseUrl  string `json:"baseUrl,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	// MaxSteps limits the number of Starlark computation steps a script can take
+	MaxSteps uint64 `json:"maxSteps,omitempty"`
+	// Timeout limits the wall-clock time a script can run, such as "30s"
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// StarlarkStageProvider runs an inline Starlark script. The script defines a process(inputs) function
+// that takes the stage inputs as a dict and returns the stage outputs as a dict. Scripts can't access
+// the host file system or processes; they can only call the built-ins of the json and symphony modules.
+type StarlarkStageProvider struct {
+	Config  StarlarkStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (s *StarlarkStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+	mockConfig, err := toStarlarkStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	if mockConfig.Timeout != "" {
+		if _, err := time.ParseDuration(mockConfig.Timeout); err != nil {
+			return v1alpha2.NewCOAError(err, "invalid timeout", v1alpha2.BadConfig)
+		}
+	}
+	s.Config = mockConfig
+	return nil
+}
+func (s *StarlarkStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toStarlarkStageProviderConfig(config providers.IProviderConfig) (StarlarkStageProviderConfig, error) {
+	ret := StarlarkStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+func (i *StarlarkStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := StarlarkStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func StarlarkStageProviderConfigFromMap(properties map[string]string) (StarlarkStageProviderConfig, error) {
+	ret := StarlarkStageProviderConfig{}
+	ret.BaseUrl = utils.ReadString(properties, "baseUrl", "")
+	ret.User = utils.ReadString(properties, "user", "")
+	ret.Password = utils.ReadString(properties, "password", "")
+	if ret.BaseUrl != "" && ret.User == "" {
+		return ret, v1alpha2.NewCOAError(nil, "user is required", v1alpha2.BadConfig)
+	}
+	if v, ok := properties["maxSteps"]; ok {
+		maxSteps, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid maxSteps", v1alpha2.BadConfig)
+		}
+		ret.MaxSteps = maxSteps
+	}
+	ret.Timeout = utils.ReadString(properties, "timeout", "")
+	return ret, nil
+}
+
+func (i *StarlarkStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Starlark Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Info("  P (Starlark Stage): start process request")
+
+	script := stage.ReadInputString(inputs, "script")
+	if script == "" {
+		err = v1alpha2.NewCOAError(nil, "script is required", v1alpha2.BadRequest)
+		return nil, false, err
+	}
+
+	maxSteps := i.Config.MaxSteps
+	if maxSteps == 0 {
+		maxSteps = defaultMaxSteps
+	}
+	timeout := i.Config.Timeout
+	if timeout == "" {
+		timeout = defaultTimeout
+	}
+	duration, _ := time.ParseDuration(timeout)
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	thread := &sl.Thread{
+		Name: "stage",
+		Print: func(_ *sl.Thread, msg string) {
+			log.Infof("  P (Starlark Stage): %s", msg)
+		},
+		// scripts can't load other modules
+		Load: func(_ *sl.Thread, module string) (sl.StringDict, error) {
+			return nil, fmt.Errorf("cannot load %s", module)
+		},
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	thread.SetLocal(contextKey, ctx)
+	thread.SetLocal(mgrContextKey, mgrContext)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(fmt.Sprintf("script didn't finish within %s", timeout))
+		case <-done:
+		}
+	}()
+
+	scriptInputs := make(map[string]interface{}, len(inputs))
+	for k, v := range inputs {
+		if k != "script" {
+			scriptInputs[k] = v
+		}
+	}
+	var args sl.Value
+	args, err = toStarlark(scriptInputs)
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, "failed to convert inputs", v1alpha2.BadRequest)
+		return nil, false, err
+	}
+
+	var globals sl.StringDict
+	globals, err = sl.ExecFile(thread, "script.star", script, i.predeclared())
+	if err != nil {
+		log.Errorf("  P (Starlark Stage): failed to run script: %s", evalMessage(err))
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to run script: %s", evalMessage(err)), v1alpha2.BadRequest)
+		return nil, false, err
+	}
+	process, ok := globals[ProcessFunction].(sl.Callable)
+	if !ok {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("script doesn't define a %s(inputs) function", ProcessFunction), v1alpha2.BadRequest)
+		return nil, false, err
+	}
+
+	var result sl.Value
+	result, err = sl.Call(thread, process, sl.Tuple{args}, nil)
+	if err != nil {
+		log.Errorf("  P (Starlark Stage): script failed: %s", evalMessage(err))
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("script failed: %s", evalMessage(err)), v1alpha2.InternalError)
+		return nil, false, err
+	}
+	if result == sl.None {
+		return map[string]interface{}{}, false, nil
+	}
+	if _, ok := result.(*sl.Dict); !ok {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("%s() returned %s, expected a dict", ProcessFunction, result.Type()), v1alpha2.InternalError)
+		return nil, false, err
+	}
+	var outputs interface{}
+	outputs, err = fromStarlark(result)
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, "failed to convert outputs", v1alpha2.InternalError)
+		return nil, false, err
+	}
+	return outputs.(map[string]interface{}), false, nil
+}
+
+func evalMessage(err error) string {
+	if evalErr, ok := err.(*sl.EvalError); ok {
+		return evalErr.Backtrace()
+	}
+	return err.Error()
+}
+
+func (i *StarlarkStageProvider) predeclared() sl.StringDict {
+	return sl.StringDict{
+		"json": sljson.Module,
+		"symphony": &starlarkstruct.Module{
+			Name: "symphony",
+			Members: sl.StringDict{
+				"get":   sl.NewBuiltin("symphony.get", i.get),
+				"list":  sl.NewBuiltin("symphony.list", i.list),
+				"patch": sl.NewBuiltin("symphony.patch", i.patch),
+			},
+		},
+	}
+}
+
+func (i *StarlarkStageProvider) checkApi(b *sl.Builtin) error {
+	if i.Config.BaseUrl == "" {
+		return fmt.Errorf("%s: the provider is not configured with a Symphony API baseUrl", b.Name())
+	}
+	return nil
+}
+
+// get reads a Symphony object: symphony.get(objectType, name, scope="default")
+func (i *StarlarkStageProvider) get(thread *sl.Thread, b *sl.Builtin, args sl.Tuple, kwargs []sl.Tuple) (sl.Value, error) {
+	var objectType, name string
+	scope := "default"
+	if err := sl.UnpackArgs(b.Name(), args, kwargs, "objectType", &objectType, "name", &name, "scope?", &scope); err != nil {
+		return nil, err
+	}
+	if err := i.checkApi(b); err != nil {
+		return nil, err
+	}
+	ctx := thread.Local(contextKey).(context.Context)
+	var obj interface{}
+	var err error
+	switch objectType {
+	case "instance":
+		obj, err = utils.GetInstance(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password, scope)
+	case "solution":
+		obj, err = utils.GetSolution(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password, scope)
+	case "target":
+		obj, err = utils.GetTarget(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password, scope)
+	case "catalog":
+		obj, err = utils.GetCatalog(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password)
+	case "campaign":
+		obj, err = utils.GetCampaign(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password)
+	case "activation":
+		obj, err = utils.GetActivation(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password)
+	default:
+		return nil, fmt.Errorf("%s: unsupported object type '%s'", b.Name(), objectType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return toStarlark(obj)
+}
+
+// list lists Symphony objects the same way the list stage provider does: symphony.list(objectType, namesOnly=False, objectScope="default")
+func (i *StarlarkStageProvider) list(thread *sl.Thread, b *sl.Builtin, args sl.Tuple, kwargs []sl.Tuple) (sl.Value, error) {
+	var objectType string
+	namesOnly := false
+	objectScope := "default"
+	if err := sl.UnpackArgs(b.Name(), args, kwargs, "objectType", &objectType, "namesOnly?", &namesOnly, "objectScope?", &objectScope); err != nil {
+		return nil, err
+	}
+	if err := i.checkApi(b); err != nil {
+		return nil, err
+	}
+	provider := list.ListStageProvider{
+		Config: list.ListStageProviderConfig{
+			BaseUrl:  i.Config.BaseUrl,
+			User:     i.Config.User,
+			Password: i.Config.Password,
+		},
+		Context: i.Context,
+	}
+	outputs, _, err := provider.Process(thread.Local(contextKey).(context.Context), thread.Local(mgrContextKey).(contexts.ManagerContext), map[string]interface{}{
+		"objectType":  objectType,
+		"namesOnly":   namesOnly,
+		"objectScope": objectScope,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	if outputs["items"] == nil {
+		return sl.NewList(nil), nil
+	}
+	return toStarlark(outputs["items"])
+}
+
+// patch patches a Symphony object the same way the patch stage provider does. It takes the inputs of
+// the patch stage provider as keyword arguments, such as symphony.patch(objectType="solution", ...).
+func (i *StarlarkStageProvider) patch(thread *sl.Thread, b *sl.Builtin, args sl.Tuple, kwargs []sl.Tuple) (sl.Value, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%s: unexpected positional arguments", b.Name())
+	}
+	if err := i.checkApi(b); err != nil {
+		return nil, err
+	}
+	inputs := make(map[string]interface{}, len(kwargs))
+	for _, kv := range kwargs {
+		v, err := fromStarlark(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", b.Name(), err)
+		}
+		inputs[string(kv[0].(sl.String))] = v
+	}
+	provider := patch.PatchStageProvider{
+		Config: patch.PatchStageProviderConfig{
+			BaseUrl:  i.Config.BaseUrl,
+			User:     i.Config.User,
+			Password: i.Config.Password,
+		},
+		Context: i.Context,
+	}
+	outputs, _, err := provider.Process(thread.Local(contextKey).(context.Context), thread.Local(mgrContextKey).(contexts.ManagerContext), inputs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return toStarlark(outputs)
+}
+
+// toStarlark converts a Go value to a Starlark value. Values other than basic types, slices and maps
+// are converted through their JSON form.
+func toStarlark(v interface{}) (sl.Value, error) {
+	switch val := v.(type) {
+	case nil:
+		return sl.None, nil
+	case sl.Value:
+		return val, nil
+	case bool:
+		return sl.Bool(val), nil
+	case string:
+		return sl.String(val), nil
+	case int:
+		return sl.MakeInt(val), nil
+	case int32:
+		return sl.MakeInt64(int64(val)), nil
+	case int64:
+		return sl.MakeInt64(val), nil
+	case uint64:
+		return sl.MakeUint64(val), nil
+	case float32:
+		return sl.Float(val), nil
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			// JSON numbers are decoded as floats
+			return sl.MakeInt64(int64(val)), nil
+		}
+		return sl.Float(val), nil
+	case []interface{}:
+		elems := make([]sl.Value, 0, len(val))
+		for _, e := range val {
+			ev, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, ev)
+		}
+		return sl.NewList(elems), nil
+	case []string:
+		elems := make([]sl.Value, 0, len(val))
+		for _, e := range val {
+			elems = append(elems, sl.String(e))
+		}
+		return sl.NewList(elems), nil
+	case map[string]interface{}:
+		dict := sl.NewDict(len(val))
+		for k, e := range val {
+			ev, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(sl.String(k), ev); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case map[string]string:
+		dict := sl.NewDict(len(val))
+		for k, e := range val {
+			if err := dict.SetKey(sl.String(k), sl.String(e)); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		var obj interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		return toStarlark(obj)
+	}
+}
+
+// fromStarlark converts a Starlark value made of None, bools, numbers, strings, lists, tuples and
+// dicts with string keys to a Go value
+func fromStarlark(v sl.Value) (interface{}, error) {
+	switch val := v.(type) {
+	case sl.NoneType:
+		return nil, nil
+	case sl.Bool:
+		return bool(val), nil
+	case sl.String:
+		return string(val), nil
+	case sl.Int:
+		if i, ok := val.Int64(); ok {
+			if i >= math.MinInt && i <= math.MaxInt {
+				return int(i), nil
+			}
+			return i, nil
+		}
+		return nil, fmt.Errorf("integer %s is too large", val.String())
+	case sl.Float:
+		return float64(val), nil
+	case *sl.List:
+		ret := make([]interface{}, 0, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			e, err := fromStarlark(val.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, e)
+		}
+		return ret, nil
+	case sl.Tuple:
+		ret := make([]interface{}, 0, len(val))
+		for _, e := range val {
+			ev, err := fromStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, ev)
+		}
+		return ret, nil
+	case *sl.Dict:
+		ret := make(map[string]interface{}, val.Len())
+		for _, item := range val.Items() {
+			k, ok := item[0].(sl.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is not a string", item[0].String())
+			}
+			e, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			ret[string(k)] = e
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %s", v.Type())
+	}
+}
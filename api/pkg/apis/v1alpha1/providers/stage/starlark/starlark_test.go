@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package starlark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStarlarkInitWithMap(t *testing.T) {
+	provider := StarlarkStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"maxSteps": "1000",
+		"timeout":  "5s",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1000), provider.Config.MaxSteps)
+	assert.Equal(t, "5s", provider.Config.Timeout)
+
+	err = provider.InitWithMap(map[string]string{
+		"maxSteps": "many",
+	})
+	assert.NotNil(t, err)
+
+	err = provider.InitWithMap(map[string]string{
+		"timeout": "soon",
+	})
+	assert.NotNil(t, err)
+
+	err = provider.InitWithMap(map[string]string{
+		"baseUrl": "http://localhost:8082/v1alpha2/",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestStarlarkProcess(t *testing.T) {
+	provider := StarlarkStageProvider{}
+	err := provider.Init(StarlarkStageProviderConfig{})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"script": `
+def process(inputs):
+    sites = [s.upper() for s in inputs["sites"]]
+    return {
+        "sites": sites,
+        "count": len(sites),
+        "ratio": 0.5,
+        "ready": inputs["count"] > 1,
+        "payload": json.encode({"version": inputs["version"]}),
+    }
+`,
+		"sites":   []interface{}{"a", "b"},
+		"count":   float64(2),
+		"version": "1.0",
+	})
+	assert.Nil(t, err)
+	assert.False(t, pause)
+	assert.Equal(t, []interface{}{"A", "B"}, outputs["sites"])
+	assert.Equal(t, 2, outputs["count"])
+	assert.Equal(t, 0.5, outputs["ratio"])
+	assert.Equal(t, true, outputs["ready"])
+	assert.Equal(t, `{"version":"1.0"}`, outputs["payload"])
+}
+
+func TestStarlarkProcessInvalidScripts(t *testing.T) {
+	provider := StarlarkStageProvider{}
+	err := provider.Init(StarlarkStageProviderConfig{})
+	assert.Nil(t, err)
+	for script, message := range map[string]string{
+		"":                                     "script is required",
+		"x = 1":                                "doesn't define a process(inputs) function",
+		"def process(inputs)\n    return {}":   "failed to run script",
+		"def process(inputs):\n    return 1":   "returned int, expected a dict",
+		"def process(inputs):\n    fail('no')": "script failed",
+		"load('os.star', 'exec')":              "cannot load os.star",
+		"def process(inputs):\n    return {1: 2}": "dict key 1 is not a string",
+	} {
+		_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+			"script": script,
+		})
+		assert.NotNil(t, err, script)
+		if err != nil {
+			assert.Contains(t, err.Error(), message, script)
+		}
+	}
+}
+
+func TestStarlarkProcessMaxSteps(t *testing.T) {
+	provider := StarlarkStageProvider{}
+	err := provider.Init(StarlarkStageProviderConfig{
+		MaxSteps: 1000,
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"script": `
+def process(inputs):
+    total = 0
+    for i in range(100000):
+        total += i
+    return {"total": total}
+`,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "too many steps")
+}
+
+func TestStarlarkProcessTimeout(t *testing.T) {
+	provider := StarlarkStageProvider{}
+	err := provider.Init(StarlarkStageProviderConfig{
+		MaxSteps: 1 << 62,
+		Timeout:  "100ms",
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"script": `
+def process(inputs):
+    for i in range(1 << 40):
+        pass
+    return {}
+`,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "didn't finish within 100ms")
+}
+
+func TestStarlarkProcessSymphonyApi(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/auth":
+			w.Write([]byte(`{"accessToken":"token"}`))
+		case "/instances":
+			w.Write([]byte(`[{"id":"instance-1","spec":{"name":"instance-1","solution":"app"}},{"id":"instance-2","spec":{"name":"instance-2","solution":"app"}}]`))
+		case "/instances/instance-1":
+			w.Write([]byte(`{"id":"instance-1","spec":{"name":"instance-1","solution":"app"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := StarlarkStageProvider{}
+	err := provider.Init(StarlarkStageProviderConfig{
+		BaseUrl: server.URL + "/",
+		User:    "admin",
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"script": `
+def process(inputs):
+    names = symphony.list("instance", namesOnly=True)
+    instance = symphony.get("instance", names[0])
+    return {"names": names, "solution": instance["spec"]["solution"]}
+`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"instance-1", "instance-2"}, outputs["names"])
+	assert.Equal(t, "app", outputs["solution"])
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"script": `
+def process(inputs):
+    return {"instance": symphony.get("instance", "instance-3")}
+`,
+	})
+	assert.NotNil(t, err)
+}
+
+func TestStarlarkProcessWithoutSymphonyApi(t *testing.T) {
+	provider := StarlarkStageProvider{}
+	err := provider.Init(StarlarkStageProviderConfig{})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"script": `
+def process(inputs):
+    return {"items": symphony.list("instance")}
+`,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not configured with a Symphony API baseUrl")
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package subcampaign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var msLock sync.Mutex
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// Stage outputs that identify the child activation
+	ActivationOutput = "activation"
+	CampaignOutput   = "campaign"
+)
+
+type SubCampaignStageProviderConfig struct {
+	BaseUrl  string `json:"baseUrl"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// SubCampaignStageProvider starts an activation of another campaign and pauses the current activation
+// until the child activation finishes
+type SubCampaignStageProvider struct {
+	Config  SubCampaignStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (s *SubCampaignStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+	mockConfig, err := toSubCampaignStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	s.Config = mockConfig
+	return nil
+}
+func (s *SubCampaignStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toSubCampaignStageProviderConfig(config providers.IProviderConfig) (SubCampaignStageProviderConfig, error) {
+	ret := SubCampaignStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+func (i *SubCampaignStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := SubCampaignStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func SubCampaignStageProviderConfigFromMap(properties map[string]string) (SubCampaignStageProviderConfig, error) {
+	ret := SubCampaignStageProviderConfig{}
+	baseUrl, err := utils.GetString(properties, "baseUrl")
+	if err != nil {
+		return ret, err
+	}
+	ret.BaseUrl = baseUrl
+	if ret.BaseUrl == "" {
+		return ret, v1alpha2.NewCOAError(nil, "baseUrl is required", v1alpha2.BadConfig)
+	}
+	user, err := utils.GetString(properties, "user")
+	if err != nil {
+		return ret, err
+	}
+	ret.User = user
+	if ret.User == "" {
+		return ret, v1alpha2.NewCOAError(nil, "user is required", v1alpha2.BadConfig)
+	}
+	password, err := utils.GetString(properties, "password")
+	if err != nil {
+		return ret, err
+	}
+	ret.Password = password
+	return ret, nil
+}
+
+// Process creates an activation of the campaign named by the "campaign" input, with the "inputs" input
+// as its inputs, and pauses the current activation. The child activation is named by the "activation"
+// input, or after the current activation and stage.
+func (i *SubCampaignStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] SubCampaign provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Info("  P (SubCampaign Stage): Process")
+
+	campaign := stage.ReadInputString(inputs, "campaign")
+	if campaign == "" {
+		err = v1alpha2.NewCOAError(nil, "campaign input is required", v1alpha2.BadRequest)
+		return nil, false, err
+	}
+	parent := stage.ReadInputString(inputs, "__activation")
+	name := stage.ReadInputString(inputs, "activation")
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", parent, stage.ReadInputString(inputs, "__stage"))
+	}
+	spec := model.ActivationSpec{
+		Campaign: campaign,
+		Name:     name,
+		Parent:   parent,
+	}
+	if v, ok := inputs["inputs"]; ok && v != nil {
+		childInputs, ok := v.(map[string]interface{})
+		if !ok {
+			err = v1alpha2.NewCOAError(nil, "inputs input must be a map", v1alpha2.BadRequest)
+			return nil, false, err
+		}
+		spec.Inputs = childInputs
+	}
+
+	data, _ := json.Marshal(spec)
+	err = utils.CreateActivation(ctx, i.Config.BaseUrl, name, i.Config.User, i.Config.Password, data)
+	if err != nil {
+		log.Errorf("  P (SubCampaign Stage): failed to create activation %s of campaign %s: %v", name, campaign, err)
+		return nil, false, err
+	}
+	log.Infof("  P (SubCampaign Stage): waiting for activation %s of campaign %s", name, campaign)
+	return map[string]interface{}{
+		ActivationOutput: name,
+		CampaignOutput:   campaign,
+	}, true, nil
+}
+
+// Complete returns the status that resumes a parent activation paused on a sub-campaign stage, given
+// the final status of the child activation. The child's outputs, other than the built-in "__" outputs,
+// become the outputs of the stage. The stage fails when the child activation didn't finish as done.
+func Complete(parent model.ActivationStatus, child string, childStatus model.ActivationStatus) model.ActivationStatus {
+	outputs := make(map[string]interface{})
+	for k, v := range parent.Outputs {
+		outputs[k] = v
+	}
+	for k, v := range childStatus.Outputs {
+		if !strings.HasPrefix(k, "__") {
+			outputs[k] = v
+		}
+	}
+	parent.Outputs = outputs
+	parent.IsActive = false
+	if childStatus.Status == v1alpha2.Done {
+		parent.Status = v1alpha2.Done
+		outputs[v1alpha2.StatusOutput] = v1alpha2.OK
+		return parent
+	}
+	parent.Status = v1alpha2.InternalError
+	parent.ErrorMessage = fmt.Sprintf("child activation %s failed: %s", child, childStatus.ErrorMessage)
+	outputs[v1alpha2.StatusOutput] = childStatus.Status
+	outputs[v1alpha2.ErrorOutput] = childStatus.ErrorMessage
+	return parent
+}
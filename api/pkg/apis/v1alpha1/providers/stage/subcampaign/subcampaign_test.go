@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package subcampaign
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubCampaignInitWithMap(t *testing.T) {
+	provider := SubCampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"baseUrl":  "http://localhost:8082/v1alpha2/",
+		"user":     "admin",
+		"password": "",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", provider.Config.User)
+
+	err = provider.InitWithMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSubCampaignProcess(t *testing.T) {
+	var created model.ActivationSpec
+	var createdName string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/auth":
+			w.Write([]byte(`{"accessToken":"token"}`))
+		case "/activations/registry/parent-rollout":
+			createdName = "parent-rollout"
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &created)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := SubCampaignStageProvider{}
+	err := provider.Init(SubCampaignStageProviderConfig{
+		BaseUrl: server.URL + "/",
+		User:    "admin",
+	})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"campaign":     "rollout",
+		"inputs":       map[string]interface{}{"version": "1.2"},
+		"__activation": "parent",
+		"__stage":      "rollout",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	assert.Equal(t, "parent-rollout", outputs[ActivationOutput])
+	assert.Equal(t, "rollout", outputs[CampaignOutput])
+	assert.Equal(t, "parent-rollout", createdName)
+	assert.Equal(t, "rollout", created.Campaign)
+	assert.Equal(t, "parent", created.Parent)
+	assert.Equal(t, "1.2", created.Inputs["version"])
+}
+
+func TestSubCampaignProcessMissingCampaign(t *testing.T) {
+	provider := SubCampaignStageProvider{}
+	_, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"__activation": "parent",
+	})
+	assert.NotNil(t, err)
+	assert.False(t, pause)
+}
+
+func TestCompleteDone(t *testing.T) {
+	parent := model.ActivationStatus{
+		Stage:  "rollout",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			"__activation":   "parent",
+			"__stage":        "rollout",
+			ActivationOutput: "parent-rollout",
+		},
+	}
+	status := Complete(parent, "parent-rollout", model.ActivationStatus{
+		Status: v1alpha2.Done,
+		Outputs: map[string]interface{}{
+			"__activation": "parent-rollout",
+			"result":       "ok",
+		},
+	})
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, "parent", status.Outputs["__activation"])
+	assert.Equal(t, "rollout", status.Outputs["__stage"])
+	assert.Equal(t, "ok", status.Outputs["result"])
+	assert.Equal(t, v1alpha2.OK, status.Outputs["__status"])
+	// the parent status passed in isn't changed
+	_, ok := parent.Outputs["result"]
+	assert.False(t, ok)
+}
+
+func TestCompleteFailed(t *testing.T) {
+	status := Complete(model.ActivationStatus{Status: v1alpha2.Paused}, "child", model.ActivationStatus{
+		Status:       v1alpha2.InternalError,
+		ErrorMessage: "deploy failed",
+	})
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.False(t, status.IsActive)
+	assert.Equal(t, "child activation child failed: deploy failed", status.ErrorMessage)
+	assert.Equal(t, "deploy failed", status.Outputs["__error"])
+}
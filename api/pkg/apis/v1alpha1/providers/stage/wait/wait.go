@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -215,7 +216,13 @@ func (i *WaitStageProvider) Process(ctx context.Context, mgrContext contexts.Man
 		}
 		counter++
 		if i.Config.WaitInterval > 0 {
-			time.Sleep(time.Duration(i.Config.WaitInterval) * time.Second)
+			if err = stage.Sleep(ctx, time.Duration(i.Config.WaitInterval)*time.Second); err != nil {
+				log.Errorf("  P (Wait Processor): stopped waiting for %v %v: %v", objectType, objects, err)
+				return nil, false, err
+			}
+		} else if err = ctx.Err(); err != nil {
+			log.Errorf("  P (Wait Processor): stopped waiting for %v %v: %v", objectType, objects, err)
+			return nil, false, err
 		}
 	}
 
@@ -264,6 +264,19 @@ func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertReques
 	return entry.Value.ID, nil
 }
 
+// CheckHealth checks that the Kubernetes API server is reachable
+func (s *K8sStateProvider) CheckHealth(ctx context.Context) error {
+	if s.DynamicClient == nil {
+		return v1alpha2.NewCOAError(nil, "Kubernetes client is not initialized", v1alpha2.InternalError)
+	}
+	namespaceResource := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+	_, err := s.DynamicClient.Resource(namespaceResource).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to reach Kubernetes API server", v1alpha2.InternalError)
+	}
+	return nil
+}
+
 func (s *K8sStateProvider) ListAllNamespaces(ctx context.Context, version string) ([]string, error) {
 	namespaceResource := schema.GroupVersionResource{Group: "", Version: version, Resource: "namespaces"}
 	namespaces, err := s.DynamicClient.Resource(namespaceResource).List(ctx, metav1.ListOptions{})
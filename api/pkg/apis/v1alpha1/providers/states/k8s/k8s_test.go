@@ -15,6 +15,9 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
 )
 
 func TestK8sStateProviderConfigFromMapNil(t *testing.T) {
@@ -316,3 +319,13 @@ func TestUpSertWithStateOnly(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "s234", id)
 }
+
+func TestCheckHealth(t *testing.T) {
+	provider := K8sStateProvider{}
+	assert.NotNil(t, provider.CheckHealth(context.Background()))
+
+	provider.DynamicClient = fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "", Version: "v1", Resource: "namespaces"}: "NamespaceList",
+	})
+	assert.Nil(t, provider.CheckHealth(context.Background()))
+}
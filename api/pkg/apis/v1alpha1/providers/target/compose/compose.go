@@ -0,0 +1,436 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package compose
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	composeContent     = "compose.content"
+	composeCatalog     = "compose.catalog"
+	composeProject     = "compose.project"
+	composeContentHash = "compose.contentHash"
+	composeFileName    = "compose.yaml"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+// projectNamePattern is the charset Compose accepts for project names. As the project name is also used as
+// a directory under the working dir, it must never contain path separators or dots.
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type ComposeTargetProviderConfig struct {
+	Name string `json:"name"`
+	// Command is the command used to invoke Compose, such as "docker compose" or "docker-compose"
+	Command string `json:"command,omitempty"`
+	// WorkingDir is where the compose documents of deployed projects are kept
+	WorkingDir string `json:"workingDir,omitempty"`
+	// BaseUrl, User and Password are used to resolve compose documents stored in catalogs
+	BaseUrl  string `json:"baseUrl,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type ComposeTargetProvider struct {
+	Config  ComposeTargetProviderConfig
+	Context *contexts.ManagerContext
+}
+
+// serviceState is an entry of the "compose ps --format json" output
+type serviceState struct {
+	Service string `json:"Service"`
+	State   string `json:"State"`
+	Health  string `json:"Health"`
+}
+
+func ComposeTargetProviderConfigFromMap(properties map[string]string) (ComposeTargetProviderConfig, error) {
+	ret := ComposeTargetProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	if v, ok := properties["command"]; ok {
+		ret.Command = v
+	}
+	if v, ok := properties["workingDir"]; ok {
+		ret.WorkingDir = v
+	}
+	if v, ok := properties["baseUrl"]; ok {
+		ret.BaseUrl = v
+	}
+	if v, ok := properties["user"]; ok {
+		ret.User = v
+	}
+	if v, ok := properties["password"]; ok {
+		ret.Password = v
+	}
+	return ret, nil
+}
+
+func (i *ComposeTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := ComposeTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *ComposeTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (i *ComposeTargetProvider) Init(config providers.IProviderConfig) error {
+	_, span := observability.StartSpan("Compose Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (Compose Target): Init()")
+
+	updateConfig, err := toComposeTargetProviderConfig(config)
+	if err != nil {
+		err = errors.New("expected ComposeTargetProviderConfig")
+		return err
+	}
+	if updateConfig.Command == "" {
+		updateConfig.Command = "docker compose"
+	}
+	if updateConfig.WorkingDir == "" {
+		updateConfig.WorkingDir = filepath.Join(os.TempDir(), "symphony-compose")
+	}
+	i.Config = updateConfig
+	return nil
+}
+
+func toComposeTargetProviderConfig(config providers.IProviderConfig) (ComposeTargetProviderConfig, error) {
+	ret := ComposeTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func (i *ComposeTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("Compose Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Compose Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, component := range references {
+		var project string
+		project, err = i.projectName(component.Component, injections)
+		if err != nil {
+			sLog.Errorf("  P (Compose Target): %+v", err)
+			return nil, err
+		}
+		deployed, rerr := os.ReadFile(i.composeFilePath(project))
+		if rerr != nil {
+			if os.IsNotExist(rerr) {
+				continue
+			}
+			err = rerr
+			sLog.Errorf("  P (Compose Target): failed to read compose file of project %s: %+v", project, err)
+			return nil, err
+		}
+
+		var services []serviceState
+		services, err = i.listServices(ctx, project)
+		if err != nil {
+			sLog.Errorf("  P (Compose Target): failed to list services of project %s: %+v", project, err)
+			return nil, err
+		}
+		if len(services) == 0 {
+			continue
+		}
+
+		var desired []byte
+		desired, err = i.readContent(ctx, component.Component, injections)
+		if err != nil {
+			sLog.Errorf("  P (Compose Target): failed to read compose content: %+v", err)
+			return nil, err
+		}
+
+		properties := map[string]interface{}{
+			composeProject: project,
+		}
+		// the content hash is only reported when the deployed document matches the desired one, so a
+		// missing hash is picked up as a change by the validation rule
+		if hash := contentHash(deployed); hash == contentHash(desired) {
+			properties[composeContentHash] = hash
+		}
+		for _, s := range services {
+			properties["service."+s.Service+".state"] = s.State
+			if s.Health != "" {
+				properties["service."+s.Service+".health"] = s.Health
+			}
+		}
+		ret = append(ret, model.ComponentSpec{
+			Name:       component.Component.Name,
+			Type:       component.Component.Type,
+			Properties: properties,
+		})
+	}
+	return ret, nil
+}
+
+func (i *ComposeTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("Compose Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Compose Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	components := step.GetComponents()
+	err = i.GetValidationRule(ctx).Validate(components)
+	if err != nil {
+		return nil, err
+	}
+	if isDryRun {
+		err = nil
+		return nil, nil
+	}
+
+	ret := step.PrepareResultMap()
+
+	for _, component := range step.Components {
+		var project string
+		project, err = i.projectName(component.Component, injections)
+		if err != nil {
+			status := v1alpha2.UpdateFailed
+			if component.Action == "delete" {
+				status = v1alpha2.DeleteFailed
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  status,
+				Message: err.Error(),
+			}
+			sLog.Errorf("  P (Compose Target): %+v", err)
+			return ret, err
+		}
+		if component.Action == "update" {
+			var content []byte
+			content, err = i.readContent(ctx, component.Component, injections)
+			if err == nil {
+				err = i.writeComposeFile(project, content)
+			}
+			if err == nil {
+				_, err = i.runCompose(ctx, project, "up", "-d", "--remove-orphans")
+			}
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Compose Target): failed to bring up project %s: %+v", project, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Updated,
+				Message: "",
+			}
+		} else {
+			if _, serr := os.Stat(i.composeFilePath(project)); serr == nil {
+				_, err = i.runCompose(ctx, project, "down", "--remove-orphans")
+				if err != nil {
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.DeleteFailed,
+						Message: err.Error(),
+					}
+					sLog.Errorf("  P (Compose Target): failed to bring down project %s: %+v", project, err)
+					return ret, err
+				}
+			}
+			err = os.RemoveAll(filepath.Join(i.Config.WorkingDir, project))
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
+				}
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Deleted,
+				Message: "",
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (*ComposeTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		RequiredProperties:    []string{},
+		OptionalProperties:    []string{composeContent, composeCatalog, composeProject},
+		RequiredComponentType: "",
+		RequiredMetadata:      []string{},
+		OptionalMetadata:      []string{},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: composeContentHash, IgnoreCase: false, SkipIfMissing: false},
+		},
+	}
+}
+
+func (i *ComposeTargetProvider) projectName(component model.ComponentSpec, injections *model.ValueInjections) (string, error) {
+	project := model.ReadPropertyCompat(component.Properties, composeProject, injections)
+	if project == "" {
+		project = component.Name
+	}
+	if !projectNamePattern.MatchString(project) {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' is not a valid compose project name, it must match %s", project, projectNamePattern.String()), v1alpha2.BadRequest)
+	}
+	return project, nil
+}
+
+func (i *ComposeTargetProvider) composeFilePath(project string) string {
+	return filepath.Join(i.Config.WorkingDir, project, composeFileName)
+}
+
+func (i *ComposeTargetProvider) writeComposeFile(project string, content []byte) error {
+	path := i.composeFilePath(project)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// readContent reads the compose document of a component, either inline from the compose.content property
+// or from the compose.content (or content) property of the catalog named by compose.catalog
+func (i *ComposeTargetProvider) readContent(ctx context.Context, component model.ComponentSpec, injections *model.ValueInjections) ([]byte, error) {
+	if v, ok := component.Properties[composeContent]; ok {
+		return toComposeDocument(v, injections)
+	}
+	catalogName := model.ReadPropertyCompat(component.Properties, composeCatalog, injections)
+	if catalogName == "" {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("component %s needs either a %s or a %s property", component.Name, composeContent, composeCatalog), v1alpha2.BadRequest)
+	}
+	if i.Config.BaseUrl == "" {
+		return nil, v1alpha2.NewCOAError(nil, "baseUrl is required to read compose documents from catalogs", v1alpha2.BadConfig)
+	}
+	catalog, err := utils.GetCatalog(ctx, i.Config.BaseUrl, catalogName, i.Config.User, i.Config.Password)
+	if err != nil {
+		return nil, err
+	}
+	if catalog.Spec != nil {
+		for _, key := range []string{composeContent, "content"} {
+			if v, ok := catalog.Spec.Properties[key]; ok {
+				return toComposeDocument(v, injections)
+			}
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("catalog %s doesn't contain a compose document", catalogName), v1alpha2.NotFound)
+}
+
+// toComposeDocument accepts a compose document either as a YAML string or as a structured object
+func toComposeDocument(value interface{}, injections *model.ValueInjections) ([]byte, error) {
+	if str, ok := value.(string); ok {
+		return []byte(model.ResolveString(str, injections)), nil
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to convert compose content to YAML", v1alpha2.BadRequest)
+	}
+	return data, nil
+}
+
+func contentHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func (i *ComposeTargetProvider) listServices(ctx context.Context, project string) ([]serviceState, error) {
+	out, err := i.runCompose(ctx, project, "ps", "--all", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]serviceState, 0)
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return ret, nil
+	}
+	// older Compose versions print a JSON array, newer versions print one JSON object per line
+	if out[0] == '[' {
+		if err := json.Unmarshal(out, &ret); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, line := range strings.Split(string(out), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			var s serviceState
+			if err := json.Unmarshal([]byte(line), &s); err != nil {
+				return nil, err
+			}
+			ret = append(ret, s)
+		}
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Service < ret[b].Service
+	})
+	return ret, nil
+}
+
+func (i *ComposeTargetProvider) runCompose(ctx context.Context, project string, args ...string) ([]byte, error) {
+	command := strings.Fields(i.Config.Command)
+	params := append(command[1:], "--project-name", project, "--file", i.composeFilePath(project))
+	params = append(params, args...)
+	cmd := exec.CommandContext(ctx, command[0], params...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err.Error(), msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCompose = `services:
+  web:
+    image: nginx:latest
+`
+
+// fakeCompose writes a script that records its arguments and prints the content of the ps file for "ps" calls
+func fakeCompose(t *testing.T) (*ComposeTargetProvider, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping because the fake compose command is a shell script")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "compose.sh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" >> "`+dir+`/calls"
+for arg in "$@"; do
+	if [ "$arg" = "ps" ]; then
+		cat "`+dir+`/ps" 2>/dev/null
+	fi
+	if [ "$arg" = "fail" ]; then
+		echo "compose failed" >&2
+		exit 1
+	fi
+done
+`), 0755)
+	assert.Nil(t, err)
+	provider := &ComposeTargetProvider{}
+	err = provider.Init(ComposeTargetProviderConfig{
+		Command:    script,
+		WorkingDir: filepath.Join(dir, "projects"),
+	})
+	assert.Nil(t, err)
+	return provider, dir
+}
+
+func readCalls(t *testing.T, dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, "calls"))
+	if os.IsNotExist(err) {
+		return []string{}
+	}
+	assert.Nil(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func composeTestStep(action string, component model.ComponentSpec) (model.DeploymentSpec, model.DeploymentStep) {
+	deployment := model.DeploymentSpec{
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{component},
+		},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action:    action,
+				Component: component,
+			},
+		},
+	}
+	return deployment, step
+}
+
+func TestComposeTargetProviderConfigFromMap(t *testing.T) {
+	config, err := ComposeTargetProviderConfigFromMap(map[string]string{
+		"name":       "compose",
+		"command":    "docker-compose",
+		"workingDir": "/tmp/compose",
+		"baseUrl":    "http://localhost:8082/v1alpha2/",
+		"user":       "admin",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "docker-compose", config.Command)
+	assert.Equal(t, "/tmp/compose", config.WorkingDir)
+	assert.Equal(t, "admin", config.User)
+}
+
+func TestComposeTargetProviderInitDefaults(t *testing.T) {
+	provider := ComposeTargetProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, "docker compose", provider.Config.Command)
+	assert.NotEmpty(t, provider.Config.WorkingDir)
+}
+
+func TestComposeApplyAndGet(t *testing.T) {
+	provider, dir := fakeCompose(t)
+	component := model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.content": testCompose,
+		},
+	}
+	deployment, step := composeTestStep("update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", ret["webapp"].Status.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "projects", "webapp", "compose.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, testCompose, string(data))
+	calls := readCalls(t, dir)
+	assert.Equal(t, 1, len(calls))
+	assert.True(t, strings.HasPrefix(calls[0], "--project-name webapp --file "))
+	assert.True(t, strings.HasSuffix(calls[0], "up -d --remove-orphans"))
+
+	err = os.WriteFile(filepath.Join(dir, "ps"), []byte(`{"Service":"web","State":"running","Health":"healthy"}
+{"Service":"db","State":"exited"}
+`), 0644)
+	assert.Nil(t, err)
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "running", components[0].Properties["service.web.state"])
+	assert.Equal(t, "healthy", components[0].Properties["service.web.health"])
+	assert.Equal(t, "exited", components[0].Properties["service.db.state"])
+	assert.Equal(t, contentHash([]byte(testCompose)), components[0].Properties["compose.contentHash"])
+	assert.False(t, provider.GetValidationRule(context.Background()).IsComponentChanged(components[0], component))
+
+	// a changed document is detected through the missing content hash
+	changed := model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.content": testCompose + "  db:\n    image: redis:latest\n",
+		},
+	}
+	_, step = composeTestStep("update", changed)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Nil(t, components[0].Properties["compose.contentHash"])
+	assert.True(t, provider.GetValidationRule(context.Background()).IsComponentChanged(components[0], changed))
+}
+
+func TestComposeGetLegacyPsFormat(t *testing.T) {
+	provider, dir := fakeCompose(t)
+	component := model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.content": testCompose,
+			"compose.project": "edge-app",
+		},
+	}
+	deployment, step := composeTestStep("update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	err = os.WriteFile(filepath.Join(dir, "ps"), []byte(`[{"Service":"web","State":"running"}]`), 0644)
+	assert.Nil(t, err)
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "edge-app", components[0].Properties["compose.project"])
+	assert.Equal(t, "running", components[0].Properties["service.web.state"])
+}
+
+func TestComposeGetNotDeployed(t *testing.T) {
+	provider, dir := fakeCompose(t)
+	_, step := composeTestStep("update", model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.content": testCompose,
+		},
+	})
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+	assert.Equal(t, 0, len(readCalls(t, dir)))
+}
+
+func TestComposeApplyStructuredContent(t *testing.T) {
+	provider, dir := fakeCompose(t)
+	deployment, step := composeTestStep("update", model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.content": map[string]interface{}{
+				"services": map[string]interface{}{
+					"web": map[string]interface{}{
+						"image": "nginx:latest",
+					},
+				},
+			},
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "projects", "webapp", "compose.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, testCompose, string(data))
+}
+
+func TestComposeApplyFromCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/auth":
+			json.NewEncoder(w).Encode(map[string]string{"accessToken": "token"})
+		case "/catalogs/registry/webapp-compose":
+			json.NewEncoder(w).Encode(model.CatalogState{
+				Id: "webapp-compose",
+				Spec: &model.CatalogSpec{
+					Name: "webapp-compose",
+					Properties: map[string]interface{}{
+						"content": testCompose,
+					},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, dir := fakeCompose(t)
+	provider.Config.BaseUrl = server.URL + "/"
+	deployment, step := composeTestStep("update", model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.catalog": "webapp-compose",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "projects", "webapp", "compose.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, testCompose, string(data))
+}
+
+func TestComposeApplyMissingContent(t *testing.T) {
+	provider, _ := fakeCompose(t)
+	deployment, step := composeTestStep("update", model.ComponentSpec{
+		Name:       "webapp",
+		Properties: map[string]interface{}{},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestComposeApplyFailure(t *testing.T) {
+	provider, _ := fakeCompose(t)
+	deployment, step := composeTestStep("update", model.ComponentSpec{
+		Name: "fail",
+		Properties: map[string]interface{}{
+			"compose.content": testCompose,
+		},
+	})
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "compose failed")
+	assert.Equal(t, "Update Failed", ret["fail"].Status.String())
+}
+
+func TestComposeDelete(t *testing.T) {
+	provider, dir := fakeCompose(t)
+	component := model.ComponentSpec{
+		Name: "webapp",
+		Properties: map[string]interface{}{
+			"compose.content": testCompose,
+		},
+	}
+	deployment, step := composeTestStep("update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+
+	deployment, step = composeTestStep("delete", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Deleted", ret["webapp"].Status.String())
+	calls := readCalls(t, dir)
+	assert.Equal(t, 2, len(calls))
+	assert.True(t, strings.HasSuffix(calls[1], "down --remove-orphans"))
+	_, err = os.Stat(filepath.Join(dir, "projects", "webapp"))
+	assert.True(t, os.IsNotExist(err))
+
+	// deleting a project that was never deployed doesn't invoke compose
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Deleted", ret["webapp"].Status.String())
+	assert.Equal(t, 2, len(readCalls(t, dir)))
+}
+
+func TestComposeInvalidProjectName(t *testing.T) {
+	provider, dir := fakeCompose(t)
+	outside := filepath.Join(dir, "outside")
+	err := os.MkdirAll(outside, 0755)
+	assert.Nil(t, err)
+	for _, project := range []string{"../outside", "..", "web/app", "WebApp", "-webapp"} {
+		component := model.ComponentSpec{
+			Name: "webapp",
+			Properties: map[string]interface{}{
+				"compose.content": testCompose,
+				"compose.project": project,
+			},
+		}
+		deployment, step := composeTestStep("update", component)
+		_, err = provider.Get(context.Background(), deployment, step.Components)
+		assert.NotNil(t, err, project)
+		ret, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.NotNil(t, err, project)
+		assert.Equal(t, "Update Failed", ret["webapp"].Status.String())
+
+		deployment, step = composeTestStep("delete", component)
+		ret, err = provider.Apply(context.Background(), deployment, step, false)
+		assert.NotNil(t, err, project)
+		assert.Equal(t, "Delete Failed", ret["webapp"].Status.String())
+	}
+	_, err = os.Stat(outside)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "projects"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, 0, len(readCalls(t, dir)))
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := &ComposeTargetProvider{}
+	err := provider.Init(ComposeTargetProviderConfig{})
+	assert.Nil(t, err)
+	conformance.ConformanceSuite(t, provider)
+}
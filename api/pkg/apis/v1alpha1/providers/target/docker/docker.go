@@ -8,31 +8,58 @@ package docker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
 var sLog = logger.NewLogger("coa.runtime")
 
+const (
+	pullPolicyAlways       = "Always"
+	pullPolicyIfNotPresent = "IfNotPresent"
+	pullPolicyNever        = "Never"
+	labelSpecHash          = "symphony.specHash"
+	defaultHealthTimeout   = 60
+)
+
+// healthPollInterval is how often a container is inspected while waiting for it to become healthy
+var healthPollInterval = time.Second
+
 type DockerTargetProviderConfig struct {
 	Name string `json:"name"`
+	// Host overrides the Docker daemon address read from the DOCKER_HOST environment variable
+	Host string `json:"host,omitempty"`
 }
 
 type DockerTargetProvider struct {
 	Config  DockerTargetProviderConfig
 	Context *contexts.ManagerContext
+	// SecretProvider overrides the secret provider of the vendor evaluation context when set
+	SecretProvider secret.ISecretProvider
 }
 
 func DockerTargetProviderConfigFromMap(properties map[string]string) (DockerTargetProviderConfig, error) {
@@ -40,6 +67,9 @@ func DockerTargetProviderConfigFromMap(properties map[string]string) (DockerTarg
 	if v, ok := properties["name"]; ok {
 		ret.Name = v
 	}
+	if v, ok := properties["host"]; ok {
+		ret.Host = v
+	}
 	return ret, nil
 }
 func (d *DockerTargetProvider) InitWithMap(properties map[string]string) error {
@@ -91,11 +121,12 @@ func (i *DockerTargetProvider) Get(ctx context.Context, deployment model.Deploym
 
 	sLog.Infof("  P (Docker Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
-	cli, err := client.NewClientWithOpts(client.FromEnv)
+	cli, err := i.newClient()
 	if err != nil {
 		sLog.Errorf("  P (Docker Target): failed to create docker client: %+v", err)
 		return nil, err
 	}
+	defer cli.Close()
 
 	ret := make([]model.ComponentSpec, 0)
 	for _, component := range references {
@@ -120,6 +151,33 @@ func (i *DockerTargetProvider) Get(ctx context.Context, deployment model.Deploym
 			if info.HostConfig != nil {
 				resources, _ := json.Marshal(info.HostConfig.Resources)
 				component.Properties["container.resources"] = string(resources)
+				// container.restartPolicy
+				if info.HostConfig.RestartPolicy.Name != "" {
+					component.Properties["container.restartPolicy"] = formatRestartPolicy(info.HostConfig.RestartPolicy)
+				}
+			}
+			// container.networks
+			if info.NetworkSettings != nil && len(info.NetworkSettings.Networks) > 0 {
+				networks := make([]string, 0)
+				for k := range info.NetworkSettings.Networks {
+					networks = append(networks, k)
+				}
+				sort.Strings(networks)
+				component.Properties["container.networks"] = strings.Join(networks, ",")
+			}
+			// labels that are passed in by the reference
+			if info.Config.Labels != nil {
+				for _, s := range references {
+					if s.Component.Name == component.Name {
+						for k := range s.Component.Properties {
+							if strings.HasPrefix(k, "label.") {
+								if v, ok := info.Config.Labels[strings.TrimPrefix(k, "label.")]; ok {
+									component.Properties[k] = v
+								}
+							}
+						}
+					}
+				}
 			}
 			// container.ports
 			if info.NetworkSettings != nil && len(info.NetworkSettings.Ports) > 0 {
@@ -188,52 +246,71 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 
 	ret := step.PrepareResultMap()
 
-	cli, err := client.NewClientWithOpts(client.FromEnv)
+	cli, err := i.newClient()
 	if err != nil {
 		sLog.Errorf("  P (Docker Target): failed to create docker client: %+v", err)
 		return ret, err
 	}
+	defer cli.Close()
 
 	for _, component := range step.Components {
 		if component.Action == "update" {
 			image := model.ReadPropertyCompat(component.Component.Properties, model.ContainerImage, injections)
-			resources := model.ReadPropertyCompat(component.Component.Properties, "container.resources", injections)
 			if image == "" {
 				err = errors.New("component doesn't have container.image property")
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
 					Message: err.Error(),
 				}
-				sLog.Errorf("  P (Helm Target): component doesn't have container.image property")
+				sLog.Errorf("  P (Docker Target): component doesn't have container.image property")
+				return ret, err
+			}
+
+			var imageID string
+			imageID, err = i.ensureImage(ctx, cli, component.Component, image, injections)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Docker Target): failed to prepare image %s: %+v", image, err)
 				return ret, err
 			}
 
+			specHash := computeSpecHash(component.Component)
 			alreadyRunning := true
-			_, err = cli.ContainerInspect(ctx, component.Component.Name)
-			if err != nil { //TODO: check if the error is ErrNotFound
+			var info types.ContainerJSON
+			info, err = cli.ContainerInspect(ctx, component.Component.Name)
+			if err != nil {
+				if !client.IsErrNotFound(err) {
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.UpdateFailed,
+						Message: err.Error(),
+					}
+					sLog.Errorf("  P (Docker Target): failed to inspect container: %+v", err)
+					return ret, err
+				}
 				alreadyRunning = false
 			}
 
-			// TODO: I don't think we need to do an explict image pull here, as Docker will pull the image upon cache miss
-			// reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{})
-			// if err != nil {
-			// 	observ_utils.CloseSpanWithError(span, &err)
-			// 	sLog.Errorf("  P (Docker Target): failed to pull docker image: %+v", err)
-			// 	return err
-			// }
-
-			// defer reader.Close()
-			// io.Copy(os.Stdout, reader)
+			if alreadyRunning && isUpToDate(info, imageID, specHash) {
+				sLog.Infof("  P (Docker Target): container %s is up to date, skipping", component.Component.Name)
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.Updated,
+					Message: "container is up to date",
+				}
+				continue
+			}
 
 			if alreadyRunning {
-				err = cli.ContainerStop(context.TODO(), component.Component.Name, nil)
+				err = cli.ContainerStop(ctx, component.Component.Name, nil)
 				if err != nil {
 					if !client.IsErrNotFound(err) {
 						sLog.Errorf("  P (Docker Target): failed to stop a running container: %+v", err)
 						return ret, err
 					}
 				}
-				err = cli.ContainerRemove(context.TODO(), component.Component.Name, types.ContainerRemoveOptions{})
+				err = cli.ContainerRemove(ctx, component.Component.Name, types.ContainerRemoveOptions{})
 				if err != nil {
 					ret[component.Component.Name] = model.ComponentResultSpec{
 						Status:  v1alpha2.UpdateFailed,
@@ -244,36 +321,40 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 				}
 			}
 
-			// prepare environment variables
-			env := make([]string, 0)
-			for k, v := range component.Component.Properties {
-				if strings.HasPrefix(k, "env.") {
-					env = append(env, strings.TrimPrefix(k, "env.")+"="+v.(string))
+			var containerConfig *container.Config
+			var hostConfig *container.HostConfig
+			var networks []string
+			containerConfig, hostConfig, networks, err = buildContainerConfig(component.Component, image, specHash, injections)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
 				}
+				sLog.Errorf("  P (Docker Target): failed to read container settings: %+v", err)
+				return ret, err
 			}
 
-			containerConfig := container.Config{
-				Image: image,
-				Env:   env,
-			}
-			var hostConfig *container.HostConfig
-			if resources != "" {
-				var resourceSpec container.Resources
-				err = json.Unmarshal([]byte(resources), &resourceSpec)
-				if err != nil {
-					ret[component.Component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.UpdateFailed,
-						Message: err.Error(),
-					}
-					sLog.Errorf("  P (Docker Target): failed to read container resource settings: %+v", err)
-					return ret, err
+			err = ensureNetworks(ctx, cli, networks)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
 				}
-				hostConfig = &container.HostConfig{
-					Resources: resourceSpec,
+				sLog.Errorf("  P (Docker Target): failed to prepare networks: %+v", err)
+				return ret, err
+			}
+			var networkingConfig *network.NetworkingConfig
+			if len(networks) > 0 {
+				hostConfig.NetworkMode = container.NetworkMode(networks[0])
+				networkingConfig = &network.NetworkingConfig{
+					EndpointsConfig: map[string]*network.EndpointSettings{
+						networks[0]: {},
+					},
 				}
 			}
-			var container container.ContainerCreateCreatedBody
-			container, err = cli.ContainerCreate(context.TODO(), &containerConfig, hostConfig, nil, nil, component.Component.Name)
+
+			var created container.ContainerCreateCreatedBody
+			created, err = cli.ContainerCreate(ctx, containerConfig, hostConfig, networkingConfig, nil, component.Component.Name)
 			if err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
@@ -283,7 +364,20 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 				return ret, err
 			}
 
-			if err = cli.ContainerStart(context.TODO(), container.ID, types.ContainerStartOptions{}); err != nil {
+			// the create call attaches a single network, additional networks are connected afterwards
+			for _, n := range networks[min(1, len(networks)):] {
+				err = cli.NetworkConnect(ctx, n, created.ID, &network.EndpointSettings{})
+				if err != nil {
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.UpdateFailed,
+						Message: err.Error(),
+					}
+					sLog.Errorf("  P (Docker Target): failed to connect container to network %s: %+v", n, err)
+					return ret, err
+				}
+			}
+
+			if err = cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
 					Message: err.Error(),
@@ -291,25 +385,50 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 				sLog.Errorf("  P (Docker Target): failed to start container: %+v", err)
 				return ret, err
 			}
+
+			if model.ReadPropertyCompat(component.Component.Properties, "container.waitForHealthy", injections) == "true" {
+				timeout := defaultHealthTimeout
+				if v := model.ReadPropertyCompat(component.Component.Properties, "container.healthTimeout", injections); v != "" {
+					timeout, err = strconv.Atoi(v)
+					if err != nil {
+						ret[component.Component.Name] = model.ComponentResultSpec{
+							Status:  v1alpha2.UpdateFailed,
+							Message: err.Error(),
+						}
+						sLog.Errorf("  P (Docker Target): invalid container.healthTimeout: %+v", err)
+						return ret, err
+					}
+				}
+				err = waitForHealthy(ctx, cli, created.ID, time.Duration(timeout)*time.Second)
+				if err != nil {
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.UpdateFailed,
+						Message: err.Error(),
+					}
+					sLog.Errorf("  P (Docker Target): container %s didn't become healthy: %+v", component.Component.Name, err)
+					return ret, err
+				}
+			}
 			ret[component.Component.Name] = model.ComponentResultSpec{
 				Status:  v1alpha2.Updated,
 				Message: "",
 			}
 		} else {
-			err = cli.ContainerStop(context.TODO(), component.Component.Name, nil)
+			err = cli.ContainerStop(ctx, component.Component.Name, nil)
 			if err != nil {
 				if !client.IsErrNotFound(err) {
 					sLog.Errorf("  P (Docker Target): failed to stop a running container: %+v", err)
 					return ret, err
 				}
 			}
-			err = cli.ContainerRemove(context.TODO(), component.Component.Name, types.ContainerRemoveOptions{})
+			err = cli.ContainerRemove(ctx, component.Component.Name, types.ContainerRemoveOptions{})
 			if err != nil {
 				if !client.IsErrNotFound(err) {
 					sLog.Errorf("  P (Docker Target): failed to remove existing container: %+v", err)
 					return ret, err
 				}
 			}
+			err = nil
 			ret[component.Component.Name] = model.ComponentResultSpec{
 				Status:  v1alpha2.Deleted,
 				Message: "",
@@ -321,8 +440,20 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 
 func (*DockerTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
-		RequiredProperties:    []string{model.ContainerImage},
-		OptionalProperties:    []string{"container.resources"},
+		RequiredProperties: []string{model.ContainerImage},
+		OptionalProperties: []string{
+			"container.resources",
+			"container.pullPolicy",
+			"container.registry.secret",
+			"container.registry.server",
+			"container.ports",
+			"container.commands",
+			"container.networks",
+			"container.restartPolicy",
+			"container.healthcheck",
+			"container.waitForHealthy",
+			"container.healthTimeout",
+		},
 		RequiredComponentType: "",
 		RequiredMetadata:      []string{},
 		OptionalMetadata:      []string{},
@@ -330,6 +461,343 @@ func (*DockerTargetProvider) GetValidationRule(ctx context.Context) model.Valida
 			{Name: model.ContainerImage, IgnoreCase: false, SkipIfMissing: false},
 			{Name: "container.ports", IgnoreCase: false, SkipIfMissing: true},
 			{Name: "container.resources", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.restartPolicy", IgnoreCase: true, SkipIfMissing: true},
+			{Name: "container.networks", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "label.*", IgnoreCase: false, SkipIfMissing: true},
 		},
 	}
 }
+
+func (i *DockerTargetProvider) newClient() (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if i.Config.Host != "" {
+		opts = append(opts, client.WithHost(i.Config.Host))
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+// readRegistryAuth builds the encoded registry credentials for an image pull. Credentials are read from
+// the username and password fields of the secret object named by the container.registry.secret property.
+func (i *DockerTargetProvider) readRegistryAuth(component model.ComponentSpec, injections *model.ValueInjections) (string, error) {
+	secretName := model.ReadPropertyCompat(component.Properties, "container.registry.secret", injections)
+	if secretName == "" {
+		return "", nil
+	}
+	secretProvider := target.SecretProvider(i.SecretProvider, i.Context)
+	username, err := target.ReadSecretValue(secretProvider, "", secretName, "username", "container.registry.secret")
+	if err != nil {
+		return "", err
+	}
+	password, err := target.ReadSecretValue(secretProvider, "", secretName, "password", "container.registry.secret")
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(types.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: model.ReadPropertyCompat(component.Properties, "container.registry.server", injections),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// ensureImage makes the image available according to the container.pullPolicy property and returns
+// the local image ID.
+func (i *DockerTargetProvider) ensureImage(ctx context.Context, cli *client.Client, component model.ComponentSpec, image string, injections *model.ValueInjections) (string, error) {
+	policy := model.ReadPropertyCompat(component.Properties, "container.pullPolicy", injections)
+	if policy == "" {
+		policy = pullPolicyIfNotPresent
+	}
+	pull := false
+	switch policy {
+	case pullPolicyAlways:
+		pull = true
+	case pullPolicyIfNotPresent:
+		_, _, err := cli.ImageInspectWithRaw(ctx, image)
+		if err != nil {
+			if !client.IsErrNotFound(err) {
+				return "", err
+			}
+			pull = true
+		}
+	case pullPolicyNever:
+	default:
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("container.pullPolicy '%s' is not supported", policy), v1alpha2.BadRequest)
+	}
+	if pull {
+		auth, err := i.readRegistryAuth(component, injections)
+		if err != nil {
+			return "", err
+		}
+		reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: auth})
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		// pull failures are reported in the progress stream rather than as a status code
+		decoder := json.NewDecoder(reader)
+		for {
+			var message struct {
+				Error string `json:"error,omitempty"`
+			}
+			if err := decoder.Decode(&message); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return "", err
+			}
+			if message.Error != "" {
+				return "", errors.New(message.Error)
+			}
+		}
+	}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	return inspect.ID, nil
+}
+
+func buildContainerConfig(component model.ComponentSpec, image string, specHash string, injections *model.ValueInjections) (*container.Config, *container.HostConfig, []string, error) {
+	// prepare environment variables and labels
+	env := make([]string, 0)
+	labels := map[string]string{
+		labelSpecHash: specHash,
+	}
+	for k, v := range component.Properties {
+		if strings.HasPrefix(k, "env.") {
+			env = append(env, strings.TrimPrefix(k, "env.")+"="+model.ResolveString(fmt.Sprintf("%v", v), injections))
+		}
+		if strings.HasPrefix(k, "label.") {
+			labels[strings.TrimPrefix(k, "label.")] = model.ResolveString(fmt.Sprintf("%v", v), injections)
+		}
+	}
+	sort.Strings(env)
+
+	containerConfig := &container.Config{
+		Image:  image,
+		Env:    env,
+		Labels: labels,
+	}
+	hostConfig := &container.HostConfig{}
+
+	if resources := model.ReadPropertyCompat(component.Properties, "container.resources", injections); resources != "" {
+		var resourceSpec container.Resources
+		if err := json.Unmarshal([]byte(resources), &resourceSpec); err != nil {
+			return nil, nil, nil, err
+		}
+		hostConfig.Resources = resourceSpec
+	}
+	if ports := model.ReadPropertyCompat(component.Properties, "container.ports", injections); ports != "" {
+		var portMap nat.PortMap
+		if err := json.Unmarshal([]byte(ports), &portMap); err != nil {
+			return nil, nil, nil, err
+		}
+		containerConfig.ExposedPorts = nat.PortSet{}
+		for p := range portMap {
+			containerConfig.ExposedPorts[p] = struct{}{}
+		}
+		hostConfig.PortBindings = portMap
+	}
+	if commands := model.ReadPropertyCompat(component.Properties, "container.commands", injections); commands != "" {
+		var cmd []string
+		if err := json.Unmarshal([]byte(commands), &cmd); err != nil {
+			return nil, nil, nil, err
+		}
+		containerConfig.Cmd = cmd
+	}
+	if policy := model.ReadPropertyCompat(component.Properties, "container.restartPolicy", injections); policy != "" {
+		restartPolicy, err := parseRestartPolicy(policy)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hostConfig.RestartPolicy = restartPolicy
+	}
+	if healthcheck := model.ReadPropertyCompat(component.Properties, "container.healthcheck", injections); healthcheck != "" {
+		healthConfig, err := parseHealthCheck(healthcheck)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		containerConfig.Healthcheck = healthConfig
+	}
+	networks, err := parseList(model.ReadPropertyCompat(component.Properties, "container.networks", injections))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return containerConfig, hostConfig, networks, nil
+}
+
+// parseRestartPolicy parses a restart policy in the Docker CLI format: no, always, unless-stopped or on-failure[:max-retries]
+func parseRestartPolicy(policy string) (container.RestartPolicy, error) {
+	parts := strings.SplitN(policy, ":", 2)
+	ret := container.RestartPolicy{Name: parts[0]}
+	switch parts[0] {
+	case "no", "always", "unless-stopped":
+		if len(parts) > 1 {
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("restart policy '%s' doesn't take a retry count", parts[0]), v1alpha2.BadRequest)
+		}
+	case "on-failure":
+		if len(parts) > 1 {
+			count, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid retry count in restart policy '%s'", policy), v1alpha2.BadRequest)
+			}
+			ret.MaximumRetryCount = count
+		}
+	default:
+		return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("restart policy '%s' is not supported", policy), v1alpha2.BadRequest)
+	}
+	return ret, nil
+}
+
+func formatRestartPolicy(policy container.RestartPolicy) string {
+	if policy.Name == "on-failure" && policy.MaximumRetryCount > 0 {
+		return fmt.Sprintf("%s:%d", policy.Name, policy.MaximumRetryCount)
+	}
+	return policy.Name
+}
+
+type healthCheckSpec struct {
+	Test        []string `json:"test"`
+	Interval    string   `json:"interval,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`
+	StartPeriod string   `json:"startPeriod,omitempty"`
+	Retries     int      `json:"retries,omitempty"`
+}
+
+// parseHealthCheck parses a health check like {"test": ["CMD", "curl", "-f", "http://localhost"], "interval": "10s"}
+func parseHealthCheck(healthcheck string) (*container.HealthConfig, error) {
+	var spec healthCheckSpec
+	if err := json.Unmarshal([]byte(healthcheck), &spec); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "container.healthcheck is not a valid health check", v1alpha2.BadRequest)
+	}
+	ret := &container.HealthConfig{
+		Test:    spec.Test,
+		Retries: spec.Retries,
+	}
+	var err error
+	if spec.Interval != "" {
+		if ret.Interval, err = time.ParseDuration(spec.Interval); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Timeout != "" {
+		if ret.Timeout, err = time.ParseDuration(spec.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	if spec.StartPeriod != "" {
+		if ret.StartPeriod, err = time.ParseDuration(spec.StartPeriod); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// parseList parses either a JSON array or a comma-separated list of strings
+func parseList(value string) ([]string, error) {
+	ret := make([]string, 0)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ret, nil
+	}
+	if strings.HasPrefix(value, "[") {
+		err := json.Unmarshal([]byte(value), &ret)
+		return ret, err
+	}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret, nil
+}
+
+func ensureNetworks(ctx context.Context, cli *client.Client, networks []string) error {
+	for _, n := range networks {
+		_, err := cli.NetworkInspect(ctx, n, types.NetworkInspectOptions{})
+		if err == nil {
+			continue
+		}
+		if !client.IsErrNotFound(err) {
+			return err
+		}
+		sLog.Infof("  P (Docker Target): creating network %s", n)
+		_, err = cli.NetworkCreate(ctx, n, types.NetworkCreate{CheckDuplicate: true})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// computeSpecHash hashes the component properties so that a running container can be checked against
+// the desired spec without comparing every setting individually.
+func computeSpecHash(component model.ComponentSpec) string {
+	keys := make([]string, 0, len(component.Properties))
+	for k := range component.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, k := range keys {
+		hash.Write([]byte(fmt.Sprintf("%s=%v\n", k, component.Properties[k])))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isUpToDate(info types.ContainerJSON, imageID string, specHash string) bool {
+	if info.ContainerJSONBase == nil || info.State == nil || !info.State.Running {
+		return false
+	}
+	if info.Image != imageID {
+		return false
+	}
+	return info.Config != nil && info.Config.Labels[labelSpecHash] == specHash
+}
+
+// waitForHealthy waits for a container to report a healthy status. Containers without a health check
+// are considered healthy once they are running.
+func waitForHealthy(ctx context.Context, cli *client.Client, id string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return err
+		}
+		if info.State != nil {
+			if info.State.Health == nil {
+				if info.State.Running {
+					return nil
+				}
+				if info.State.Status == "exited" || info.State.Status == "dead" {
+					return fmt.Errorf("container stopped with exit code %d", info.State.ExitCode)
+				}
+			} else {
+				switch info.State.Health.Status {
+				case types.Healthy:
+					return nil
+				case types.Unhealthy:
+					return errors.New("container reported an unhealthy status")
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
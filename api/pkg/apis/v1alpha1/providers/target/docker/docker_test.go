@@ -8,11 +8,22 @@ package docker
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -225,3 +236,415 @@ func TestConformanceSuite(t *testing.T) {
 	assert.Nil(t, err)
 	conformance.ConformanceSuite(t, provider)
 }
+
+type fakeContainer struct {
+	ID       string
+	Image    string
+	Config   container.Config
+	Host     container.HostConfig
+	Running  bool
+	Health   string
+	Networks []string
+}
+
+// fakeDockerServer emulates the subset of the Docker Engine API used by the provider
+type fakeDockerServer struct {
+	lock       sync.Mutex
+	containers map[string]*fakeContainer
+	images     map[string]string
+	networks   map[string]bool
+	pulls      []string
+	pullAuth   string
+	creates    int
+	health     string
+}
+
+func newFakeDockerServer(t *testing.T) (*fakeDockerServer, *DockerTargetProvider) {
+	fake := &fakeDockerServer{
+		containers: map[string]*fakeContainer{},
+		images:     map[string]string{},
+		networks:   map[string]bool{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+	provider := &DockerTargetProvider{}
+	err := provider.Init(DockerTargetProviderConfig{Host: "tcp://" + strings.TrimPrefix(server.URL, "http://")})
+	assert.Nil(t, err)
+	return fake, provider
+}
+
+func (f *fakeDockerServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/v") {
+		path = path[strings.Index(path[1:], "/")+1:]
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "not found"})
+	}
+	switch {
+	case parts[0] == "images" && parts[1] == "create":
+		image := r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+		f.pulls = append(f.pulls, image)
+		f.pullAuth = r.Header.Get("X-Registry-Auth")
+		if strings.HasPrefix(image, "missing") {
+			json.NewEncoder(w).Encode(map[string]string{"error": "manifest unknown"})
+			return
+		}
+		f.images[image] = "sha256:" + image
+		json.NewEncoder(w).Encode(map[string]string{"status": "Downloaded"})
+	case parts[0] == "images" && parts[len(parts)-1] == "json":
+		image := strings.Join(parts[1:len(parts)-1], "/")
+		id, ok := f.images[image]
+		if !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(types.ImageInspect{ID: id})
+	case parts[0] == "networks" && len(parts) == 2 && parts[1] == "create":
+		var req types.NetworkCreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.networks[req.Name] = true
+		json.NewEncoder(w).Encode(types.NetworkCreateResponse{ID: req.Name})
+	case parts[0] == "networks" && len(parts) == 3 && parts[2] == "connect":
+		var req types.NetworkConnect
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, c := range f.containers {
+			if c.ID == req.Container {
+				c.Networks = append(c.Networks, parts[1])
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	case parts[0] == "networks" && len(parts) == 2:
+		if !f.networks[parts[1]] {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(types.NetworkResource{Name: parts[1], ID: parts[1]})
+	case parts[0] == "containers" && parts[1] == "create":
+		name := r.URL.Query().Get("name")
+		var req struct {
+			container.Config
+			HostConfig       container.HostConfig
+			NetworkingConfig network.NetworkingConfig
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.creates++
+		c := &fakeContainer{ID: "id-" + name, Image: f.images[req.Image], Config: req.Config, Host: req.HostConfig}
+		for n := range req.NetworkingConfig.EndpointsConfig {
+			c.Networks = append(c.Networks, n)
+		}
+		f.containers[name] = c
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(container.ContainerCreateCreatedBody{ID: c.ID})
+	case parts[0] == "containers" && len(parts) >= 2:
+		var c *fakeContainer
+		var name string
+		for n, v := range f.containers {
+			if n == parts[1] || v.ID == parts[1] {
+				c, name = v, n
+			}
+		}
+		if c == nil {
+			notFound()
+			return
+		}
+		action := ""
+		if len(parts) > 2 {
+			action = parts[2]
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.containers, name)
+			w.WriteHeader(http.StatusNoContent)
+		case action == "start":
+			c.Running = true
+			c.Health = f.health
+			w.WriteHeader(http.StatusNoContent)
+		case action == "stop":
+			c.Running = false
+			w.WriteHeader(http.StatusNoContent)
+		case action == "json":
+			state := &types.ContainerState{Running: c.Running, Status: "created"}
+			if c.Running {
+				state.Status = "running"
+			}
+			if c.Health != "" {
+				state.Health = &types.Health{Status: c.Health}
+			}
+			endpoints := map[string]*network.EndpointSettings{}
+			for _, n := range c.Networks {
+				endpoints[n] = &network.EndpointSettings{}
+			}
+			config := c.Config
+			host := c.Host
+			json.NewEncoder(w).Encode(types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					ID:         c.ID,
+					Name:       "/" + name,
+					Image:      c.Image,
+					State:      state,
+					HostConfig: &host,
+				},
+				Config:          &config,
+				NetworkSettings: &types.NetworkSettings{Networks: endpoints},
+			})
+		default:
+			notFound()
+		}
+	default:
+		notFound()
+	}
+}
+
+func dockerTestStep(component model.ComponentSpec) (model.DeploymentSpec, model.DeploymentStep) {
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{Name: "instance"},
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{component},
+		},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action:    "update",
+				Component: component,
+			},
+		},
+	}
+	return deployment, step
+}
+
+func TestApplyPullPolicyIfNotPresent(t *testing.T) {
+	fake, provider := newFakeDockerServer(t)
+	component := model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage: "redis:latest",
+			"env.MODE":           "test",
+			"label.team":         "edge",
+			"container.commands": "[\"redis-server\", \"--save\", \"\"]",
+		},
+	}
+	deployment, step := dockerTestStep(component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", ret["redis"].Status.String())
+	assert.Equal(t, []string{"redis:latest"}, fake.pulls)
+	assert.Equal(t, []string{"MODE=test"}, fake.containers["redis"].Config.Env)
+	assert.Equal(t, "edge", fake.containers["redis"].Config.Labels["team"])
+	assert.Equal(t, []string{"redis-server", "--save", ""}, []string(fake.containers["redis"].Config.Cmd))
+
+	// the image is present now so it isn't pulled again
+	component.Properties["env.MODE"] = "prod"
+	deployment, step = dockerTestStep(component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(fake.pulls))
+	assert.Equal(t, 2, fake.creates)
+}
+
+func TestApplyPullPolicyAlways(t *testing.T) {
+	fake, provider := newFakeDockerServer(t)
+	fake.images["redis:latest"] = "sha256:old"
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage:   "redis:latest",
+			"container.pullPolicy": "Always",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"redis:latest"}, fake.pulls)
+	assert.Equal(t, "sha256:redis:latest", fake.containers["redis"].Image)
+}
+
+func TestApplyPullPolicyNever(t *testing.T) {
+	fake, provider := newFakeDockerServer(t)
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage:   "redis:latest",
+			"container.pullPolicy": "Never",
+		},
+	})
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Update Failed", ret["redis"].Status.String())
+	assert.Equal(t, 0, len(fake.pulls))
+}
+
+func TestApplyPullFailure(t *testing.T) {
+	_, provider := newFakeDockerServer(t)
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "app",
+		Properties: map[string]interface{}{
+			model.ContainerImage: "missing:latest",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "manifest unknown")
+}
+
+func TestApplyRegistryAuth(t *testing.T) {
+	fake, provider := newFakeDockerServer(t)
+	provider.SecretProvider = &mock.MockSecretProvider{}
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "app",
+		Properties: map[string]interface{}{
+			model.ContainerImage:        "registry.example.com/app:1.0",
+			"container.registry.secret": "regcred",
+			"container.registry.server": "registry.example.com",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	data, err := base64.URLEncoding.DecodeString(fake.pullAuth)
+	assert.Nil(t, err)
+	var auth types.AuthConfig
+	assert.Nil(t, json.Unmarshal(data, &auth))
+	assert.Equal(t, "regcred>>username", auth.Username)
+	assert.Equal(t, "regcred>>password", auth.Password)
+	assert.Equal(t, "registry.example.com", auth.ServerAddress)
+}
+
+func TestApplyRegistryAuthWithoutSecretProvider(t *testing.T) {
+	_, provider := newFakeDockerServer(t)
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "app",
+		Properties: map[string]interface{}{
+			model.ContainerImage:        "registry.example.com/app:1.0",
+			"container.registry.secret": "regcred",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestApplyNetworksAndRestartPolicy(t *testing.T) {
+	fake, provider := newFakeDockerServer(t)
+	fake.networks["existing"] = true
+	component := model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage:      "redis:latest",
+			"container.networks":      "backend, existing",
+			"container.restartPolicy": "on-failure:3",
+			"container.ports":         "{\"6379/tcp\":[{\"HostPort\":\"6380\"}]}",
+		},
+	}
+	deployment, step := dockerTestStep(component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.True(t, fake.networks["backend"])
+	c := fake.containers["redis"]
+	assert.Equal(t, []string{"backend", "existing"}, c.Networks)
+	assert.Equal(t, "on-failure", c.Host.RestartPolicy.Name)
+	assert.Equal(t, 3, c.Host.RestartPolicy.MaximumRetryCount)
+	assert.Equal(t, "6380", c.Host.PortBindings["6379/tcp"][0].HostPort)
+
+	ret, err := provider.Get(context.Background(), deployment, []model.ComponentStep{
+		{
+			Action: "update",
+			Component: model.ComponentSpec{
+				Name:       "redis",
+				Properties: map[string]interface{}{model.ContainerImage: "redis:latest"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ret))
+	assert.Equal(t, "on-failure:3", ret[0].Properties["container.restartPolicy"])
+	assert.Equal(t, "backend,existing", ret[0].Properties["container.networks"])
+}
+
+func TestApplyUnchangedIsNoOp(t *testing.T) {
+	fake, provider := newFakeDockerServer(t)
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage: "redis:latest",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", ret["redis"].Status.String())
+	assert.Equal(t, 1, fake.creates)
+
+	// a new image digest forces the container to be recreated
+	fake.images["redis:latest"] = "sha256:new"
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, fake.creates)
+}
+
+func TestApplyWaitForHealthy(t *testing.T) {
+	healthPollInterval = 10 * time.Millisecond
+	fake, provider := newFakeDockerServer(t)
+	fake.health = types.Healthy
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage:       "redis:latest",
+			"container.healthcheck":    "{\"test\":[\"CMD\",\"redis-cli\",\"ping\"],\"interval\":\"5s\",\"retries\":3}",
+			"container.waitForHealthy": "true",
+		},
+	})
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", ret["redis"].Status.String())
+	assert.Equal(t, 5*time.Second, fake.containers["redis"].Config.Healthcheck.Interval)
+}
+
+func TestApplyWaitForHealthyTimeout(t *testing.T) {
+	healthPollInterval = 10 * time.Millisecond
+	fake, provider := newFakeDockerServer(t)
+	fake.health = types.Starting
+	deployment, step := dockerTestStep(model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage:       "redis:latest",
+			"container.healthcheck":    "{\"test\":[\"CMD\",\"redis-cli\",\"ping\"]}",
+			"container.waitForHealthy": "true",
+			"container.healthTimeout":  "0",
+		},
+	})
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Update Failed", ret["redis"].Status.String())
+}
+
+func TestApplyDeleteMissingContainer(t *testing.T) {
+	_, provider := newFakeDockerServer(t)
+	component := model.ComponentSpec{
+		Name: "redis",
+		Properties: map[string]interface{}{
+			model.ContainerImage: "redis:latest",
+		},
+	}
+	deployment, step := dockerTestStep(component)
+	step.Components[0].Action = "delete"
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Deleted", ret["redis"].Status.String())
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	policy, err := parseRestartPolicy("unless-stopped")
+	assert.Nil(t, err)
+	assert.Equal(t, "unless-stopped", formatRestartPolicy(policy))
+	policy, err = parseRestartPolicy("on-failure:5")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, policy.MaximumRetryCount)
+	_, err = parseRestartPolicy("always:5")
+	assert.NotNil(t, err)
+	_, err = parseRestartPolicy("sometimes")
+	assert.NotNil(t, err)
+}
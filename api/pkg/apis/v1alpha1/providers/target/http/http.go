@@ -9,17 +9,25 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
@@ -32,6 +40,8 @@ type HttpTargetProviderConfig struct {
 type HttpTargetProvider struct {
 	Config  HttpTargetProviderConfig
 	Context *contexts.ManagerContext
+	// SecretProvider overrides the secret provider of the vendor evaluation context when set
+	SecretProvider secret.ISecretProvider
 }
 
 func HttpTargetProviderConfigFromMap(properties map[string]string) (HttpTargetProviderConfig, error) {
@@ -82,7 +92,7 @@ func toHttpTargetProviderConfig(config providers.IProviderConfig) (HttpTargetPro
 	return ret, err
 }
 func (i *HttpTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
-	_, span := observability.StartSpan("Http Target Provider", ctx, &map[string]string{
+	ctx, span := observability.StartSpan("Http Target Provider", ctx, &map[string]string{
 		"method": "Get",
 	})
 	var err error = nil
@@ -90,8 +100,43 @@ func (i *HttpTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 
 	sLog.Infof("  P(HTTP Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
-	// This provider doesn't remember what it does, so it always return nil when asked
-	return nil, nil
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, reference := range references {
+		// Components without a http.getUrl can't be read back, so they are always reported as missing
+		getUrl := model.ReadPropertyCompat(reference.Component.Properties, "http.getUrl", injections)
+		if getUrl == "" {
+			continue
+		}
+		var statusCode int
+		var body []byte
+		statusCode, body, err = i.sendRequest(ctx, reference.Component, http.MethodGet, getUrl, "", injections)
+		if err != nil {
+			sLog.Errorf("  P(HTTP Target): failed to read back component %s: %+v", reference.Component.Name, err)
+			return nil, err
+		}
+		if statusCode == http.StatusNotFound {
+			continue
+		}
+		if !isAcceptedStatus(model.ReadPropertyCompat(reference.Component.Properties, "http.acceptedStatus", injections), statusCode) {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("HTTP read back of component %s responded %d: %s", reference.Component.Name, statusCode, string(body)), v1alpha2.InternalError)
+			sLog.Errorf("  P(HTTP Target): %+v", err)
+			return nil, err
+		}
+		var component model.ComponentSpec
+		component, err = mapResponse(reference.Component, body, injections)
+		if err != nil {
+			sLog.Errorf("  P(HTTP Target): failed to map read back of component %s: %+v", reference.Component.Name, err)
+			return nil, err
+		}
+		ret = append(ret, component)
+	}
+	return ret, nil
 }
 
 func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
@@ -121,11 +166,13 @@ func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 
 	ret := step.PrepareResultMap()
 	for _, component := range step.Components {
+		var url, method, body string
+		failedState := v1alpha2.UpdateFailed
+		successState := v1alpha2.Updated
 		if component.Action == "update" {
-			body := model.ReadPropertyCompat(component.Component.Properties, "http.body", injections)
-			url := model.ReadPropertyCompat(component.Component.Properties, "http.url", injections)
-			method := model.ReadPropertyCompat(component.Component.Properties, "http.method", injections)
-
+			body = model.ReadPropertyCompat(component.Component.Properties, "http.body", injections)
+			url = model.ReadPropertyCompat(component.Component.Properties, "http.url", injections)
+			method = model.ReadPropertyCompat(component.Component.Properties, "http.method", injections)
 			if url == "" {
 				err = errors.New("component doesn't have a http.url property")
 				ret[component.Component.Name] = model.ComponentResultSpec{
@@ -136,58 +183,300 @@ func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 				return ret, err
 			}
 			if method == "" {
-				method = "POST"
+				method = http.MethodPost
 			}
-			jsonData := []byte(body)
-			var request *http.Request
-			request, err = http.NewRequest(method, url, bytes.NewBuffer(jsonData))
-			if err != nil {
+		} else if component.Action == "delete" {
+			failedState = v1alpha2.DeleteFailed
+			successState = v1alpha2.Deleted
+			body = model.ReadPropertyCompat(component.Component.Properties, "http.deleteBody", injections)
+			url = model.ReadPropertyCompat(component.Component.Properties, "http.deleteUrl", injections)
+			method = model.ReadPropertyCompat(component.Component.Properties, "http.deleteMethod", injections)
+			if url == "" {
+				// nothing to call when removing a component without a http.deleteUrl
 				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: err.Error(),
+					Status:  v1alpha2.Deleted,
+					Message: "",
 				}
-				sLog.Errorf("  P(HTTP Target): %v", err)
-				return ret, err
+				continue
+			}
+			if method == "" {
+				method = http.MethodDelete
 			}
-			request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+		} else {
+			continue
+		}
 
-			client := &http.Client{}
-			var resp *http.Response
-			resp, err = client.Do(request)
-			if err != nil {
-				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: err.Error(),
-				}
-				sLog.Errorf("  P(HTTP Target): %v", err)
-				return ret, err
+		var statusCode int
+		var respBody []byte
+		statusCode, respBody, err = i.sendRequest(ctx, component.Component, method, url, body, injections)
+		if err != nil {
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  failedState,
+				Message: err.Error(),
 			}
-			if resp.StatusCode != http.StatusOK {
-				bodyBytes, err := io.ReadAll(resp.Body)
-				var message string
-				if err != nil {
-					message = err.Error()
-				} else {
-					message = string(bodyBytes)
-				}
-				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: message,
-				}
-				err = errors.New("HTTP request didn't respond 200 OK")
-				sLog.Errorf("  P(HTTP Target): %v", err)
-				return ret, err
+			sLog.Errorf("  P(HTTP Target): %v", err)
+			return ret, err
+		}
+		if !isAcceptedStatus(model.ReadPropertyCompat(component.Component.Properties, "http.acceptedStatus", injections), statusCode) {
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  failedState,
+				Message: string(respBody),
 			}
+			err = fmt.Errorf("HTTP request responded with an unaccepted status code %d", statusCode)
+			sLog.Errorf("  P(HTTP Target): %v", err)
+			return ret, err
+		}
+		ret[component.Component.Name] = model.ComponentResultSpec{
+			Status:  successState,
+			Message: "",
 		}
 	}
 	return ret, nil
 }
+
 func (*HttpTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
-		RequiredProperties:    []string{"http.url"},
-		OptionalProperties:    []string{"http.method", "http.body"},
+		RequiredProperties: []string{"http.url"},
+		OptionalProperties: []string{
+			"http.method",
+			"http.body",
+			"http.headers",
+			"http.contentType",
+			"http.acceptedStatus",
+			"http.auth.type",
+			"http.auth.secret",
+			"http.deleteUrl",
+			"http.deleteMethod",
+			"http.deleteBody",
+			"http.getUrl",
+			"http.getMapping",
+		},
 		RequiredComponentType: "",
 		RequiredMetadata:      []string{},
 		OptionalMetadata:      []string{},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: "http.url", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "http.method", IgnoreCase: true, SkipIfMissing: true},
+			{Name: "http.body", IgnoreCase: false, SkipIfMissing: true},
+			// properties mapped from the http.getUrl response by http.getMapping have no fixed names
+			{Name: "*", IgnoreCase: false, SkipIfMissing: true},
+		},
+	}
+}
+
+// readAuthValue reads an authentication value, such as a bearer token or a client certificate. A value
+// set directly as a http.auth.<field> property takes precedence. Otherwise, the value is read from the
+// <field> field of the secret object named by the http.auth.secret property.
+func (i *HttpTargetProvider) readAuthValue(component model.ComponentSpec, field string, injections *model.ValueInjections) (string, error) {
+	if v := model.ReadPropertyCompat(component.Properties, "http.auth."+field, injections); v != "" {
+		return v, nil
+	}
+	secretName := model.ReadPropertyCompat(component.Properties, "http.auth.secret", injections)
+	return target.ReadSecretValue(target.SecretProvider(i.SecretProvider, i.Context), "", secretName, field, "http.auth.secret")
+}
+
+func (i *HttpTargetProvider) sendRequest(ctx context.Context, component model.ComponentSpec, method string, url string, body string, injections *model.ValueInjections) (int, []byte, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer([]byte(body)))
+	if err != nil {
+		return 0, nil, err
+	}
+	contentType := model.ReadPropertyCompat(component.Properties, "http.contentType", injections)
+	if contentType == "" {
+		contentType = "application/json; charset=UTF-8"
+	}
+	request.Header.Set("Content-Type", contentType)
+	headers, err := readHeaders(component.Properties, injections)
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+
+	client := &http.Client{}
+	switch authType := strings.ToLower(model.ReadPropertyCompat(component.Properties, "http.auth.type", injections)); authType {
+	case "":
+	case "bearer":
+		token, err := i.readAuthValue(component, "token", injections)
+		if err != nil {
+			return 0, nil, err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	case "basic":
+		username, err := i.readAuthValue(component, "username", injections)
+		if err != nil {
+			return 0, nil, err
+		}
+		password, err := i.readAuthValue(component, "password", injections)
+		if err != nil {
+			return 0, nil, err
+		}
+		request.SetBasicAuth(username, password)
+	case "mtls":
+		tlsConfig, err := i.buildTLSConfig(component, injections)
+		if err != nil {
+			return 0, nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	default:
+		return 0, nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("http.auth.type '%s' is not supported", authType), v1alpha2.BadRequest)
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+func (i *HttpTargetProvider) buildTLSConfig(component model.ComponentSpec, injections *model.ValueInjections) (*tls.Config, error) {
+	cert, err := i.readAuthValue(component, "cert", injections)
+	if err != nil {
+		return nil, err
+	}
+	key, err := i.readAuthValue(component, "key", injections)
+	if err != nil {
+		return nil, err
+	}
+	if cert == "" || key == "" {
+		return nil, v1alpha2.NewCOAError(nil, "mtls authentication requires both a client certificate and a key", v1alpha2.BadRequest)
+	}
+	pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+	}
+	ca, err := i.readAuthValue(component, "ca", injections)
+	if err != nil {
+		return nil, err
+	}
+	if ca != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, v1alpha2.NewCOAError(nil, "failed to parse the CA certificate", v1alpha2.BadRequest)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// readHeaders reads the http.headers property, which can be either an object or a JSON string of
+// header names and values.
+func readHeaders(properties map[string]interface{}, injections *model.ValueInjections) (map[string]string, error) {
+	ret := make(map[string]string)
+	v, ok := properties["http.headers"]
+	if !ok || v == nil {
+		return ret, nil
+	}
+	var headers map[string]interface{}
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		headers = tv
+	case string:
+		if tv == "" {
+			return ret, nil
+		}
+		err := json.Unmarshal([]byte(tv), &headers)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "http.headers is not a valid JSON object", v1alpha2.BadRequest)
+		}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, "http.headers must be an object", v1alpha2.BadRequest)
+	}
+	for k, hv := range headers {
+		ret[k] = model.ResolveString(fmt.Sprintf("%v", hv), injections)
+	}
+	return ret, nil
+}
+
+// isAcceptedStatus checks a status code against a comma-separated list of accepted codes, such as
+// "200,201,204". Class patterns like "2xx" are also supported. Only 200 is accepted by default.
+func isAcceptedStatus(accepted string, statusCode int) bool {
+	if accepted == "" {
+		return statusCode == http.StatusOK
+	}
+	for _, s := range strings.Split(accepted, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if len(s) == 3 && strings.HasSuffix(s, "xx") {
+			if class, err := strconv.Atoi(s[:1]); err == nil && statusCode/100 == class {
+				return true
+			}
+			continue
+		}
+		if code, err := strconv.Atoi(s); err == nil && code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// mapResponse maps a read back response into a copy of the reference component. When a http.getMapping
+// object of property names and JsonPath expressions is given, each property is set to the result of its
+// expression. Otherwise, the response is reported as the http.body property.
+func mapResponse(reference model.ComponentSpec, body []byte, injections *model.ValueInjections) (model.ComponentSpec, error) {
+	component := model.ComponentSpec{
+		Name:       reference.Name,
+		Type:       reference.Type,
+		Metadata:   reference.Metadata,
+		Properties: make(map[string]interface{}),
+	}
+	for k, v := range reference.Properties {
+		component.Properties[k] = v
+	}
+
+	var mapping map[string]interface{}
+	switch tv := reference.Properties["http.getMapping"].(type) {
+	case map[string]interface{}:
+		mapping = tv
+	case string:
+		if tv != "" {
+			err := json.Unmarshal([]byte(tv), &mapping)
+			if err != nil {
+				return component, v1alpha2.NewCOAError(err, "http.getMapping is not a valid JSON object", v1alpha2.BadRequest)
+			}
+		}
+	}
+
+	if len(mapping) == 0 {
+		desired := model.ReadPropertyCompat(reference.Properties, "http.body", injections)
+		if !jsonEquals(desired, string(body)) {
+			component.Properties["http.body"] = string(body)
+		}
+		return component, nil
+	}
+
+	var obj interface{}
+	err := json.Unmarshal(body, &obj)
+	if err != nil {
+		return component, v1alpha2.NewCOAError(err, "read back response is not a valid JSON document", v1alpha2.InternalError)
+	}
+	for property, path := range mapping {
+		val, err := utils.JsonPathQuery(obj, fmt.Sprintf("%v", path))
+		if err != nil {
+			delete(component.Properties, property)
+			continue
+		}
+		component.Properties[property] = utils.FormatAsString(val)
+	}
+	return component, nil
+}
+
+func jsonEquals(a string, b string) bool {
+	if a == b {
+		return true
+	}
+	var oa, ob interface{}
+	if json.Unmarshal([]byte(a), &oa) != nil || json.Unmarshal([]byte(b), &ob) != nil {
+		return false
 	}
+	da, _ := json.Marshal(oa)
+	db, _ := json.Marshal(ob)
+	return string(da) == string(db)
 }
@@ -8,12 +8,15 @@ package http
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -302,6 +305,259 @@ func TestHttpTargetProviderRemove(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestHttpTargetProviderApplyHeadersAndBearerAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer webhook>>token" || r.Header.Get("X-Custom") != "abc" || r.Header.Get("Content-Type") != "text/plain" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{SecretProvider: &mock.MockSecretProvider{}}
+	err := provider.Init(HttpTargetProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+	component := model.ComponentSpec{
+		Name: "http-component",
+		Properties: map[string]interface{}{
+			"http.url":            ts.URL,
+			"http.headers":        map[string]interface{}{"X-Custom": "abc"},
+			"http.contentType":    "text/plain",
+			"http.auth.type":      "bearer",
+			"http.auth.secret":    "webhook",
+			"http.acceptedStatus": "200,202",
+		},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action:    "update",
+				Component: component,
+			},
+		},
+	}
+	ret, err := provider.Apply(context.Background(), model.DeploymentSpec{}, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["http-component"].Status)
+}
+
+func TestHttpTargetProviderApplyBasicAuthRejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "wrong" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+	component := model.ComponentSpec{
+		Name: "http-component",
+		Properties: map[string]interface{}{
+			"http.url":           ts.URL,
+			"http.headers":       `{"X-Custom": "abc"}`,
+			"http.auth.type":     "basic",
+			"http.auth.username": "admin",
+			"http.auth.password": "secret",
+		},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action:    "update",
+				Component: component,
+			},
+		},
+	}
+	ret, err := provider.Apply(context.Background(), model.DeploymentSpec{}, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["http-component"].Status)
+}
+
+func TestHttpTargetProviderApplyAuthSecretWithoutProvider(t *testing.T) {
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+	component := model.ComponentSpec{
+		Name: "http-component",
+		Properties: map[string]interface{}{
+			"http.url":         "http://localhost:1",
+			"http.auth.type":   "bearer",
+			"http.auth.secret": "webhook",
+		},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action:    "update",
+				Component: component,
+			},
+		},
+	}
+	_, err = provider.Apply(context.Background(), model.DeploymentSpec{}, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestHttpTargetProviderApplyDelete(t *testing.T) {
+	deleted := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && r.URL.Path == "/hooks/1" {
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action: "delete",
+				Component: model.ComponentSpec{
+					Name: "http-component",
+					Properties: map[string]interface{}{
+						"http.url":            ts.URL + "/hooks",
+						"http.deleteUrl":      ts.URL + "/hooks/1",
+						"http.acceptedStatus": "2xx",
+					},
+				},
+			},
+			{
+				Action: "delete",
+				Component: model.ComponentSpec{
+					Name: "http-component-2",
+					Properties: map[string]interface{}{
+						"http.url": ts.URL + "/hooks",
+					},
+				},
+			},
+		},
+	}
+	ret, err := provider.Apply(context.Background(), model.DeploymentSpec{}, step, false)
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	assert.Equal(t, v1alpha2.Deleted, ret["http-component"].Status)
+	assert.Equal(t, v1alpha2.Deleted, ret["http-component-2"].Status)
+}
+
+func TestHttpTargetProviderGetReadBack(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hooks/1":
+			w.Write([]byte(`{"config": {"color": "blue"}, "enabled": true}`))
+		case "/hooks/2":
+			w.Write([]byte(`{ "name": "hook-2" }`))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			http.Error(w, string(body), http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, []model.ComponentStep{
+		{
+			Action: "update",
+			Component: model.ComponentSpec{
+				Name: "mapped",
+				Properties: map[string]interface{}{
+					"http.url":        ts.URL + "/hooks",
+					"http.getUrl":     ts.URL + "/hooks/1",
+					"http.getMapping": `{"color": "$.config.color", "enabled": "$.enabled"}`,
+				},
+			},
+		},
+		{
+			Action: "update",
+			Component: model.ComponentSpec{
+				Name: "unmapped",
+				Properties: map[string]interface{}{
+					"http.url":    ts.URL + "/hooks",
+					"http.body":   `{"name":"hook-2"}`,
+					"http.getUrl": ts.URL + "/hooks/2",
+				},
+			},
+		},
+		{
+			Action: "update",
+			Component: model.ComponentSpec{
+				Name: "missing",
+				Properties: map[string]interface{}{
+					"http.url":    ts.URL + "/hooks",
+					"http.getUrl": ts.URL + "/hooks/3",
+				},
+			},
+		},
+		{
+			Action: "update",
+			Component: model.ComponentSpec{
+				Name: "no-readback",
+				Properties: map[string]interface{}{
+					"http.url": ts.URL + "/hooks",
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(components))
+	assert.Equal(t, "mapped", components[0].Name)
+	assert.Equal(t, "blue", components[0].Properties["color"])
+	assert.Equal(t, "true", components[0].Properties["enabled"])
+	assert.Equal(t, "unmapped", components[1].Name)
+	assert.Equal(t, `{"name":"hook-2"}`, components[1].Properties["http.body"])
+}
+
+func TestHttpTargetProviderReadBackDrift(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config": {"color": "red"}}`))
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+	desired := model.ComponentSpec{
+		Name: "mapped",
+		Properties: map[string]interface{}{
+			"http.url":        ts.URL + "/hooks",
+			"http.getUrl":     ts.URL + "/hooks/1",
+			"http.getMapping": `{"color": "$.config.color"}`,
+			"color":           "blue",
+		},
+	}
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, []model.ComponentStep{
+		{
+			Action:    "update",
+			Component: desired,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	rule := provider.GetValidationRule(context.Background())
+	assert.True(t, rule.IsComponentChanged(components[0], desired))
+	assert.False(t, rule.IsComponentChanged(desired, desired))
+}
+
+func TestIsAcceptedStatus(t *testing.T) {
+	assert.True(t, isAcceptedStatus("", 200))
+	assert.False(t, isAcceptedStatus("", 201))
+	assert.True(t, isAcceptedStatus("200, 201", 201))
+	assert.True(t, isAcceptedStatus("2xx", 204))
+	assert.False(t, isAcceptedStatus("2xx,404", 500))
+	assert.True(t, isAcceptedStatus("2xx,404", 404))
+}
+
 // TestReadProperty tests that ReadProperty returns the correct value
 func TestReadProperty(t *testing.T) {
 	url := "https://manual-approval.azurewebsites.net:443/api/approval/triggers/manual/invoke?api-version=2022-05-01&sp=%2Ftriggers%2Fmanual%2Frun&sv=1.0&sig=<redacted>"
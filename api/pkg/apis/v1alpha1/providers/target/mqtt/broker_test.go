@@ -0,0 +1,357 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is a minimal embedded MQTT 3.1.1 broker for tests. It supports QoS 0-2 publishing,
+// subscriptions with + and # wildcards and username/password checks. Messages are forwarded to
+// subscribers at QoS 0.
+type testBroker struct {
+	Address  string
+	Username string
+	Password string
+
+	listener  net.Listener
+	lock      sync.Mutex
+	clients   map[*brokerClient]bool
+	published []publishedMessage
+}
+
+type brokerClient struct {
+	conn      net.Conn
+	writeLock sync.Mutex
+	subs      map[string]bool
+}
+
+type publishedMessage struct {
+	Topic    string
+	QoS      byte
+	Retained bool
+	Payload  []byte
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return startTestBroker(t, listener, "tcp://")
+}
+
+// newTLSTestBroker starts a broker that requires clients to present a certificate signed by the returned CA
+func newTLSTestBroker(t *testing.T) (*testBroker, testCerts) {
+	certs := generateTestCerts(t)
+	serverCert, err := tls.X509KeyPair([]byte(certs.ServerCert), []byte(certs.ServerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM([]byte(certs.CA))
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return startTestBroker(t, listener, "ssl://"), certs
+}
+
+func startTestBroker(t *testing.T, listener net.Listener, scheme string) *testBroker {
+	broker := &testBroker{
+		Address:  scheme + listener.Addr().String(),
+		listener: listener,
+		clients:  map[*brokerClient]bool{},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+func (b *testBroker) Close() {
+	b.listener.Close()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for c := range b.clients {
+		c.conn.Close()
+	}
+}
+
+func (b *testBroker) Published(topic string) []publishedMessage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ret := make([]publishedMessage, 0)
+	for _, m := range b.published {
+		if m.Topic == topic {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	client := &brokerClient{conn: conn, subs: map[string]bool{}}
+	defer func() {
+		b.lock.Lock()
+		delete(b.clients, client)
+		b.lock.Unlock()
+		conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case 1: // CONNECT
+			if !b.checkCredentials(body) {
+				client.write([]byte{0x20, 0x02, 0x00, 0x04})
+				return
+			}
+			b.lock.Lock()
+			b.clients[client] = true
+			b.lock.Unlock()
+			client.write([]byte{0x20, 0x02, 0x00, 0x00})
+		case 3: // PUBLISH
+			qos := (header >> 1) & 0x03
+			topic, rest := readString(body)
+			if qos > 0 {
+				id := rest[:2]
+				rest = rest[2:]
+				if qos == 1 {
+					client.write([]byte{0x40, 0x02, id[0], id[1]})
+				} else {
+					client.write([]byte{0x50, 0x02, id[0], id[1]})
+				}
+			}
+			b.publish(publishedMessage{Topic: topic, QoS: qos, Retained: header&0x01 == 1, Payload: rest})
+		case 6: // PUBREL
+			client.write([]byte{0x70, 0x02, body[0], body[1]})
+		case 8: // SUBSCRIBE
+			granted := make([]byte, 0)
+			rest := body[2:]
+			for len(rest) > 0 {
+				var topic string
+				topic, rest = readString(rest)
+				granted = append(granted, rest[0])
+				rest = rest[1:]
+				b.lock.Lock()
+				client.subs[topic] = true
+				b.lock.Unlock()
+			}
+			client.write(append(append([]byte{0x90}, encodeLength(2+len(granted))...), append(body[:2], granted...)...))
+		case 10: // UNSUBSCRIBE
+			rest := body[2:]
+			for len(rest) > 0 {
+				var topic string
+				topic, rest = readString(rest)
+				b.lock.Lock()
+				delete(client.subs, topic)
+				b.lock.Unlock()
+			}
+			client.write([]byte{0xB0, 0x02, body[0], body[1]})
+		case 12: // PINGREQ
+			client.write([]byte{0xD0, 0x00})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func (b *testBroker) checkCredentials(body []byte) bool {
+	_, rest := readString(body) // protocol name
+	flags := rest[1]
+	rest = rest[4:]
+	_, rest = readString(rest) // client ID
+	if flags&0x04 != 0 {
+		_, rest = readString(rest) // will topic
+		_, rest = readString(rest) // will message
+	}
+	username, password := "", ""
+	if flags&0x80 != 0 {
+		username, rest = readString(rest)
+	}
+	if flags&0x40 != 0 {
+		password, _ = readString(rest)
+	}
+	return b.Username == "" || (b.Username == username && b.Password == password)
+}
+
+func (b *testBroker) publish(message publishedMessage) {
+	b.lock.Lock()
+	b.published = append(b.published, message)
+	targets := make([]*brokerClient, 0)
+	for c := range b.clients {
+		for filter := range c.subs {
+			if topicMatches(filter, message.Topic) {
+				targets = append(targets, c)
+				break
+			}
+		}
+	}
+	b.lock.Unlock()
+
+	body := append(encodeString(message.Topic), message.Payload...)
+	packet := append(append([]byte{0x30}, encodeLength(len(body))...), body...)
+	for _, c := range targets {
+		c.write(packet)
+	}
+}
+
+func (c *brokerClient) write(data []byte) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	c.conn.Write(data)
+}
+
+func topicMatches(filter string, topic string) bool {
+	filterParts := strings.Split(filter, "/")
+	topicParts := strings.Split(topic, "/")
+	for i, f := range filterParts {
+		if f == "#" {
+			return true
+		}
+		if i >= len(topicParts) || (f != "+" && f != topicParts[i]) {
+			return false
+		}
+	}
+	return len(filterParts) == len(topicParts)
+}
+
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := 0
+	for shift := 0; ; shift += 7 {
+		if shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	return header, body, err
+}
+
+func readString(data []byte) (string, []byte) {
+	length := int(binary.BigEndian.Uint16(data))
+	return string(data[2 : 2+length]), data[2+length:]
+}
+
+func encodeString(s string) []byte {
+	ret := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(ret, uint16(len(s)))
+	return append(ret, s...)
+}
+
+func encodeLength(length int) []byte {
+	ret := make([]byte, 0, 4)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		ret = append(ret, b)
+		if length == 0 {
+			return ret
+		}
+	}
+}
+
+type testCerts struct {
+	CA         string
+	ServerCert string
+	ServerKey  string
+	ClientCert string
+	ClientKey  string
+}
+
+func generateTestCerts(t *testing.T) testCerts {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (string, string) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	}
+	ret := testCerts{
+		CA: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+	}
+	ret.ServerCert, ret.ServerKey = issue(2, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	ret.ClientCert, ret.ClientKey = issue(3, "symphony", x509.ExtKeyUsageClientAuth)
+	return ret
+}
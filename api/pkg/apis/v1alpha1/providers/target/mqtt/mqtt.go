@@ -8,6 +8,8 @@ package mqtt
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -15,11 +17,13 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	gmqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/google/uuid"
@@ -27,6 +31,12 @@ import (
 
 var sLog = logger.NewLogger("coa.runtime")
 
+const (
+	callContextKey   = "call-context"
+	requestIdKey     = "request-id"
+	responseTopicKey = "response-topic"
+)
+
 type MQTTTargetProviderConfig struct {
 	Name               string `json:"name"`
 	BrokerAddress      string `json:"brokerAddress"`
@@ -36,6 +46,26 @@ type MQTTTargetProviderConfig struct {
 	TimeoutSeconds     int    `json:"timeoutSeconds,omitempty"`
 	KeepAliveSeconds   int    `json:"keepAliveSeconds,omitempty"`
 	PingTimeoutSeconds int    `json:"pingTimeoutSeconds,omitempty"`
+	// QoS is the quality of service level used for publishing requests and subscribing to responses
+	QoS int `json:"qos,omitempty"`
+	// Retained sets the retain flag on published requests
+	Retained bool `json:"retained,omitempty"`
+	// PersistentSession keeps the broker session (and queued responses) across reconnects. The configured
+	// client ID is used in this case, otherwise a random client ID is generated.
+	PersistentSession bool   `json:"persistentSession,omitempty"`
+	Username          string `json:"username,omitempty"`
+	Password          string `json:"password,omitempty"`
+	// CACert, ClientCert and ClientKey are PEM-encoded TLS settings
+	CACert             string `json:"caCert,omitempty"`
+	ClientCert         string `json:"clientCert,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	// AuthSecret names a secret object whose username and password fields are used when they aren't
+	// set directly in the config
+	AuthSecret string `json:"authSecret,omitempty"`
+	// TLSSecret names a secret object whose ca, cert and key fields are used when they aren't set
+	// directly in the config
+	TLSSecret string `json:"tlsSecret,omitempty"`
 }
 
 var lock sync.Mutex
@@ -45,16 +75,23 @@ type ProxyResponse struct {
 	State   v1alpha2.State
 	Payload interface{}
 }
+
+type pendingRequest struct {
+	callContext string
+	response    chan ProxyResponse
+}
+
 type MQTTTargetProvider struct {
-	Config          MQTTTargetProviderConfig
-	Context         *contexts.ManagerContext
-	MQTTClient      gmqtt.Client
-	GetChan         chan ProxyResponse
-	RemoveChan      chan ProxyResponse
-	NeedsUpdateChan chan ProxyResponse
-	NeedsRemoveChan chan ProxyResponse
-	ApplyChan       chan ProxyResponse
-	Initialized     bool
+	Config         MQTTTargetProviderConfig
+	Context        *contexts.ManagerContext
+	MQTTClient     gmqtt.Client
+	SecretProvider secret.ISecretProvider
+	Initialized    bool
+	// pending holds the requests waiting for a response, keyed by request ID
+	pending      map[string]*pendingRequest
+	pendingOrder []string
+	pendingLock  sync.Mutex
+	connectLock  sync.Mutex
 }
 
 func MQTTTargetProviderConfigFromMap(properties map[string]string) (MQTTTargetProviderConfig, error) {
@@ -109,6 +146,55 @@ func MQTTTargetProviderConfigFromMap(properties map[string]string) (MQTTTargetPr
 	} else {
 		ret.PingTimeoutSeconds = 1
 	}
+	if v, ok := properties["qos"]; ok {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 && num <= 2 {
+			ret.QoS = num
+		} else {
+			return ret, v1alpha2.NewCOAError(nil, "'qos' must be 0, 1 or 2 in MQTT provider config", v1alpha2.BadConfig)
+		}
+	}
+	if v, ok := properties["retained"]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			ret.Retained = b
+		} else {
+			return ret, v1alpha2.NewCOAError(nil, "'retained' is not a bool in MQTT provider config", v1alpha2.BadConfig)
+		}
+	}
+	if v, ok := properties["persistentSession"]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			ret.PersistentSession = b
+		} else {
+			return ret, v1alpha2.NewCOAError(nil, "'persistentSession' is not a bool in MQTT provider config", v1alpha2.BadConfig)
+		}
+	}
+	if v, ok := properties["insecureSkipVerify"]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			ret.InsecureSkipVerify = b
+		} else {
+			return ret, v1alpha2.NewCOAError(nil, "'insecureSkipVerify' is not a bool in MQTT provider config", v1alpha2.BadConfig)
+		}
+	}
+	if v, ok := properties["username"]; ok {
+		ret.Username = v
+	}
+	if v, ok := properties["password"]; ok {
+		ret.Password = v
+	}
+	if v, ok := properties["caCert"]; ok {
+		ret.CACert = v
+	}
+	if v, ok := properties["clientCert"]; ok {
+		ret.ClientCert = v
+	}
+	if v, ok := properties["clientKey"]; ok {
+		ret.ClientKey = v
+	}
+	if v, ok := properties["authSecret"]; ok {
+		ret.AuthSecret = v
+	}
+	if v, ok := properties["tlsSecret"]; ok {
+		ret.TLSSecret = v
+	}
 	return ret, nil
 }
 
@@ -141,63 +227,26 @@ func (i *MQTTTargetProvider) Init(config providers.IProviderConfig) error {
 	}
 	updateConfig, err := toMQTTTargetProviderConfig(config)
 	if err != nil {
-		sLog.Errorf("  P (MQTT Target): expected HttpTargetProviderConfig: %+v", err)
+		sLog.Errorf("  P (MQTT Target): expected MQTTTargetProviderConfig: %+v", err)
+		return err
+	}
+	if updateConfig.QoS < 0 || updateConfig.QoS > 2 {
+		err = v1alpha2.NewCOAError(nil, "'qos' must be 0, 1 or 2 in MQTT provider config", v1alpha2.BadConfig)
 		return err
 	}
 	i.Config = updateConfig
-	id := uuid.New()
-	opts := gmqtt.NewClientOptions().AddBroker(i.Config.BrokerAddress).SetClientID(id.String())
-	opts.SetKeepAlive(time.Duration(i.Config.KeepAliveSeconds) * time.Second)
-	opts.SetPingTimeout(time.Duration(i.Config.PingTimeoutSeconds) * time.Second)
-	opts.CleanSession = true
-	i.MQTTClient = gmqtt.NewClient(opts)
-	if token := i.MQTTClient.Connect(); token.Wait() && token.Error() != nil {
-		sLog.Errorf("  P (MQTT Target): faild to connect to MQTT broker - %+v", err)
-		return v1alpha2.NewCOAError(token.Error(), "failed to connect to MQTT broker", v1alpha2.InternalError)
-	}
-
-	i.GetChan = make(chan ProxyResponse)
-	i.RemoveChan = make(chan ProxyResponse)
-	i.NeedsUpdateChan = make(chan ProxyResponse)
-	i.NeedsRemoveChan = make(chan ProxyResponse)
-	i.ApplyChan = make(chan ProxyResponse)
-
-	if token := i.MQTTClient.Subscribe(i.Config.ResponseTopic, 0, func(client gmqtt.Client, msg gmqtt.Message) {
-		var response v1alpha2.COAResponse
-		json.Unmarshal(msg.Payload(), &response)
-		proxyResponse := ProxyResponse{
-			IsOK:  response.State == v1alpha2.OK || response.State == v1alpha2.Accepted,
-			State: response.State,
-		}
-		if !proxyResponse.IsOK {
-			proxyResponse.Payload = string(response.Body)
-		}
-		switch response.Metadata["call-context"] {
-		case "TargetProvider-Get":
-			if proxyResponse.IsOK {
-				var ret []model.ComponentSpec
-				err = json.Unmarshal(response.Body, &ret)
-				if err != nil {
-					sLog.Errorf("  P (MQTT Target): faild to deserialize components from MQTT - %+v, %s", err.Error(), string(response.Body))
-				}
-				proxyResponse.Payload = ret
-			}
-			i.GetChan <- proxyResponse
-		case "TargetProvider-Remove":
-			i.RemoveChan <- proxyResponse
-		case "TargetProvider-NeedsUpdate":
-			i.NeedsUpdateChan <- proxyResponse
-		case "TargetProvider-NeedsRemove":
-			i.NeedsRemoveChan <- proxyResponse
-		case "TargetProvider-Apply":
-			i.ApplyChan <- proxyResponse
-		}
-	}); token.Wait() && token.Error() != nil {
-		if token.Error().Error() != "subscription exists" {
-			sLog.Errorf("  P (MQTT Target): faild to connect to subscribe to the response topic - %+v", token.Error())
-			err = v1alpha2.NewCOAError(token.Error(), "failed to subscribe to response topic", v1alpha2.InternalError)
-			return err
-		}
+	i.pending = make(map[string]*pendingRequest)
+
+	// credentials in a secret can only be read once the provider has a context, so the connection is
+	// established on the first request in this case
+	if (i.Config.AuthSecret != "" || i.Config.TLSSecret != "") && target.SecretProvider(i.SecretProvider, i.Context) == nil {
+		sLog.Info("  P (MQTT Target): secret provider is not available yet, deferring connection to MQTT broker")
+		i.Initialized = true
+		return nil
+	}
+	_, err = i.connect()
+	if err != nil {
+		return err
 	}
 	i.Initialized = true
 	return nil
@@ -212,98 +261,253 @@ func toMQTTTargetProviderConfig(config providers.IProviderConfig) (MQTTTargetPro
 	return ret, err
 }
 
-func (i *MQTTTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
-	_, span := observability.StartSpan("MQTT Target Provider", ctx, &map[string]string{
-		"method": "Get",
+// connect returns the client connected to the broker, connecting on the first call
+func (i *MQTTTargetProvider) connect() (gmqtt.Client, error) {
+	i.connectLock.Lock()
+	defer i.connectLock.Unlock()
+	if i.MQTTClient != nil {
+		return i.MQTTClient, nil
+	}
+
+	clientID := uuid.New().String()
+	if i.Config.PersistentSession && i.Config.ClientID != "" {
+		clientID = i.Config.ClientID
+	}
+	opts := gmqtt.NewClientOptions().AddBroker(i.Config.BrokerAddress).SetClientID(clientID)
+	opts.SetKeepAlive(time.Duration(i.Config.KeepAliveSeconds) * time.Second)
+	opts.SetPingTimeout(time.Duration(i.Config.PingTimeoutSeconds) * time.Second)
+	opts.CleanSession = !i.Config.PersistentSession
+
+	username, err := i.readAuthValue(i.Config.Username, i.Config.AuthSecret, "username")
+	if err != nil {
+		return nil, err
+	}
+	password, err := i.readAuthValue(i.Config.Password, i.Config.AuthSecret, "password")
+	if err != nil {
+		return nil, err
+	}
+	if username != "" {
+		opts.SetUsername(username)
+		opts.SetPassword(password)
+	}
+	tlsConfig, err := i.buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	// resubscribe after a reconnect, as a clean session drops the subscription
+	opts.SetOnConnectHandler(func(client gmqtt.Client) {
+		if token := client.Subscribe(i.Config.ResponseTopic, byte(i.Config.QoS), i.handleResponse); token.Wait() && token.Error() != nil {
+			sLog.Errorf("  P (MQTT Target): faild to subscribe to the response topic - %+v", token.Error())
+		}
 	})
-	var err error = nil
-	defer observ_utils.CloseSpanWithError(span, &err)
-	sLog.Infof("  P (MQTT Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
-	data, _ := json.Marshal(deployment)
-	request := v1alpha2.COARequest{
-		Route:  "instances",
-		Method: "GET",
-		Body:   data,
-		Metadata: map[string]string{
-			"call-context": "TargetProvider-Get",
-		},
+	client := gmqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		sLog.Errorf("  P (MQTT Target): faild to connect to MQTT broker - %+v", token.Error())
+		return nil, v1alpha2.NewCOAError(token.Error(), "failed to connect to MQTT broker", v1alpha2.InternalError)
 	}
-	data, _ = json.Marshal(request)
+	// the connect handler runs asynchronously, subscribe here as well so that responses aren't missed
+	// by a request sent right after Init
+	if token := client.Subscribe(i.Config.ResponseTopic, byte(i.Config.QoS), i.handleResponse); token.Wait() && token.Error() != nil {
+		if token.Error().Error() != "subscription exists" {
+			sLog.Errorf("  P (MQTT Target): faild to connect to subscribe to the response topic - %+v", token.Error())
+			client.Disconnect(0)
+			return nil, v1alpha2.NewCOAError(token.Error(), "failed to subscribe to response topic", v1alpha2.InternalError)
+		}
+	}
+	i.MQTTClient = client
+	return client, nil
+}
 
-	if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-		sLog.Infof("  P (MQTT Target): failed to getting artifacts - %s", token.Error())
-		err = token.Error()
+// readAuthValue returns the value set directly in the config, or the <field> field of the given secret object
+func (i *MQTTTargetProvider) readAuthValue(value string, secretName string, field string) (string, error) {
+	return target.ReadSecretValue(target.SecretProvider(i.SecretProvider, i.Context), value, secretName, field, "secrets in MQTT provider config")
+}
+
+func (i *MQTTTargetProvider) buildTLSConfig() (*tls.Config, error) {
+	ca, err := i.readAuthValue(i.Config.CACert, i.Config.TLSSecret, "ca")
+	if err != nil {
+		return nil, err
+	}
+	cert, err := i.readAuthValue(i.Config.ClientCert, i.Config.TLSSecret, "cert")
+	if err != nil {
 		return nil, err
 	}
+	key, err := i.readAuthValue(i.Config.ClientKey, i.Config.TLSSecret, "key")
+	if err != nil {
+		return nil, err
+	}
+	if ca == "" && cert == "" && key == "" && !i.Config.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: i.Config.InsecureSkipVerify,
+	}
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, v1alpha2.NewCOAError(nil, "TLS client authentication requires both a client certificate and a key", v1alpha2.BadConfig)
+		}
+		pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to parse the client certificate", v1alpha2.BadConfig)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	if ca != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, v1alpha2.NewCOAError(nil, "failed to parse the CA certificate", v1alpha2.BadConfig)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
 
-	timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-	select {
-	case resp := <-i.GetChan:
-		if resp.IsOK {
-			var data []byte
-			data, err = json.Marshal(resp.Payload)
-			if err != nil {
-				sLog.Infof("  P (MQTT Target): failed to serialize payload - %s - %s", err.Error(), fmt.Sprint(resp.Payload))
-				err = v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.InternalError)
-				return nil, err
-			}
-			var ret []model.ComponentSpec
-			err = json.Unmarshal(data, &ret)
-			if err != nil {
-				sLog.Infof("  P (MQTT Target): failed to deserialize components - %s - %s", err.Error(), fmt.Sprint(data))
-				err = v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.InternalError)
-				return nil, err
+// handleResponse routes a response to the request waiting for it. Responses are matched by request ID.
+// Responders that don't echo the request ID back are matched to the oldest pending request with the
+// same call context.
+func (i *MQTTTargetProvider) handleResponse(client gmqtt.Client, msg gmqtt.Message) {
+	var response v1alpha2.COAResponse
+	err := json.Unmarshal(msg.Payload(), &response)
+	if err != nil {
+		sLog.Errorf("  P (MQTT Target): faild to deserialize response from MQTT - %+v", err)
+		return
+	}
+	proxyResponse := ProxyResponse{
+		IsOK:  response.State == v1alpha2.OK || response.State == v1alpha2.Accepted,
+		State: response.State,
+	}
+	if proxyResponse.IsOK {
+		proxyResponse.Payload = response.Body
+	} else {
+		proxyResponse.Payload = string(response.Body)
+	}
+
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	requestId := response.Metadata[requestIdKey]
+	if requestId == "" {
+		for _, id := range i.pendingOrder {
+			if i.pending[id].callContext == response.Metadata[callContextKey] {
+				requestId = id
+				break
 			}
-			return ret, nil
-		} else {
-			err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-			return nil, err
 		}
-	case <-timeout:
-		err = v1alpha2.NewCOAError(nil, "didn't get response to Get() call over MQTT", v1alpha2.InternalError)
-		return nil, err
 	}
+	request, ok := i.pending[requestId]
+	if !ok {
+		sLog.Debugf("  P (MQTT Target): ignoring response to an unknown request '%s'", requestId)
+		return
+	}
+	i.removePending(requestId)
+	request.response <- proxyResponse
 }
-func (i *MQTTTargetProvider) Remove(ctx context.Context, deployment model.DeploymentSpec, currentRef []model.ComponentSpec) error {
-	_, span := observability.StartSpan("MQTT Target Provider", ctx, &map[string]string{
-		"method": "Remove",
-	})
-	var err error = nil
-	defer observ_utils.CloseSpanWithError(span, &err)
 
-	sLog.Infof("  P (MQTT Target): deleting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+func (i *MQTTTargetProvider) removePending(requestId string) {
+	delete(i.pending, requestId)
+	for idx, id := range i.pendingOrder {
+		if id == requestId {
+			i.pendingOrder = append(i.pendingOrder[:idx], i.pendingOrder[idx+1:]...)
+			break
+		}
+	}
+}
+
+// sendRequest publishes a request and waits for the matching response. The provider speaks MQTT 3.1.1,
+// which has no response topic or correlation data properties, so the request carries a request ID and
+// the topic to respond to in its metadata. Responders are expected to echo the request ID back and to
+// publish the response to that topic.
+func (i *MQTTTargetProvider) sendRequest(ctx context.Context, method string, callContext string, body []byte) (ProxyResponse, error) {
+	client, err := i.connect()
+	if err != nil {
+		return ProxyResponse{}, err
+	}
+
+	requestId := uuid.New().String()
+	pending := &pendingRequest{
+		callContext: callContext,
+		response:    make(chan ProxyResponse, 1),
+	}
+	i.pendingLock.Lock()
+	i.pending[requestId] = pending
+	i.pendingOrder = append(i.pendingOrder, requestId)
+	i.pendingLock.Unlock()
+	defer func() {
+		i.pendingLock.Lock()
+		i.removePending(requestId)
+		i.pendingLock.Unlock()
+	}()
 
-	data, _ := json.Marshal(deployment)
 	request := v1alpha2.COARequest{
 		Route:  "instances",
-		Method: "DELETE",
-		Body:   data,
+		Method: method,
+		Body:   body,
 		Metadata: map[string]string{
-			"call-context": "TargetProvider-Remove",
+			callContextKey:   callContext,
+			requestIdKey:     requestId,
+			responseTopicKey: i.Config.ResponseTopic,
 		},
 	}
-	data, _ = json.Marshal(request)
-
-	if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-		err = token.Error()
-		return err
+	// the receiving side continues the trace of the call
+	observ_utils.PropagateSpanContextToMetadata(ctx, request.Metadata)
+	data, _ := json.Marshal(request)
+	if token := client.Publish(i.Config.RequestTopic, byte(i.Config.QoS), i.Config.Retained, data); token.Wait() && token.Error() != nil {
+		return ProxyResponse{}, token.Error()
 	}
 
 	timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
 	select {
-	case resp := <-i.RemoveChan:
-		if resp.IsOK {
-			err = nil
-			return err
-		} else {
-			err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-			return err
+	case resp := <-pending.response:
+		if !resp.IsOK {
+			return resp, v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
 		}
+		return resp, nil
 	case <-timeout:
-		err = v1alpha2.NewCOAError(nil, "didn't get response to Remove() call over MQTT", v1alpha2.InternalError)
-		return err
+		return ProxyResponse{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("didn't get response to %s call over MQTT", callContext), v1alpha2.InternalError)
+	case <-ctx.Done():
+		return ProxyResponse{}, v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("%s call over MQTT was cancelled", callContext), v1alpha2.InternalError)
+	}
+}
+
+func (i *MQTTTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("MQTT Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	sLog.Infof("  P (MQTT Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	data, _ := json.Marshal(deployment)
+	var resp ProxyResponse
+	resp, err = i.sendRequest(ctx, "GET", "TargetProvider-Get", data)
+	if err != nil {
+		sLog.Infof("  P (MQTT Target): failed to getting artifacts - %s", err.Error())
+		return nil, err
+	}
+	var ret []model.ComponentSpec
+	err = json.Unmarshal(resp.Payload.([]byte), &ret)
+	if err != nil {
+		sLog.Infof("  P (MQTT Target): failed to deserialize components - %s - %s", err.Error(), string(resp.Payload.([]byte)))
+		err = v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.InternalError)
+		return nil, err
 	}
+	return ret, nil
+}
+func (i *MQTTTargetProvider) Remove(ctx context.Context, deployment model.DeploymentSpec, currentRef []model.ComponentSpec) error {
+	ctx, span := observability.StartSpan("MQTT Target Provider", ctx, &map[string]string{
+		"method": "Remove",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (MQTT Target): deleting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	data, _ := json.Marshal(deployment)
+	_, err = i.sendRequest(ctx, "DELETE", "TargetProvider-Remove", data)
+	return err
 }
 
 func (i *MQTTTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
@@ -330,66 +534,15 @@ func (i *MQTTTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 
 	components = step.GetUpdatedComponents()
 	if len(components) > 0 {
-
-		request := v1alpha2.COARequest{
-			Route:  "instances",
-			Method: "POST",
-			Body:   data,
-			Metadata: map[string]string{
-				"call-context": "TargetProvider-Apply",
-			},
-		}
-		data, _ = json.Marshal(request)
-
-		if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-			err = token.Error()
-			return ret, err
-		}
-
-		timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-		select {
-		case resp := <-i.ApplyChan:
-			if resp.IsOK {
-				err = nil
-				return ret, err
-			} else {
-				err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-				return ret, err
-			}
-		case <-timeout:
-			err = v1alpha2.NewCOAError(nil, "didn't get response to Apply() call over MQTT", v1alpha2.InternalError)
+		_, err = i.sendRequest(ctx, "POST", "TargetProvider-Apply", data)
+		if err != nil {
 			return ret, err
 		}
 	}
 	components = step.GetDeletedComponents()
 	if len(components) > 0 {
-		request := v1alpha2.COARequest{
-			Route:  "instances",
-			Method: "DELETE",
-			Body:   data,
-			Metadata: map[string]string{
-				"call-context": "TargetProvider-Remove",
-			},
-		}
-		data, _ = json.Marshal(request)
-
-		if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-			err = token.Error()
-			return ret, err
-		}
-
-		timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-		select {
-		case resp := <-i.RemoveChan:
-			if resp.IsOK {
-				err = nil
-				return ret, err
-			} else {
-				err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-				return ret, err
-			}
-		case <-timeout:
-			err = v1alpha2.NewCOAError(nil, "didn't get response to Remove() call over MQTT", v1alpha2.InternalError)
+		_, err = i.sendRequest(ctx, "DELETE", "TargetProvider-Remove", data)
+		if err != nil {
 			return ret, err
 		}
 	}
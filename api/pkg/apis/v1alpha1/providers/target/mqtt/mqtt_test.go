@@ -8,14 +8,19 @@ package mqtt
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	mqttbinding "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/mqtt"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	gmqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/stretchr/testify/assert"
 )
@@ -487,3 +492,367 @@ func TestConformanceSuite(t *testing.T) {
 	// assert.Nil(t, err) okay if provider is not fully initialized
 	conformance.ConformanceSuite(t, provider)
 }
+
+func embeddedBrokerConfig(broker *testBroker) MQTTTargetProviderConfig {
+	return MQTTTargetProviderConfig{
+		Name:               "me",
+		BrokerAddress:      broker.Address,
+		ClientID:           "coa-test",
+		RequestTopic:       "coa-request",
+		ResponseTopic:      "coa-response",
+		TimeoutSeconds:     5,
+		KeepAliveSeconds:   2,
+		PingTimeoutSeconds: 1,
+	}
+}
+
+// startResponder connects a remote agent to the broker that answers requests with the given handler.
+// Responses are sent to the configured response topic, not the one carried by the request.
+func startResponder(t *testing.T, broker *testBroker, handler func(request v1alpha2.COARequest) v1alpha2.COAResponse) gmqtt.Client {
+	opts := gmqtt.NewClientOptions().AddBroker(broker.Address).SetClientID("test-responder")
+	c := gmqtt.NewClient(opts)
+	if token := c.Connect(); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	t.Cleanup(func() { c.Disconnect(0) })
+	if token := c.Subscribe("coa-request", 0, func(client gmqtt.Client, msg gmqtt.Message) {
+		var request v1alpha2.COARequest
+		json.Unmarshal(msg.Payload(), &request)
+		go func() {
+			data, _ := json.Marshal(handler(request))
+			client.Publish("coa-response", 0, false, data).Wait()
+		}()
+	}); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	return c
+}
+
+func componentsResponse(request v1alpha2.COARequest, components []model.ComponentSpec) v1alpha2.COAResponse {
+	data, _ := json.Marshal(components)
+	return v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+		Body:  data,
+		Metadata: map[string]string{
+			"call-context": request.Metadata["call-context"],
+			"request-id":   request.Metadata["request-id"],
+		},
+	}
+}
+
+func TestConcurrentRequestsAreCorrelated(t *testing.T) {
+	broker := newTestBroker(t)
+	// the responder holds on to the requests until both have arrived, then answers them at the same time
+	var lock sync.Mutex
+	count := 0
+	barrier := make(chan bool)
+	startResponder(t, broker, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		var deployment model.DeploymentSpec
+		json.Unmarshal(request.Body, &deployment)
+		lock.Lock()
+		count++
+		if count == 2 {
+			close(barrier)
+		}
+		lock.Unlock()
+		<-barrier
+		return componentsResponse(request, []model.ComponentSpec{{Name: deployment.Instance.Name}})
+	})
+
+	provider := MQTTTargetProvider{}
+	err := provider.Init(embeddedBrokerConfig(broker))
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"instance-a", "instance-b"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			components, err := provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: name}}, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(components))
+			if len(components) == 1 {
+				assert.Equal(t, name, components[0].Name)
+			}
+		}(name)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentRequestsOutOfOrder(t *testing.T) {
+	broker := newTestBroker(t)
+	release := make(chan bool)
+	startResponder(t, broker, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		var deployment model.DeploymentSpec
+		json.Unmarshal(request.Body, &deployment)
+		if deployment.Instance.Name == "slow" {
+			<-release
+		}
+		return componentsResponse(request, []model.ComponentSpec{{Name: deployment.Instance.Name}})
+	})
+
+	provider := MQTTTargetProvider{}
+	err := provider.Init(embeddedBrokerConfig(broker))
+	assert.Nil(t, err)
+
+	results := make(chan string, 2)
+	for _, name := range []string{"slow", "fast"} {
+		go func(name string) {
+			components, err := provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: name}}, nil)
+			assert.Nil(t, err)
+			if len(components) == 1 && components[0].Name == name {
+				results <- name
+			} else {
+				results <- "mismatch"
+			}
+		}(name)
+		time.Sleep(50 * time.Millisecond)
+	}
+	// the slow request is only answered once the fast one has got its response
+	assert.Equal(t, "fast", <-results)
+	close(release)
+	assert.Equal(t, "slow", <-results)
+}
+
+func TestResponderWithoutRequestId(t *testing.T) {
+	broker := newTestBroker(t)
+	startResponder(t, broker, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		return v1alpha2.COAResponse{
+			State:    v1alpha2.OK,
+			Metadata: map[string]string{"call-context": request.Metadata["call-context"]},
+		}
+	})
+	provider := MQTTTargetProvider{}
+	err := provider.Init(embeddedBrokerConfig(broker))
+	assert.Nil(t, err)
+	component := model.ComponentSpec{Name: "c1"}
+	_, err = provider.Apply(context.Background(), model.DeploymentSpec{}, model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: "update", Component: component}},
+	}, false)
+	assert.Nil(t, err)
+}
+
+func TestRequestFailure(t *testing.T) {
+	broker := newTestBroker(t)
+	startResponder(t, broker, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte("BAD!!"),
+			Metadata: map[string]string{
+				"call-context": request.Metadata["call-context"],
+				"request-id":   request.Metadata["request-id"],
+			},
+		}
+	})
+	provider := MQTTTargetProvider{}
+	err := provider.Init(embeddedBrokerConfig(broker))
+	assert.Nil(t, err)
+	err = provider.Remove(context.Background(), model.DeploymentSpec{}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "BAD!!", err.Error())
+}
+
+func TestRequestTimeoutAndCancel(t *testing.T) {
+	broker := newTestBroker(t)
+	config := embeddedBrokerConfig(broker)
+	config.TimeoutSeconds = 1
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	_, err = provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.NotNil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = provider.Get(ctx, model.DeploymentSpec{}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(provider.pending))
+}
+
+func TestQoSAndRetained(t *testing.T) {
+	broker := newTestBroker(t)
+	startResponder(t, broker, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		return componentsResponse(request, []model.ComponentSpec{})
+	})
+	config := embeddedBrokerConfig(broker)
+	config.QoS = 1
+	config.Retained = true
+	config.PersistentSession = true
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	_, err = provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.Nil(t, err)
+	requests := broker.Published("coa-request")
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, byte(1), requests[0].QoS)
+	assert.True(t, requests[0].Retained)
+}
+
+func TestResponseTopicFromRequest(t *testing.T) {
+	broker := newTestBroker(t)
+	binding := mqttbinding.MQTTBinding{}
+	err := binding.Launch(mqttbinding.MQTTBindingConfig{
+		BrokerAddress: broker.Address,
+		ClientID:      "test-binding",
+		RequestTopic:  "coa-request",
+		ResponseTopic: "coa-response",
+	}, []v1alpha2.Endpoint{
+		{
+			Methods: []string{"GET"},
+			Route:   "instances",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				data, _ := json.Marshal([]model.ComponentSpec{{Name: "from-binding"}})
+				return v1alpha2.COAResponse{State: v1alpha2.OK, Body: data}
+			},
+		},
+	})
+	assert.Nil(t, err)
+	defer binding.MQTTClient.Disconnect(0)
+
+	config := embeddedBrokerConfig(broker)
+	config.ResponseTopic = "coa-response/me"
+	provider := MQTTTargetProvider{}
+	err = provider.Init(config)
+	assert.Nil(t, err)
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "from-binding", components[0].Name)
+	assert.Equal(t, 1, len(broker.Published("coa-response/me")))
+	assert.Equal(t, 0, len(broker.Published("coa-response")))
+}
+
+func TestBindingResponseQoS(t *testing.T) {
+	broker := newTestBroker(t)
+	binding := mqttbinding.MQTTBinding{}
+	err := binding.Launch(mqttbinding.MQTTBindingConfig{
+		BrokerAddress: broker.Address,
+		ClientID:      "test-binding",
+		RequestTopic:  "coa-request",
+		ResponseTopic: "coa-response",
+		QoS:           1,
+	}, []v1alpha2.Endpoint{
+		{
+			Methods: []string{"GET"},
+			Route:   "instances",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				data, _ := json.Marshal([]model.ComponentSpec{})
+				return v1alpha2.COAResponse{State: v1alpha2.OK, Body: data}
+			},
+		},
+	})
+	assert.Nil(t, err)
+	defer binding.MQTTClient.Disconnect(0)
+
+	provider := MQTTTargetProvider{}
+	err = provider.Init(embeddedBrokerConfig(broker))
+	assert.Nil(t, err)
+	_, err = provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.Nil(t, err)
+	responses := broker.Published("coa-response")
+	assert.Equal(t, 1, len(responses))
+	assert.Equal(t, byte(1), responses[0].QoS)
+}
+
+func TestCredentialsFromSecretProvider(t *testing.T) {
+	broker := newTestBroker(t)
+	startResponder(t, broker, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		return componentsResponse(request, []model.ComponentSpec{})
+	})
+	broker.Username = "mqtt-cred>>username"
+	broker.Password = "mqtt-cred>>password"
+
+	config := embeddedBrokerConfig(broker)
+	config.AuthSecret = "mqtt-cred"
+	provider := MQTTTargetProvider{}
+	// without a secret provider the connection is deferred to the first request
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	assert.Nil(t, provider.MQTTClient)
+	_, err = provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.NotNil(t, err)
+
+	provider.SecretProvider = &mock.MockSecretProvider{}
+	_, err = provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.Nil(t, err)
+}
+
+func TestWrongCredentials(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.Username = "admin"
+	broker.Password = "secret"
+	config := embeddedBrokerConfig(broker)
+	config.Username = "admin"
+	config.Password = "wrong"
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.NotNil(t, err)
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	broker, certs := newTLSTestBroker(t)
+	opts := gmqtt.NewClientOptions().AddBroker(broker.Address).SetClientID("test-responder")
+	pair, _ := tls.X509KeyPair([]byte(certs.ClientCert), []byte(certs.ClientKey))
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM([]byte(certs.CA))
+	opts.SetTLSConfig(&tls.Config{Certificates: []tls.Certificate{pair}, RootCAs: pool})
+	responder := gmqtt.NewClient(opts)
+	if token := responder.Connect(); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	defer responder.Disconnect(0)
+	responder.Subscribe("coa-request", 0, func(client gmqtt.Client, msg gmqtt.Message) {
+		var request v1alpha2.COARequest
+		json.Unmarshal(msg.Payload(), &request)
+		go func() {
+			data, _ := json.Marshal(componentsResponse(request, []model.ComponentSpec{{Name: "secure"}}))
+			client.Publish("coa-response", 0, false, data).Wait()
+		}()
+	}).Wait()
+
+	config := embeddedBrokerConfig(broker)
+	config.CACert = certs.CA
+	config.ClientCert = certs.ClientCert
+	config.ClientKey = certs.ClientKey
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+
+	// the broker rejects clients without a certificate
+	config.ClientCert = ""
+	config.ClientKey = ""
+	other := MQTTTargetProvider{}
+	err = other.Init(config)
+	assert.NotNil(t, err)
+}
+
+func TestInitWithMapExtendedConfig(t *testing.T) {
+	config, err := MQTTTargetProviderConfigFromMap(map[string]string{
+		"brokerAddress":     "ssl://localhost:8883",
+		"clientID":          "coa-test",
+		"requestTopic":      "coa-request",
+		"responseTopic":     "coa-response",
+		"qos":               "2",
+		"retained":          "true",
+		"persistentSession": "true",
+		"authSecret":        "mqtt-cred",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, config.QoS)
+	assert.True(t, config.Retained)
+	assert.True(t, config.PersistentSession)
+	assert.Equal(t, "mqtt-cred", config.AuthSecret)
+
+	_, err = MQTTTargetProviderConfigFromMap(map[string]string{
+		"brokerAddress": "ssl://localhost:8883",
+		"clientID":      "coa-test",
+		"requestTopic":  "coa-request",
+		"responseTopic": "coa-response",
+		"qos":           "3",
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package target
+
+import (
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+)
+
+// SecretProvider returns the secret provider a target provider reads credentials with: the override if
+// it's set, otherwise the secret provider of the evaluation context of the vendor.
+func SecretProvider(override secret.ISecretProvider, ctx *contexts.ManagerContext) secret.ISecretProvider {
+	if override != nil {
+		return override
+	}
+	if ctx != nil && ctx.VencorContext != nil && ctx.VencorContext.EvaluationContext != nil {
+		return ctx.VencorContext.EvaluationContext.SecretProvider
+	}
+	return nil
+}
+
+// ReadSecretValue returns the value if it's set. Otherwise, it reads the field of the secret object, or
+// returns an empty string if no secret object is named. setting is the setting that names the secret
+// object, which is reported when there's no secret provider to read it with.
+func ReadSecretValue(provider secret.ISecretProvider, value string, secretName string, field string, setting string) (string, error) {
+	if value != "" || secretName == "" {
+		return value, nil
+	}
+	if provider == nil {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("a secret provider is needed to read %s", setting), v1alpha2.MissingConfig)
+	}
+	return provider.Get(secretName, field)
+}
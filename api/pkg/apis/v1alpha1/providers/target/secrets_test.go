@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package target
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretProvider(t *testing.T) {
+	assert.Nil(t, SecretProvider(nil, nil))
+	assert.Nil(t, SecretProvider(nil, &contexts.ManagerContext{}))
+
+	fromContext := &mock.MockSecretProvider{}
+	ctx := &contexts.ManagerContext{
+		VencorContext: &contexts.VendorContext{
+			EvaluationContext: &utils.EvaluationContext{SecretProvider: fromContext},
+		},
+	}
+	assert.Equal(t, fromContext, SecretProvider(nil, ctx))
+	override := &mock.MockSecretProvider{}
+	assert.Same(t, override, SecretProvider(override, ctx))
+}
+
+func TestReadSecretValue(t *testing.T) {
+	value, err := ReadSecretValue(nil, "direct", "creds", "password", "auth.secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "direct", value)
+	value, err = ReadSecretValue(nil, "", "", "password", "auth.secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+
+	_, err = ReadSecretValue(nil, "", "creds", "password", "auth.secret")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "auth.secret")
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.MissingConfig, coaErr.State)
+
+	// the mock provider returns <object>>><field>
+	value, err = ReadSecretValue(&mock.MockSecretProvider{}, "", "creds", "password", "auth.secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "creds>>password", value)
+}
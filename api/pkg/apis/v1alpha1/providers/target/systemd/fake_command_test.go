@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCommand is a shell script standing in for systemctl. It records the arguments of every call. A call
+// with an argument that has an output set prints the output, and a call with the failing argument prints
+// the failure message to stderr and exits with 1.
+type fakeCommand struct {
+	Path string
+	dir  string
+}
+
+// newFakeCommand writes the script to a temp folder. Tests are skipped on Windows.
+func newFakeCommand(t *testing.T, failOn string, failure string) fakeCommand {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping because the fake command is a shell script")
+	}
+	dir := t.TempDir()
+	ret := fakeCommand{
+		Path: filepath.Join(dir, "command.sh"),
+		dir:  dir,
+	}
+	err := os.Mkdir(filepath.Join(dir, "outputs"), 0755)
+	assert.Nil(t, err)
+	err = os.WriteFile(ret.Path, []byte(`#!/bin/sh
+echo "$@" >> "`+dir+`/calls"
+for arg in "$@"; do
+	if [ -f "`+dir+`/outputs/$arg" ]; then
+		cat "`+dir+`/outputs/$arg"
+	fi
+	if [ "$arg" = "`+failOn+`" ]; then
+		echo "`+failure+`" >&2
+		exit 1
+	fi
+done
+`), 0755)
+	assert.Nil(t, err)
+	return ret
+}
+
+// Dir is the temp folder of the script, which tests can use for other files
+func (f fakeCommand) Dir() string {
+	return f.dir
+}
+
+// SetOutput sets what calls with the argument print
+func (f fakeCommand) SetOutput(t *testing.T, arg string, output string) {
+	err := os.WriteFile(filepath.Join(f.dir, "outputs", arg), []byte(output), 0644)
+	assert.Nil(t, err)
+}
+
+// Calls returns the arguments of the calls so far, one line per call
+func (f fakeCommand) Calls(t *testing.T) []string {
+	data, err := os.ReadFile(filepath.Join(f.dir, "calls"))
+	if os.IsNotExist(err) {
+		return []string{}
+	}
+	assert.Nil(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// singleComponentStep returns a deployment of instance-1 with the component, and a step applying the
+// action to it
+func singleComponentStep(action string, component model.ComponentSpec) (model.DeploymentSpec, model.DeploymentStep) {
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{Name: "instance-1"},
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{component},
+		},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{
+			{
+				Action:    action,
+				Component: component,
+			},
+		},
+	}
+	return deployment, step
+}
@@ -0,0 +1,514 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+const (
+	systemdUnit     = "systemd.unit"
+	systemdContent  = "systemd.content"
+	systemdEnabled  = "systemd.enabled"
+	systemdUnitHash = "systemd.unitHash"
+	systemdActive   = "systemd.activeState"
+	systemdSub      = "systemd.subState"
+	systemUnitDir   = "/etc/systemd/system"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+// unitSettings maps component properties to the unit file section and key they render to
+var unitSettings = []struct {
+	Property string
+	Section  string
+	Key      string
+}{
+	{"systemd.description", "Unit", "Description"},
+	{"systemd.after", "Unit", "After"},
+	{"systemd.wants", "Unit", "Wants"},
+	{"systemd.requires", "Unit", "Requires"},
+	{"systemd.type", "Service", "Type"},
+	{"systemd.execStartPre", "Service", "ExecStartPre"},
+	{"systemd.execStart", "Service", "ExecStart"},
+	{"systemd.execStop", "Service", "ExecStop"},
+	{"systemd.workingDirectory", "Service", "WorkingDirectory"},
+	{"systemd.user", "Service", "User"},
+	{"systemd.group", "Service", "Group"},
+	{"systemd.restart", "Service", "Restart"},
+	{"systemd.restartSec", "Service", "RestartSec"},
+	{"systemd.wantedBy", "Install", "WantedBy"},
+}
+
+type SystemdTargetProviderConfig struct {
+	Name string `json:"name"`
+	// UserMode manages units of the calling user's service manager (systemctl --user)
+	UserMode bool `json:"userMode,omitempty"`
+	// UnitDir is the folder unit files are written to. It defaults to /etc/systemd/system, or to
+	// ~/.config/systemd/user in user mode
+	UnitDir string `json:"unitDir,omitempty"`
+	// RootDir is an alternate root directory, such as an image being prepared, the unit folder is resolved
+	// against. No service manager runs there, so units are only enabled or disabled with systemctl --root.
+	RootDir string `json:"rootDir,omitempty"`
+	// SystemctlPath is the path to the systemctl binary
+	SystemctlPath string `json:"systemctlPath,omitempty"`
+}
+
+type SystemdTargetProvider struct {
+	Config  SystemdTargetProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func SystemdTargetProviderConfigFromMap(properties map[string]string) (SystemdTargetProviderConfig, error) {
+	ret := SystemdTargetProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	if v, ok := properties["userMode"]; ok {
+		bVal, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'userMode' setting of systemd provider", v1alpha2.BadConfig)
+		}
+		ret.UserMode = bVal
+	}
+	if v, ok := properties["unitDir"]; ok {
+		ret.UnitDir = v
+	}
+	if v, ok := properties["rootDir"]; ok {
+		ret.RootDir = v
+	}
+	if v, ok := properties["systemctlPath"]; ok {
+		ret.SystemctlPath = v
+	}
+	return ret, nil
+}
+
+func (i *SystemdTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := SystemdTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *SystemdTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (i *SystemdTargetProvider) Init(config providers.IProviderConfig) error {
+	_, span := observability.StartSpan("Systemd Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (Systemd Target): Init()")
+
+	updateConfig, err := toSystemdTargetProviderConfig(config)
+	if err != nil {
+		err = errors.New("expected SystemdTargetProviderConfig")
+		return err
+	}
+	if updateConfig.SystemctlPath == "" {
+		updateConfig.SystemctlPath = "systemctl"
+	}
+	if updateConfig.UnitDir == "" {
+		if updateConfig.UserMode {
+			var home string
+			home, err = os.UserHomeDir()
+			if err != nil {
+				return v1alpha2.NewCOAError(err, "failed to locate the user unit folder", v1alpha2.BadConfig)
+			}
+			updateConfig.UnitDir = filepath.Join(home, ".config", "systemd", "user")
+		} else {
+			updateConfig.UnitDir = systemUnitDir
+		}
+	}
+	i.Config = updateConfig
+	return nil
+}
+
+func toSystemdTargetProviderConfig(config providers.IProviderConfig) (SystemdTargetProviderConfig, error) {
+	ret := SystemdTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func (i *SystemdTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("Systemd Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Systemd Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, component := range references {
+		var unit string
+		unit, err = unitName(component.Component, injections)
+		if err != nil {
+			return nil, err
+		}
+		deployed, rerr := os.ReadFile(i.unitFilePath(unit))
+		if rerr != nil {
+			if os.IsNotExist(rerr) {
+				continue
+			}
+			err = rerr
+			sLog.Errorf("  P (Systemd Target): failed to read unit file %s: %+v", unit, err)
+			return nil, err
+		}
+
+		properties := map[string]interface{}{
+			systemdUnit: unit,
+		}
+		// units in an alternate root aren't running, so only their unit files are compared
+		active := true
+		if !i.offline() {
+			var out []byte
+			out, err = i.systemctl(ctx, "show", unit, "--no-pager", "--property=ActiveState,SubState")
+			if err != nil {
+				sLog.Errorf("  P (Systemd Target): failed to read state of unit %s: %+v", unit, err)
+				return nil, err
+			}
+			state := parseShowOutput(out)
+			properties[systemdActive] = state["ActiveState"]
+			properties[systemdSub] = state["SubState"]
+			active = state["ActiveState"] == "active"
+		}
+		// the unit hash is only reported when the unit file on disk matches the desired one and the unit
+		// is active, so a missing hash is picked up as a change by the validation rule
+		desired, rerr := i.renderUnit(component.Component, injections)
+		if rerr == nil && unitHash(deployed) == unitHash(desired) && active {
+			properties[systemdUnitHash] = unitHash(deployed)
+		}
+		ret = append(ret, model.ComponentSpec{
+			Name:       component.Component.Name,
+			Type:       component.Component.Type,
+			Properties: properties,
+		})
+	}
+	return ret, nil
+}
+
+func (i *SystemdTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("Systemd Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Systemd Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	components := step.GetComponents()
+	err = i.GetValidationRule(ctx).Validate(components)
+	if err != nil {
+		return nil, err
+	}
+	if isDryRun {
+		err = nil
+		return nil, nil
+	}
+
+	ret := step.PrepareResultMap()
+
+	for _, component := range step.Components {
+		var unit string
+		unit, err = unitName(component.Component, injections)
+		if err != nil {
+			status := v1alpha2.UpdateFailed
+			if component.Action != "update" {
+				status = v1alpha2.DeleteFailed
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  status,
+				Message: err.Error(),
+			}
+			return ret, err
+		}
+		if component.Action == "update" {
+			err = i.installUnit(ctx, component.Component, unit, injections)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Systemd Target): failed to install unit %s: %+v", unit, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Updated,
+				Message: "",
+			}
+		} else {
+			err = i.removeUnit(ctx, unit)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Systemd Target): failed to remove unit %s: %+v", unit, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Deleted,
+				Message: "",
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (*SystemdTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	optional := []string{systemdUnit, systemdContent, systemdEnabled}
+	for _, s := range unitSettings {
+		optional = append(optional, s.Property)
+	}
+	return model.ValidationRule{
+		RequiredProperties:    []string{},
+		OptionalProperties:    optional,
+		RequiredComponentType: "",
+		RequiredMetadata:      []string{},
+		OptionalMetadata:      []string{},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: systemdUnitHash, IgnoreCase: false, SkipIfMissing: false},
+		},
+	}
+}
+
+// installUnit writes the unit file, reloads the service manager if the file changed and makes sure the
+// unit is enabled and running. A changed unit that was already running is restarted. In an alternate
+// root, the unit is only enabled or disabled.
+func (i *SystemdTargetProvider) installUnit(ctx context.Context, component model.ComponentSpec, unit string, injections *model.ValueInjections) error {
+	content, err := i.renderUnit(component, injections)
+	if err != nil {
+		return err
+	}
+	path := i.unitFilePath(unit)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	changed := err != nil || !bytes.Equal(existing, content)
+	if changed {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+		if !i.offline() {
+			if _, err = i.systemctl(ctx, "daemon-reload"); err != nil {
+				return err
+			}
+		}
+	}
+	enabled := true
+	if v := model.ReadPropertyCompat(component.Properties, systemdEnabled, injections); v != "" {
+		if enabled, err = strconv.ParseBool(v); err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid bool value in the '%s' property", systemdEnabled), v1alpha2.BadRequest)
+		}
+	}
+	if enabled {
+		_, err = i.systemctl(ctx, "enable", unit)
+	} else {
+		_, err = i.systemctl(ctx, "disable", unit)
+	}
+	if err != nil || i.offline() {
+		return err
+	}
+	if changed {
+		_, err = i.systemctl(ctx, "restart", unit)
+	} else {
+		_, err = i.systemctl(ctx, "start", unit)
+	}
+	return err
+}
+
+func (i *SystemdTargetProvider) removeUnit(ctx context.Context, unit string) error {
+	path := i.unitFilePath(unit)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if i.offline() {
+		if _, err := i.systemctl(ctx, "disable", unit); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	}
+	if _, err := i.systemctl(ctx, "disable", "--now", unit); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	_, err := i.systemctl(ctx, "daemon-reload")
+	return err
+}
+
+// renderUnit returns the content of the systemd.content property if set, otherwise it renders a service
+// unit from the systemd.* and env.* properties
+func (i *SystemdTargetProvider) renderUnit(component model.ComponentSpec, injections *model.ValueInjections) ([]byte, error) {
+	if content := model.ReadPropertyCompat(component.Properties, systemdContent, injections); content != "" {
+		return []byte(content), nil
+	}
+	if model.ReadPropertyCompat(component.Properties, "systemd.execStart", injections) == "" {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("component %s needs either a %s or a systemd.execStart property", component.Name, systemdContent), v1alpha2.BadRequest)
+	}
+	sections := map[string][]string{}
+	for _, s := range unitSettings {
+		if v := model.ReadPropertyCompat(component.Properties, s.Property, injections); v != "" {
+			sections[s.Section] = append(sections[s.Section], s.Key+"="+v)
+		}
+	}
+	env := make([]string, 0)
+	for k, v := range component.Properties {
+		if strings.HasPrefix(k, "env.") {
+			value := model.ResolveString(fmt.Sprintf("%v", v), injections)
+			env = append(env, strconv.Quote(strings.TrimPrefix(k, "env.")+"="+value))
+		}
+	}
+	sort.Strings(env)
+	for _, e := range env {
+		sections["Service"] = append(sections["Service"], "Environment="+e)
+	}
+	if _, ok := sections["Install"]; !ok {
+		if i.Config.UserMode {
+			sections["Install"] = []string{"WantedBy=default.target"}
+		} else {
+			sections["Install"] = []string{"WantedBy=multi-user.target"}
+		}
+	}
+
+	var buffer bytes.Buffer
+	for _, section := range []string{"Unit", "Service", "Install"} {
+		if len(sections[section]) == 0 {
+			continue
+		}
+		if buffer.Len() > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString("[" + section + "]\n")
+		for _, line := range sections[section] {
+			buffer.WriteString(line + "\n")
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func (i *SystemdTargetProvider) unitFilePath(unit string) string {
+	return filepath.Join(i.Config.RootDir, i.Config.UnitDir, unit)
+}
+
+// offline is true when units are installed in an alternate root directory
+func (i *SystemdTargetProvider) offline() bool {
+	return i.Config.RootDir != ""
+}
+
+func (i *SystemdTargetProvider) systemctl(ctx context.Context, args ...string) ([]byte, error) {
+	params := make([]string, 0)
+	if i.Config.UserMode {
+		params = append(params, "--user")
+	}
+	if i.offline() {
+		params = append(params, "--root="+i.Config.RootDir)
+	}
+	params = append(params, args...)
+	cmd := exec.CommandContext(ctx, i.Config.SystemctlPath, params...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err.Error(), msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+var unitTypes = []string{".service", ".socket", ".timer", ".path", ".mount", ".target"}
+
+// validUnitName matches the characters systemd allows in unit names
+var validUnitName = regexp.MustCompile(`^[A-Za-z0-9:_.@\\-]+$`)
+
+// unitName returns the systemd.unit property, or the component name. Names without a unit type suffix
+// are treated as services. Names that aren't valid unit names are rejected, as the unit name is the
+// name of the unit file.
+func unitName(component model.ComponentSpec, injections *model.ValueInjections) (string, error) {
+	name := model.ReadPropertyCompat(component.Properties, systemdUnit, injections)
+	if name == "" {
+		name = component.Name
+	}
+	if !validUnitName.MatchString(name) || strings.Contains(name, "..") || len(name) > 255 {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' is not a valid systemd unit name", name), v1alpha2.BadRequest)
+	}
+	for _, suffix := range unitTypes {
+		if strings.HasSuffix(name, suffix) {
+			return name, nil
+		}
+	}
+	return name + ".service", nil
+}
+
+func unitHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// parseShowOutput parses the Key=Value lines printed by "systemctl show"
+func parseShowOutput(out []byte) map[string]string {
+	ret := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) == 2 {
+			ret[parts[0]] = parts[1]
+		}
+	}
+	return ret
+}
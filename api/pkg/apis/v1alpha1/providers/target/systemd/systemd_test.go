@@ -0,0 +1,302 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSystemctl creates a provider writing unit files to a temp folder, running a fake systemctl that
+// prints the output set for "show" and fails for broken.service
+func fakeSystemctl(t *testing.T, userMode bool) (*SystemdTargetProvider, fakeCommand) {
+	fake := newFakeCommand(t, "broken.service", "Failed to enable unit")
+	fake.SetOutput(t, "show", "ActiveState=active\nSubState=running\n")
+	provider := &SystemdTargetProvider{}
+	err := provider.Init(SystemdTargetProviderConfig{
+		UserMode:      userMode,
+		UnitDir:       filepath.Join(fake.Dir(), "units"),
+		SystemctlPath: fake.Path,
+	})
+	assert.Nil(t, err)
+	return provider, fake
+}
+
+func agentComponent() model.ComponentSpec {
+	return model.ComponentSpec{
+		Name: "edge-agent",
+		Properties: map[string]interface{}{
+			"systemd.description": "Edge agent for ${{$instance()}}",
+			"systemd.after":       "network-online.target",
+			"systemd.execStart":   "/usr/local/bin/edge-agent --config /etc/edge-agent.json",
+			"systemd.restart":     "always",
+			"env.LOG_LEVEL":       "debug",
+			"env.MODE":            "edge mode",
+		},
+	}
+}
+
+func TestSystemdTargetProviderConfigFromMap(t *testing.T) {
+	config, err := SystemdTargetProviderConfigFromMap(map[string]string{
+		"name":     "systemd",
+		"userMode": "true",
+		"rootDir":  "/tmp/root",
+	})
+	assert.Nil(t, err)
+	assert.True(t, config.UserMode)
+	assert.Equal(t, "/tmp/root", config.RootDir)
+
+	_, err = SystemdTargetProviderConfigFromMap(map[string]string{
+		"userMode": "maybe",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSystemdTargetProviderInitDefaults(t *testing.T) {
+	provider := SystemdTargetProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, "systemctl", provider.Config.SystemctlPath)
+	assert.Equal(t, "/etc/systemd/system", provider.Config.UnitDir)
+
+	err = provider.InitWithMap(map[string]string{"userMode": "true"})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(provider.Config.UnitDir, filepath.Join(".config", "systemd", "user")))
+}
+
+func TestSystemdApplyRendersUnit(t *testing.T) {
+	provider, fake := fakeSystemctl(t, false)
+	deployment, step := singleComponentStep("update", agentComponent())
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", ret["edge-agent"].Status.String())
+
+	data, err := os.ReadFile(filepath.Join(fake.Dir(), "units", "edge-agent.service"))
+	assert.Nil(t, err)
+	assert.Equal(t, `[Unit]
+Description=Edge agent for instance-1
+After=network-online.target
+
+[Service]
+ExecStart=/usr/local/bin/edge-agent --config /etc/edge-agent.json
+Restart=always
+Environment="LOG_LEVEL=debug"
+Environment="MODE=edge mode"
+
+[Install]
+WantedBy=multi-user.target
+`, string(data))
+	assert.Equal(t, []string{
+		"daemon-reload",
+		"enable edge-agent.service",
+		"restart edge-agent.service",
+	}, fake.Calls(t))
+
+	// applying an unchanged unit only makes sure it's started
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	calls := fake.Calls(t)
+	assert.Equal(t, []string{"enable edge-agent.service", "start edge-agent.service"}, calls[3:])
+}
+
+func TestSystemdApplyUserModeAndContent(t *testing.T) {
+	provider, fake := fakeSystemctl(t, true)
+	content := "[Unit]\nDescription=Backup\n\n[Timer]\nOnCalendar=daily\n"
+	deployment, step := singleComponentStep("update", model.ComponentSpec{
+		Name: "backup",
+		Properties: map[string]interface{}{
+			"systemd.unit":    "backup.timer",
+			"systemd.content": content,
+			"systemd.enabled": "false",
+		},
+	})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(fake.Dir(), "units", "backup.timer"))
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, []string{
+		"--user daemon-reload",
+		"--user disable backup.timer",
+		"--user restart backup.timer",
+	}, fake.Calls(t))
+}
+
+func TestSystemdApplyMissingExecStart(t *testing.T) {
+	provider, _ := fakeSystemctl(t, false)
+	deployment, step := singleComponentStep("update", model.ComponentSpec{
+		Name:       "edge-agent",
+		Properties: map[string]interface{}{},
+	})
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Update Failed", ret["edge-agent"].Status.String())
+}
+
+func TestSystemdApplySystemctlFailure(t *testing.T) {
+	provider, _ := fakeSystemctl(t, false)
+	component := agentComponent()
+	component.Name = "broken"
+	deployment, step := singleComponentStep("update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Failed to enable unit")
+	assert.Equal(t, "Update Failed", ret["broken"].Status.String())
+}
+
+func TestSystemdGet(t *testing.T) {
+	provider, fake := fakeSystemctl(t, false)
+	component := agentComponent()
+	deployment, step := singleComponentStep("update", component)
+
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "edge-agent.service", components[0].Properties["systemd.unit"])
+	assert.Equal(t, "active", components[0].Properties["systemd.activeState"])
+	assert.Equal(t, "running", components[0].Properties["systemd.subState"])
+	assert.NotNil(t, components[0].Properties["systemd.unitHash"])
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(components[0], component))
+
+	// a changed unit is reported as a change
+	changed := agentComponent()
+	changed.Properties["systemd.restart"] = "on-failure"
+	_, step = singleComponentStep("update", changed)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Nil(t, components[0].Properties["systemd.unitHash"])
+	assert.True(t, rule.IsComponentChanged(components[0], changed))
+
+	// so is a unit that stopped running
+	fake.SetOutput(t, "show", "ActiveState=failed\nSubState=failed\n")
+	_, step = singleComponentStep("update", component)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, "failed", components[0].Properties["systemd.activeState"])
+	assert.True(t, rule.IsComponentChanged(components[0], component))
+}
+
+func TestSystemdDelete(t *testing.T) {
+	provider, fake := fakeSystemctl(t, false)
+	component := agentComponent()
+	deployment, step := singleComponentStep("update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+
+	deployment, step = singleComponentStep("delete", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Deleted", ret["edge-agent"].Status.String())
+	_, err = os.Stat(filepath.Join(fake.Dir(), "units", "edge-agent.service"))
+	assert.True(t, os.IsNotExist(err))
+	calls := fake.Calls(t)
+	assert.Equal(t, []string{"disable --now edge-agent.service", "daemon-reload"}, calls[3:])
+
+	// removing a unit that isn't installed is a no-op
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Deleted", ret["edge-agent"].Status.String())
+	assert.Equal(t, 5, len(fake.Calls(t)))
+}
+
+func TestSystemdApplyRootDir(t *testing.T) {
+	fake := newFakeCommand(t, "broken.service", "Failed to enable unit")
+	root := filepath.Join(fake.Dir(), "root")
+	provider := &SystemdTargetProvider{}
+	err := provider.Init(SystemdTargetProviderConfig{
+		RootDir:       root,
+		SystemctlPath: fake.Path,
+	})
+	assert.Nil(t, err)
+	component := agentComponent()
+	deployment, step := singleComponentStep("update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(root, "etc", "systemd", "system", "edge-agent.service"))
+	assert.Nil(t, err)
+	// the host's service manager isn't touched
+	assert.Equal(t, []string{"--root=" + root + " enable edge-agent.service"}, fake.Calls(t))
+
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Nil(t, components[0].Properties["systemd.activeState"])
+	assert.False(t, provider.GetValidationRule(context.Background()).IsComponentChanged(components[0], component))
+	assert.Equal(t, 1, len(fake.Calls(t)))
+
+	deployment, step = singleComponentStep("delete", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "--root="+root+" disable edge-agent.service", fake.Calls(t)[1])
+	_, err = os.Stat(filepath.Join(root, "etc", "systemd", "system", "edge-agent.service"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSystemdApplyInvalidUnitName(t *testing.T) {
+	provider, fake := fakeSystemctl(t, false)
+	for _, name := range []string{"../../../etc/cron.d/x.service", "units/agent", "agent..service", "agent service"} {
+		component := agentComponent()
+		component.Properties["systemd.unit"] = name
+		deployment, step := singleComponentStep("update", component)
+		ret, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.NotNil(t, err)
+		assert.Equal(t, "Update Failed", ret["edge-agent"].Status.String())
+		deployment, step = singleComponentStep("delete", component)
+		ret, err = provider.Apply(context.Background(), deployment, step, false)
+		assert.NotNil(t, err)
+		assert.Equal(t, "Delete Failed", ret["edge-agent"].Status.String())
+		_, err = provider.Get(context.Background(), deployment, step.Components)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, 0, len(fake.Calls(t)))
+	entries, err := os.ReadDir(fake.Dir())
+	assert.Nil(t, err)
+	for _, e := range entries {
+		assert.NotEqual(t, "etc", e.Name())
+	}
+}
+
+func TestUnitName(t *testing.T) {
+	name, err := unitName(model.ComponentSpec{Name: "agent"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "agent.service", name)
+	name, err = unitName(model.ComponentSpec{Name: "my.app"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "my.app.service", name)
+	name, err = unitName(model.ComponentSpec{
+		Name:       "backup",
+		Properties: map[string]interface{}{"systemd.unit": "backup.timer"},
+	}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "backup.timer", name)
+	name, err = unitName(model.ComponentSpec{Name: "getty@tty1"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "getty@tty1.service", name)
+	_, err = unitName(model.ComponentSpec{Name: "../agent"}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := &SystemdTargetProvider{}
+	err := provider.Init(SystemdTargetProviderConfig{})
+	assert.Nil(t, err)
+	conformance.ConformanceSuite(t, provider)
+}
@@ -0,0 +1,371 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+// minimum and maximum number of arguments of expression functions, -1 means no maximum
+var functionArguments = map[string][2]int{
+	"and":      {2, 2},
+	"between":  {3, 3},
+	"config":   {2, -1},
+	"context":  {0, 1},
+	"equal":    {2, 2},
+	"ge":       {2, 2},
+	"gt":       {2, 2},
+	"if":       {3, 3},
+	"in":       {2, -1},
+	"input":    {1, 1},
+	"instance": {0, 0},
+	"json":     {1, 1},
+	"le":       {2, 2},
+	"lt":       {2, 2},
+	"not":      {1, 1},
+	"or":       {2, 2},
+	"output":   {2, 2},
+	"param":    {1, 1},
+	"property": {1, 1},
+	"secret":   {2, 2},
+	"val":      {0, 1},
+}
+
+type CampaignValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type CampaignValidationResult struct {
+	Valid    bool                      `json:"valid"`
+	Errors   []CampaignValidationError `json:"errors,omitempty"`
+	Warnings []CampaignValidationError `json:"warnings,omitempty"`
+}
+
+func (r *CampaignValidationResult) addError(field string, format string, args ...interface{}) {
+	r.Valid = false
+	r.Errors = append(r.Errors, CampaignValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *CampaignValidationResult) addWarning(field string, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, CampaignValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// String joins the errors of the result into a single message
+func (r CampaignValidationResult) String() string {
+	messages := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", e.Field, e.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateCampaign checks a campaign without running it. It reports expressions that can't be
+// parsed, references to missing stages, providers that aren't among the stage providers, invalid
+// timeout and retry durations and loops that can never exit as errors, and stages that can't be
+// reached from the first stage as warnings.
+func ValidateCampaign(spec model.CampaignSpec, stageProviders []string) CampaignValidationResult {
+	ret := CampaignValidationResult{Valid: true}
+
+	names := make([]string, 0, len(spec.Stages))
+	for name := range spec.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if spec.FirstStage != "" {
+		if _, ok := spec.Stages[spec.FirstStage]; !ok {
+			ret.addError("firstStage", "stage '%s' is not found", spec.FirstStage)
+		}
+	}
+
+	next := make(map[string][]string)
+	handlers := make(map[string][]string)
+	exits := make(map[string]bool)
+	dynamic := make(map[string]bool)
+	for _, name := range names {
+		stage := spec.Stages[name]
+		field := "stages." + name
+
+		if stage.Provider == "" {
+			ret.addError(field+".provider", "provider is required")
+		} else if !isStageProvider(stage.Provider, stageProviders) {
+			ret.addError(field+".provider", "unknown stage provider '%s'", stage.Provider)
+		}
+
+		validateDuration(&ret, field+".timeout", stage.Timeout, false)
+		if stage.Retry != nil {
+			validateDuration(&ret, field+".retry.backoff", stage.Retry.Backoff, true)
+			validateDuration(&ret, field+".retry.maxBackoff", stage.Retry.MaxBackoff, true)
+		}
+
+		for _, ref := range []struct {
+			field string
+			stage string
+		}{
+			{"onFailure", stage.OnFailure},
+			{"onTimeout", stage.OnTimeout},
+		} {
+			if ref.stage == "" {
+				continue
+			}
+			if _, ok := spec.Stages[ref.stage]; !ok {
+				ret.addError(field+"."+ref.field, "stage '%s' is not found", ref.stage)
+			} else {
+				handlers[name] = append(handlers[name], ref.stage)
+			}
+		}
+
+		nodes := validateExpression(&ret, field+".stageSelector", stage.StageSelector, spec)
+		if nodes != nil {
+			targets, isDynamic := selectorTargets(nodes)
+			dynamic[name] = isDynamic
+			exits[name] = isDynamic
+			for _, target := range targets {
+				if target == "" {
+					exits[name] = true
+				} else if _, ok := spec.Stages[target]; ok {
+					next[name] = append(next[name], target)
+				} else {
+					ret.addError(field+".stageSelector", "stage '%s' is not found", target)
+				}
+			}
+		} else {
+			// the selector can't be parsed, don't report it as a loop as well
+			exits[name] = true
+		}
+
+		validateExpression(&ret, field+".contexts", stage.Contexts, spec)
+		if stage.ForEach != nil {
+			if stage.ForEach.Items == "" {
+				ret.addError(field+".foreach.items", "items is required")
+			} else {
+				validateExpression(&ret, field+".foreach.items", stage.ForEach.Items, spec)
+			}
+		}
+		validateValue(&ret, field+".inputs", stage.Inputs, spec)
+	}
+
+	// a stage that can't reach a stage selector exit stays in its loop forever
+	canExit := make(map[string]bool)
+	for name := range exits {
+		canExit[name] = exits[name]
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			if canExit[name] {
+				continue
+			}
+			for _, target := range next[name] {
+				if canExit[target] {
+					canExit[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		if !canExit[name] && reaches(next, name, name) {
+			ret.addError("stages."+name+".stageSelector", "stage is part of a loop that never exits")
+		}
+	}
+
+	// unreachable stages can't be told apart when a stage selector is computed at runtime
+	if _, ok := spec.Stages[spec.FirstStage]; ok {
+		visited := map[string]bool{spec.FirstStage: true}
+		queue := []string{spec.FirstStage}
+		hasDynamic := false
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			hasDynamic = hasDynamic || dynamic[name]
+			targets := append(append([]string{}, next[name]...), handlers[name]...)
+			for _, target := range targets {
+				if !visited[target] {
+					visited[target] = true
+					queue = append(queue, target)
+				}
+			}
+		}
+		if !hasDynamic {
+			for _, name := range names {
+				if !visited[name] {
+					ret.addWarning("stages."+name, "stage is not reachable from first stage '%s'", spec.FirstStage)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// validateDuration checks a duration setting, which is positive, or not negative if zero is allowed
+func validateDuration(ret *CampaignValidationResult, field string, value string, allowZero bool) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 || (d == 0 && !allowZero) {
+		ret.addError(field, "'%s' is not a valid duration", value)
+	}
+}
+
+func isStageProvider(provider string, stageProviders []string) bool {
+	for _, p := range stageProviders {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+func reaches(next map[string][]string, from string, to string) bool {
+	visited := make(map[string]bool)
+	queue := append([]string{}, next[from]...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if name == to {
+			return true
+		}
+		if !visited[name] {
+			visited[name] = true
+			queue = append(queue, next[name]...)
+		}
+	}
+	return false
+}
+
+func validateValue(ret *CampaignValidationResult, field string, value interface{}, spec model.CampaignSpec) {
+	switch v := value.(type) {
+	case string:
+		validateExpression(ret, field, v, spec)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			validateValue(ret, field+"."+key, v[key], spec)
+		}
+	case []interface{}:
+		for i, val := range v {
+			validateValue(ret, fmt.Sprintf("%s[%d]", field, i), val, spec)
+		}
+	}
+}
+
+// validateExpression parses an expression and checks the functions it calls and the stages it
+// reads outputs from. It returns nil if the expression can't be parsed.
+func validateExpression(ret *CampaignValidationResult, field string, expression string, spec model.CampaignSpec) []Node {
+	nodes, err := NewParser(expression).Parse()
+	if err != nil {
+		ret.addError(field, err.Error())
+		return nil
+	}
+	for _, node := range nodes {
+		validateNode(ret, field, node, spec)
+	}
+	return nodes
+}
+
+func validateNode(ret *CampaignValidationResult, field string, node Node, spec model.CampaignSpec) {
+	switch n := node.(type) {
+	case *UnaryNode:
+		validateNode(ret, field, n.Expr, spec)
+	case *BinaryNode:
+		validateNode(ret, field, n.Left, spec)
+		validateNode(ret, field, n.Right, spec)
+	case *FunctionNode:
+		arguments, ok := functionArguments[n.Name]
+		if !ok {
+			ret.addError(field, "unknown function '$%s()'", n.Name)
+			return
+		}
+		if len(n.Args) < arguments[0] || (arguments[1] >= 0 && len(n.Args) > arguments[1]) {
+			ret.addError(field, "$%s() doesn't take %d arguments", n.Name, len(n.Args))
+			return
+		}
+		if n.Name == "output" {
+			if stage, ok := staticValue(n.Args[0]); ok {
+				if _, ok := spec.Stages[stage]; !ok {
+					ret.addError(field, "$output() refers to stage '%s' which is not found", stage)
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			validateNode(ret, field, arg, spec)
+		}
+	}
+}
+
+// selectorTargets returns the stages a stage selector can select, including "" when it can end the
+// activation. The bool is true if the selection depends on values only known at runtime.
+func selectorTargets(nodes []Node) ([]string, bool) {
+	if len(nodes) == 0 {
+		return []string{""}, false
+	}
+	if len(nodes) == 1 {
+		return nodeTargets(nodes[0])
+	}
+	value := ""
+	for _, node := range nodes {
+		v, ok := staticValue(node)
+		if !ok {
+			return nil, true
+		}
+		value += v
+	}
+	return []string{value}, false
+}
+
+func nodeTargets(node Node) ([]string, bool) {
+	if f, ok := node.(*FunctionNode); ok && f.Name == "if" && len(f.Args) == 3 {
+		targets, dynamic := nodeTargets(f.Args[1])
+		elseTargets, elseDynamic := nodeTargets(f.Args[2])
+		return append(targets, elseTargets...), dynamic || elseDynamic
+	}
+	if v, ok := staticValue(node); ok {
+		return []string{v}, false
+	}
+	return nil, true
+}
+
+// staticValue evaluates a node that doesn't call any functions
+func staticValue(node Node) (string, bool) {
+	if !isStatic(node) {
+		return "", false
+	}
+	v, err := node.Eval(utils.EvaluationContext{})
+	if err != nil {
+		return "", false
+	}
+	if v == nil {
+		return "", true
+	}
+	return fmt.Sprintf("%v", v), true
+}
+
+func isStatic(node Node) bool {
+	switch n := node.(type) {
+	case *FunctionNode:
+		return false
+	case *UnaryNode:
+		return isStatic(n.Expr)
+	case *BinaryNode:
+		return isStatic(n.Left) && isStatic(n.Right)
+	}
+	return true
+}
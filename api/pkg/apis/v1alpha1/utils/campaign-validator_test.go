@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// the campaigns manager validates against the stage providers of the provider factory, which the
+// utils package can't import
+var testStageProviders = []string{
+	"providers.stage.counter",
+	"providers.stage.list",
+	"providers.stage.mock",
+	"providers.stage.remote",
+}
+
+func validCampaign() model.CampaignSpec {
+	return model.CampaignSpec{
+		FirstStage: "list",
+		Stages: map[string]model.StageSpec{
+			"list": {
+				Name:          "list",
+				Provider:      "providers.stage.list",
+				StageSelector: "deploy",
+			},
+			"deploy": {
+				Name:          "deploy",
+				Provider:      "providers.stage.remote",
+				Contexts:      "${{$output(list,items)}}",
+				StageSelector: "${{$if($equal($output(deploy,__status), 200), check-weight, '')}}",
+				OnFailure:     "roll-back",
+				Inputs: map[string]interface{}{
+					"names": []interface{}{"${{$input(app)}}-v1"},
+				},
+			},
+			"check-weight": {
+				Name:          "check-weight",
+				Provider:      "providers.stage.counter",
+				StageSelector: "${{$if($lt($output(check-weight,weight), 100), deploy, '')}}",
+			},
+			"roll-back": {
+				Name:     "roll-back",
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+}
+
+func TestValidateCampaign(t *testing.T) {
+	result := ValidateCampaign(validCampaign(), testStageProviders)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Errors)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestValidateEmptyCampaign(t *testing.T) {
+	result := ValidateCampaign(model.CampaignSpec{}, testStageProviders)
+	assert.True(t, result.Valid)
+}
+
+func TestValidateCampaignMissingStages(t *testing.T) {
+	spec := validCampaign()
+	spec.FirstStage = "lst"
+	deploy := spec.Stages["deploy"]
+	deploy.StageSelector = "${{$if($equal($output(deploy,__status), 200), chek-weight, '')}}"
+	deploy.OnFailure = "rollback"
+	deploy.Contexts = "${{$output(lst,items)}}"
+	spec.Stages["deploy"] = deploy
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []CampaignValidationError{
+		{Field: "firstStage", Message: "stage 'lst' is not found"},
+		{Field: "stages.deploy.onFailure", Message: "stage 'rollback' is not found"},
+		{Field: "stages.deploy.stageSelector", Message: "stage 'chek-weight' is not found"},
+		{Field: "stages.deploy.contexts", Message: "$output() refers to stage 'lst' which is not found"},
+	}, result.Errors)
+}
+
+func TestValidateCampaignProviders(t *testing.T) {
+	spec := validCampaign()
+	list := spec.Stages["list"]
+	list.Provider = "providers.stage.lists"
+	spec.Stages["list"] = list
+	mock := spec.Stages["roll-back"]
+	mock.Provider = ""
+	spec.Stages["roll-back"] = mock
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []CampaignValidationError{
+		{Field: "stages.list.provider", Message: "unknown stage provider 'providers.stage.lists'"},
+		{Field: "stages.roll-back.provider", Message: "provider is required"},
+	}, result.Errors)
+}
+
+func TestValidateCampaignDurations(t *testing.T) {
+	spec := validCampaign()
+	deploy := spec.Stages["deploy"]
+	deploy.Timeout = "10 minutes"
+	deploy.Retry = &model.RetrySpec{Attempts: 3, Backoff: "5s", MaxBackoff: "-1m"}
+	spec.Stages["deploy"] = deploy
+	list := spec.Stages["list"]
+	list.Retry = &model.RetrySpec{Attempts: 2, Backoff: "soon"}
+	spec.Stages["list"] = list
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []CampaignValidationError{
+		{Field: "stages.deploy.timeout", Message: "'10 minutes' is not a valid duration"},
+		{Field: "stages.deploy.retry.maxBackoff", Message: "'-1m' is not a valid duration"},
+		{Field: "stages.list.retry.backoff", Message: "'soon' is not a valid duration"},
+	}, result.Errors)
+}
+
+func TestValidateCampaignExpressions(t *testing.T) {
+	spec := validCampaign()
+	deploy := spec.Stages["deploy"]
+	deploy.Inputs = map[string]interface{}{
+		"name":  "${{$inptu(app)}}",
+		"count": "${{$input(app, count)}}",
+		"site":  "${{$input(site)",
+	}
+	deploy.StageSelector = "${{$if($equal($output(deploy,__status), 200), check-weight, '')))}}"
+	spec.Stages["deploy"] = deploy
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.False(t, result.Valid)
+	assert.Equal(t, 3, len(result.Errors))
+	assert.Equal(t, "stages.deploy.stageSelector", result.Errors[0].Field)
+	assert.Contains(t, result.Errors[0].Message, "invalid expression")
+	assert.Equal(t, CampaignValidationError{Field: "stages.deploy.inputs.count", Message: "$input() doesn't take 2 arguments"}, result.Errors[1])
+	assert.Equal(t, CampaignValidationError{Field: "stages.deploy.inputs.name", Message: "unknown function '$inptu()'"}, result.Errors[2])
+}
+
+func TestValidateCampaignLoopWithoutExit(t *testing.T) {
+	spec := validCampaign()
+	check := spec.Stages["check-weight"]
+	check.StageSelector = "${{$if($lt($output(check-weight,weight), 100), deploy, check-weight)}}"
+	spec.Stages["check-weight"] = check
+	deploy := spec.Stages["deploy"]
+	deploy.StageSelector = "check-weight"
+	spec.Stages["deploy"] = deploy
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []CampaignValidationError{
+		{Field: "stages.check-weight.stageSelector", Message: "stage is part of a loop that never exits"},
+		{Field: "stages.deploy.stageSelector", Message: "stage is part of a loop that never exits"},
+	}, result.Errors)
+}
+
+func TestValidateCampaignDynamicLoop(t *testing.T) {
+	spec := validCampaign()
+	check := spec.Stages["check-weight"]
+	check.StageSelector = "${{$output(check-weight,next)}}"
+	spec.Stages["check-weight"] = check
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.True(t, result.Valid)
+}
+
+func TestValidateCampaignUnreachableStage(t *testing.T) {
+	spec := validCampaign()
+	spec.Stages["cleanup"] = model.StageSpec{
+		Name:     "cleanup",
+		Provider: "providers.stage.mock",
+	}
+
+	result := ValidateCampaign(spec, testStageProviders)
+	assert.True(t, result.Valid)
+	assert.Equal(t, []CampaignValidationError{
+		{Field: "stages.cleanup", Message: "stage is not reachable from first stage 'list'"},
+	}, result.Warnings)
+
+	// a selector computed at runtime can select any stage
+	deploy := spec.Stages["deploy"]
+	deploy.StageSelector = "${{$output(deploy,next)}}"
+	spec.Stages["deploy"] = deploy
+	result = ValidateCampaign(spec, testStageProviders)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestParseExpression(t *testing.T) {
+	nodes, err := NewParser("prefix-${{$input(a)}}").Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(nodes))
+	assert.Equal(t, &IdentifierNode{"prefix-"}, nodes[0])
+	assert.Equal(t, "input", nodes[1].(*FunctionNode).Name)
+
+	_, err = NewParser("${{$input(a))}}").Parse()
+	assert.NotNil(t, err)
+}
@@ -909,6 +909,25 @@ func (p *Parser) Eval(context utils.EvaluationContext) (interface{}, error) {
 	return ret, nil
 }
 
+// Parse parses the expressions in the text without evaluating them. Literal
+// segments are returned as identifier nodes.
+func (p *Parser) Parse() ([]Node, error) {
+	nodes := make([]Node, 0)
+	for _, s := range p.Segments {
+		if strings.HasPrefix(s, "${{") && strings.HasSuffix(s, "}}") {
+			parser := newExpressionParser(s[3 : len(s)-2])
+			n, err := parser.parse()
+			if err != nil {
+				return nil, fmt.Errorf("invalid expression '%s': %v", s, err)
+			}
+			nodes = append(nodes, n...)
+		} else {
+			nodes = append(nodes, &IdentifierNode{s})
+		}
+	}
+	return nodes, nil
+}
+
 func newExpressionParser(text string) *ExpressionParser {
 	var s scanner.Scanner // TODO: this is mostly used to scan go code, we should use a custom scanner
 	s.Init(strings.NewReader(strings.TrimSpace(text)))
@@ -979,6 +998,20 @@ func (p *ExpressionParser) Eval(context utils.EvaluationContext) (interface{}, e
 	}
 }
 
+func (p *ExpressionParser) parse() ([]Node, error) {
+	n, err := p.expr(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != EOF {
+		return nil, fmt.Errorf("unexpected '%s'", p.text)
+	}
+	if _, ok := n.(*NullNode); ok {
+		return []Node{}, nil
+	}
+	return []Node{n}, nil
+}
+
 func (p *ExpressionParser) next() {
 	p.token = p.scan()
 }
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// FilterObjects applies a label selector and a field selector (both using the Kubernetes selector
+// syntax, such as "env=prod,tier!=edge") to a list of objects. Labels are read from the object's
+// "metadata" and "spec.metadata" collections. Fields are addressed by their dotted JSON path, such
+// as "id", "scope" or "status.status". When both selectors are empty, the list is returned as-is.
+func FilterObjects(list interface{}, labelSelector string, fieldSelector string) (interface{}, error) {
+	if labelSelector == "" && fieldSelector == "" {
+		return list, nil
+	}
+	lSelector := labels.Everything()
+	if labelSelector != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid label selector '%s'", labelSelector), v1alpha2.BadRequest)
+		}
+		lSelector = selector
+	}
+	fSelector := fields.Everything()
+	if fieldSelector != "" {
+		selector, err := fields.ParseSelector(fieldSelector)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid field selector '%s'", fieldSelector), v1alpha2.BadRequest)
+		}
+		fSelector = selector
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var items []interface{}
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]interface{}, 0)
+	for _, item := range items {
+		dict, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if !lSelector.Matches(collectLabels(dict)) {
+			continue
+		}
+		fieldSet := fields.Set{}
+		flattenFields(dict, "", fieldSet)
+		if !fSelector.Matches(fieldSet) {
+			continue
+		}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+func collectLabels(dict map[string]interface{}) labels.Set {
+	ret := labels.Set{}
+	if metadata, ok := dict["metadata"].(map[string]interface{}); ok {
+		for k, v := range metadata {
+			ret[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	if spec, ok := dict["spec"].(map[string]interface{}); ok {
+		if metadata, ok := spec["metadata"].(map[string]interface{}); ok {
+			for k, v := range metadata {
+				if _, exist := ret[k]; !exist {
+					ret[k] = fmt.Sprintf("%v", v)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func flattenFields(value interface{}, prefix string, set fields.Set) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, iv := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenFields(iv, key, set)
+		}
+	case []interface{}:
+		for i, iv := range v {
+			flattenFields(iv, fmt.Sprintf("%s[%d]", prefix, i), set)
+		}
+	case nil:
+		set[prefix] = ""
+	default:
+		set[prefix] = fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func selectorTestTargets() []model.TargetState {
+	return []model.TargetState{
+		{
+			Id:    "target-1",
+			Scope: "default",
+			Spec: &model.TargetSpec{
+				Metadata: map[string]string{
+					"env": "prod",
+				},
+			},
+			Status: map[string]string{
+				"status": "Succeeded",
+			},
+		},
+		{
+			Id:    "target-2",
+			Scope: "edge",
+			Metadata: map[string]string{
+				"env": "dev",
+			},
+			Status: map[string]string{
+				"status": "Failed",
+			},
+		},
+	}
+}
+
+func TestFilterObjectsNoSelectors(t *testing.T) {
+	list := selectorTestTargets()
+	ret, err := FilterObjects(list, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, list, ret)
+}
+func TestFilterObjectsLabelSelector(t *testing.T) {
+	ret, err := FilterObjects(selectorTestTargets(), "env=prod", "")
+	assert.Nil(t, err)
+	items := ret.([]interface{})
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "target-1", items[0].(map[string]interface{})["id"])
+}
+func TestFilterObjectsLabelSelectorTopLevelMetadata(t *testing.T) {
+	ret, err := FilterObjects(selectorTestTargets(), "env in (dev,test)", "")
+	assert.Nil(t, err)
+	items := ret.([]interface{})
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "target-2", items[0].(map[string]interface{})["id"])
+}
+func TestFilterObjectsFieldSelector(t *testing.T) {
+	ret, err := FilterObjects(selectorTestTargets(), "", "status.status!=Succeeded,scope=edge")
+	assert.Nil(t, err)
+	items := ret.([]interface{})
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "target-2", items[0].(map[string]interface{})["id"])
+}
+func TestFilterObjectsCombinedSelectorsNoMatch(t *testing.T) {
+	ret, err := FilterObjects(selectorTestTargets(), "env=prod", "id=target-2")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ret.([]interface{})))
+}
+func TestFilterObjectsInvalidSelector(t *testing.T) {
+	_, err := FilterObjects(selectorTestTargets(), "env=(", "")
+	assert.NotNil(t, err)
+}
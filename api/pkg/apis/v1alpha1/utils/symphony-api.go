@@ -239,6 +239,18 @@ func GetActivation(context context.Context, baseUrl string, activation string, u
 	}
 	return ret, nil
 }
+func CreateActivation(context context.Context, baseUrl string, activation string, user string, password string, payload []byte) error {
+	token, err := auth(context, baseUrl, user, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = callRestAPI(context, baseUrl, "activations/registry/"+activation, "POST", payload, token)
+	if err != nil {
+		return err
+	}
+	return nil
+}
 func ReportActivationStatus(context context.Context, baseUrl string, name string, user string, password string, activation model.ActivationStatus) error {
 	token, err := auth(context, baseUrl, user, password)
 
@@ -7,10 +7,15 @@
 package vendors
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
@@ -25,6 +30,9 @@ import (
 
 var vLog = logger.NewLogger("coa.runtime")
 
+// activationWatchInterval is how often a watched activation is read for changes
+const activationWatchInterval = time.Second
+
 type ActivationsVendor struct {
 	vendors.Vendor
 	ActivationsManager *activations.ActivationsManager
@@ -61,11 +69,12 @@ func (o *ActivationsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route + "/registry",
-			Version:    o.Version,
-			Handler:    o.onActivations,
-			Parameters: []string{"name?"},
+			Methods:       []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:         route + "/registry",
+			Version:       o.Version,
+			Handler:       o.onActivations,
+			StreamHandler: o.onWatchActivation,
+			Parameters:    []string{"name?"},
 		},
 		{
 			Methods:    []string{fasthttp.MethodPost},
@@ -74,6 +83,221 @@ func (o *ActivationsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    o.onStatus,
 			Parameters: []string{"name?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/approve",
+			Version:    o.Version,
+			Handler:    o.onApprove,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/reject",
+			Version:    o.Version,
+			Handler:    o.onReject,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/cancel",
+			Version:    o.Version,
+			Handler:    o.onCancel,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/history",
+			Version:    o.Version,
+			Handler:    o.onHistory,
+			Parameters: []string{"name"},
+		},
+	}
+}
+
+func (c *ActivationsVendor) onHistory(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onHistory",
+	})
+	defer span.End()
+
+	cLog.Info("V (Activations Vendor): onHistory")
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onHistory-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		offset, limit := 0, 0
+		for key, value := range map[string]*int{"offset": &offset, "limit": &limit} {
+			if v, ok := request.Parameters[key]; ok && v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+						State: v1alpha2.BadRequest,
+						Body:  []byte(fmt.Sprintf("invalid %s '%s'", key, v)),
+					})
+				}
+				*value = n
+			}
+		}
+		history, err := c.ActivationsManager.GetHistory(ctx, id, offset, limit)
+		if err != nil {
+			errState := v1alpha2.InternalError
+			if cErr, ok := err.(v1alpha2.COAError); ok {
+				errState = cErr.State
+			}
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: errState,
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(history)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *ActivationsVendor) onCancel(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onCancel",
+	})
+	defer span.End()
+
+	cLog.Info("V (Activations Vendor): onCancel")
+	switch request.Method {
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onCancel-POST", pCtx, nil)
+		id := request.Parameters["__name"]
+		state, err := c.ActivationsManager.CancelActivation(ctx, id)
+		if err != nil {
+			errState := v1alpha2.InternalError
+			if cErr, ok := err.(v1alpha2.COAError); ok {
+				errState = cErr.State
+			}
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: errState,
+				Body:  []byte(err.Error()),
+			})
+		}
+		// the stage vendor stops the running stage of the activation
+		err = c.Context.Publish("cancel", v1alpha2.Event{
+			Body: v1alpha2.ActivationData{
+				Campaign:             state.Spec.Campaign,
+				Activation:           id,
+				ActivationGeneration: state.Status.ActivationGeneration,
+			},
+		})
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(state)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *ActivationsVendor) onApprove(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.resolveApproval(request, true)
+}
+func (c *ActivationsVendor) onReject(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.resolveApproval(request, false)
+}
+func (c *ActivationsVendor) resolveApproval(request v1alpha2.COARequest, approve bool) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "resolveApproval",
+	})
+	defer span.End()
+
+	cLog.Infof("V (Activations Vendor): resolveApproval, approve: %t", approve)
+	if request.Method != fasthttp.MethodPost {
+		resp := v1alpha2.COAResponse{
+			State:       v1alpha2.MethodNotAllowed,
+			Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+			ContentType: "application/json",
+		}
+		observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+		return resp
+	}
+	id := request.Parameters["__name"]
+	var decision model.ApprovalDecision
+	if len(request.Body) > 0 {
+		err := json.Unmarshal(request.Body, &decision)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+	}
+	// a name in the body is only recorded for approvals anyone can decide on, the approval stage requires
+	// the identity found by the JWT middleware when it lists approvers or roles
+	approver := readIdentity(request)
+	if !approver.Authenticated {
+		approver.Name = decision.Approver
+	}
+	status, err := c.ActivationsManager.ResolveApproval(ctx, id, approve, approver, decision.Comment)
+	if err != nil {
+		state := v1alpha2.InternalError
+		if cErr, ok := err.(v1alpha2.COAError); ok {
+			state = cErr.State
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: state,
+			Body:  []byte(err.Error()),
+		})
+	}
+	if approve {
+		// the stage vendor resumes the activation the same way it does for remote stages
+		err = c.Context.Publish("job-report", v1alpha2.Event{
+			Body: status,
+		})
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+	}
+	jData, _ := json.Marshal(status)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        jData,
+		ContentType: "application/json",
+	})
+}
+
+// readIdentity returns the user name and roles set by the JWT middleware, if any
+func readIdentity(request v1alpha2.COARequest) approval.Approver {
+	if request.Context == nil {
+		return approval.Approver{}
+	}
+	user, _ := request.Context.Value(v1alpha2.AuthUserKey).(string)
+	roles, hasRoles := request.Context.Value(v1alpha2.AuthRolesKey).([]string)
+	return approval.Approver{
+		Name:          user,
+		Roles:         roles,
+		Authenticated: user != "" || hasRoles,
 	}
 }
 
@@ -115,6 +339,54 @@ func (c *ActivationsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COARe
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// onWatchActivation streams the state of an activation whenever it changes, until the activation is done,
+// cancelled or failed, or until the watch is cancelled. Bindings that stream, such as gRPC, serve it.
+func (c *ActivationsVendor) onWatchActivation(request v1alpha2.COARequest, send func(v1alpha2.COAResponse) error) error {
+	cLog.Info("V (Activations Vendor): onWatchActivation")
+	id := request.Parameters["__name"]
+	if id == "" {
+		return send(v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("activation name is required"),
+		})
+	}
+	var last []byte
+	for {
+		state, err := c.ActivationsManager.GetSpec(request.Context, id)
+		if err != nil {
+			errState := v1alpha2.InternalError
+			if cErr, ok := err.(v1alpha2.COAError); ok {
+				errState = cErr.State
+			}
+			return send(v1alpha2.COAResponse{
+				State: errState,
+				Body:  []byte(err.Error()),
+			})
+		}
+		data, _ := json.Marshal(state)
+		if !bytes.Equal(data, last) {
+			last = data
+			err = send(v1alpha2.COAResponse{
+				State:       v1alpha2.OK,
+				Body:        data,
+				ContentType: "application/json",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if state.Status != nil && state.Status.IsFinished() {
+			return nil
+		}
+		select {
+		case <-request.Context.Done():
+			return nil
+		case <-time.After(activationWatchInterval):
+		}
+	}
+}
+
 func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
 		"method": "onActivations",
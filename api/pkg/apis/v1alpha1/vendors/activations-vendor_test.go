@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func createActivationsVendor() ActivationsVendor {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := activations.ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	return ActivationsVendor{
+		ActivationsManager: &manager,
+	}
+}
+
+func TestActivationsWatch(t *testing.T) {
+	vendor := createActivationsVendor()
+	err := vendor.ActivationsManager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	err = vendor.ActivationsManager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running})
+	assert.Nil(t, err)
+
+	responses := make(chan v1alpha2.COAResponse, 10)
+	done := make(chan error)
+	go func() {
+		done <- vendor.onWatchActivation(v1alpha2.COARequest{
+			Context:    context.Background(),
+			Parameters: map[string]string{"__name": "test"},
+		}, func(response v1alpha2.COAResponse) error {
+			responses <- response
+			return nil
+		})
+	}()
+	response := <-responses
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var state model.ActivationState
+	assert.Nil(t, json.Unmarshal(response.Body, &state))
+	assert.Equal(t, v1alpha2.Running, state.Status.Status)
+
+	err = vendor.ActivationsManager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	select {
+	case err = <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch didn't end when the activation is done")
+	}
+	response = <-responses
+	assert.Nil(t, json.Unmarshal(response.Body, &state))
+	assert.Equal(t, v1alpha2.Done, state.Status.Status)
+}
+
+func TestActivationsWatchCancelled(t *testing.T) {
+	vendor := createActivationsVendor()
+	err := vendor.ActivationsManager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := 0
+	err = vendor.onWatchActivation(v1alpha2.COARequest{
+		Context:    ctx,
+		Parameters: map[string]string{"__name": "test"},
+	}, func(response v1alpha2.COAResponse) error {
+		sent++
+		cancel()
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestActivationsWatchWithoutName(t *testing.T) {
+	vendor := createActivationsVendor()
+	var response v1alpha2.COAResponse
+	err := vendor.onWatchActivation(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Parameters: map[string]string{"__name": ""},
+	}, func(r v1alpha2.COAResponse) error {
+		response = r
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+}
+
+func TestActivationsWatchFailed(t *testing.T) {
+	vendor := createActivationsVendor()
+	err := vendor.ActivationsManager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	err = vendor.ActivationsManager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.TimedOut})
+	assert.Nil(t, err)
+
+	sent := 0
+	done := make(chan error)
+	go func() {
+		done <- vendor.onWatchActivation(v1alpha2.COARequest{
+			Context:    context.Background(),
+			Parameters: map[string]string{"__name": "test"},
+		}, func(response v1alpha2.COAResponse) error {
+			sent++
+			return nil
+		})
+	}()
+	select {
+	case err = <-done:
+		assert.Nil(t, err)
+		assert.Equal(t, 1, sent)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch didn't end when the activation failed")
+	}
+}
+
+func TestActivationsWatchNotFound(t *testing.T) {
+	vendor := createActivationsVendor()
+	var response v1alpha2.COAResponse
+	err := vendor.onWatchActivation(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Parameters: map[string]string{"__name": "missing"},
+	}, func(r v1alpha2.COAResponse) error {
+		response = r
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+}
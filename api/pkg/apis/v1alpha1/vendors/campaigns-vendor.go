@@ -120,6 +120,16 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 			})
 		}
 
+		result := c.CampaignsManager.ValidateSpec(ctx, campaign)
+		if !result.Valid {
+			jData, _ := json.Marshal(result)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State:       v1alpha2.BadRequest,
+				Body:        jData,
+				ContentType: "application/json",
+			})
+		}
+
 		err = c.CampaignsManager.UpsertSpec(ctx, id, campaign)
 		if err != nil {
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
@@ -87,6 +87,15 @@ func (c *DevicesVendor) onDevices(request v1alpha2.COARequest) v1alpha2.COARespo
 		isArray := false
 		if id == "" {
 			state, err = c.DevicesManager.ListSpec(ctx)
+			if err == nil {
+				state, err = utils.FilterObjects(state, request.Parameters["label-selector"], request.Parameters["field-selector"])
+				if err != nil {
+					return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+						State: v1alpha2.BadRequest,
+						Body:  []byte(err.Error()),
+					})
+				}
+			}
 			isArray = true
 		} else {
 			state, err = c.DevicesManager.GetSpec(ctx, id)
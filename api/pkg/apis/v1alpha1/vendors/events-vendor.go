@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/events"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var evLog = logger.NewLogger("coa.runtime")
+
+// EventsVendor forwards the events published to the topics of its event sink managers to external
+// systems. It doesn't have any endpoints.
+type EventsVendor struct {
+	vendors.Vendor
+	EventSinkManagers []*events.EventSinkManager
+}
+
+func (o *EventsVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  o.Vendor.Version,
+		Name:     "Events",
+		Producer: "Microsoft",
+	}
+}
+
+func (e *EventsVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	err := e.Vendor.Init(config, factories, providers, pubsubProvider)
+	if err != nil {
+		return err
+	}
+	for _, m := range e.Managers {
+		if c, ok := m.(*events.EventSinkManager); ok {
+			e.EventSinkManagers = append(e.EventSinkManagers, c)
+		}
+	}
+	if len(e.EventSinkManagers) == 0 {
+		return v1alpha2.NewCOAError(nil, "event sink manager is not supplied", v1alpha2.MissingConfig)
+	}
+	for _, m := range e.EventSinkManagers {
+		manager := m
+		for _, topic := range manager.Topics() {
+			evLog.Infof("V (Events): forwarding events of topic %s", topic)
+			err = e.Vendor.Context.Subscribe(topic, func(topic string, event v1alpha2.Event) error {
+				return manager.HandleEvent(context.Background(), topic, event)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *EventsVendor) GetEndpoints() []v1alpha2.Endpoint {
+	return []v1alpha2.Endpoint{}
+}
@@ -96,7 +96,8 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 		if !ok {
 			return v1alpha2.NewCOAError(nil, "site is not supplied", v1alpha2.BadRequest)
 		}
-		f.StagingManager.HandleJobEvent(context.TODO(), event) //TODO: how to handle errors in this case?
+		ctx := observ_utils.SpanContextFromMetadata(context.TODO(), event.Metadata)
+		f.StagingManager.HandleJobEvent(ctx, event) //TODO: how to handle errors in this case?
 		return nil
 	})
 	f.Vendor.Context.Subscribe("report", func(topic string, event v1alpha2.Event) error {
@@ -106,7 +107,7 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 		err := json.Unmarshal(jData, &status)
 		if err == nil {
 			err := utils.SyncActivationStatus(
-				context.TODO(),
+				observ_utils.SpanContextFromMetadata(context.TODO(), event.Metadata),
 				f.Vendor.Context.SiteInfo.ParentSite.BaseUrl,
 				f.Vendor.Context.SiteInfo.ParentSite.Username,
 				f.Vendor.Context.SiteInfo.ParentSite.Password, status)
@@ -96,6 +96,15 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 				scope = ""
 			}
 			state, err = c.InstancesManager.ListSpec(ctx, scope)
+			if err == nil {
+				state, err = utils.FilterObjects(state, request.Parameters["label-selector"], request.Parameters["field-selector"])
+				if err != nil {
+					return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+						State: v1alpha2.BadRequest,
+						Body:  []byte(err.Error()),
+					})
+				}
+			}
 			isArray = true
 		} else {
 			state, err = c.InstancesManager.GetSpec(ctx, id, scope)
@@ -170,11 +179,15 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 			})
 		}
 		if c.Config.Properties["useJobManager"] == "true" {
+			metadata := map[string]string{
+				"objectType": "instance",
+				"scope":      scope,
+				// jobs of the same instance are handled in order
+				pubsub.OrderingKeyMetadata: "instance/" + scope + "/" + id,
+			}
+			observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 			c.Context.Publish("job", v1alpha2.Event{
-				Metadata: map[string]string{
-					"objectType": "instance",
-					"scope":      scope,
-				},
+				Metadata: metadata,
 				Body: v1alpha2.JobData{
 					Id:     id,
 					Action: "UPDATE",
@@ -193,11 +206,15 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 			scope = "default"
 		}
 		if c.Config.Properties["useJobManager"] == "true" && direct != "true" {
+			metadata := map[string]string{
+				"objectType": "instance",
+				"scope":      scope,
+				// jobs of the same instance are handled in order
+				pubsub.OrderingKeyMetadata: "instance/" + scope + "/" + id,
+			}
+			observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 			c.Context.Publish("job", v1alpha2.Event{
-				Metadata: map[string]string{
-					"objectType": "instance",
-					"scope":      scope,
-				},
+				Metadata: metadata,
 				Body: v1alpha2.JobData{
 					Id:     id,
 					Action: "DELETE",
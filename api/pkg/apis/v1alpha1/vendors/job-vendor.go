@@ -61,9 +61,11 @@ func (e *JobVendor) Init(config vendors.VendorConfig, factories []managers.IMana
 		return nil
 	})
 	e.Vendor.Context.Subscribe("job", func(topic string, event v1alpha2.Event) error {
-		err := e.JobsManager.HandleJobEvent(context.Background(), event)
+		err := e.JobsManager.HandleJobEvent(observ_utils.SpanContextFromMetadata(context.Background(), event.Metadata), event)
 		if err != nil && v1alpha2.IsDelayed(err) {
+			// the job is published again instead of being retried by the pub-sub provider
 			go e.Vendor.Context.Publish(topic, event)
+			return nil
 		}
 		return err
 	})
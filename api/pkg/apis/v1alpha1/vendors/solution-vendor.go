@@ -191,6 +191,7 @@ func (c *SolutionVendor) onReconcile(request v1alpha2.COARequest) v1alpha2.COARe
 		}
 		delete := request.Parameters["delete"]
 		summary, err := c.SolutionManager.Reconcile(ctx, deployment, delete == "true", scope)
+		c.publishDeploymentEvents(deployment, summary, scope, err)
 		data, _ := json.Marshal(summary)
 		if err != nil {
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
@@ -302,6 +303,7 @@ func (c *SolutionVendor) doDeploy(ctx context.Context, deployment model.Deployme
 	})
 	defer span.End()
 	summary, err := c.SolutionManager.Reconcile(ctx, deployment, false, scope)
+	c.publishDeploymentEvents(deployment, summary, scope, err)
 	data, _ := json.Marshal(summary)
 	if err != nil {
 		response := v1alpha2.COAResponse{
@@ -326,6 +328,7 @@ func (c *SolutionVendor) doRemove(ctx context.Context, deployment model.Deployme
 	defer span.End()
 
 	summary, err := c.SolutionManager.Reconcile(ctx, deployment, true, scope)
+	c.publishDeploymentEvents(deployment, summary, scope, err)
 	data, _ := json.Marshal(summary)
 	if err != nil {
 		response := v1alpha2.COAResponse{
@@ -343,3 +346,15 @@ func (c *SolutionVendor) doRemove(ctx context.Context, deployment model.Deployme
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, response)
 	return response
 }
+
+// publishDeploymentEvents publishes the outcome of a reconciliation for external systems
+func (c *SolutionVendor) publishDeploymentEvents(deployment model.DeploymentSpec, summary model.SummarySpec, scope string, err error) {
+	if c.Vendor.Context == nil {
+		return
+	}
+	for _, event := range model.NewDeploymentEvents(deployment, summary, scope, err) {
+		if pErr := c.Vendor.Context.Publish(model.DeploymentEventTopic, event); pErr != nil {
+			log.Errorf("V (Solution): failed to publish deployment event: %v", pErr)
+		}
+	}
+}
@@ -93,6 +93,15 @@ func (c *SolutionsVendor) onSolutions(request v1alpha2.COARequest) v1alpha2.COAR
 				scope = ""
 			}
 			state, err = c.SolutionsManager.ListSpec(ctx, scope)
+			if err == nil {
+				state, err = utils.FilterObjects(state, request.Parameters["label-selector"], request.Parameters["field-selector"])
+				if err != nil {
+					return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+						State: v1alpha2.BadRequest,
+						Body:  []byte(err.Error()),
+					})
+				}
+			}
 			isArray = true
 		} else {
 			state, err = c.SolutionsManager.GetSpec(ctx, id, scope)
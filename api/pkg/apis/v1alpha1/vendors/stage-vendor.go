@@ -17,9 +17,11 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/materialize"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/mock"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/subcampaign"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/wait"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -74,6 +76,11 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	}
 	s.Vendor.Context.Subscribe("activation", func(topic string, event v1alpha2.Event) error {
 		log.Info("V (Stage): handling activation event")
+		// a stage manager that is shutting down fails the event, so that it's delivered again
+		if err := s.StageManager.BeginWork(); err != nil {
+			return err
+		}
+		defer s.StageManager.EndWork()
 		var actData v1alpha2.ActivationData
 		jData, _ := json.Marshal(event.Body)
 		err := json.Unmarshal(jData, &actData)
@@ -105,6 +112,10 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	})
 	s.Vendor.Context.Subscribe("trigger", func(topic string, event v1alpha2.Event) error {
 		log.Info("V (Stage): handling trigger event")
+		if err := s.StageManager.BeginWork(); err != nil {
+			return err
+		}
+		defer s.StageManager.EndWork()
 		status := model.ActivationStatus{
 			Stage:        "",
 			NextStage:    "",
@@ -127,6 +138,10 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 				sLog.Errorf("V (Stage): failed to report error status: %v (%v)", status.ErrorMessage, err)
 			}
 		}
+		if !triggerData.NeedsReport && s.isCancelled(triggerData.Activation) {
+			sLog.Infof("V (Stage): skipping stage %s of cancelled activation %s", triggerData.Stage, triggerData.Activation)
+			return nil
+		}
 		campaign, err := s.CampaignsManager.GetSpec(context.TODO(), triggerData.Campaign)
 		if err != nil {
 			status.Status = v1alpha2.BadRequest
@@ -155,7 +170,8 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 			}
 		}
 
-		status, activation := s.StageManager.HandleTriggerEvent(context.TODO(), *campaign.Spec, triggerData)
+		ctx := observ_utils.SpanContextFromMetadata(context.TODO(), event.Metadata)
+		status, activation := s.StageManager.HandleTriggerEvent(ctx, *campaign.Spec, triggerData)
 
 		if triggerData.NeedsReport {
 			sLog.Debugf("V (Stage): reporting status: %v", status)
@@ -164,14 +180,25 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 			})
 
 		} else {
+			if status.Status != v1alpha2.Cancelled && s.isCancelled(triggerData.Activation) {
+				sLog.Infof("V (Stage): activation %s was cancelled while running stage %s", triggerData.Activation, triggerData.Stage)
+				return nil
+			}
 			err = s.ActivationsManager.ReportStatus(context.TODO(), triggerData.Activation, status)
 			if err != nil {
 				sLog.Errorf("V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
 				return err
 			}
+			if status.Status != v1alpha2.Cancelled && status.IsFinished() {
+				s.completeChildActivation(triggerData.Activation, status)
+			}
 			if activation != nil && status.Status != v1alpha2.Done && status.Status != v1alpha2.Paused {
+				// the next stage continues the trace of this stage
+				metadata := map[string]string{}
+				observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 				s.Vendor.Context.Publish("trigger", v1alpha2.Event{
-					Body: *activation,
+					Metadata: metadata,
+					Body:     *activation,
 				})
 			}
 		}
@@ -179,10 +206,19 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 		return nil
 	})
 	s.Vendor.Context.Subscribe("job-report", func(topic string, event v1alpha2.Event) error {
+		if err := s.StageManager.BeginWork(); err != nil {
+			return err
+		}
+		defer s.StageManager.EndWork()
 		sLog.Debugf("V (Stage): handling job report event: %v", event)
 		jData, _ := json.Marshal(event.Body)
 		var status model.ActivationStatus
 		json.Unmarshal(jData, &status)
+		if activation, ok := status.Outputs["__activation"].(string); ok && s.isCancelled(activation) {
+			sLog.Infof("V (Stage): ignoring job report of cancelled activation %s", activation)
+			return nil
+		}
+		resumed := false
 		if status.Status == v1alpha2.Done || status.Status == v1alpha2.OK {
 			campaign, err := s.CampaignsManager.GetSpec(context.TODO(), status.Outputs["__campaign"].(string))
 			if err != nil {
@@ -198,6 +234,7 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 					sLog.Errorf("V (Stage): failed to resume stage: %v", err)
 				}
 				if activation != nil {
+					resumed = true
 					s.Vendor.Context.Publish("trigger", v1alpha2.Event{
 						Body: *activation,
 					})
@@ -211,9 +248,32 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 			sLog.Errorf("V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
 			return err
 		}
+		if !resumed && status.IsFinished() {
+			s.completeChildActivation(status.Outputs["__activation"].(string), status)
+		}
+		return nil
+	})
+	s.Vendor.Context.Subscribe("cancel", func(topic string, event v1alpha2.Event) error {
+		var actData v1alpha2.ActivationData
+		jData, _ := json.Marshal(event.Body)
+		err := json.Unmarshal(jData, &actData)
+		if err != nil {
+			return v1alpha2.NewCOAError(nil, "event body is not an activation job", v1alpha2.BadRequest)
+		}
+		err = s.StageManager.CancelActivation(context.TODO(), actData.Campaign, actData.Activation, actData.ActivationGeneration)
+		if err != nil {
+			return err
+		}
+		if state, err := s.ActivationsManager.GetSpec(context.TODO(), actData.Activation); err == nil && state.Status != nil {
+			s.completeChildActivation(actData.Activation, *state.Status)
+		}
 		return nil
 	})
 	s.Vendor.Context.Subscribe("remote-job", func(topic string, event v1alpha2.Event) error {
+		if err := s.StageManager.BeginWork(); err != nil {
+			return err
+		}
+		defer s.StageManager.EndWork()
 		// Unwrap data package from event body
 		jData, _ := json.Marshal(event.Body)
 		var job v1alpha2.JobData
@@ -272,12 +332,43 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 		default:
 			return v1alpha2.NewCOAError(nil, fmt.Sprintf("operation %v is not supported", dataPackage.Inputs["operation"]), v1alpha2.BadRequest)
 		}
-		status := s.StageManager.HandleDirectTriggerEvent(context.TODO(), triggerData)
+		ctx := observ_utils.SpanContextFromMetadata(context.TODO(), event.Metadata)
+		status := s.StageManager.HandleDirectTriggerEvent(ctx, triggerData)
 		sLog.Debugf("V (Stage): reporting status: %v", status)
+		metadata := map[string]string{}
+		observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 		s.Vendor.Context.Publish("report", v1alpha2.Event{
-			Body: status,
+			Metadata: metadata,
+			Body:     status,
 		})
 		return nil
 	})
 	return nil
 }
+
+func (s *StageVendor) isCancelled(activation string) bool {
+	state, err := s.ActivationsManager.GetSpec(context.TODO(), activation)
+	return err == nil && state.Status != nil && state.Status.Status == v1alpha2.Cancelled
+}
+
+// completeChildActivation resumes the parent activation, if any, that waits for a child activation
+// started by a sub-campaign stage to finish
+func (s *StageVendor) completeChildActivation(child string, status model.ActivationStatus) {
+	state, err := s.ActivationsManager.GetSpec(context.TODO(), child)
+	if err != nil || state.Spec.Parent == "" {
+		return
+	}
+	parent, err := s.ActivationsManager.GetSpec(context.TODO(), state.Spec.Parent)
+	if err != nil {
+		sLog.Errorf("V (Stage): failed to get parent activation %s of %s: %v", state.Spec.Parent, child, err)
+		return
+	}
+	if parent.Status == nil || parent.Status.Status != v1alpha2.Paused || parent.Status.Outputs[subcampaign.ActivationOutput] != child {
+		sLog.Infof("V (Stage): parent activation %s isn't waiting for activation %s", state.Spec.Parent, child)
+		return
+	}
+	sLog.Infof("V (Stage): child activation %s finished, resuming parent activation %s", child, state.Spec.Parent)
+	s.Vendor.Context.Publish("job-report", v1alpha2.Event{
+		Body: subcampaign.Complete(*parent.Status, child, status),
+	})
+}
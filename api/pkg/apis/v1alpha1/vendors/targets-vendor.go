@@ -132,6 +132,15 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 				scope = ""
 			}
 			state, err = c.TargetsManager.ListSpec(ctx, scope)
+			if err == nil {
+				state, err = utils.FilterObjects(state, request.Parameters["label-selector"], request.Parameters["field-selector"])
+				if err != nil {
+					return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+						State: v1alpha2.BadRequest,
+						Body:  []byte(err.Error()),
+					})
+				}
+			}
 			isArray = true
 		} else {
 			state, err = c.TargetsManager.GetSpec(ctx, id, scope)
@@ -213,11 +222,15 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 			})
 		}
 		if c.Config.Properties["useJobManager"] == "true" {
+			metadata := map[string]string{
+				"objectType": "target",
+				"scope":      scope,
+				// jobs of the same target are handled in order
+				pubsub.OrderingKeyMetadata: "target/" + scope + "/" + id,
+			}
+			observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 			c.Context.Publish("job", v1alpha2.Event{
-				Metadata: map[string]string{
-					"objectType": "target",
-					"scope":      scope,
-				},
+				Metadata: metadata,
 				Body: v1alpha2.JobData{
 					Id:     id,
 					Action: "UPDATE",
@@ -233,11 +246,15 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 		direct := request.Parameters["direct"]
 
 		if c.Config.Properties["useJobManager"] == "true" && direct != "true" {
+			metadata := map[string]string{
+				"objectType": "target",
+				"scope":      scope,
+				// jobs of the same target are handled in order
+				pubsub.OrderingKeyMetadata: "target/" + scope + "/" + id,
+			}
+			observ_utils.PropagateSpanContextToMetadata(ctx, metadata)
 			c.Context.Publish("job", v1alpha2.Event{
-				Metadata: map[string]string{
-					"objectType": "target",
-					"scope":      scope,
-				},
+				Metadata: metadata,
 				Body: v1alpha2.JobData{
 					Id:     id,
 					Action: "DELETE",
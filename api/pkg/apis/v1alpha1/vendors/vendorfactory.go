@@ -55,6 +55,8 @@ func (c SymphonyVendorFactory) CreateVendor(config vendors.VendorConfig) (vendor
 		return &TrailsVendor{}, nil
 	case "vendors.backgroundjob":
 		return &BackgroundJobVendor{}, nil
+	case "vendors.events":
+		return &EventsVendor{}, nil
 	default:
 		return nil, nil //Can't throw errors as other factories may create it...
 	}
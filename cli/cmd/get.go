@@ -10,15 +10,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"sort"
 
-	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/cli/config"
 	"github.com/eclipse-symphony/symphony/cli/utils"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 )
 
 var (
@@ -27,7 +29,22 @@ var (
 	jsonPath      string
 	docType       string
 	configContext string
+	outputFormat  string
+	labelSelector string
+	fieldSelector string
+	scope         string
+	allScopes     bool
+	watch         bool
+	watchInterval int
 )
+
+const customColumnsPrefix = "custom-columns="
+
+type column struct {
+	Header string
+	Path   string
+}
+
 var GetCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Query Symphony objects",
@@ -42,24 +59,257 @@ var GetCmd = &cobra.Command{
 			ctx = "default"
 		}
 
-		for _, a := range args {
-			list, err := utils.Get(
-				c.Contexts[ctx].Url,
-				c.Contexts[ctx].User,
-				c.Contexts[ctx].Secret,
-				a,
-				jsonPath,
-				docType,
-				objectName)
-			if err != nil {
-				fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+		if scope != "" && allScopes {
+			fmt.Printf("\n%s  --scope and --all-scopes can't be used together%s\n\n", utils.ColorRed(), utils.ColorReset())
+			return
+		}
+		if err := validateOutputFormat(outputFormat); err != nil {
+			fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+
+		query := utils.QueryOptions{
+			LabelSelector: labelSelector,
+			FieldSelector: fieldSelector,
+		}
+		if !allScopes {
+			query.Scope = scope
+			if query.Scope == "" {
+				query.Scope = "default"
+			}
+		}
+
+		lastResults := make(map[string]string)
+		for {
+			for _, a := range args {
+				list, err := utils.Get(
+					c.Contexts[ctx].Url,
+					c.Contexts[ctx].User,
+					c.Contexts[ctx].Secret,
+					a,
+					jsonPath,
+					docType,
+					objectName,
+					query)
+				if err != nil {
+					fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+					return
+				}
+				if watch {
+					// only re-render a result set when it has changed since the last poll
+					data, _ := json.Marshal(list)
+					if lastResults[a] == string(data) {
+						continue
+					}
+					lastResults[a] = string(data)
+				}
+				err = outputResults(list, a)
+				if err != nil {
+					fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+					return
+				}
+			}
+			if !watch {
 				return
 			}
-			outputList(list, a, jsonPath)
+			interval := watchInterval
+			if interval <= 0 {
+				interval = 5
+			}
+			time.Sleep(time.Duration(interval) * time.Second)
 		}
 	},
 }
 
+func validateOutputFormat(format string) error {
+	switch format {
+	case "", "json", "yaml", "wide", "name":
+		return nil
+	}
+	if strings.HasPrefix(format, customColumnsPrefix) {
+		_, err := parseCustomColumns(strings.TrimPrefix(format, customColumnsPrefix))
+		return err
+	}
+	return fmt.Errorf("unsupported output format '%s', expected one of: json, yaml, wide, name, custom-columns=<header>:<path>,...", format)
+}
+
+func parseCustomColumns(spec string) ([]column, error) {
+	ret := make([]column, 0)
+	for _, part := range strings.Split(spec, ",") {
+		pair := strings.SplitN(part, ":", 2)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, fmt.Errorf("invalid custom column '%s', expected <header>:<path>", part)
+		}
+		ret = append(ret, column{Header: pair[0], Path: pair[1]})
+	}
+	return ret, nil
+}
+
+func outputResults(list []interface{}, objType string) error {
+	switch {
+	case outputFormat == "":
+		outputList(list, objType, jsonPath)
+	case outputFormat == "wide":
+		columns := defaultColumns(objType, true)
+		if columns == nil || jsonPath != "" {
+			outputList(list, objType, jsonPath)
+		} else {
+			outputColumns(list, columns)
+		}
+	case outputFormat == "name":
+		for _, item := range list {
+			fmt.Printf("%s/%s\n", singularType(objType), formatCell(lookupPath(item, "id")))
+		}
+	case outputFormat == "json":
+		var data []byte
+		var err error
+		if objectName != "" && len(list) == 1 {
+			data, err = json.MarshalIndent(list[0], "", "  ")
+		} else {
+			data, err = json.MarshalIndent(list, "", "  ")
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case outputFormat == "yaml":
+		var data []byte
+		var err error
+		if objectName != "" && len(list) == 1 {
+			data, err = yaml.Marshal(list[0])
+		} else {
+			data, err = yaml.Marshal(list)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	case strings.HasPrefix(outputFormat, customColumnsPrefix):
+		columns, err := parseCustomColumns(strings.TrimPrefix(outputFormat, customColumnsPrefix))
+		if err != nil {
+			return err
+		}
+		outputColumns(list, columns)
+	}
+	return nil
+}
+
+func singularType(objType string) string {
+	switch objType {
+	case "targets":
+		return "target"
+	case "devices":
+		return "device"
+	case "solutions":
+		return "solution"
+	case "instances":
+		return "instance"
+	}
+	return objType
+}
+
+func defaultColumns(objType string, wide bool) []column {
+	var columns []column
+	switch objType {
+	case "target", "targets":
+		columns = []column{{"Name", "id"}, {"Status", "status.status"}}
+		if wide {
+			columns = append(columns, column{"Generation", "spec.generation"}, column{"Version", "spec.version"})
+		}
+	case "device", "devices":
+		columns = []column{{"Name", "id"}, {"Status", "status.status"}}
+		if wide {
+			columns = append(columns, column{"Display Name", "spec.displayName"})
+		}
+	case "solution", "solutions":
+		columns = []column{{"Name", "id"}}
+		if wide {
+			columns = append(columns, column{"Display Name", "spec.displayName"}, column{"Version", "spec.version"})
+		}
+	case "instance", "instances":
+		columns = []column{{"Name", "id"}, {"Status", "status.status"}, {"Targets", "status.targets"}, {"Deployed", "status.deployed"}}
+		if wide {
+			columns = append(columns, column{"Solution", "spec.solution"}, column{"Target", "spec.target.name"})
+		}
+	default:
+		return nil
+	}
+	if allScopes && objType != "device" && objType != "devices" {
+		columns = append([]column{{"Scope", "scope"}}, columns...)
+	}
+	return columns
+}
+
+// lookupPath resolves a dotted path such as ".spec.components[0].name" against a decoded JSON object.
+func lookupPath(item interface{}, path string) interface{} {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return item
+	}
+	current := item
+	for _, segment := range strings.Split(path, ".") {
+		name := segment
+		indexes := make([]int, 0)
+		if i := strings.Index(segment, "["); i >= 0 {
+			name = segment[:i]
+			for _, idx := range strings.Split(strings.TrimSuffix(segment[i+1:], "]"), "][") {
+				n, err := strconv.Atoi(idx)
+				if err != nil {
+					return nil
+				}
+				indexes = append(indexes, n)
+			}
+		}
+		if name != "" {
+			dict, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = dict[name]
+		}
+		for _, n := range indexes {
+			arr, ok := current.([]interface{})
+			if !ok || n < 0 || n >= len(arr) {
+				return nil
+			}
+			current = arr[n]
+		}
+	}
+	return current
+}
+
+func formatCell(v interface{}) interface{} {
+	switch v.(type) {
+	case nil:
+		return "<none>"
+	case map[string]interface{}:
+		return "map[...]"
+	case []interface{}:
+		return "array[...]"
+	}
+	return v
+}
+
+func outputColumns(list []interface{}, columns []column) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{}
+	for _, c := range columns {
+		header = append(header, c.Header)
+	}
+	t.AppendHeader(header)
+	for _, item := range list {
+		row := table.Row{}
+		for _, c := range columns {
+			row = append(row, formatCell(lookupPath(item, c.Path)))
+		}
+		t.AppendRow(row)
+	}
+	t.SetStyle(table.StyleColoredBright)
+	t.Render()
+}
+
 func outputAsAttributes(t table.Writer, data []byte, objType string, path string, keys []string) error {
 	var topAttrs map[string]interface{}
 	err := json.Unmarshal(data, &topAttrs)
@@ -109,23 +359,6 @@ func outputAsArray(t table.Writer, item interface{}) error {
 	return nil
 }
 func addTableHeader(t table.Writer, list interface{}, objType string, path string, itemType string) []string {
-	if path == "" {
-		switch objType {
-		case "target", "targets":
-			t.AppendHeader(table.Row{"Name", "Status"})
-			return []string{"Name", "Status"}
-		case "device", "devices":
-			t.AppendHeader(table.Row{"Name", "Status"})
-			return []string{"Name", "Status"}
-		case "solution", "solutions":
-			t.AppendHeader(table.Row{"Name"})
-			return []string{"Name"}
-		case "instance", "instances":
-			t.AppendHeader(table.Row{"Name", "Status", "Targets", "Deployed"})
-			return []string{"Name", "Status", "Targets", "Deployed"}
-		}
-		return nil
-	}
 	if itemType == "string" {
 		header := path[strings.LastIndex(path, ".")+1:]
 		t.AppendHeader(table.Row{header})
@@ -154,6 +387,12 @@ func addTableHeader(t table.Writer, list interface{}, objType string, path strin
 	return nil
 }
 func outputList(list []interface{}, objType string, path string) {
+	if path == "" {
+		if columns := defaultColumns(objType, false); columns != nil {
+			outputColumns(list, columns)
+			return
+		}
+	}
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
 	if len(list) > 0 {
@@ -166,47 +405,6 @@ func outputList(list []interface{}, objType string, path string) {
 	t.SetStyle(table.StyleColoredBright)
 	t.Render()
 }
-func outputTarget(t table.Writer, data []byte) {
-	var target Target
-	err := json.Unmarshal(data, &target)
-	if err == nil {
-		row := table.Row{}
-		row = append(row, target.Id)
-		row = append(row, target.Status["status"])
-		t.AppendRow(row)
-	}
-}
-func outputDevice(t table.Writer, data []byte) {
-	var device Device
-	err := json.Unmarshal(data, &device)
-	if err == nil {
-		row := table.Row{}
-		row = append(row, device.Id)
-		row = append(row, device.Status["status"])
-		t.AppendRow(row)
-	}
-}
-func outputSolution(t table.Writer, data []byte) {
-	var solution Solution
-	err := json.Unmarshal(data, &solution)
-	if err == nil {
-		row := table.Row{}
-		row = append(row, solution.Id)
-		t.AppendRow(row)
-	}
-}
-func outputInstance(t table.Writer, data []byte) {
-	var instance Instance
-	err := json.Unmarshal(data, &instance)
-	if err == nil {
-		row := table.Row{}
-		row = append(row, instance.Id)
-		row = append(row, instance.Status["status"])
-		row = append(row, instance.Status["targets"])
-		row = append(row, instance.Status["deployed"])
-		t.AppendRow(row)
-	}
-}
 func interfaceType(item interface{}) string {
 	if _, ok := item.(map[string]interface{}); ok {
 		return "property-bag"
@@ -224,22 +422,6 @@ func interfaceType(item interface{}) string {
 }
 func outputListItem(t table.Writer, item interface{}, objType string, path string, keys []string) {
 	data, _ := json.Marshal(item)
-	if path == "" {
-		switch objType {
-		case "device", "devices":
-			outputDevice(t, data)
-			return
-		case "target", "targets":
-			outputTarget(t, data)
-			return
-		case "solution", "solutions":
-			outputSolution(t, data)
-			return
-		case "instance", "instances":
-			outputInstance(t, data)
-			return
-		}
-	}
 	err := outputAsAttributes(t, data, objType, path, keys)
 	if err == nil {
 		return
@@ -260,26 +442,12 @@ func init() {
 	GetCmd.Flags().StringVarP(&jsonPath, "json-path", "", "", "Jason Path query to be applied on results")
 	GetCmd.Flags().StringVarP(&docType, "doc-type", "", "", "Result type (Json or Yaml)")
 	GetCmd.Flags().StringVarP(&configContext, "context", "", "", "Maestro CLI configuration context")
+	GetCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json, yaml, wide, name or custom-columns=<header>:<path>,...)")
+	GetCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Label selector to filter on, such as 'env=prod,tier!=edge'")
+	GetCmd.Flags().StringVarP(&fieldSelector, "field-selector", "", "", "Field selector to filter on, such as 'status.status=Succeeded'")
+	GetCmd.Flags().StringVarP(&scope, "scope", "s", "", "Scope to query (defaults to 'default')")
+	GetCmd.Flags().BoolVarP(&allScopes, "all-scopes", "A", false, "Query objects across all scopes")
+	GetCmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for changes and print updated results")
+	GetCmd.Flags().IntVarP(&watchInterval, "watch-interval", "", 5, "Polling interval in seconds when watching")
 	RootCmd.AddCommand(GetCmd)
 }
-
-type Target struct {
-	Id     string            `json:"id"`
-	Spec   model.TargetSpec  `json:"spec,omitempty"`
-	Status map[string]string `json:"status,omitempty"`
-}
-type Device struct {
-	Id     string            `json:"id"`
-	Spec   model.DeviceSpec  `json:"spec,omitempty"`
-	Status map[string]string `json:"status,omitempty"`
-}
-type Solution struct {
-	Id     string             `json:"id"`
-	Spec   model.SolutionSpec `json:"spec,omitempty"`
-	Status map[string]string  `json:"status,omitempty"`
-}
-type Instance struct {
-	Id     string             `json:"id"`
-	Spec   model.InstanceSpec `json:"spec,omitempty"`
-	Status map[string]string  `json:"status,omitempty"`
-}
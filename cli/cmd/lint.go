@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+var LintCmd = &cobra.Command{
+	Use:   "lint <campaign file>...",
+	Short: "Check campaign definitions for errors",
+	Long:  "Check campaign definitions, in YAML or JSON, for invalid expressions, missing stages, unknown providers, loops that never exit and unreachable stages",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, file := range args {
+			names, specs, err := readCampaigns(file)
+			if err != nil {
+				return err
+			}
+			for i, spec := range specs {
+				if !lintCampaign(file, names[i], spec) {
+					failed++
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d campaign(s) have errors", failed)
+		}
+		return nil
+	},
+}
+
+// readCampaigns reads the campaigns of a file, which can hold several Campaign objects or a bare campaign spec
+func readCampaigns(file string) ([]string, []model.CampaignSpec, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0)
+	specs := make([]model.CampaignSpec, 0)
+	for i, doc := range documentSeparator.Split(string(data), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var obj struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec model.CampaignSpec `json:"spec"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		if obj.Kind != "" && obj.Kind != "Campaign" {
+			continue
+		}
+		spec := obj.Spec
+		if obj.Kind == "" {
+			if err := yaml.Unmarshal([]byte(doc), &spec); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse %s: %v", file, err)
+			}
+		}
+		name := obj.Metadata.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		names = append(names, name)
+		specs = append(specs, spec)
+	}
+	return names, specs, nil
+}
+
+func lintCampaign(file string, name string, spec model.CampaignSpec) bool {
+	result := api_utils.ValidateCampaign(spec)
+	for _, e := range result.Errors {
+		fmt.Printf("%s%s (%s): error: %s: %s%s\n", utils.ColorRed(), file, name, e.Field, e.Message, utils.ColorReset())
+	}
+	for _, w := range result.Warnings {
+		fmt.Printf("%s%s (%s): warning: %s: %s%s\n", utils.ColorYellow(), file, name, w.Field, w.Message, utils.ColorReset())
+	}
+	if result.Valid && len(result.Warnings) == 0 && verbose {
+		fmt.Printf("%s%s (%s): ok%s\n", utils.ColorGreen(), file, name, utils.ColorReset())
+	}
+	return result.Valid
+}
+
+func init() {
+	RootCmd.AddCommand(LintCmd)
+}
@@ -1,6 +1,6 @@
 module github.com/eclipse-symphony/symphony/cli
 
-go 1.19
+go 1.23.0
 
 replace github.com/eclipse-symphony/symphony/api => ../api
 
@@ -25,9 +25,18 @@ require (
 	github.com/eclipse-symphony/symphony/api v0.0.0
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
 	github.com/jedib0t/go-pretty/v6 v6.4.2
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-runewidth v0.0.13 // indirect
+	github.com/nats-io/nats.go v1.42.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/crypto v0.37.0
+	golang.org/x/net v0.21.0
+	golang.org/x/sync v0.13.0
+	golang.org/x/sys v0.32.0 // indirect
+	golang.org/x/term v0.31.0
+	golang.org/x/text v0.24.0
 	sigs.k8s.io/yaml v1.3.0
 )
@@ -96,7 +96,15 @@ func yamlToJson(payload []byte) ([]byte, error) {
 	return json.Marshal(o.Spec)
 }
 
-func Get(url string, username string, password string, objType string, path string, docType string, objName string) ([]interface{}, error) {
+// QueryOptions carries the server-side filtering options of a Get call. An empty Scope queries
+// across all scopes when listing objects.
+type QueryOptions struct {
+	Scope         string
+	LabelSelector string
+	FieldSelector string
+}
+
+func Get(url string, username string, password string, objType string, path string, docType string, objName string, options QueryOptions) ([]interface{}, error) {
 	token, err := Login(url, username, password)
 	if err != nil {
 		return nil, err
@@ -122,6 +130,15 @@ func Get(url string, username string, password string, objType string, path stri
 	if docType != "" {
 		params["doc-type"] = docType
 	}
+	if options.Scope != "" {
+		params["scope"] = options.Scope
+	}
+	if options.LabelSelector != "" {
+		params["label-selector"] = options.LabelSelector
+	}
+	if options.FieldSelector != "" {
+		params["field-selector"] = options.FieldSelector
+	}
 	resp, err := callRestAPI(url, route, "GET", nil, token, params)
 	if err != nil {
 		return nil, err
@@ -6,5 +6,9 @@
 
 package bindings
 
+import "context"
+
 type IBinding interface {
+	// Shutdown stops accepting new requests and waits for the requests in flight until ctx is done
+	Shutdown(ctx context.Context) error
 }
@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/grpc/proto"
+	coahttp "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/localfile"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+// GRPCBindingConfig configures a GRPCBinding
+type GRPCBindingConfig struct {
+	Port         int                        `json:"port"`
+	Pipeline     []MiddlewareConfig         `json:"pipeline"`
+	TLS          bool                       `json:"tls"`
+	CertProvider coahttp.CertProviderConfig `json:"certProvider"`
+	// ClientCAFile turns on mutual TLS. Clients must present a certificate signed by one of the CAs in the file.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+}
+
+// GRPCBinding provides service endpoints through the COA gRPC service. Calls are routed the same way
+// as HTTP requests, by their method and route. Responses carry the COA state of the endpoint, while
+// calls that don't reach an endpoint, such as calls to unknown routes or calls that aren't authorized,
+// fail with a gRPC status.
+type GRPCBinding struct {
+	proto.UnimplementedCOAServer
+	CertProvider certs.ICertProvider
+	server       *grpc.Server
+	listener     net.Listener
+	router       *router
+	handler      Handler
+	// stopping is closed when the binding shuts down, which ends the watches in flight
+	stopping     chan struct{}
+	stoppingOnce sync.Once
+}
+
+// Launch starts serving the endpoints on the configured port. It returns once the port is listened on.
+func (g *GRPCBinding) Launch(config GRPCBindingConfig, endpoints []v1alpha2.Endpoint) error {
+	pipeline, err := BuildPipeline(config)
+	if err != nil {
+		return err
+	}
+	g.router = newRouter(endpoints)
+	g.handler = pipeline.Apply(g.dispatch)
+	g.stopping = make(chan struct{})
+
+	options := []grpc.ServerOption{}
+	if config.TLS {
+		tlsConfig, err := g.getTLSConfig(config)
+		if err != nil {
+			return err
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else if config.ClientCAFile != "" {
+		return v1alpha2.NewCOAError(nil, "mutual TLS requires TLS to be enabled", v1alpha2.BadConfig)
+	}
+
+	g.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to listen on port %d", config.Port), v1alpha2.InternalError)
+	}
+	g.server = grpc.NewServer(options...)
+	proto.RegisterCOAServer(g.server, g)
+	go func() {
+		if err := g.server.Serve(g.listener); err != nil {
+			log.Errorf("gRPC binding stopped serving: %+v", err)
+		}
+	}()
+	return nil
+}
+
+func (g *GRPCBinding) getTLSConfig(config GRPCBindingConfig) (*tls.Config, error) {
+	switch config.CertProvider.Type {
+	case "certs.autogen":
+		g.CertProvider = &autogen.AutoGenCertProvider{}
+	case "certs.localfile":
+		g.CertProvider = &localfile.LocalCertFileProvider{}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("cert provider type '%s' is not recognized", config.CertProvider.Type), v1alpha2.BadConfig)
+	}
+	err := g.CertProvider.Init(config.CertProvider.Config)
+	if err != nil {
+		return nil, err
+	}
+	cert, key, err := g.CertProvider.GetCert("localhost") //TODO: user proper host/DNS name
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to load server certificate", v1alpha2.BadConfig)
+	}
+	ret := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if config.ClientCAFile != "" {
+		data, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to read client CA file", v1alpha2.BadConfig)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, v1alpha2.NewCOAError(nil, "client CA file doesn't contain any PEM certificates", v1alpha2.BadConfig)
+		}
+		ret.ClientCAs = pool
+		ret.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return ret, nil
+}
+
+// Shutdown stops accepting new calls, ends the watches in flight and waits for the calls in flight. If
+// ctx is done before the calls are finished, they're cancelled and an error is returned.
+func (g *GRPCBinding) Shutdown(ctx context.Context) error {
+	if g.server == nil {
+		return nil
+	}
+	g.stoppingOnce.Do(func() {
+		close(g.stopping)
+	})
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.server.Stop()
+		return v1alpha2.NewCOAError(ctx.Err(), "gRPC binding didn't finish the calls in flight", v1alpha2.InternalError)
+	}
+}
+
+// Call serves a call of the Call method
+func (g *GRPCBinding) Call(ctx context.Context, request *proto.Request) (*proto.Response, error) {
+	var response *proto.Response
+	err := g.handler(ctx, Call{
+		Request: request,
+		Send: func(r *proto.Response) error {
+			response = r
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, status.Errorf(codes.Internal, "route '%s' didn't send a response", request.Route)
+	}
+	return response, nil
+}
+
+// Watch serves a call of the Watch method. The watch ends when the caller cancels it, when the endpoint
+// has no more changes to send or when the binding shuts down.
+func (g *GRPCBinding) Watch(request *proto.Request, stream proto.COA_WatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-g.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := g.handler(ctx, Call{
+		Request: request,
+		Watch:   true,
+		Send:    stream.Send,
+	})
+	select {
+	case <-g.stopping:
+		return status.Error(codes.Unavailable, "gRPC binding is shutting down")
+	default:
+		return err
+	}
+}
+
+// dispatch is the last handler of the pipeline, which calls the endpoint the request is routed to
+func (g *GRPCBinding) dispatch(ctx context.Context, call Call) error {
+	endpoint, parameters, err := g.router.match(call.Request.Method, call.Request.Route)
+	if err != nil {
+		return err
+	}
+	for k, v := range call.Request.Parameters {
+		parameters[k] = v
+	}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		// continue the trace of the caller, if the request carries one
+		ctx = observ_utils.SpanContextFromMetadata(ctx, call.Request.Metadata)
+	}
+	request := v1alpha2.COARequest{
+		Context:     ctx,
+		Method:      call.Request.Method,
+		Route:       call.Request.Route,
+		ContentType: call.Request.ContentType,
+		Body:        call.Request.Body,
+		Metadata:    call.Request.Metadata,
+		Parameters:  parameters,
+	}
+	if !call.Watch {
+		return call.Send(toProtoResponse(endpoint.Handler(request)))
+	}
+	if endpoint.StreamHandler == nil {
+		return status.Errorf(codes.Unimplemented, "route '%s' doesn't support watching", call.Request.Route)
+	}
+	return endpoint.StreamHandler(request, func(response v1alpha2.COAResponse) error {
+		return call.Send(toProtoResponse(response))
+	})
+}
+
+func toProtoResponse(response v1alpha2.COAResponse) *proto.Response {
+	return &proto.Response{
+		State:       uint32(response.State),
+		ContentType: response.ContentType,
+		Body:        response.Body,
+		Metadata:    response.Metadata,
+		RedirectUri: response.RedirectUri,
+	}
+}
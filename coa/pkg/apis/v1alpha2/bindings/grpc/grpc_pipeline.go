@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/grpc/proto"
+	coahttp "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type MiddlewareConfig struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Call is a call to the gRPC binding. Watch is set for calls of the Watch method, which may send a
+// response for every change. Calls of the Call method send one response.
+type Call struct {
+	Request *proto.Request
+	Watch   bool
+	Send    func(*proto.Response) error
+}
+
+// Handler serves a call. It returns an error for calls that don't reach an endpoint.
+type Handler func(ctx context.Context, call Call) error
+
+// Middleware wraps the calls of the gRPC binding, the way HTTP middlewares wrap HTTP requests
+type Middleware func(next Handler) Handler
+
+type Pipeline struct {
+	Handlers []Middleware
+}
+
+func BuildPipeline(config GRPCBindingConfig) (Pipeline, error) {
+	ret := Pipeline{Handlers: make([]Middleware, 0)}
+	for _, c := range config.Pipeline {
+		switch c.Type {
+		case "middleware.grpc.jwt":
+			jwts := JWT{}
+			jData, _ := json.Marshal(c.Properties)
+			err := json.Unmarshal(jData, &jwts.JWT)
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(nil, "incorrect jwt pipeline configuration format", v1alpha2.BadConfig)
+			}
+			if jwts.JWT.AuthHeader == "" {
+				jwts.JWT.AuthHeader = "Authorization"
+			}
+			ret.Handlers = append(ret.Handlers, jwts.Handle)
+		case "middleware.grpc.tracing":
+			config := observability.ObservabilityConfig{}
+			if p, ok := c.Properties["pipeline"]; ok {
+				data, _ := json.Marshal(p)
+				pipelines := make([]observability.PipelineConfig, 0)
+				err := json.Unmarshal(data, &pipelines)
+				if err != nil {
+					return ret, v1alpha2.NewCOAError(nil, "incorrect tracing pipeline configuration format", v1alpha2.BadConfig)
+				}
+				config.Pipelines = pipelines
+			}
+			tracing := Tracing{}
+			err := tracing.Observability.Init(config)
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(nil, "failed to initialize tracing middleware", v1alpha2.InternalError)
+			}
+			ret.Handlers = append(ret.Handlers, tracing.Handle)
+		default:
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("middleware type '%s' is not recognized", c.Type), v1alpha2.BadConfig)
+		}
+	}
+	return ret, nil
+}
+
+func (p Pipeline) Apply(handler Handler) Handler {
+	for i := len(p.Handlers) - 1; i >= 0; i-- {
+		handler = p.Handlers[i](handler)
+	}
+	return handler
+}
+
+// JWT authorizes calls with the bearer token in the gRPC metadata, using the same configuration as
+// the HTTP JWT middleware, including its RBAC policy. Routes and methods of calls are checked against
+// the policy the way paths and methods of HTTP requests are.
+type JWT struct {
+	JWT coahttp.JWT
+}
+
+func (j *JWT) Handle(next Handler) Handler {
+	return func(ctx context.Context, call Call) error {
+		for _, p := range j.JWT.IgnorePaths {
+			if p == call.Request.Route {
+				return next(ctx, call)
+			}
+		}
+		authValue := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(strings.ToLower(j.JWT.AuthHeader)); len(v) > 0 {
+				authValue = v[0]
+			}
+		}
+		if authValue == "" {
+			return status.Error(codes.Unauthenticated, "bearer token is not found")
+		}
+		user, roles, err := j.JWT.Authenticate(authValue)
+		if err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err := j.JWT.Authorize(roles, call.Request.Route, call.Request.Method); err != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		// the same context keys as the HTTP binding, which handlers read the caller from
+		if user != "" {
+			ctx = context.WithValue(ctx, v1alpha2.AuthUserKey, user)
+		}
+		ctx = context.WithValue(ctx, v1alpha2.AuthRolesKey, roles)
+		return next(ctx, call)
+	}
+}
+
+// Tracing starts a server span for every call, continuing the trace of the caller if the gRPC
+// metadata carries W3C trace context
+type Tracing struct {
+	Observability observability.Observability
+}
+
+func (t Tracing) Handle(next Handler) Handler {
+	return func(ctx context.Context, call Call) error {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			carrier := make(map[string]string)
+			for k, v := range md {
+				if len(v) > 0 {
+					carrier[k] = v[0]
+				}
+			}
+			ctx = observ_utils.SpanContextFromMetadata(ctx, carrier)
+		}
+		ctx, span := otel.Tracer("coa.grpc").Start(ctx, call.Request.Route, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		var state uint32
+		send := call.Send
+		call.Send = func(response *proto.Response) error {
+			state = response.State
+			return send(response)
+		}
+		err := next(ctx, call)
+		_, vendor := observ_utils.GetVendor(call.Request.Route)
+		observ_utils.AddAttributesToSpan(span, map[string]string{
+			"vendor": vendor,
+			"path":   call.Request.Route,
+			"method": call.Request.Method,
+			"status": fmt.Sprint(state),
+			"watch":  fmt.Sprint(call.Watch),
+		})
+		if err != nil {
+			span.SetStatus(otelcodes.Error, err.Error())
+		} else if state != 0 {
+			// COA states of responses follow HTTP status codes
+			coahttp.UpdateSpanStatusFromHTTPStatus(span, int(state))
+		}
+		return err
+	}
+}
@@ -0,0 +1,291 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/grpc/proto"
+	coahttp "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/localfile"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// echo returns the request it's given, with the caller the JWT middleware found
+func echo(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	user, _ := request.Context.Value(v1alpha2.AuthUserKey).(string)
+	data, _ := json.Marshal(map[string]interface{}{
+		"method":     request.Method,
+		"body":       string(request.Body),
+		"parameters": request.Parameters,
+		"user":       user,
+	})
+	return v1alpha2.COAResponse{
+		State:       v1alpha2.Accepted,
+		ContentType: "application/json",
+		Body:        data,
+		Metadata:    request.Metadata,
+	}
+}
+
+func testEndpoints() []v1alpha2.Endpoint {
+	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost},
+			Version:    "v1alpha2",
+			Route:      "solutions",
+			Handler:    echo,
+			Parameters: []string{"name?"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Version:    "v1alpha2",
+			Route:      "solutions/queue",
+			Handler:    echo,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Version: "v1alpha2",
+			Route:   "counters",
+			Handler: echo,
+			StreamHandler: func(request v1alpha2.COARequest, send func(v1alpha2.COAResponse) error) error {
+				for i := 0; i < 3; i++ {
+					if err := send(v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte(fmt.Sprint(i))}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Version: "v1alpha2",
+			Route:   "events",
+			Handler: echo,
+			StreamHandler: func(request v1alpha2.COARequest, send func(v1alpha2.COAResponse) error) error {
+				if err := send(v1alpha2.COAResponse{State: v1alpha2.OK}); err != nil {
+					return err
+				}
+				<-request.Context.Done()
+				return nil
+			},
+		},
+	}
+}
+
+func launchTestBinding(t *testing.T, config GRPCBindingConfig) *GRPCBinding {
+	binding := &GRPCBinding{}
+	err := binding.Launch(config, testEndpoints())
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		binding.Shutdown(context.Background())
+	})
+	return binding
+}
+
+func dialTestBinding(t *testing.T, binding *GRPCBinding, creds credentials.TransportCredentials) proto.COAClient {
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.Dial(binding.listener.Addr().String(), grpc.WithTransportCredentials(creds))
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return proto.NewCOAClient(conn)
+}
+
+func TestCall(t *testing.T) {
+	binding := launchTestBinding(t, GRPCBindingConfig{})
+	client := dialTestBinding(t, binding, nil)
+
+	response, err := client.Call(context.Background(), &proto.Request{
+		Method:     fasthttp.MethodPost,
+		Route:      "/v1alpha2/solutions/my-solution",
+		Body:       []byte("spec"),
+		Metadata:   map[string]string{"request-id": "1"},
+		Parameters: map[string]string{"doc-type": "yaml"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(v1alpha2.Accepted), response.State)
+	assert.Equal(t, "application/json", response.ContentType)
+	assert.Equal(t, map[string]string{"request-id": "1"}, response.Metadata)
+	var echoed map[string]interface{}
+	assert.Nil(t, json.Unmarshal(response.Body, &echoed))
+	assert.Equal(t, "POST", echoed["method"])
+	assert.Equal(t, "spec", echoed["body"])
+	assert.Equal(t, map[string]interface{}{"__name": "my-solution", "doc-type": "yaml"}, echoed["parameters"])
+
+	// the longest route wins
+	response, err = client.Call(context.Background(), &proto.Request{Method: fasthttp.MethodGet, Route: "v1alpha2/solutions/queue/my-solution"})
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(response.Body, &echoed))
+	assert.Equal(t, map[string]interface{}{"__name": "my-solution"}, echoed["parameters"])
+}
+
+func TestCallNotRouted(t *testing.T) {
+	binding := launchTestBinding(t, GRPCBindingConfig{})
+	client := dialTestBinding(t, binding, nil)
+
+	_, err := client.Call(context.Background(), &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/targets"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.Call(context.Background(), &proto.Request{Method: fasthttp.MethodDelete, Route: "/v1alpha2/solutions/my-solution"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	// a required parameter is missing
+	_, err = client.Call(context.Background(), &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/solutions/queue/a/b"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWatch(t *testing.T) {
+	binding := launchTestBinding(t, GRPCBindingConfig{})
+	client := dialTestBinding(t, binding, nil)
+
+	stream, err := client.Watch(context.Background(), &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/counters"})
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		response, err := stream.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprint(i), string(response.Body))
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	stream, err = client.Watch(context.Background(), &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/solutions"})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestShutdownEndsWatches(t *testing.T) {
+	binding := launchTestBinding(t, GRPCBindingConfig{})
+	client := dialTestBinding(t, binding, nil)
+
+	stream, err := client.Watch(context.Background(), &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/events"})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, binding.Shutdown(ctx))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestJWT(t *testing.T) {
+	binding := launchTestBinding(t, GRPCBindingConfig{
+		Pipeline: []MiddlewareConfig{
+			{
+				Type: "middleware.grpc.jwt",
+				Properties: map[string]interface{}{
+					"verifyKey":  "SymphonyKey",
+					"enableRBAC": true,
+					"roles": []map[string]string{
+						{"role": "reader", "claim": "user", "value": "*"},
+					},
+					"policy": map[string]interface{}{
+						"reader": map[string]interface{}{"items": map[string]string{"/v1alpha2/solutions": "GET"}},
+					},
+					"userClaim": "user",
+				},
+			},
+		},
+	})
+	client := dialTestBinding(t, binding, nil)
+	request := &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/solutions/my-solution"}
+
+	_, err := client.Call(context.Background(), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// a token that isn't valid doesn't authenticate the caller
+	invalid, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "admin"}).SignedString([]byte("OtherKey"))
+	assert.Nil(t, err)
+	_, err = client.Call(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+invalid), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "admin"}).SignedString([]byte("SymphonyKey"))
+	assert.Nil(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	response, err := client.Call(ctx, request)
+	assert.Nil(t, err)
+	var echoed map[string]interface{}
+	assert.Nil(t, json.Unmarshal(response.Body, &echoed))
+	assert.Equal(t, "admin", echoed["user"])
+
+	// the policy doesn't allow the method
+	_, err = client.Call(ctx, &proto.Request{Method: fasthttp.MethodPost, Route: "/v1alpha2/solutions/my-solution"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestMutualTLS(t *testing.T) {
+	cert, key, err := fasthttp.GenerateTestCertificate("localhost")
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.Nil(t, os.WriteFile(certFile, cert, 0600))
+	assert.Nil(t, os.WriteFile(keyFile, key, 0600))
+
+	binding := launchTestBinding(t, GRPCBindingConfig{
+		TLS: true,
+		CertProvider: coahttp.CertProviderConfig{
+			Type:   "certs.localfile",
+			Config: localfile.LocalCertFileProviderConfig{CertFile: certFile, KeyFile: keyFile},
+		},
+		ClientCAFile: certFile,
+	})
+	pool := x509.NewCertPool()
+	assert.True(t, pool.AppendCertsFromPEM(cert))
+	request := &proto.Request{Method: fasthttp.MethodGet, Route: "/v1alpha2/solutions"}
+
+	// the test certificate is its own CA, so it's trusted as a client certificate too
+	certificate, err := tls.X509KeyPair(cert, key)
+	assert.Nil(t, err)
+	client := dialTestBinding(t, binding, credentials.NewTLS(&tls.Config{
+		RootCAs:      pool,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{certificate},
+	}))
+	response, err := client.Call(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(v1alpha2.Accepted), response.State)
+
+	client = dialTestBinding(t, binding, credentials.NewTLS(&tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+	}))
+	_, err = client.Call(context.Background(), request)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestLaunchWithInvalidConfig(t *testing.T) {
+	binding := &GRPCBinding{}
+	err := binding.Launch(GRPCBindingConfig{ClientCAFile: "ca.pem"}, testEndpoints())
+	assert.NotNil(t, err)
+	err = binding.Launch(GRPCBindingConfig{Pipeline: []MiddlewareConfig{{Type: "middleware.http.cors"}}}, testEndpoints())
+	assert.NotNil(t, err)
+	assert.Nil(t, binding.Shutdown(context.Background()))
+}
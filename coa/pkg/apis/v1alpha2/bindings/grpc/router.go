@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"strings"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// router matches the routes of calls to endpoints the way the HTTP binding matches paths, so that a
+// call to "/v1alpha2/solutions/my-solution" reaches the same endpoint as the HTTP request does
+type router struct {
+	routes []route
+}
+
+type route struct {
+	endpoint v1alpha2.Endpoint
+	segments []string
+	// required is the number of parameters that aren't optional
+	required int
+}
+
+func newRouter(endpoints []v1alpha2.Endpoint) *router {
+	ret := &router{routes: make([]route, 0, len(endpoints))}
+	for _, e := range endpoints {
+		path := e.Route
+		if e.Version != "" {
+			path = e.Version + "/" + e.Route
+		}
+		r := route{
+			endpoint: e,
+			segments: splitPath(path),
+		}
+		for _, p := range e.Parameters {
+			if !strings.HasSuffix(p, "?") {
+				r.required++
+			}
+		}
+		ret.routes = append(ret.routes, r)
+	}
+	return ret
+}
+
+// match finds the endpoint with the longest route that matches the path and takes the method. The rest
+// of the path is read into the parameters of the endpoint, which are named "__<parameter>".
+func (r *router) match(method string, path string) (v1alpha2.Endpoint, map[string]string, error) {
+	segments := splitPath(path)
+	var found *route
+	for i := range r.routes {
+		candidate := &r.routes[i]
+		if !candidate.matches(segments) {
+			continue
+		}
+		if !hasMethod(candidate.endpoint.Methods, method) {
+			continue
+		}
+		if found == nil || len(candidate.segments) > len(found.segments) {
+			found = candidate
+		}
+	}
+	if found == nil {
+		if r.pathMatches(segments) {
+			return v1alpha2.Endpoint{}, nil, status.Errorf(codes.Unimplemented, "method '%s' is not allowed on route '%s'", method, path)
+		}
+		return v1alpha2.Endpoint{}, nil, status.Errorf(codes.NotFound, "route '%s' is not found", path)
+	}
+	parameters := make(map[string]string)
+	values := segments[len(found.segments):]
+	for i, p := range found.endpoint.Parameters {
+		k := "__" + strings.TrimSuffix(p, "?")
+		if i < len(values) {
+			parameters[k] = values[i]
+		} else {
+			parameters[k] = ""
+		}
+	}
+	return found.endpoint, parameters, nil
+}
+
+func (r *router) pathMatches(segments []string) bool {
+	for i := range r.routes {
+		if r.routes[i].matches(segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r route) matches(segments []string) bool {
+	if len(segments) < len(r.segments) {
+		return false
+	}
+	for i, s := range r.segments {
+		if segments[i] != s {
+			return false
+		}
+	}
+	rest := len(segments) - len(r.segments)
+	return rest >= r.required && rest <= len(r.endpoint.Parameters)
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(path string) []string {
+	ret := make([]string, 0)
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
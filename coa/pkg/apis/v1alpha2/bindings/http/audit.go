@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	AuditSinkFile   = "file"
+	AuditSinkLedger = "ledger"
+
+	// AuditTrailType is the type of the trails audit records are appended to the ledger as
+	AuditTrailType = "audits.record.symphony/v1"
+)
+
+// AuditRecord records who called which route, and how the call ended
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user,omitempty"`
+	Roles  []string  `json:"roles,omitempty"`
+	Client string    `json:"client"`
+	Method string    `json:"method"`
+	// Route is the version and vendor of the path, such as /v1alpha2/solutions
+	Route string `json:"route"`
+	// Object is the rest of the path, which is usually the name of the object, such as my-solution
+	Object string `json:"object,omitempty"`
+	Status int    `json:"status"`
+}
+
+type LedgerProviderConfig struct {
+	Type   string                    `json:"type"`
+	Config providers.IProviderConfig `json:"config"`
+}
+
+type Audit struct {
+	// Sink is where records are written, either "file" or "ledger"
+	Sink string `json:"sink"`
+	// Path is the file records are appended to as JSON lines, with the file sink
+	Path string `json:"path,omitempty"`
+	// Provider is the ledger provider records are appended to as trails, with the ledger sink
+	Provider LedgerProviderConfig `json:"provider"`
+	// Methods are the methods that are audited, default is the methods that change objects. Use "*"
+	// to audit all requests.
+	Methods        []string               `json:"methods,omitempty"`
+	IgnorePaths    []string               `json:"ignorePaths,omitempty"`
+	LedgerProvider ledger.ILedgerProvider `json:"-"`
+	file           *os.File
+	lock           sync.Mutex
+}
+
+var defaultAuditMethods = []string{fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch, fasthttp.MethodDelete}
+
+// open opens the sink of the audit records
+func (a *Audit) open() error {
+	if len(a.Methods) == 0 {
+		a.Methods = defaultAuditMethods
+	}
+	switch a.Sink {
+	case AuditSinkFile:
+		if a.Path == "" {
+			return v1alpha2.NewCOAError(nil, "audit file sink requires a path", v1alpha2.BadConfig)
+		}
+		file, err := os.OpenFile(a.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to open audit file '%s'", a.Path), v1alpha2.BadConfig)
+		}
+		a.file = file
+	case AuditSinkLedger:
+		if a.LedgerProvider != nil {
+			return nil
+		}
+		switch a.Provider.Type {
+		case "providers.ledger.mock":
+			provider := &mock.MockLedgerProvider{}
+			err := provider.Init(a.Provider.Config)
+			if err != nil {
+				return err
+			}
+			a.LedgerProvider = provider
+		default:
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("ledger provider type '%s' is not recognized", a.Provider.Type), v1alpha2.BadConfig)
+		}
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("audit sink '%s' is not recognized", a.Sink), v1alpha2.BadConfig)
+	}
+	return nil
+}
+
+// Audit middleware writes a record of every audited request once it's handled. Put it after the JWT
+// middleware to record the user and the roles of the caller. Requests the middlewares before it reject
+// aren't recorded. Failing to write a record doesn't fail the request.
+func (a *Audit) Audit(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		next(ctx)
+		path := string(ctx.Path())
+		if !a.audits(path, string(ctx.Method())) {
+			return
+		}
+		record := AuditRecord{
+			Time:   time.Now().UTC(),
+			Client: ctx.RemoteIP().String(),
+			Method: string(ctx.Method()),
+			Route:  metricsRoute(path),
+			Object: auditObject(path),
+			Status: ctx.Response.StatusCode(),
+		}
+		if user, ok := ctx.UserValue(v1alpha2.AuthUserKey).(string); ok {
+			record.User = user
+		}
+		if roles, ok := ctx.UserValue(v1alpha2.AuthRolesKey).([]string); ok {
+			record.Roles = roles
+		}
+		if err := a.write(ctx, record); err != nil {
+			log.Errorf("failed to write audit record of %s %s: %+v", record.Method, path, err)
+		}
+	}
+}
+
+func (a *Audit) audits(path string, method string) bool {
+	for _, p := range a.IgnorePaths {
+		if p == path {
+			return false
+		}
+	}
+	for _, m := range a.Methods {
+		if m == "*" || m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Audit) write(ctx context.Context, record AuditRecord) error {
+	if a.LedgerProvider != nil {
+		properties := make(map[string]interface{})
+		data, _ := json.Marshal(record)
+		json.Unmarshal(data, &properties)
+		return a.LedgerProvider.Append(ctx, []v1alpha2.Trail{
+			{
+				Type:       AuditTrailType,
+				Properties: properties,
+			},
+		})
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// auditObject returns the path after its version and vendor
+func auditObject(path string) string {
+	tokens := strings.SplitN(path, "/", 4)
+	if len(tokens) < 4 {
+		return ""
+	}
+	return strings.Trim(tokens[3], "/")
+}
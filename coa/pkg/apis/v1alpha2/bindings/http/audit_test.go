@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func auditRequest(handler fasthttp.RequestHandler, method string, path string, user string) {
+	ctx := &fasthttp.RequestCtx{}
+	request := fasthttp.Request{}
+	request.Header.SetMethod(method)
+	request.SetRequestURI(path)
+	ctx.Init(&request, &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}, nil)
+	if user != "" {
+		ctx.SetUserValue(v1alpha2.AuthUserKey, user)
+		ctx.SetUserValue(v1alpha2.AuthRolesKey, []string{"admin"})
+	}
+	handler(ctx)
+}
+
+func TestAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	handler, err := buildMiddleware(MiddlewareConfig{
+		Type:       "middleware.http.audit",
+		Properties: map[string]interface{}{"sink": "file", "path": path},
+	}, nil)
+	assert.Nil(t, err)
+	h := handler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	})
+
+	auditRequest(h, fasthttp.MethodPost, "/v1alpha2/solutions/my-solution", "admin")
+	// reads aren't audited by default
+	auditRequest(h, fasthttp.MethodGet, "/v1alpha2/solutions/my-solution", "admin")
+	auditRequest(h, fasthttp.MethodDelete, "/v1alpha2/targets/my-target", "")
+
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	records := make([]AuditRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "admin", records[0].User)
+	assert.Equal(t, []string{"admin"}, records[0].Roles)
+	assert.Equal(t, "10.0.0.1", records[0].Client)
+	assert.Equal(t, fasthttp.MethodPost, records[0].Method)
+	assert.Equal(t, "/v1alpha2/solutions", records[0].Route)
+	assert.Equal(t, "my-solution", records[0].Object)
+	assert.Equal(t, fasthttp.StatusAccepted, records[0].Status)
+	assert.Equal(t, "", records[1].User)
+	assert.Equal(t, "my-target", records[1].Object)
+}
+
+func TestAuditLedger(t *testing.T) {
+	audit := &Audit{
+		Sink:     AuditSinkLedger,
+		Provider: LedgerProviderConfig{Type: "providers.ledger.mock", Config: mock.MockLedgerProviderConfig{}},
+		Methods:  []string{"*"},
+	}
+	assert.Nil(t, audit.open())
+	h := audit.Audit(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusForbidden)
+	})
+
+	auditRequest(h, fasthttp.MethodGet, "/v1alpha2/solutions/my-solution", "reader")
+	ledger := audit.LedgerProvider.(*mock.MockLedgerProvider)
+	assert.Equal(t, 1, len(ledger.LedgerData))
+	trail := ledger.LedgerData[0]
+	assert.Equal(t, AuditTrailType, trail.Type)
+	assert.Equal(t, "reader", trail.Properties["user"])
+	assert.Equal(t, "/v1alpha2/solutions", trail.Properties["route"])
+	assert.Equal(t, float64(fasthttp.StatusForbidden), trail.Properties["status"])
+}
+
+func TestAuditInvalidConfig(t *testing.T) {
+	_, err := buildMiddleware(MiddlewareConfig{Type: "middleware.http.audit", Properties: map[string]interface{}{"sink": "syslog"}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.audit", Properties: map[string]interface{}{"sink": "file"}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.audit", Properties: map[string]interface{}{
+		"sink":     "ledger",
+		"provider": map[string]interface{}{"type": "providers.ledger.unknown"},
+	}}, nil)
+	assert.NotNil(t, err)
+}
@@ -7,9 +7,14 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -43,16 +48,36 @@ type HttpBindingConfig struct {
 // HttpBinding provides service endpoints as a fasthttp web server
 type HttpBinding struct {
 	CertProvider certs.ICertProvider
+	server       *fasthttp.Server
+	// handler is the router wrapped by the middlewares, which is replaced when the pipeline is reconfigured
+	handler atomic.Value
+	// limits are the limits middlewares of the pipeline, whose body limits are applied while requests are read
+	limits         atomic.Value
+	router         fasthttp.RequestHandler
+	pubsubProvider pubsub.IPubSubProvider
+	reloadLock     sync.Mutex
+	running        HttpBindingConfig
+	middlewares    []Middleware
 }
 
 // Launch fasthttp server
 func (h *HttpBinding) Launch(config HttpBindingConfig, endpoints []v1alpha2.Endpoint, pubsubProvider pubsub.IPubSubProvider) error {
-	handler := h.useRouter(endpoints)
+	// copied before the middlewares get the configuration, as some of them add properties
+	running, err := copyBindingConfig(config)
+	if err != nil {
+		return err
+	}
+	h.router = h.useRouter(endpoints)
+	h.pubsubProvider = pubsubProvider
 
 	pipeline, err := BuildPipeline(config, pubsubProvider)
 	if err != nil {
 		return err
 	}
+	h.running = running
+	h.middlewares = pipeline.Handlers
+	h.handler.Store(pipeline.Apply(h.router))
+	h.limits.Store(pipelineLimits(running.Pipeline))
 
 	if config.TLS {
 		switch config.CertProvider.Type {
@@ -69,17 +94,188 @@ func (h *HttpBinding) Launch(config HttpBindingConfig, endpoints []v1alpha2.Endp
 		}
 	}
 
+	h.server = &fasthttp.Server{
+		Handler:        h.serve,
+		HeaderReceived: h.requestConfig,
+		ErrorHandler:   requestError,
+	}
 	go func() {
 		if config.TLS {
 			cert, key, _ := h.CertProvider.GetCert("localhost") //TODO: user proper host/DNS name
-			fasthttp.ListenAndServeTLSEmbed(fmt.Sprintf(":%d", config.Port), cert, key, pipeline.Apply(handler))
+			h.server.ListenAndServeTLSEmbed(fmt.Sprintf(":%d", config.Port), cert, key)
 		} else {
-			fasthttp.ListenAndServe(fmt.Sprintf(":%d", config.Port), pipeline.Apply(handler))
+			h.server.ListenAndServe(fmt.Sprintf(":%d", config.Port))
 		}
 	}()
 	return nil
 }
 
+// Shutdown closes the listener and waits for the requests in flight. Idle keep-alive connections are
+// closed. It returns an error if ctx is done before the requests are finished.
+func (h *HttpBinding) Shutdown(ctx context.Context) error {
+	if h.server == nil {
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- h.server.Shutdown()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to shut down HTTP binding", v1alpha2.InternalError)
+		}
+		return nil
+	case <-ctx.Done():
+		return v1alpha2.NewCOAError(ctx.Err(), "HTTP binding didn't finish the requests in flight", v1alpha2.InternalError)
+	}
+}
+
+func (h *HttpBinding) serve(ctx *fasthttp.RequestCtx) {
+	h.handler.Load().(fasthttp.RequestHandler)(ctx)
+}
+
+// requestConfig applies the smallest body limit of the limits middlewares to a request once its header
+// is read, so that the server rejects a larger body instead of reading it
+func (h *HttpBinding) requestConfig(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	limits, _ := h.limits.Load().([]*Limits)
+	if len(limits) == 0 {
+		return fasthttp.RequestConfig{}
+	}
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+	if err := uri.Parse(nil, header.RequestURI()); err != nil {
+		return fasthttp.RequestConfig{}
+	}
+	path := string(uri.Path())
+	maxBodySize := 0
+	for _, l := range limits {
+		if n := l.bodyLimit(path); n > 0 && (maxBodySize == 0 || n < maxBodySize) {
+			maxBodySize = n
+		}
+	}
+	return fasthttp.RequestConfig{MaxRequestBodySize: maxBodySize}
+}
+
+// requestError answers requests the server fails to read, with 413 Request Entity Too Large for a body
+// over the limit and the responses of fasthttp otherwise
+func requestError(ctx *fasthttp.RequestCtx, err error) {
+	if _, ok := err.(*fasthttp.ErrSmallBuffer); ok {
+		ctx.Error("Too big request header", fasthttp.StatusRequestHeaderFieldsTooLarge)
+	} else if netErr, ok := err.(*net.OpError); ok && netErr.Timeout() {
+		ctx.Error("Request timeout", fasthttp.StatusRequestTimeout)
+	} else if errors.Is(err, fasthttp.ErrBodyTooLarge) {
+		ctx.Error("request body is too large", fasthttp.StatusRequestEntityTooLarge)
+	} else {
+		ctx.Error("Error when parsing request", fasthttp.StatusBadRequest)
+	}
+}
+
+// pipelineLimits returns the limits middlewares of a pipeline that's already built
+func pipelineLimits(pipeline []MiddlewareConfig) []*Limits {
+	ret := make([]*Limits, 0)
+	for _, c := range pipeline {
+		if c.Type != "middleware.http.limits" {
+			continue
+		}
+		if limits, err := newLimits(c); err == nil {
+			ret = append(ret, limits)
+		}
+	}
+	return ret
+}
+
+// Reconfigure applies a new middleware pipeline while the binding runs. Middlewares whose
+// configuration doesn't change are kept. The pipeline is replaced only if all the middlewares that are
+// added, changed or removed can be changed live, and requests already in flight finish with the previous
+// pipeline. The port and TLS settings can't be changed live. Reconfigure returns the changes it finds,
+// with paths relative to the binding.
+func (h *HttpBinding) Reconfigure(config HttpBindingConfig) []v1alpha2.ConfigChange {
+	h.reloadLock.Lock()
+	defer h.reloadLock.Unlock()
+	changes := make([]v1alpha2.ConfigChange, 0)
+	if config.Port != h.running.Port || config.TLS != h.running.TLS || !v1alpha2.ConfigEqual(config.CertProvider, h.running.CertProvider) {
+		changes = append(changes, v1alpha2.RejectedChange("listener", "the port, TLS and certificate settings are applied when the binding is launched"))
+	}
+	if v1alpha2.ConfigEqual(config.Pipeline, h.running.Pipeline) {
+		return changes
+	}
+	running, err := copyBindingConfig(config)
+	if err != nil {
+		return append(changes, v1alpha2.RejectedChange("pipeline", err.Error()))
+	}
+
+	middlewares := make([]Middleware, 0, len(config.Pipeline))
+	pipelineChanges := make([]v1alpha2.ConfigChange, 0)
+	kept := make([]bool, len(h.running.Pipeline))
+	rejected := false
+	for i, c := range config.Pipeline {
+		path := fmt.Sprintf("pipeline[%d]", i)
+		// a middleware that moves in the pipeline is kept as it is
+		reused := false
+		for j, old := range h.running.Pipeline {
+			if !kept[j] && v1alpha2.ConfigEqual(c, old) {
+				kept[j] = true
+				middlewares = append(middlewares, h.middlewares[j])
+				reused = true
+				break
+			}
+		}
+		if reused {
+			continue
+		}
+		if !liveMiddlewares[c.Type] {
+			pipelineChanges = append(pipelineChanges, v1alpha2.RejectedChange(path, fmt.Sprintf("middleware type '%s' can't be changed live", c.Type)))
+			rejected = true
+			continue
+		}
+		middleware, err := buildMiddleware(c, h.pubsubProvider)
+		if err != nil {
+			pipelineChanges = append(pipelineChanges, v1alpha2.RejectedChange(path, err.Error()))
+			rejected = true
+			continue
+		}
+		middlewares = append(middlewares, middleware)
+		pipelineChanges = append(pipelineChanges, v1alpha2.AppliedChange(path))
+	}
+	for j, old := range h.running.Pipeline {
+		if !kept[j] && !liveMiddlewares[old.Type] {
+			pipelineChanges = append(pipelineChanges, v1alpha2.RejectedChange(fmt.Sprintf("pipeline[%d]", j), fmt.Sprintf("middleware type '%s' can't be removed live", old.Type)))
+			rejected = true
+		}
+	}
+	if rejected {
+		// the pipeline is kept as it is, so none of its changes are applied
+		for i := range pipelineChanges {
+			if pipelineChanges[i].Applied {
+				pipelineChanges[i] = v1alpha2.RejectedChange(pipelineChanges[i].Path, "other changes of the pipeline can't be applied live")
+			}
+		}
+		return append(changes, pipelineChanges...)
+	}
+	h.running.Pipeline = running.Pipeline
+	h.middlewares = middlewares
+	h.handler.Store(Pipeline{Handlers: middlewares}.Apply(h.router))
+	h.limits.Store(pipelineLimits(running.Pipeline))
+	if len(pipelineChanges) == 0 {
+		// only the order of the middlewares changed
+		pipelineChanges = append(pipelineChanges, v1alpha2.AppliedChange("pipeline"))
+	}
+	return append(changes, pipelineChanges...)
+}
+
+func copyBindingConfig(config HttpBindingConfig) (HttpBindingConfig, error) {
+	ret := HttpBindingConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to copy HTTP binding config", v1alpha2.BadConfig)
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to copy HTTP binding config", v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
 func (h *HttpBinding) useRouter(endpoints []v1alpha2.Endpoint) fasthttp.RequestHandler {
 	router := h.getRouter(endpoints)
 	return router.Handler
@@ -88,6 +284,10 @@ func (h *HttpBinding) getRouter(endpoints []v1alpha2.Endpoint) *routing.Router {
 	router := routing.New()
 	for _, e := range endpoints {
 		path := fmt.Sprintf("/%s/%s", e.Version, e.Route)
+		if e.Version == "" {
+			// endpoints of the host itself, such as health probes, aren't versioned
+			path = "/" + e.Route
+		}
 		for _, p := range e.Parameters {
 			path += "/{" + p + "}"
 		}
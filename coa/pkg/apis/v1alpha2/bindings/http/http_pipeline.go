@@ -24,58 +24,121 @@ type Pipeline struct {
 	Handlers []Middleware
 }
 
+// liveMiddlewares are the middleware types that can be added, changed or removed while the binding
+// runs. Tracing, metrics and telemetry middlewares register process-wide exporters when they're created,
+// and audit middlewares open their sinks.
+var liveMiddlewares = map[string]bool{
+	"middleware.http.cors":  true,
+	"middleware.http.jwt":   true,
+	"middleware.http.trail": true,
+	// a rate limit that's changed starts with full buckets
+	"middleware.http.ratelimit": true,
+	"middleware.http.limits":    true,
+}
+
 func BuildPipeline(config HttpBindingConfig, pubsubProvider pubsub.IPubSubProvider) (Pipeline, error) {
 	ret := Pipeline{Handlers: make([]Middleware, 0)}
 	for _, c := range config.Pipeline {
-		switch c.Type {
-		case "middleware.http.cors":
-			cors := CORS{Properties: c.Properties}
-			ret.Handlers = append(ret.Handlers, cors.CORS)
-		case "middleware.http.trail":
-			trail := Trail{}
-			trail.SetPubSubProvider(pubsubProvider)
-			ret.Handlers = append(ret.Handlers, trail.Trail)
-		case "middleware.http.telemetry":
-			enableAppInsight := os.Getenv("ENABLE_APP_INSIGHT")
-			c.Properties["enabled"] = enableAppInsight == "true"
-			c.Properties["client"] = uuid.New().String()
-			telemetry := Telemetry{Properties: c.Properties}
-			ret.Handlers = append(ret.Handlers, telemetry.Telemetry)
-		case "middleware.http.jwt":
-			jwts := JWT{}
-			jData, _ := json.Marshal(c.Properties)
-			err := json.Unmarshal(jData, &jwts)
-			if err != nil {
-				return ret, v1alpha2.NewCOAError(nil, "incorrect jwt pipeline configuration format", v1alpha2.BadConfig)
-			}
-			if jwts.AuthHeader == "" {
-				jwts.AuthHeader = "Authorization"
-			}
-			ret.Handlers = append(ret.Handlers, jwts.JWT)
-		case "middleware.http.tracing":
-			tracing := Tracing{
-				Observability: observability.Observability{},
-			}
-			config := observability.ObservabilityConfig{}
-			if p, ok := c.Properties["pipeline"]; ok {
-				data, _ := json.Marshal(p)
-				pipelines := make([]observability.PipelineConfig, 0)
-				err := json.Unmarshal(data, &pipelines)
-				if err != nil {
-					return ret, v1alpha2.NewCOAError(nil, "incorrect tracing pipeline configuration format", v1alpha2.BadConfig)
-				}
-				config.Pipelines = pipelines
-			}
-			err := tracing.Observability.Init(config)
+		middleware, err := buildMiddleware(c, pubsubProvider)
+		if err != nil {
+			return ret, err
+		}
+		ret.Handlers = append(ret.Handlers, middleware)
+	}
+	return ret, nil
+}
+
+func buildMiddleware(c MiddlewareConfig, pubsubProvider pubsub.IPubSubProvider) (Middleware, error) {
+	switch c.Type {
+	case "middleware.http.cors":
+		cors := CORS{Properties: c.Properties}
+		return cors.CORS, nil
+	case "middleware.http.trail":
+		trail := Trail{}
+		trail.SetPubSubProvider(pubsubProvider)
+		return trail.Trail, nil
+	case "middleware.http.telemetry":
+		enableAppInsight := os.Getenv("ENABLE_APP_INSIGHT")
+		c.Properties["enabled"] = enableAppInsight == "true"
+		c.Properties["client"] = uuid.New().String()
+		telemetry := Telemetry{Properties: c.Properties}
+		return telemetry.Telemetry, nil
+	case "middleware.http.jwt":
+		jwts := JWT{}
+		jData, _ := json.Marshal(c.Properties)
+		err := json.Unmarshal(jData, &jwts)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(nil, "incorrect jwt pipeline configuration format", v1alpha2.BadConfig)
+		}
+		if jwts.AuthHeader == "" {
+			jwts.AuthHeader = "Authorization"
+		}
+		return jwts.JWT, nil
+	case "middleware.http.tracing":
+		tracing := Tracing{
+			Observability: observability.Observability{},
+		}
+		config := observability.ObservabilityConfig{}
+		if p, ok := c.Properties["pipeline"]; ok {
+			data, _ := json.Marshal(p)
+			pipelines := make([]observability.PipelineConfig, 0)
+			err := json.Unmarshal(data, &pipelines)
 			if err != nil {
-				return ret, v1alpha2.NewCOAError(nil, "failed to initialize tracing middleware", v1alpha2.InternalError)
+				return nil, v1alpha2.NewCOAError(nil, "incorrect tracing pipeline configuration format", v1alpha2.BadConfig)
 			}
-			ret.Handlers = append(ret.Handlers, tracing.Tracing)
-		default:
-			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("middleware type '%s' is not recognized", c.Type), v1alpha2.BadConfig)
+			config.Pipelines = pipelines
+		}
+		err := tracing.Observability.Init(config)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(nil, "failed to initialize tracing middleware", v1alpha2.InternalError)
+		}
+		return tracing.Tracing, nil
+	case "middleware.http.metrics":
+		metrics, err := observability.InitMetrics()
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := pubsubProvider.(pubsub.IQueueDepthProvider); ok {
+			metrics.RegisterQueueDepth(p.QueueDepth)
+		}
+		m := &Metrics{Provider: metrics}
+		if route, ok := c.Properties["route"].(string); ok {
+			m.Route = route
 		}
+		return m.Metrics, nil
+	case "middleware.http.ratelimit":
+		rateLimit := &RateLimit{}
+		jData, _ := json.Marshal(c.Properties)
+		err := json.Unmarshal(jData, rateLimit)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(nil, "incorrect rate limit pipeline configuration format", v1alpha2.BadConfig)
+		}
+		err = rateLimit.validate()
+		if err != nil {
+			return nil, err
+		}
+		return rateLimit.RateLimit, nil
+	case "middleware.http.limits":
+		limits, err := newLimits(c)
+		if err != nil {
+			return nil, err
+		}
+		return limits.Limits, nil
+	case "middleware.http.audit":
+		audit := &Audit{}
+		jData, _ := json.Marshal(c.Properties)
+		err := json.Unmarshal(jData, audit)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(nil, "incorrect audit pipeline configuration format", v1alpha2.BadConfig)
+		}
+		err = audit.open()
+		if err != nil {
+			return nil, err
+		}
+		return audit.Audit, nil
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("middleware type '%s' is not recognized", c.Type), v1alpha2.BadConfig)
 	}
-	return ret, nil
 }
 
 func (p Pipeline) Apply(handler fasthttp.RequestHandler) fasthttp.RequestHandler {
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func reloadTestBindingConfig(origin string) HttpBindingConfig {
+	return HttpBindingConfig{
+		Port: 0,
+		Pipeline: []MiddlewareConfig{
+			{
+				Type:       "middleware.http.cors",
+				Properties: map[string]interface{}{"Access-Control-Allow-Origin": origin},
+			},
+			{
+				Type:       "middleware.http.telemetry",
+				Properties: map[string]interface{}{},
+			},
+		},
+	}
+}
+
+func launchTestBinding(t *testing.T, config HttpBindingConfig) *HttpBinding {
+	binding := &HttpBinding{}
+	err := binding.Launch(config, []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   "greetings",
+			Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte("hello")}
+			},
+		},
+	}, nil)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		binding.Shutdown(context.Background())
+	})
+	return binding
+}
+
+func serveTestRequest(binding *HttpBinding) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/greetings")
+	binding.serve(ctx)
+	return ctx
+}
+
+func TestReconfigurePipeline(t *testing.T) {
+	binding := launchTestBinding(t, reloadTestBindingConfig("https://a.contoso.com"))
+	ctx := serveTestRequest(binding)
+	assert.Equal(t, "https://a.contoso.com", string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")))
+
+	// the telemetry middleware adds properties of its own, which aren't a change
+	assert.Empty(t, binding.Reconfigure(reloadTestBindingConfig("https://a.contoso.com")))
+
+	changes := binding.Reconfigure(reloadTestBindingConfig("https://b.contoso.com"))
+	assert.Equal(t, []v1alpha2.ConfigChange{v1alpha2.AppliedChange("pipeline[0]")}, changes)
+	ctx = serveTestRequest(binding)
+	assert.Equal(t, "https://b.contoso.com", string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")))
+	assert.Equal(t, "hello", string(ctx.Response.Body()))
+}
+
+func TestReconfigurePipelineRejected(t *testing.T) {
+	binding := launchTestBinding(t, reloadTestBindingConfig("https://a.contoso.com"))
+
+	config := reloadTestBindingConfig("https://b.contoso.com")
+	config.Port = 8099
+	config.Pipeline = config.Pipeline[:1]
+	changes := binding.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("listener", "the port, TLS and certificate settings are applied when the binding is launched"),
+		v1alpha2.RejectedChange("pipeline[0]", "other changes of the pipeline can't be applied live"),
+		v1alpha2.RejectedChange("pipeline[1]", "middleware type 'middleware.http.telemetry' can't be removed live"),
+	}, changes)
+
+	// the pipeline is kept as it is
+	ctx := serveTestRequest(binding)
+	assert.Equal(t, "https://a.contoso.com", string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")))
+}
@@ -27,6 +27,8 @@ type JWT struct {
 	Roles       []ClaimRoleMap    `json:"roles,omitempty"`
 	EnableRBAC  bool              `json:"enableRBAC,omitempty"`
 	Policy      map[string]Policy `json:"policy,omitempty"`
+	// UserClaim is the claim that identifies the caller, default is "sub"
+	UserClaim string `json:"userClaim,omitempty"`
 }
 type ClaimRoleMap struct {
 	Role  string `json:"role"`
@@ -55,25 +57,12 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 		if tokenStr == "" {
 			ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 		} else {
-			_, roles, err := j.validateToken(tokenStr)
+			claims, roles, err := j.validateToken(tokenStr)
 			if err != nil {
 				ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 			} else {
-				if j.EnableRBAC {
-					path := string(ctx.Path())
-					method := string(ctx.Method())
-					for _, role := range roles {
-						if v, ok := j.Policy[role]; ok {
-							for key, val := range v.Items {
-								if key == "*" || strings.HasPrefix(path, key) {
-									if val == "*" || strings.Contains(val, method) {
-										next(ctx)
-										return
-									}
-								}
-							}
-						}
-					}
+				j.setIdentity(ctx, claims, roles)
+				if j.EnableRBAC && !j.allows(roles, string(ctx.Path()), string(ctx.Method())) {
 					ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 					return
 				}
@@ -82,16 +71,77 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 		}
 	}
 }
+
+// Authenticate validates the bearer token in the value of an auth header. Bindings other than HTTP use it
+// to apply the same JWT configuration. It returns the user name and the roles of the caller.
+func (j *JWT) Authenticate(authValue string) (string, []string, error) {
+	tokenStr := bearerToken(authValue)
+	if tokenStr == "" {
+		return "", nil, v1alpha2.NewCOAError(nil, "bearer token is not found", v1alpha2.Unauthorized)
+	}
+	claims, _, err := j.validateToken(tokenStr)
+	if err != nil {
+		return "", nil, v1alpha2.NewCOAError(err, "invalid bearer token", v1alpha2.Unauthorized)
+	}
+	return j.userName(claims), j.claimRoles(claims), nil
+}
+
+// Authorize checks, with RBAC enabled, that the roles of an authenticated caller allow the method on the path
+func (j *JWT) Authorize(roles []string, path string, method string) error {
+	if j.EnableRBAC && !j.allows(roles, path, method) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s %s is not allowed", method, path), v1alpha2.Unauthorized)
+	}
+	return nil
+}
+
+// allows checks if any of the roles is given the method on the path by the RBAC policy
+func (j JWT) allows(roles []string, path string, method string) bool {
+	for _, role := range roles {
+		if v, ok := j.Policy[role]; ok {
+			for key, val := range v.Items {
+				if key == "*" || strings.HasPrefix(path, key) {
+					if val == "*" || strings.Contains(val, method) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// setIdentity makes the caller's user name and roles available to handlers through the request context.
+// Roles are mapped from the claims for handlers, such as approvals, even when RBAC isn't enabled.
+func (j JWT) setIdentity(ctx *fasthttp.RequestCtx, claims map[string]interface{}, roles []string) {
+	if user := j.userName(claims); user != "" {
+		ctx.SetUserValue(v1alpha2.AuthUserKey, user)
+	}
+	if roles == nil {
+		roles = j.claimRoles(claims)
+	}
+	ctx.SetUserValue(v1alpha2.AuthRolesKey, roles)
+}
+func (j JWT) userName(claims map[string]interface{}) string {
+	userClaim := j.UserClaim
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+	if v, ok := claims[userClaim]; ok {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
 func (j JWT) readAuthHeader(ctx *fasthttp.RequestCtx) string {
 	v := ctx.Request.Header.Peek(j.AuthHeader)
 	if v != nil {
-		tokenStr := string(v)
-		token := strings.Split(tokenStr, "Bearer ")
-		if len(token) == 2 {
-			return strings.TrimSpace(token[1])
-		} else {
-			return ""
-		}
+		return bearerToken(string(v))
+	}
+	return ""
+}
+func bearerToken(value string) string {
+	token := strings.Split(value, "Bearer ")
+	if len(token) == 2 {
+		return strings.TrimSpace(token[1])
 	}
 	return ""
 }
@@ -147,15 +197,20 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 	}
 	var roles []string
 	if j.EnableRBAC {
-		roles = make([]string, 0)
-		for _, m := range j.Roles {
-			if v, ok := ret[m.Claim]; ok {
-				if m.Value == "*" || v == m.Value {
-					roles = append(roles, m.Role)
-				}
+		roles = j.claimRoles(ret)
+	}
+	return ret, roles, nil
+}
+
+// claimRoles maps the claims of a token to roles
+func (j JWT) claimRoles(claims map[string]interface{}) []string {
+	roles := make([]string, 0)
+	for _, m := range j.Roles {
+		if v, ok := claims[m.Claim]; ok {
+			if m.Value == "*" || v == m.Value {
+				roles = append(roles, m.Role)
 			}
 		}
-
 	}
-	return ret, roles, nil
+	return roles
 }
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestJWTIdentityWithoutRBAC(t *testing.T) {
+	j := JWT{
+		AuthHeader: "Authorization",
+		VerifyKey:  "SymphonyKey",
+		Roles: []ClaimRoleMap{
+			{Role: "release-manager", Claim: "group", Value: "releases"},
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "group": "releases"}).SignedString([]byte("SymphonyKey"))
+	assert.Nil(t, err)
+
+	// validation doesn't map roles unless RBAC is enabled
+	_, roles, err := j.validateToken(token)
+	assert.Nil(t, err)
+	assert.Nil(t, roles)
+
+	// handlers still get the roles of the caller
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	called := false
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(ctx)
+	assert.True(t, called)
+	assert.Equal(t, "alice", ctx.UserValue(v1alpha2.AuthUserKey))
+	assert.Equal(t, []string{"release-manager"}, ctx.UserValue(v1alpha2.AuthRolesKey))
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/valyala/fasthttp"
+)
+
+type Limits struct {
+	// MaxBodySize is the largest request body in bytes. 0 means no limit other than the server's.
+	MaxBodySize int `json:"maxBodySize,omitempty"`
+	// Timeout is how long a request can take, such as "30s". Empty means no timeout.
+	Timeout     string   `json:"timeout,omitempty"`
+	IgnorePaths []string `json:"ignorePaths,omitempty"`
+	timeout     time.Duration
+}
+
+func newLimits(c MiddlewareConfig) (*Limits, error) {
+	limits := &Limits{}
+	jData, _ := json.Marshal(c.Properties)
+	err := json.Unmarshal(jData, limits)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(nil, "incorrect limits pipeline configuration format", v1alpha2.BadConfig)
+	}
+	err = limits.validate()
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+func (l *Limits) validate() error {
+	if l.MaxBodySize < 0 {
+		return v1alpha2.NewCOAError(nil, "max body size can't be negative", v1alpha2.BadConfig)
+	}
+	if l.Timeout != "" {
+		timeout, err := time.ParseDuration(l.Timeout)
+		if err != nil || timeout <= 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("timeout '%s' is not a positive duration", l.Timeout), v1alpha2.BadConfig)
+		}
+		l.timeout = timeout
+	}
+	return nil
+}
+
+// bodyLimit returns the largest request body in bytes for a path, 0 if the path isn't limited
+func (l *Limits) bodyLimit(path string) int {
+	for _, p := range l.IgnorePaths {
+		if p == path {
+			return 0
+		}
+	}
+	return l.MaxBodySize
+}
+
+// Limits middleware rejects requests with a body larger than the limit with 413 Request Entity Too
+// Large, and answers requests that take longer than the timeout with 503 Service Unavailable. The handler
+// of a request that times out isn't stopped, it finishes in the background. The HTTP binding also applies
+// the body limit while it reads a request, so that a larger body isn't read into memory.
+func (l *Limits) Limits(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	limited := next
+	if l.timeout > 0 {
+		limited = fasthttp.TimeoutWithCodeHandler(next, l.timeout, "request timed out", fasthttp.StatusServiceUnavailable)
+	}
+	return func(ctx *fasthttp.RequestCtx) {
+		path := string(ctx.Path())
+		for _, p := range l.IgnorePaths {
+			if p == path {
+				next(ctx)
+				return
+			}
+		}
+		if l.MaxBodySize > 0 && (ctx.Request.Header.ContentLength() > l.MaxBodySize || len(ctx.PostBody()) > l.MaxBodySize) {
+			ctx.Error(fmt.Sprintf("request body is larger than %d bytes", l.MaxBodySize), fasthttp.StatusRequestEntityTooLarge)
+			return
+		}
+		limited(ctx)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func limitsRequest(handler fasthttp.RequestHandler, path string, body string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	request := fasthttp.Request{}
+	request.Header.SetMethod(fasthttp.MethodPost)
+	request.SetRequestURI(path)
+	request.SetBodyString(body)
+	ctx.Init(&request, &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}, nil)
+	handler(ctx)
+	return ctx
+}
+
+func TestLimitsBodySize(t *testing.T) {
+	l := &Limits{MaxBodySize: 4, IgnorePaths: []string{"/v1alpha2/catalogs"}}
+	assert.Nil(t, l.validate())
+	handler := l.Limits(func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := limitsRequest(handler, "/v1alpha2/solutions", "spec")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	ctx = limitsRequest(handler, "/v1alpha2/solutions", "large spec")
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, ctx.Response.StatusCode())
+	ctx = limitsRequest(handler, "/v1alpha2/catalogs", "large spec")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	l := &Limits{Timeout: "50ms"}
+	assert.Nil(t, l.validate())
+	done := make(chan struct{})
+	handler := l.Limits(func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/v1alpha2/slow" {
+			<-done
+		}
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	})
+	defer close(done)
+
+	// the server sends the response of a request that times out
+	listener := fasthttputil.NewInmemoryListener()
+	server := &fasthttp.Server{Handler: handler}
+	go server.Serve(listener)
+	defer server.Shutdown()
+	client := &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return listener.Dial()
+		},
+	}
+
+	status, _, err := client.Get(nil, "http://symphony/v1alpha2/fast")
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusAccepted, status)
+
+	start := time.Now()
+	status, _, err = client.Get(nil, "http://symphony/v1alpha2/slow")
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, status)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestLimitsInvalidConfig(t *testing.T) {
+	_, err := buildMiddleware(MiddlewareConfig{Type: "middleware.http.limits", Properties: map[string]interface{}{"timeout": "soon"}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.limits", Properties: map[string]interface{}{"maxBodySize": -1}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.limits", Properties: map[string]interface{}{"maxBodySize": 1048576, "timeout": "30s"}}, nil)
+	assert.Nil(t, err)
+}
+
+func TestLimitsBodySizeWhileReading(t *testing.T) {
+	config := HttpBindingConfig{
+		Pipeline: []MiddlewareConfig{
+			{
+				Type:       "middleware.http.limits",
+				Properties: map[string]interface{}{"maxBodySize": 4, "ignorePaths": []string{"/greetings"}},
+			},
+		},
+	}
+	binding := launchTestBinding(t, config)
+	listener := fasthttputil.NewInmemoryListener()
+	go binding.server.Serve(listener)
+	client := &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return listener.Dial()
+		},
+	}
+	post := func(path string, body string) (int, string) {
+		request := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(request)
+		response := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(response)
+		request.Header.SetMethod(fasthttp.MethodPost)
+		request.SetRequestURI("http://symphony" + path)
+		request.SetBodyString(body)
+		assert.Nil(t, client.Do(request, response))
+		return response.StatusCode(), string(response.Body())
+	}
+
+	// the server rejects the body before the pipeline runs
+	status, body := post("/v1alpha2/solutions", "large spec")
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, status)
+	assert.Equal(t, "request body is too large", body)
+	status, _ = post("/v1alpha2/solutions", "spec")
+	assert.Equal(t, fasthttp.StatusNotFound, status)
+	status, _ = post("/greetings", "large spec")
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, status)
+
+	// the limit follows the pipeline when it's reconfigured
+	config.Pipeline[0].Properties = map[string]interface{}{"maxBodySize": 100}
+	binding.Reconfigure(config)
+	status, _ = post("/v1alpha2/solutions", "large spec")
+	assert.Equal(t, fasthttp.StatusNotFound, status)
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+const defaultMetricsRoute = "/metrics"
+
+type Metrics struct {
+	Provider *observability.Metrics
+	Route    string
+	handler  fasthttp.RequestHandler
+}
+
+// Metrics middleware serves the metrics in the Prometheus format on its route, and records the rate
+// and the duration of the other requests by route and status code. The route of a request is its
+// version and vendor, such as /v1alpha2/solutions, so that object names don't become labels.
+func (m *Metrics) Metrics(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if m.Route == "" {
+		m.Route = defaultMetricsRoute
+	}
+	m.handler = fasthttpadaptor.NewFastHTTPHandler(m.Provider.Handler())
+	return func(ctx *fasthttp.RequestCtx) {
+		path := string(ctx.Path())
+		if path == m.Route {
+			m.handler(ctx)
+			return
+		}
+		start := time.Now()
+		next(ctx)
+		m.Provider.RecordHTTPRequest(context.Background(), metricsRoute(path), string(ctx.Method()), ctx.Response.StatusCode(), time.Since(start))
+	}
+}
+
+func metricsRoute(path string) string {
+	version, vendor := observ_utils.GetVendor(path)
+	if vendor == "" {
+		return "other"
+	}
+	return fmt.Sprintf("/%s/%s", version, vendor)
+}
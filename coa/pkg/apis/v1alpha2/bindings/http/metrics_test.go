@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"testing"
+
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMetricsRoute(t *testing.T) {
+	assert.Equal(t, "/v1alpha2/solutions", metricsRoute("/v1alpha2/solutions/my-solution"))
+	assert.Equal(t, "/v1alpha2/instances", metricsRoute("/v1alpha2/instances"))
+	assert.Equal(t, "other", metricsRoute("/"))
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	provider, err := observability.InitMetrics()
+	assert.Nil(t, err)
+	m := &Metrics{Provider: provider}
+	handler := m.Metrics(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+	})
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/v1alpha2/targets/my-target")
+	handler(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/metrics")
+	handler(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Contains(t, string(ctx.Response.Body()), `symphony_http_requests_total{method="GET",route="/v1alpha2/targets",status="404"} 1`)
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	RateLimitByClient = "client"
+	RateLimitByUser   = "user"
+
+	// rateLimitSweepInterval is how often buckets that have refilled are dropped
+	rateLimitSweepInterval = time.Minute
+)
+
+// RateLimitRule is the rate, in requests per second, and the burst of a token bucket
+type RateLimitRule struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// burst is the size of the bucket, which defaults to a second's worth of requests
+func (rule RateLimitRule) burst() float64 {
+	if rule.Burst >= 1 {
+		return float64(rule.Burst)
+	}
+	return math.Max(1, rule.Rate)
+}
+
+type RateLimit struct {
+	RateLimitRule
+	// Key is what requests are counted by, either "client" (the remote IP) or "user" (the user the JWT
+	// middleware finds, falling back to the remote IP for anonymous requests). Default is "client".
+	Key string `json:"key,omitempty"`
+	// Routes overrides the rule for routes starting with a prefix, such as /v1alpha2/federation/sync
+	Routes      map[string]RateLimitRule `json:"routes,omitempty"`
+	IgnorePaths []string                 `json:"ignorePaths,omitempty"`
+	// TrustedProxies are the addresses or CIDR ranges of proxies, such as an ingress controller, whose
+	// X-Forwarded-For header gives the client of a request. Without it, all the clients behind a proxy
+	// share the bucket of the proxy's address.
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+	trusted        []*net.IPNet
+	buckets        map[string]*tokenBucket
+	lastSweep      time.Time
+	lock           sync.Mutex
+	now            func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rule   RateLimitRule
+}
+
+// RateLimit middleware keeps a token bucket for every caller and route, and rejects requests with
+// 429 Too Many Requests once the bucket is empty. The route of a request is its version and vendor, or
+// the longest configured route prefix it starts with. Put it after the JWT middleware to count by user.
+func (r *RateLimit) RateLimit(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		path := string(ctx.Path())
+		for _, p := range r.IgnorePaths {
+			if p == path {
+				next(ctx)
+				return
+			}
+		}
+		route, rule := r.rule(path)
+		if rule.Rate <= 0 {
+			next(ctx)
+			return
+		}
+		if wait, ok := r.take(r.caller(ctx)+" "+route, rule); !ok {
+			ctx.Error("rate limit exceeded", fasthttp.StatusTooManyRequests)
+			ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			return
+		}
+		next(ctx)
+	}
+}
+
+func (r *RateLimit) validate() error {
+	if r.Key != "" && r.Key != RateLimitByClient && r.Key != RateLimitByUser {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("rate limit key '%s' is not recognized", r.Key), v1alpha2.BadConfig)
+	}
+	if r.Rate < 0 {
+		return v1alpha2.NewCOAError(nil, "rate limit rate can't be negative", v1alpha2.BadConfig)
+	}
+	for k, v := range r.Routes {
+		if v.Rate < 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("rate limit rate of route '%s' can't be negative", k), v1alpha2.BadConfig)
+		}
+	}
+	r.trusted = make([]*net.IPNet, 0, len(r.TrustedProxies))
+	for _, p := range r.TrustedProxies {
+		cidr := p
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("trusted proxy '%s' is not an IP address or a CIDR range", p), v1alpha2.BadConfig)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return nil
+}
+
+func (r *RateLimit) rule(path string) (string, RateLimitRule) {
+	route := ""
+	for k := range r.Routes {
+		if strings.HasPrefix(path, k) && len(k) > len(route) {
+			route = k
+		}
+	}
+	if route != "" {
+		return route, r.Routes[route]
+	}
+	return metricsRoute(path), r.RateLimitRule
+}
+
+func (r *RateLimit) caller(ctx *fasthttp.RequestCtx) string {
+	if r.Key == RateLimitByUser {
+		if user, ok := ctx.UserValue(v1alpha2.AuthUserKey).(string); ok && user != "" {
+			return "user:" + user
+		}
+	}
+	return "client:" + r.clientIP(ctx).String()
+}
+
+// clientIP is the remote IP of a request, or, when the request comes from a trusted proxy, the last
+// address in X-Forwarded-For that isn't a trusted proxy. Addresses left of it could be set by the client.
+func (r *RateLimit) clientIP(ctx *fasthttp.RequestCtx) net.IP {
+	ip := ctx.RemoteIP()
+	if !r.isTrusted(ip) {
+		return ip
+	}
+	forwarded := strings.Split(string(ctx.Request.Header.Peek(fasthttp.HeaderXForwardedFor)), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !r.isTrusted(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+func (r *RateLimit) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// take takes a token from the bucket of the key. If the bucket is empty, it returns how long it takes
+// for the next token.
+func (r *RateLimit) take(key string, rule RateLimitRule) (time.Duration, bool) {
+	burst := rule.burst()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.buckets == nil {
+		r.buckets = make(map[string]*tokenBucket)
+	}
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+	r.sweep(now)
+	b, ok := r.buckets[key]
+	if !ok || b.rule != rule {
+		b = &tokenBucket{tokens: burst, last: now, rule: rule}
+		r.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rule.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rule.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep drops the buckets that have refilled since they were last used, which would start full anyway
+func (r *RateLimit) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for k, b := range r.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rule.Rate >= b.rule.burst() {
+			delete(r.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func rateLimitRequest(handler fasthttp.RequestHandler, ip string, user string, path string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	request := fasthttp.Request{}
+	request.Header.SetMethod(fasthttp.MethodGet)
+	request.SetRequestURI(path)
+	ctx.Init(&request, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+	if user != "" {
+		ctx.SetUserValue(v1alpha2.AuthUserKey, user)
+	}
+	handler(ctx)
+	return ctx
+}
+
+func TestRateLimitByClient(t *testing.T) {
+	now := time.Now()
+	r := &RateLimit{RateLimitRule: RateLimitRule{Rate: 1, Burst: 2}, now: func() time.Time { return now }}
+	handler := r.RateLimit(func(ctx *fasthttp.RequestCtx) {})
+
+	for i := 0; i < 2; i++ {
+		ctx := rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/sync/site")
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	}
+	ctx := rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/sync/site")
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode())
+	assert.Equal(t, "1", string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)))
+
+	// other clients and other routes have their own buckets
+	ctx = rateLimitRequest(handler, "10.0.0.2", "", "/v1alpha2/federation/sync/site")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	ctx = rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	now = now.Add(time.Second)
+	ctx = rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/sync/site")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	r := &RateLimit{RateLimitRule: RateLimitRule{Rate: 1}, Key: RateLimitByUser}
+	handler := r.RateLimit(func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := rateLimitRequest(handler, "10.0.0.1", "agent", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	// the same user from another client
+	ctx = rateLimitRequest(handler, "10.0.0.2", "agent", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode())
+	ctx = rateLimitRequest(handler, "10.0.0.2", "admin", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+}
+
+func TestRateLimitRoutes(t *testing.T) {
+	r := &RateLimit{
+		Routes:      map[string]RateLimitRule{"/v1alpha2/federation/sync": {Rate: 1}},
+		IgnorePaths: []string{"/v1alpha2/federation/sync/ignored"},
+	}
+	handler := r.RateLimit(func(ctx *fasthttp.RequestCtx) {})
+
+	for i := 0; i < 3; i++ {
+		ctx := rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/registry")
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+		ctx = rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/sync/ignored")
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	}
+	ctx := rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/sync/site")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	ctx = rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/federation/sync/site")
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode())
+}
+
+func TestRateLimitTrustedProxies(t *testing.T) {
+	r := &RateLimit{TrustedProxies: []string{"10.0.0.1", "192.168.0.0/16"}}
+	assert.Nil(t, r.validate())
+	forwarded := func(ip string, header string) *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		request := fasthttp.Request{}
+		request.Header.Set(fasthttp.HeaderXForwardedFor, header)
+		ctx.Init(&request, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+		return ctx
+	}
+
+	// the last address that isn't a trusted proxy is the client, as addresses left of it can be forged
+	assert.Equal(t, "client:172.16.0.5", r.caller(forwarded("10.0.0.1", "1.2.3.4, 172.16.0.5, 192.168.1.1")))
+	// all the addresses are trusted proxies
+	assert.Equal(t, "client:192.168.1.2", r.caller(forwarded("10.0.0.1", "192.168.1.2, 192.168.1.1")))
+	// a request without the header counts as the proxy
+	assert.Equal(t, "client:10.0.0.1", r.caller(forwarded("10.0.0.1", "")))
+	// the header of a client that isn't a trusted proxy is ignored
+	assert.Equal(t, "client:10.0.0.2", r.caller(forwarded("10.0.0.2", "172.16.0.5")))
+
+	// without trusted proxies, the header is ignored
+	r = &RateLimit{}
+	assert.Nil(t, r.validate())
+	assert.Equal(t, "client:10.0.0.1", r.caller(forwarded("10.0.0.1", "172.16.0.5")))
+}
+
+func TestRateLimitSweep(t *testing.T) {
+	now := time.Now()
+	r := &RateLimit{RateLimitRule: RateLimitRule{Rate: 1}, now: func() time.Time { return now }}
+	handler := r.RateLimit(func(ctx *fasthttp.RequestCtx) {})
+
+	rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/solutions")
+	rateLimitRequest(handler, "10.0.0.2", "", "/v1alpha2/solutions")
+	assert.Equal(t, 2, len(r.buckets))
+	now = now.Add(rateLimitSweepInterval)
+	rateLimitRequest(handler, "10.0.0.1", "", "/v1alpha2/solutions")
+	assert.Equal(t, 1, len(r.buckets))
+}
+
+func TestRateLimitInvalidConfig(t *testing.T) {
+	_, err := buildMiddleware(MiddlewareConfig{Type: "middleware.http.ratelimit", Properties: map[string]interface{}{"rate": 1, "key": "header"}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.ratelimit", Properties: map[string]interface{}{"rate": -1}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.ratelimit", Properties: map[string]interface{}{"rate": 1, "trustedProxies": []string{"ingress"}}}, nil)
+	assert.NotNil(t, err)
+	_, err = buildMiddleware(MiddlewareConfig{Type: "middleware.http.ratelimit", Properties: map[string]interface{}{"rate": 10, "burst": 20, "key": "user"}}, nil)
+	assert.Nil(t, err)
+}
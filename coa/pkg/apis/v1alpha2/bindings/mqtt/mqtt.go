@@ -10,9 +10,11 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	gmqtt "github.com/eclipse/paho.mqtt.golang"
 )
@@ -24,15 +26,22 @@ type MQTTBindingConfig struct {
 	ClientID      string `json:"clientID"`
 	RequestTopic  string `json:"requestTopic"`
 	ResponseTopic string `json:"responseTopic"`
+	// QoS is the quality of service level used for subscribing to requests and publishing responses
+	QoS int `json:"qos,omitempty"`
 }
 
 type MQTTBinding struct {
-	MQTTClient gmqtt.Client
+	MQTTClient   gmqtt.Client
+	requestTopic string
+	inFlight     sync.WaitGroup
 }
 
 var routeTable map[string]v1alpha2.Endpoint
 
 func (m *MQTTBinding) Launch(config MQTTBindingConfig, endpoints []v1alpha2.Endpoint) error {
+	if config.QoS < 0 || config.QoS > 2 {
+		return v1alpha2.NewCOAError(nil, "'qos' must be 0, 1 or 2 in MQTT binding config", v1alpha2.BadConfig)
+	}
 	routeTable = make(map[string]v1alpha2.Endpoint)
 	for _, endpoint := range endpoints {
 		route := endpoint.Route
@@ -52,7 +61,10 @@ func (m *MQTTBinding) Launch(config MQTTBindingConfig, endpoints []v1alpha2.Endp
 		return v1alpha2.NewCOAError(token.Error(), "failed to connect to MQTT broker", v1alpha2.InternalError)
 	}
 
-	if token := m.MQTTClient.Subscribe(config.RequestTopic, 0, func(client gmqtt.Client, msg gmqtt.Message) {
+	m.requestTopic = config.RequestTopic
+	if token := m.MQTTClient.Subscribe(config.RequestTopic, byte(config.QoS), func(client gmqtt.Client, msg gmqtt.Message) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Done()
 		var request v1alpha2.COARequest
 		var response v1alpha2.COAResponse
 		request.Context = context.TODO()
@@ -64,22 +76,31 @@ func (m *MQTTBinding) Launch(config MQTTBindingConfig, endpoints []v1alpha2.Endp
 				Body:        []byte(err.Error()),
 			}
 		} else {
+			// continue the trace of the caller, if the request carries one
+			request.Context = observ_utils.SpanContextFromMetadata(request.Context, request.Metadata)
 			response = routeTable[request.Route].Handler(request)
 		}
 
-		// needs to carry call-context from request into response
+		// needs to carry call-context and request-id from request into response
+		responseTopic := config.ResponseTopic
 		if request.Metadata != nil {
-			if v, ok := request.Metadata["call-context"]; ok {
-				if response.Metadata == nil {
-					response.Metadata = make(map[string]string)
+			for _, key := range []string{"call-context", "request-id"} {
+				if v, ok := request.Metadata[key]; ok {
+					if response.Metadata == nil {
+						response.Metadata = make(map[string]string)
+					}
+					response.Metadata[key] = v
 				}
-				response.Metadata["call-context"] = v
+			}
+			// callers can ask for the response on a topic of their own
+			if v, ok := request.Metadata["response-topic"]; ok && v != "" {
+				responseTopic = v
 			}
 		}
 
 		data, _ := json.Marshal(response)
 
-		if token := client.Publish(config.ResponseTopic, 0, false, data); token.Wait() && token.Error() != nil {
+		if token := client.Publish(responseTopic, byte(config.QoS), false, data); token.Wait() && token.Error() != nil {
 			log.Errorf("failed to handle request from MOTT: %s", token.Error())
 		}
 	}); token.Wait() && token.Error() != nil {
@@ -91,3 +112,27 @@ func (m *MQTTBinding) Launch(config MQTTBindingConfig, endpoints []v1alpha2.Endp
 
 	return nil
 }
+
+// Shutdown unsubscribes from the request topic, waits for the requests in flight to be answered and
+// disconnects from the MQTT broker. It returns an error if ctx is done before the requests are finished.
+func (m *MQTTBinding) Shutdown(ctx context.Context) error {
+	if m.MQTTClient == nil || !m.MQTTClient.IsConnected() {
+		return nil
+	}
+	if token := m.MQTTClient.Unsubscribe(m.requestTopic); token.Wait() && token.Error() != nil {
+		log.Errorf("failed to unsubscribe from MQTT request topic: %s", token.Error())
+	}
+	done := make(chan struct{})
+	go func() {
+		m.inFlight.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = v1alpha2.NewCOAError(ctx.Err(), "MQTT binding didn't finish the requests in flight", v1alpha2.InternalError)
+	}
+	m.MQTTClient.Disconnect(250)
+	return err
+}
@@ -72,3 +72,18 @@ func TestMQTTEcho(t *testing.T) {
 	token.Wait()
 	<-sig
 }
+
+func TestLaunchInvalidQoS(t *testing.T) {
+	binding := MQTTBinding{}
+	err := binding.Launch(MQTTBindingConfig{
+		BrokerAddress: "tcp://127.0.0.1:1883",
+		ClientID:      "coa-test",
+		RequestTopic:  "coa-request",
+		ResponseTopic: "coa-response",
+		QoS:           3,
+	}, nil)
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
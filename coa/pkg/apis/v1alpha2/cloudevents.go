@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of a CloudEvent in the structured JSON format
+	CloudEventsContentType = "application/cloudevents+json"
+	// DefaultCloudEventTypePrefix prefixes the topic of an event without a type
+	DefaultCloudEventTypePrefix = "org.eclipse.symphony."
+	DefaultCloudEventSource     = "symphony"
+)
+
+// Event metadata that set the attributes of the CloudEvent an event is sent as
+const (
+	CloudEventTypeMetadata    = "ce-type"
+	CloudEventSubjectMetadata = "ce-subject"
+	CloudEventIdMetadata      = "ce-id"
+	CloudEventTimeMetadata    = "ce-time"
+)
+
+// CloudEvent is a CloudEvents 1.0 event in the structured JSON format
+type CloudEvent struct {
+	SpecVersion     string            `json:"specversion"`
+	Type            string            `json:"type"`
+	Source          string            `json:"source"`
+	Id              string            `json:"id"`
+	Subject         string            `json:"subject,omitempty"`
+	Time            string            `json:"time,omitempty"`
+	DataContentType string            `json:"datacontenttype,omitempty"`
+	Data            interface{}       `json:"data,omitempty"`
+	Extensions      map[string]string `json:"-"`
+}
+
+// NewCloudEventMetadata returns the metadata of an event that is sent as a CloudEvent of the given
+// type and subject
+func NewCloudEventMetadata(eventType string, subject string) map[string]string {
+	return map[string]string{
+		CloudEventTypeMetadata:    eventType,
+		CloudEventSubjectMetadata: subject,
+		CloudEventIdMetadata:      uuid.New().String(),
+		CloudEventTimeMetadata:    time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// ToCloudEvent wraps an event published to a topic as a CloudEvent. The attributes come from the
+// ce-* metadata of the event. An event without a type gets the type org.eclipse.symphony.<topic>, and
+// the other metadata become extension attributes.
+func (e Event) ToCloudEvent(topic string, source string) CloudEvent {
+	if source == "" {
+		source = DefaultCloudEventSource
+	}
+	ret := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            e.Metadata[CloudEventTypeMetadata],
+		Source:          source,
+		Id:              e.Metadata[CloudEventIdMetadata],
+		Subject:         e.Metadata[CloudEventSubjectMetadata],
+		Time:            e.Metadata[CloudEventTimeMetadata],
+		DataContentType: "application/json",
+		Data:            e.Body,
+		Extensions:      make(map[string]string),
+	}
+	if ret.Type == "" {
+		ret.Type = DefaultCloudEventTypePrefix + topic
+	}
+	if ret.Id == "" {
+		ret.Id = uuid.New().String()
+	}
+	if ret.Time == "" {
+		ret.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	for k, v := range e.Metadata {
+		if strings.HasPrefix(k, "ce-") {
+			continue
+		}
+		if name := extensionName(k); name != "" {
+			ret.Extensions[name] = v
+		}
+	}
+	ret.Extensions["topic"] = topic
+	return ret
+}
+
+// MarshalJSON adds the extension attributes to the attributes of the event
+func (c CloudEvent) MarshalJSON() ([]byte, error) {
+	type cloudEvent CloudEvent
+	data, err := json.Marshal(cloudEvent(c))
+	if err != nil || len(c.Extensions) == 0 {
+		return data, err
+	}
+	attributes := make(map[string]interface{})
+	if err = json.Unmarshal(data, &attributes); err != nil {
+		return nil, err
+	}
+	for k, v := range c.Extensions {
+		if _, ok := attributes[k]; !ok {
+			attributes[k] = v
+		}
+	}
+	return json.Marshal(attributes)
+}
+
+// extensionName turns a metadata key into a CloudEvents extension attribute name, which can only
+// have lower-case letters and digits
+func extensionName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, key)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToCloudEventDefaults(t *testing.T) {
+	event := Event{Body: "TEST"}
+	ce := event.ToCloudEvent("job", "")
+	assert.Equal(t, CloudEventsSpecVersion, ce.SpecVersion)
+	assert.Equal(t, "org.eclipse.symphony.job", ce.Type)
+	assert.Equal(t, DefaultCloudEventSource, ce.Source)
+	assert.NotEmpty(t, ce.Id)
+	assert.NotEmpty(t, ce.Time)
+	assert.Equal(t, "TEST", ce.Data)
+	assert.Equal(t, "job", ce.Extensions["topic"])
+}
+
+func TestToCloudEventFromMetadata(t *testing.T) {
+	metadata := NewCloudEventMetadata("org.eclipse.symphony.deployment.finished", "instance1")
+	metadata["scope"] = "default"
+	metadata["ordering-Key"] = "instance1"
+	event := Event{Metadata: metadata, Body: map[string]string{"instance": "instance1"}}
+	ce := event.ToCloudEvent("deployment", "https://symphony.example.com")
+	assert.Equal(t, "org.eclipse.symphony.deployment.finished", ce.Type)
+	assert.Equal(t, "https://symphony.example.com", ce.Source)
+	assert.Equal(t, "instance1", ce.Subject)
+	assert.Equal(t, metadata[CloudEventIdMetadata], ce.Id)
+	assert.Equal(t, metadata[CloudEventTimeMetadata], ce.Time)
+	assert.Equal(t, "default", ce.Extensions["scope"])
+	assert.Equal(t, "instance1", ce.Extensions["orderingkey"])
+	_, ok := ce.Extensions["cetype"]
+	assert.False(t, ok)
+}
+
+func TestCloudEventMarshalJSON(t *testing.T) {
+	ce := CloudEvent{
+		SpecVersion: CloudEventsSpecVersion,
+		Type:        "org.eclipse.symphony.test",
+		Source:      DefaultCloudEventSource,
+		Id:          "1",
+		Data:        map[string]string{"a": "b"},
+		Extensions:  map[string]string{"topic": "test", "type": "ignored"},
+	}
+	data, err := json.Marshal(ce)
+	assert.Nil(t, err)
+	attributes := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(data, &attributes))
+	assert.Equal(t, "1.0", attributes["specversion"])
+	// extensions don't override the context attributes
+	assert.Equal(t, "org.eclipse.symphony.test", attributes["type"])
+	assert.Equal(t, "test", attributes["topic"])
+	assert.Equal(t, map[string]interface{}{"a": "b"}, attributes["data"])
+	_, ok := attributes["subject"]
+	assert.False(t, ok)
+}
@@ -26,6 +26,8 @@ type JobData struct {
 	Id     string      `json:"id"`
 	Action string      `json:"action"`
 	Body   interface{} `json:"body,omitempty"`
+	// Metadata carries the trace context of a job that is handed over to another site
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 type ActivationData struct {
 	Campaign             string                            `json:"campaign"`
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	healthzRoute       = "healthz"
+	readyzRoute        = "readyz"
+	debugStatusRoute   = "debug/status"
+	healthCheckTimeout = 5 * time.Second
+)
+
+// HealthReport is the response of the health and readiness endpoints
+type HealthReport struct {
+	Status string                `json:"status"`
+	Checks []vendors.HealthCheck `json:"checks,omitempty"`
+}
+
+// HostStatus is the response of the diagnostics endpoint
+type HostStatus struct {
+	SiteId   string                 `json:"siteId"`
+	Ready    bool                   `json:"ready"`
+	Bindings []string               `json:"bindings,omitempty"`
+	Vendors  []vendors.VendorStatus `json:"vendors"`
+	// LastReload is the outcome of the last configuration reload, if the configuration was reloaded
+	LastReload *ReloadStatus `json:"lastReload,omitempty"`
+}
+
+// getHostEndpoints returns the endpoints that the host serves on its HTTP bindings, next to the
+// endpoints of its vendors. Kubernetes probes use /healthz for liveness and /readyz for readiness.
+func (h *APIHost) getHostEndpoints() []v1alpha2.Endpoint {
+	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   healthzRoute,
+			Handler: h.onHealthz,
+		},
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   readyzRoute,
+			Handler: h.onReadyz,
+		},
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   debugStatusRoute,
+			Handler: h.onDebugStatus,
+		},
+	}
+}
+
+// onHealthz checks the managers of all vendors
+func (h *APIHost) onHealthz(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return healthResponse(h.checkHealth(request.Context, false))
+}
+
+// onReadyz checks the managers of all vendors, their providers and pub-sub connections. The host isn't
+// ready until it has launched all of its vendors and bindings, and isn't ready once it's shutting down.
+func (h *APIHost) onReadyz(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	checks := h.checkHealth(request.Context, true)
+	if !h.isReady() {
+		reason := "host is launching"
+		h.stateLock.RLock()
+		if h.shuttingDown {
+			reason = "host is shutting down"
+		}
+		h.stateLock.RUnlock()
+		checks = append([]vendors.HealthCheck{{Name: "host", Error: reason}}, checks...)
+	}
+	return healthResponse(checks)
+}
+
+func (h *APIHost) onDebugStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	h.stateLock.RLock()
+	bindingTypes := append([]string{}, h.bindingTypes...)
+	h.stateLock.RUnlock()
+	status := HostStatus{
+		SiteId:     h.siteId,
+		Ready:      h.isReady(),
+		Bindings:   bindingTypes,
+		Vendors:    make([]vendors.VendorStatus, 0, len(h.Vendors)),
+		LastReload: h.getReloadStatus(),
+	}
+	for _, v := range h.Vendors {
+		var vendorStatus vendors.VendorStatus
+		if s, ok := v.Vendor.(vendors.IStatusVendor); ok {
+			vendorStatus = s.GetStatus()
+		}
+		vendorStatus.Info = v.Vendor.GetInfo()
+		vendorStatus.LoopInterval = v.LoopInterval
+		status.Vendors = append(status.Vendors, vendorStatus)
+	}
+	data, _ := json.Marshal(status)
+	return v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	}
+}
+
+func (h *APIHost) checkHealth(ctx context.Context, includeProviders bool) []vendors.HealthCheck {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	checks := make([]vendors.HealthCheck, 0)
+	for _, v := range h.Vendors {
+		if s, ok := v.Vendor.(vendors.IStatusVendor); ok {
+			checks = append(checks, s.CheckHealth(ctx, includeProviders)...)
+		}
+	}
+	return checks
+}
+
+func (h *APIHost) isReady() bool {
+	h.stateLock.RLock()
+	defer h.stateLock.RUnlock()
+	return h.ready
+}
+
+func (h *APIHost) setReady(ready bool) {
+	h.stateLock.Lock()
+	defer h.stateLock.Unlock()
+	h.ready = ready
+}
+
+func healthResponse(checks []vendors.HealthCheck) v1alpha2.COAResponse {
+	report := HealthReport{
+		Status: "ok",
+		Checks: checks,
+	}
+	state := v1alpha2.OK
+	for _, c := range checks {
+		if c.Error != "" {
+			report.Status = "unavailable"
+			state = v1alpha2.ServiceUnavailable
+			break
+		}
+	}
+	data, _ := json.Marshal(report)
+	return v1alpha2.COAResponse{
+		State:       state,
+		Body:        data,
+		ContentType: "application/json",
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVendor struct {
+	vendors.Vendor
+}
+
+func (v *testVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  v.Vendor.Version,
+		Name:     "Test",
+		Producer: "Microsoft",
+	}
+}
+
+func (v *testVendor) GetEndpoints() []v1alpha2.Endpoint {
+	return []v1alpha2.Endpoint{}
+}
+
+type testPubSub struct {
+	healthErr error
+}
+
+func (p *testPubSub) Init(config providers.IProviderConfig) error {
+	return nil
+}
+func (p *testPubSub) Publish(topic string, message v1alpha2.Event) error {
+	return nil
+}
+func (p *testPubSub) Subscribe(topic string, handler v1alpha2.EventHandler) error {
+	return nil
+}
+func (p *testPubSub) CheckHealth(ctx context.Context) error {
+	return p.healthErr
+}
+
+func newTestHost(t *testing.T, pubsubProvider *testPubSub) *APIHost {
+	vendor := &testVendor{}
+	err := vendor.Init(vendors.VendorConfig{Type: "vendors.test", Route: "test"}, nil, nil, pubsubProvider)
+	assert.Nil(t, err)
+	return &APIHost{
+		Vendors: []VendorSpec{{Vendor: vendor, LoopInterval: 10}},
+		siteId:  "hq",
+	}
+}
+
+// stateManager checks the health of its state provider, like the managers of the Symphony API do
+type stateManager struct {
+	managers.Manager
+	StateProvider providers.IProvider
+}
+
+func (m *stateManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	m.StateProvider = providers["state"]
+	return m.Manager.Init(context, config, providers)
+}
+
+func (m *stateManager) CheckHealth(ctx context.Context) error {
+	return managers.CheckProvidersHealth(ctx, m.StateProvider)
+}
+
+type stateManagerFactory struct{}
+
+func (f stateManagerFactory) CreateManager(config managers.ManagerConfig) (managers.IManager, error) {
+	return &stateManager{}, nil
+}
+
+type testStateProvider struct {
+	healthErr error
+}
+
+func (p *testStateProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+
+func (p *testStateProvider) CheckHealth(ctx context.Context) error {
+	return p.healthErr
+}
+
+func TestHealthz(t *testing.T) {
+	h := newTestHost(t, &testPubSub{healthErr: errors.New("broker is down")})
+	resp := h.onHealthz(v1alpha2.COARequest{Context: context.Background()})
+	// liveness doesn't depend on the pub-sub connection
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var report HealthReport
+	assert.Nil(t, json.Unmarshal(resp.Body, &report))
+	assert.Equal(t, "ok", report.Status)
+}
+
+func TestHealthzWithFailingProvider(t *testing.T) {
+	stateProvider := &testStateProvider{}
+	vendor := &testVendor{}
+	err := vendor.Init(vendors.VendorConfig{
+		Type:     "vendors.test",
+		Route:    "test",
+		Managers: []managers.ManagerConfig{{Name: "state-manager", Type: "managers.test"}},
+	}, []managers.IManagerFactroy{stateManagerFactory{}}, map[string]map[string]providers.IProvider{
+		"state-manager": {"state": stateProvider},
+	}, &testPubSub{})
+	assert.Nil(t, err)
+	h := &APIHost{Vendors: []VendorSpec{{Vendor: vendor}}}
+
+	resp := h.onHealthz(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+
+	stateProvider.healthErr = errors.New("state store is unreachable")
+	resp = h.onHealthz(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.ServiceUnavailable, resp.State)
+	var report HealthReport
+	assert.Nil(t, json.Unmarshal(resp.Body, &report))
+	assert.Equal(t, "unavailable", report.Status)
+	assert.Equal(t, []vendors.HealthCheck{{Name: "vendors.test/state-manager", Error: "state store is unreachable"}}, report.Checks)
+}
+
+func TestReadyz(t *testing.T) {
+	pubsubProvider := &testPubSub{}
+	h := newTestHost(t, pubsubProvider)
+
+	resp := h.onReadyz(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.ServiceUnavailable, resp.State)
+	var report HealthReport
+	assert.Nil(t, json.Unmarshal(resp.Body, &report))
+	assert.Equal(t, "unavailable", report.Status)
+	assert.Equal(t, "host", report.Checks[0].Name)
+
+	h.setReady(true)
+	resp = h.onReadyz(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	report = HealthReport{}
+	assert.Nil(t, json.Unmarshal(resp.Body, &report))
+	assert.Equal(t, []vendors.HealthCheck{{Name: "vendors.test/pubsub"}}, report.Checks)
+
+	pubsubProvider.healthErr = errors.New("broker is down")
+	resp = h.onReadyz(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.ServiceUnavailable, resp.State)
+	report = HealthReport{}
+	assert.Nil(t, json.Unmarshal(resp.Body, &report))
+	assert.Equal(t, "broker is down", report.Checks[0].Error)
+}
+
+func TestDebugStatus(t *testing.T) {
+	h := newTestHost(t, &testPubSub{})
+	h.bindingTypes = []string{"bindings.http"}
+	resp := h.onDebugStatus(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var status HostStatus
+	assert.Nil(t, json.Unmarshal(resp.Body, &status))
+	assert.Equal(t, "hq", status.SiteId)
+	assert.False(t, status.Ready)
+	assert.Equal(t, []string{"bindings.http"}, status.Bindings)
+	assert.Equal(t, 1, len(status.Vendors))
+	assert.Equal(t, "vendors.test", status.Vendors[0].Type)
+	assert.Equal(t, "Test", status.Vendors[0].Info.Name)
+	assert.Equal(t, 10, status.Vendors[0].LoopInterval)
+}
+
+func TestHostEndpoints(t *testing.T) {
+	h := &APIHost{}
+	routes := []string{}
+	for _, e := range h.getHostEndpoints() {
+		assert.Equal(t, "", e.Version)
+		routes = append(routes, e.Route)
+	}
+	assert.Equal(t, []string{"healthz", "readyz", "debug/status"}, routes)
+}
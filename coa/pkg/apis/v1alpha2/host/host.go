@@ -7,6 +7,7 @@
 package host
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -14,9 +15,11 @@ import (
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	bindings "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/grpc"
 	http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/mqtt"
 	mf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	pf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providerfactory"
 	pv "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
@@ -27,10 +30,18 @@ import (
 
 var log = logger.NewLogger("coa.runtime")
 
+// DefaultShutdownTimeout is the time the work in flight is given to finish when the host shuts down
+const DefaultShutdownTimeout = 30 * time.Second
+
 type HostConfig struct {
 	SiteInfo v1alpha2.SiteInfo `json:"siteInfo"`
 	API      APIConfig         `json:"api"`
 	Bindings []BindingConfig   `json:"bindings"`
+	// ShutdownTimeout is the time the work in flight is given to finish when the host shuts down, such as "30s"
+	ShutdownTimeout string `json:"shutdownTimeout,omitempty"`
+	// ReloadInterval is how often the configuration file is checked for changes, such as "10s". The
+	// configuration isn't reloaded when it's not set.
+	ReloadInterval string `json:"reloadInterval,omitempty"`
 }
 type PubSubConfig struct {
 	Shared   bool              `json:"shared"`
@@ -54,18 +65,64 @@ type APIHost struct {
 	Vendors              []VendorSpec
 	Bindings             []bindings.IBinding
 	SharedPubSubProvider pv.IProvider
+	// ConfigFile is the file the configuration is read from, which is watched for changes when the
+	// configuration sets a reload interval
+	ConfigFile      string
+	siteId          string
+	bindingTypes    []string
+	ready           bool
+	stateLock       sync.RWMutex
+	pubsubProviders []pubsub.IPubSubProvider
+	shutdownTimeout time.Duration
+	shuttingDown    bool
+	done            chan struct{}
+	reloadLock      sync.Mutex
+	running         HostConfig
+	reloadInterval  time.Duration
+	lastReload      *ReloadStatus
 }
 
 func (h *APIHost) Launch(config HostConfig,
 	vendorFactories []vendors.IVendorFactory,
 	managerFactories []mf.IManagerFactroy,
 	providerFactories []pf.IProviderFactory, wait bool) error {
+	h.stateLock.Lock()
+	if h.shuttingDown {
+		// the host was shut down before it was launched
+		h.stateLock.Unlock()
+		return nil
+	}
 	h.Vendors = make([]VendorSpec, 0)
 	h.Bindings = make([]bindings.IBinding, 0)
+	h.siteId = config.SiteInfo.SiteId
+	h.ready = false
+	h.done = make(chan struct{})
+	h.stateLock.Unlock()
 	log.Info("--- launching COA host ---")
 	if config.SiteInfo.SiteId == "" {
 		return v1alpha2.NewCOAError(nil, "siteId is not specified", v1alpha2.BadConfig)
 	}
+	shutdownTimeout, err := parseDuration(config.ShutdownTimeout, DefaultShutdownTimeout)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid shutdown timeout '%s'", config.ShutdownTimeout), v1alpha2.BadConfig)
+	}
+	reloadInterval, err := parseDuration(config.ReloadInterval, 0)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid reload interval '%s'", config.ReloadInterval), v1alpha2.BadConfig)
+	}
+	// copied before the vendors and bindings get the configuration, to compare with reloaded configurations
+	running, err := copyHostConfig(config)
+	if err != nil {
+		return err
+	}
+	h.reloadLock.Lock()
+	h.running = running
+	h.reloadLock.Unlock()
+	h.stateLock.Lock()
+	h.shutdownTimeout = shutdownTimeout
+	h.reloadInterval = reloadInterval
+	done := h.done
+	h.stateLock.Unlock()
 	for _, v := range config.API.Vendors {
 		v.SiteInfo = config.SiteInfo
 		created := false
@@ -92,6 +149,7 @@ func (h *APIHost) Launch(config HostConfig,
 							if config.API.PubSub.Shared {
 								h.SharedPubSubProvider = pubsubProvider
 							}
+							h.addPubSubProvider(pubsubProvider)
 							break
 						}
 					}
@@ -126,7 +184,9 @@ func (h *APIHost) Launch(config HostConfig,
 				if err != nil {
 					return err
 				}
+				h.stateLock.Lock()
 				h.Vendors = append(h.Vendors, VendorSpec{Vendor: vendor, LoopInterval: v.LoopInterval})
+				h.stateLock.Unlock()
 				created = true
 				break
 			}
@@ -150,12 +210,8 @@ func (h *APIHost) Launch(config HostConfig,
 				v.Vendor.SetEvaluationContext(evaluationContext)
 			}
 		}
-		var wg sync.WaitGroup
 		for _, v := range h.Vendors {
 			if v.LoopInterval > 0 {
-				if wait {
-					wg.Add(1)
-				}
 				go func(v VendorSpec) {
 					v.Vendor.RunLoop(time.Duration(v.LoopInterval) * time.Second)
 				}(v)
@@ -166,17 +222,16 @@ func (h *APIHost) Launch(config HostConfig,
 			for _, v := range h.Vendors {
 				endpoints = append(endpoints, v.Vendor.GetEndpoints()...)
 			}
+			// health probes and diagnostics are served on HTTP bindings only
+			httpEndpoints := append(h.getHostEndpoints(), endpoints...)
 
 			for _, b := range config.Bindings {
 				switch b.Type {
 				case "bindings.http":
-					if wait {
-						wg.Add(1)
-					}
 					var binding bindings.IBinding
 					var err error
 					if h.SharedPubSubProvider != nil {
-						binding, err = h.launchHTTP(b.Config, endpoints, h.SharedPubSubProvider.(pubsub.IPubSubProvider))
+						binding, err = h.launchHTTP(b.Config, httpEndpoints, h.SharedPubSubProvider.(pubsub.IPubSubProvider))
 					} else {
 						var bindingPubsub pv.IProvider
 						for _, providerFactory := range providerFactories {
@@ -187,29 +242,45 @@ func (h *APIHost) Launch(config HostConfig,
 								return err
 							}
 							bindingPubsub = mProvider
+							h.addPubSubProvider(bindingPubsub)
 							break
 						}
-						binding, err = h.launchHTTP(b.Config, endpoints, bindingPubsub.(pubsub.IPubSubProvider))
+						binding, err = h.launchHTTP(b.Config, httpEndpoints, bindingPubsub.(pubsub.IPubSubProvider))
 					}
 					if err != nil {
 						return err
 					}
-					h.Bindings = append(h.Bindings, binding)
+					h.addBinding(b.Type, binding)
 				case "bindings.mqtt":
-					if wait {
-						wg.Add(1)
-					}
 					binding, err := h.launchMQTT(b.Config, endpoints)
 					if err != nil {
 						return err
 					}
-					h.Bindings = append(h.Bindings, binding)
+					h.addBinding(b.Type, binding)
+				case "bindings.grpc":
+					binding, err := h.launchGRPC(b.Config, endpoints)
+					if err != nil {
+						return err
+					}
+					h.addBinding(b.Type, binding)
 				default:
 					return v1alpha2.NewCOAError(nil, fmt.Sprintf("binding type '%s' is not recognized", b.Type), v1alpha2.BadConfig)
 				}
 			}
 		}
-		wg.Wait()
+		h.setReady(true)
+		if h.ConfigFile != "" && reloadInterval > 0 {
+			// hashed before Launch returns, so that changes made after it are not missed
+			lastHash, err := hashFile(h.ConfigFile)
+			if err != nil {
+				log.Errorf("failed to read configuration file %s: %+v", h.ConfigFile, err)
+			}
+			go h.watchConfigFile(h.ConfigFile, lastHash, done)
+		}
+		if wait {
+			// the host runs until it's shut down
+			<-h.done
+		}
 		return nil
 	} else {
 		return v1alpha2.NewCOAError(nil, "no vendors are found", v1alpha2.MissingConfig)
@@ -226,7 +297,7 @@ func (h *APIHost) launchHTTP(config interface{}, endpoints []v1alpha2.Endpoint,
 	if err != nil {
 		return nil, err
 	}
-	binding := http.HttpBinding{}
+	binding := &http.HttpBinding{}
 	return binding, binding.Launch(httpConfig, endpoints, pubsubProvider)
 }
 func (h *APIHost) launchMQTT(config interface{}, endpoints []v1alpha2.Endpoint) (bindings.IBinding, error) {
@@ -239,6 +310,99 @@ func (h *APIHost) launchMQTT(config interface{}, endpoints []v1alpha2.Endpoint)
 	if err != nil {
 		return nil, err
 	}
-	binding := mqtt.MQTTBinding{}
+	binding := &mqtt.MQTTBinding{}
 	return binding, binding.Launch(mqttConfig, endpoints)
 }
+
+func (h *APIHost) launchGRPC(config interface{}, endpoints []v1alpha2.Endpoint) (bindings.IBinding, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	grpcConfig := grpc.GRPCBindingConfig{}
+	err = json.Unmarshal(data, &grpcConfig)
+	if err != nil {
+		return nil, err
+	}
+	binding := &grpc.GRPCBinding{}
+	return binding, binding.Launch(grpcConfig, endpoints)
+}
+
+// Shutdown stops the host. It reports the host as not ready, stops the bindings from accepting new
+// requests and waits for the requests in flight, stops the loops and the managers of the vendors, and
+// then stops the pub-sub providers, which release the events that aren't handled yet. When ctx has no
+// deadline, the host waits for the work in flight for the configured shutdown timeout. Shutdown
+// returns the first error it runs into, after trying to stop everything else.
+func (h *APIHost) Shutdown(ctx context.Context) error {
+	h.stateLock.Lock()
+	if h.shuttingDown {
+		h.stateLock.Unlock()
+		return nil
+	}
+	h.shuttingDown = true
+	h.ready = false
+	timeout := h.shutdownTimeout
+	bindings := h.Bindings
+	vendors := h.Vendors
+	pubsubProviders := h.pubsubProviders
+	done := h.done
+	h.stateLock.Unlock()
+
+	if _, ok := ctx.Deadline(); !ok {
+		if timeout <= 0 {
+			timeout = DefaultShutdownTimeout
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	log.Info("--- shutting down COA host ---")
+	var ret error
+	record := func(err error) {
+		if err != nil {
+			log.Errorf("failed to shut down COA host gracefully: %+v", err)
+			if ret == nil {
+				ret = err
+			}
+		}
+	}
+	for _, b := range bindings {
+		record(b.Shutdown(ctx))
+	}
+	for _, v := range vendors {
+		record(v.Vendor.Shutdown(ctx))
+	}
+	for _, p := range pubsubProviders {
+		if s, ok := p.(pubsub.IShutdownProvider); ok {
+			record(s.Shutdown(ctx))
+		}
+	}
+	record(observability.Flush(ctx))
+	if done != nil {
+		close(done)
+	}
+	log.Info("--- COA host is shut down ---")
+	return ret
+}
+
+func (h *APIHost) addBinding(bindingType string, binding bindings.IBinding) {
+	h.stateLock.Lock()
+	defer h.stateLock.Unlock()
+	h.Bindings = append(h.Bindings, binding)
+	h.bindingTypes = append(h.bindingTypes, bindingType)
+}
+
+func (h *APIHost) addPubSubProvider(provider pv.IProvider) {
+	p, ok := provider.(pubsub.IPubSubProvider)
+	if !ok {
+		return
+	}
+	h.stateLock.Lock()
+	defer h.stateLock.Unlock()
+	for _, existing := range h.pubsubProviders {
+		if existing == p {
+			return
+		}
+	}
+	h.pubsubProviders = append(h.pubsubProviders, p)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package host
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings"
+	mf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	pf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providerfactory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVendorFactory struct {
+	vendor *testVendor
+}
+
+func (f testVendorFactory) CreateVendor(config vendors.VendorConfig) (vendors.IVendor, error) {
+	return f.vendor, nil
+}
+
+type testBinding struct {
+	shutdowns *[]string
+}
+
+func (b *testBinding) Shutdown(ctx context.Context) error {
+	*b.shutdowns = append(*b.shutdowns, "binding")
+	return nil
+}
+
+type orderedPubSub struct {
+	testPubSub
+	shutdowns *[]string
+}
+
+func (p *orderedPubSub) Shutdown(ctx context.Context) error {
+	*p.shutdowns = append(*p.shutdowns, "pubsub")
+	return nil
+}
+
+func TestLaunchReturnsOnShutdown(t *testing.T) {
+	h := &APIHost{}
+	launched := make(chan error)
+	go func() {
+		launched <- h.Launch(HostConfig{
+			SiteInfo: v1alpha2.SiteInfo{SiteId: "hq"},
+			API: APIConfig{
+				Vendors: []vendors.VendorConfig{{Type: "vendors.test", Route: "test"}},
+			},
+		}, []vendors.IVendorFactory{testVendorFactory{vendor: &testVendor{}}}, []mf.IManagerFactroy{}, []pf.IProviderFactory{}, true)
+	}()
+	assert.Eventually(t, h.isReady, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, h.Shutdown(context.Background()))
+	select {
+	case err := <-launched:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Launch didn't return")
+	}
+	assert.False(t, h.isReady())
+	// shutting down again does nothing
+	assert.Nil(t, h.Shutdown(context.Background()))
+}
+
+func TestShutdownOrder(t *testing.T) {
+	shutdowns := []string{}
+	pubsubProvider := &orderedPubSub{shutdowns: &shutdowns}
+	h := newTestHost(t, &pubsubProvider.testPubSub)
+	h.Bindings = []bindings.IBinding{&testBinding{shutdowns: &shutdowns}}
+	h.addPubSubProvider(pubsubProvider)
+	h.addPubSubProvider(pubsubProvider)
+	h.setReady(true)
+
+	assert.Nil(t, h.Shutdown(context.Background()))
+	assert.Equal(t, []string{"binding", "pubsub"}, shutdowns)
+
+	resp := h.onReadyz(v1alpha2.COARequest{Context: context.Background()})
+	assert.Equal(t, v1alpha2.ServiceUnavailable, resp.State)
+	assert.Contains(t, string(resp.Body), "host is shutting down")
+}
+
+func TestLaunchWithInvalidShutdownTimeout(t *testing.T) {
+	h := &APIHost{}
+	err := h.Launch(HostConfig{
+		SiteInfo:        v1alpha2.SiteInfo{SiteId: "hq"},
+		ShutdownTimeout: "soon",
+	}, nil, nil, nil, false)
+	assert.NotNil(t, err)
+	cErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, cErr.State)
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package host
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+)
+
+// ReloadStatus is the outcome of the last configuration reload of a host
+type ReloadStatus struct {
+	Time    time.Time               `json:"time"`
+	Error   string                  `json:"error,omitempty"`
+	Changes []v1alpha2.ConfigChange `json:"changes,omitempty"`
+}
+
+// Reload applies a new configuration to a running host. Vendors apply the changes of their managers and
+// providers that can be made live, and HTTP bindings apply changes of their middleware pipelines. The
+// shutdown timeout and the reload interval are applied as well. Other changes, such as adding a vendor
+// or changing the site, need a restart. They're logged and returned as rejected, and are reported again
+// by every reload until the host is restarted.
+func (h *APIHost) Reload(config HostConfig) []v1alpha2.ConfigChange {
+	h.reloadLock.Lock()
+	defer h.reloadLock.Unlock()
+	changes := h.reload(config)
+	for _, c := range changes {
+		if c.Applied {
+			log.Infof("applied configuration change of %s", c.Path)
+		} else {
+			log.Warnf("configuration change of %s isn't applied, restart the host to apply it: %s", c.Path, c.Reason)
+		}
+	}
+	h.setReloadStatus(ReloadStatus{Time: time.Now().UTC(), Changes: changes})
+	return changes
+}
+
+// reload is called with the reload lock held
+func (h *APIHost) reload(config HostConfig) []v1alpha2.ConfigChange {
+	changes := make([]v1alpha2.ConfigChange, 0)
+	running := h.running
+
+	if !v1alpha2.ConfigEqual(config.SiteInfo, running.SiteInfo) {
+		changes = append(changes, v1alpha2.RejectedChange("siteInfo", "the site is set when the host is launched"))
+	}
+	if !v1alpha2.ConfigEqual(config.API.PubSub, running.API.PubSub) {
+		changes = append(changes, v1alpha2.RejectedChange("api.pubsub", "pub-sub providers are created when the host is launched"))
+	}
+	if config.ShutdownTimeout != running.ShutdownTimeout {
+		timeout, err := parseDuration(config.ShutdownTimeout, DefaultShutdownTimeout)
+		if err != nil {
+			changes = append(changes, v1alpha2.RejectedChange("shutdownTimeout", err.Error()))
+		} else {
+			h.stateLock.Lock()
+			h.shutdownTimeout = timeout
+			h.stateLock.Unlock()
+			h.running.ShutdownTimeout = config.ShutdownTimeout
+			changes = append(changes, v1alpha2.AppliedChange("shutdownTimeout"))
+		}
+	}
+	if config.ReloadInterval != running.ReloadInterval {
+		interval, err := parseDuration(config.ReloadInterval, 0)
+		if err != nil {
+			changes = append(changes, v1alpha2.RejectedChange("reloadInterval", err.Error()))
+		} else if interval <= 0 {
+			changes = append(changes, v1alpha2.RejectedChange("reloadInterval", "reloading can't be turned off live"))
+		} else {
+			h.stateLock.Lock()
+			h.reloadInterval = interval
+			h.stateLock.Unlock()
+			h.running.ReloadInterval = config.ReloadInterval
+			changes = append(changes, v1alpha2.AppliedChange("reloadInterval"))
+		}
+	}
+	changes = append(changes, h.reloadVendors(config)...)
+	changes = append(changes, h.reloadBindings(config)...)
+	return changes
+}
+
+func (h *APIHost) reloadVendors(config HostConfig) []v1alpha2.ConfigChange {
+	changes := make([]v1alpha2.ConfigChange, 0)
+	existing := make(map[string]int, len(h.running.API.Vendors))
+	for i, v := range h.running.API.Vendors {
+		existing[vendorKey(v)] = i
+	}
+	found := make(map[string]bool, len(config.API.Vendors))
+	for _, v := range config.API.Vendors {
+		key := vendorKey(v)
+		path := fmt.Sprintf("api.vendors[%s]", key)
+		found[key] = true
+		i, ok := existing[key]
+		if !ok || i >= len(h.Vendors) {
+			changes = append(changes, v1alpha2.RejectedChange(path, "adding a vendor requires a restart"))
+			continue
+		}
+		running := h.running.API.Vendors[i]
+		v.SiteInfo = running.SiteInfo
+		if v.Type != running.Type {
+			changes = append(changes, v1alpha2.RejectedChange(path+".type", "changing the type of a vendor requires a restart"))
+			continue
+		}
+		if vendor, ok := h.Vendors[i].Vendor.(vendors.IReconfigurableVendor); ok {
+			for _, c := range vendor.Reconfigure(v) {
+				c.Path = path + "." + c.Path
+				changes = append(changes, c)
+			}
+		} else if !v1alpha2.ConfigEqual(v, running) {
+			changes = append(changes, v1alpha2.RejectedChange(path, "the vendor can't be reconfigured"))
+		}
+	}
+	for _, v := range h.running.API.Vendors {
+		if !found[vendorKey(v)] {
+			changes = append(changes, v1alpha2.RejectedChange(fmt.Sprintf("api.vendors[%s]", vendorKey(v)), "removing a vendor requires a restart"))
+		}
+	}
+	return changes
+}
+
+func (h *APIHost) reloadBindings(config HostConfig) []v1alpha2.ConfigChange {
+	changes := make([]v1alpha2.ConfigChange, 0)
+	h.stateLock.RLock()
+	launched := h.Bindings
+	h.stateLock.RUnlock()
+	for i, b := range config.Bindings {
+		path := fmt.Sprintf("bindings[%d]", i)
+		if i >= len(h.running.Bindings) || i >= len(launched) {
+			changes = append(changes, v1alpha2.RejectedChange(path, "adding a binding requires a restart"))
+			continue
+		}
+		running := h.running.Bindings[i]
+		if b.Type != running.Type {
+			changes = append(changes, v1alpha2.RejectedChange(path+".type", "changing the type of a binding requires a restart"))
+			continue
+		}
+		if v1alpha2.ConfigEqual(b.Config, running.Config) {
+			continue
+		}
+		binding, ok := launched[i].(*http.HttpBinding)
+		if !ok {
+			changes = append(changes, v1alpha2.RejectedChange(path+".config", fmt.Sprintf("binding type '%s' can't be reconfigured", b.Type)))
+			continue
+		}
+		httpConfig := http.HttpBindingConfig{}
+		data, _ := json.Marshal(b.Config)
+		if err := json.Unmarshal(data, &httpConfig); err != nil {
+			changes = append(changes, v1alpha2.RejectedChange(path+".config", err.Error()))
+			continue
+		}
+		for _, c := range binding.Reconfigure(httpConfig) {
+			c.Path = path + "." + c.Path
+			changes = append(changes, c)
+		}
+	}
+	for i := len(config.Bindings); i < len(h.running.Bindings); i++ {
+		changes = append(changes, v1alpha2.RejectedChange(fmt.Sprintf("bindings[%d]", i), "removing a binding requires a restart"))
+	}
+	return changes
+}
+
+// watchConfigFile reloads the configuration whenever the content of the file changes, which includes
+// a ConfigMap mounted as a volume. The file is checked every reload interval until the host is shut down.
+// lastHash is the hash of the content the host is launched with.
+func (h *APIHost) watchConfigFile(path string, lastHash [sha256.Size]byte, done chan struct{}) {
+	for {
+		h.stateLock.RLock()
+		interval := h.reloadInterval
+		h.stateLock.RUnlock()
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			// the file may be replaced right now
+			log.Debugf("failed to read configuration file %s: %+v", path, err)
+			continue
+		}
+		if hash == lastHash {
+			continue
+		}
+		lastHash = hash
+		log.Infof("configuration file %s changed, reloading", path)
+		config, err := readHostConfig(path)
+		if err != nil {
+			log.Errorf("failed to reload configuration file %s: %+v", path, err)
+			h.setReloadStatus(ReloadStatus{Time: time.Now().UTC(), Error: err.Error()})
+			continue
+		}
+		h.Reload(config)
+	}
+}
+
+func (h *APIHost) setReloadStatus(status ReloadStatus) {
+	h.stateLock.Lock()
+	defer h.stateLock.Unlock()
+	h.lastReload = &status
+}
+
+func (h *APIHost) getReloadStatus() *ReloadStatus {
+	h.stateLock.RLock()
+	defer h.stateLock.RUnlock()
+	return h.lastReload
+}
+
+func vendorKey(config vendors.VendorConfig) string {
+	if config.Route != "" {
+		return config.Route
+	}
+	return config.Type
+}
+
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	ret, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid duration '%s'", value), v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+func readHostConfig(path string) (HostConfig, error) {
+	config := HostConfig{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return config, v1alpha2.NewCOAError(err, "configuration file isn't valid JSON", v1alpha2.BadConfig)
+	}
+	return config, nil
+}
+
+// copyHostConfig copies a host configuration through JSON, so that changes made to the original while
+// the host runs don't show up as differences when the configuration is reloaded
+func copyHostConfig(config HostConfig) (HostConfig, error) {
+	ret := HostConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to copy host configuration", v1alpha2.BadConfig)
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to copy host configuration", v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	mf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	pf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providerfactory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+)
+
+func reloadTestHostConfig() HostConfig {
+	return HostConfig{
+		SiteInfo: v1alpha2.SiteInfo{SiteId: "hq"},
+		API: APIConfig{
+			Vendors: []vendors.VendorConfig{{Type: "vendors.test", Route: "test"}},
+		},
+	}
+}
+
+func launchReloadTestHost(t *testing.T, h *APIHost, config HostConfig) {
+	err := h.Launch(config, []vendors.IVendorFactory{testVendorFactory{vendor: &testVendor{}}}, []mf.IManagerFactroy{}, []pf.IProviderFactory{}, false)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		h.Shutdown(context.Background())
+	})
+}
+
+func TestReload(t *testing.T) {
+	h := &APIHost{}
+	launchReloadTestHost(t, h, reloadTestHostConfig())
+	assert.Empty(t, h.Reload(reloadTestHostConfig()))
+
+	config := reloadTestHostConfig()
+	config.SiteInfo.SiteId = "branch"
+	config.ShutdownTimeout = "5s"
+	config.API.Vendors = append(config.API.Vendors, vendors.VendorConfig{Type: "vendors.other", Route: "other"})
+	changes := h.Reload(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("siteInfo", "the site is set when the host is launched"),
+		v1alpha2.AppliedChange("shutdownTimeout"),
+		v1alpha2.RejectedChange("api.vendors[other]", "adding a vendor requires a restart"),
+	}, changes)
+	assert.Equal(t, 5*time.Second, h.shutdownTimeout)
+
+	status := h.getReloadStatus()
+	assert.NotNil(t, status)
+	assert.Equal(t, changes, status.Changes)
+
+	// rejected changes are reported until the host is restarted
+	changes = h.Reload(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("siteInfo", "the site is set when the host is launched"),
+		v1alpha2.RejectedChange("api.vendors[other]", "adding a vendor requires a restart"),
+	}, changes)
+}
+
+func TestReloadCantTurnOffReloading(t *testing.T) {
+	h := &APIHost{}
+	config := reloadTestHostConfig()
+	config.ReloadInterval = "1m"
+	launchReloadTestHost(t, h, config)
+
+	config.ReloadInterval = ""
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("reloadInterval", "reloading can't be turned off live"),
+	}, h.Reload(config))
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "symphony-api.json")
+	config := reloadTestHostConfig()
+	config.ReloadInterval = "10ms"
+	writeHostConfig(t, path, config)
+
+	h := &APIHost{ConfigFile: path}
+	launchReloadTestHost(t, h, config)
+
+	config.ShutdownTimeout = "5s"
+	writeHostConfig(t, path, config)
+	assert.Eventually(t, func() bool {
+		return h.getReloadStatus() != nil
+	}, time.Second, 10*time.Millisecond)
+	status := h.getReloadStatus()
+	assert.Empty(t, status.Error)
+	assert.Equal(t, []v1alpha2.ConfigChange{v1alpha2.AppliedChange("shutdownTimeout")}, status.Changes)
+
+	// a file that can't be read is reported, and the host keeps its configuration
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0644))
+	assert.Eventually(t, func() bool {
+		return h.getReloadStatus().Error != ""
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 5*time.Second, h.shutdownTimeout)
+}
+
+func writeHostConfig(t *testing.T, path string, config HostConfig) {
+	data, err := json.Marshal(config)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, data, 0644))
+}
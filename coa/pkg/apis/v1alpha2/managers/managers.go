@@ -7,6 +7,11 @@
 package managers
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -37,6 +42,37 @@ type IManager interface {
 	Init(context *contexts.VendorContext, config ManagerConfig, providers map[string]providers.IProvider) error
 }
 
+// IHealthChecker is implemented by managers that can check whether they are able to work. Managers
+// that don't implement it are healthy once they are initialized.
+type IHealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// CheckProvidersHealth checks the providers that implement providers.IHealthCheckProvider and returns the
+// first failure. Managers implement IHealthChecker with it to check the providers they can't work without.
+func CheckProvidersHealth(ctx context.Context, ps ...interface{}) error {
+	for _, p := range ps {
+		if c, ok := p.(providers.IHealthCheckProvider); ok {
+			if err := c.CheckHealth(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IShutdownable is implemented by managers that need to finish their work before the host exits.
+// Shutdown stops accepting new work and waits for the work in flight until ctx is done.
+type IShutdownable interface {
+	Shutdown(ctx context.Context) error
+}
+
+// IReconfigurable is implemented by managers that can apply new properties without being created again.
+// Managers that read their properties only when they're initialized shouldn't implement it.
+type IReconfigurable interface {
+	Reconfigure(config ManagerConfig) error
+}
+
 type ISchedulable interface {
 	Poll() []error
 	Reconcil() []error
@@ -55,12 +91,17 @@ type Manager struct {
 	VendorContext *contexts.VendorContext
 	Context       *contexts.ManagerContext
 	Config        ManagerConfig
+	work          *workTracker
+	// properties holds the current properties, which SetProperties swaps while the manager runs
+	properties atomic.Value
 }
 
 func (m *Manager) Init(context *contexts.VendorContext, config ManagerConfig, providers map[string]providers.IProvider) error {
 	m.VendorContext = context
 	m.Context = &contexts.ManagerContext{}
 	m.Config = config
+	m.work = &workTracker{}
+	m.SetProperties(config.Properties)
 	err := m.Context.Init(m.VendorContext, nil)
 	for _, p := range providers {
 		if c, ok := p.(contexts.IWithManagerContext); ok {
@@ -70,6 +111,41 @@ func (m *Manager) Init(context *contexts.VendorContext, config ManagerConfig, pr
 	m.Context.Logger.Debugf(" M (%s): initalize manager type '%s'", config.Name, config.Type)
 	return err
 }
+
+// Properties returns the current properties of the manager. Managers that implement IReconfigurable read
+// their properties through it while they run, as Config.Properties keeps the properties the manager was
+// initialized with. The returned map must be treated as read-only.
+func (m *Manager) Properties() map[string]string {
+	if properties, ok := m.properties.Load().(map[string]string); ok {
+		return properties
+	}
+	return m.Config.Properties
+}
+
+// SetProperties swaps the properties of the manager. The map mustn't be changed afterwards.
+func (m *Manager) SetProperties(properties map[string]string) {
+	m.properties.Store(properties)
+}
+
+// CheckProviderProperties returns an error when new properties change the properties that select the
+// providers of the manager, such as providers.state, as the providers are resolved when the manager is
+// initialized. IReconfigurable managers call it before they apply new properties.
+func (m *Manager) CheckProviderProperties(properties map[string]string) error {
+	current := m.Properties()
+	keys := make(map[string]bool)
+	for k := range current {
+		keys[k] = true
+	}
+	for k := range properties {
+		keys[k] = true
+	}
+	for k := range keys {
+		if strings.HasPrefix(k, "providers.") && current[k] != properties[k] {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("changing the '%s' property requires a restart", k), v1alpha2.BadConfig)
+		}
+	}
+	return nil
+}
 func GetQueueProvider(config ManagerConfig, providers map[string]providers.IProvider) (queue.IQueueProvider, error) {
 	queueProviderName, ok := config.Properties[v1alpha2.ProviderQueue]
 	if !ok {
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package managers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// workTracker counts the work in flight in a manager so that shutting down can wait for it
+type workTracker struct {
+	lock     sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+}
+
+// BeginWork registers a piece of work, such as a reconcile or an activation, that a shutdown waits
+// for. It returns a ServiceUnavailable error once the manager is shut down, in which case the work
+// shouldn't start. Every successful call must be paired with a call to EndWork.
+func (m *Manager) BeginWork() error {
+	if m.work == nil {
+		return nil
+	}
+	m.work.lock.Lock()
+	defer m.work.lock.Unlock()
+	if m.work.closed {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("manager '%s' is shutting down", m.Config.Name), v1alpha2.ServiceUnavailable)
+	}
+	m.work.inFlight.Add(1)
+	return nil
+}
+
+// EndWork marks the work registered by BeginWork as finished
+func (m *Manager) EndWork() {
+	if m.work != nil {
+		m.work.inFlight.Done()
+	}
+}
+
+// Shutdown stops the manager from accepting new work and waits for the work in flight. It returns an
+// error if ctx is done before the work is finished.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if m.work == nil {
+		return nil
+	}
+	m.work.lock.Lock()
+	m.work.closed = true
+	m.work.lock.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		m.work.inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("manager '%s' didn't finish its work in flight", m.Config.Name), v1alpha2.InternalError)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultOTLPHTTPEndpoint = "localhost:4318"
+	defaultOTLPHTTPPath     = "/v1/traces"
+	otlpHTTPTimeout         = 10 * time.Second
+)
+
+// otlpHTTPClient uploads spans to an OTLP collector with the binary protobuf encoding of OTLP/HTTP
+type otlpHTTPClient struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (c *otlpHTTPClient) Start(ctx context.Context) error {
+	c.client = &http.Client{Timeout: otlpHTTPTimeout}
+	return nil
+}
+
+func (c *otlpHTTPClient) Stop(ctx context.Context) error {
+	if c.client != nil {
+		c.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (c *otlpHTTPClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	data, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: protoSpans,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector at %s returned %s", c.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package exporters
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseOTLPEndpoint(t *testing.T) {
+	endpoint, path, insecure, err := ParseOTLPEndpoint("http://collector:4318/v1/traces")
+	assert.Nil(t, err)
+	assert.Equal(t, "collector:4318", endpoint)
+	assert.Equal(t, "/v1/traces", path)
+	assert.True(t, insecure)
+
+	endpoint, path, insecure, err = ParseOTLPEndpoint("collector:4317")
+	assert.Nil(t, err)
+	assert.Equal(t, "collector:4317", endpoint)
+	assert.Equal(t, "", path)
+	assert.False(t, insecure)
+
+	_, _, insecure, err = ParseOTLPEndpoint("https://collector:4318")
+	assert.Nil(t, err)
+	assert.False(t, insecure)
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	var request coltracepb.ExportTraceServiceRequest
+	var apiKey, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		apiKey = r.Header.Get("api-key")
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		assert.Nil(t, proto.Unmarshal(data, &request))
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPHTTPExporter(server.URL, map[string]string{"api-key": "secret"}, false)
+	assert.Nil(t, err)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := provider.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	assert.Nil(t, provider.Shutdown(context.Background()))
+
+	assert.Equal(t, "secret", apiKey)
+	assert.Equal(t, "application/x-protobuf", contentType)
+	assert.Equal(t, 1, len(request.ResourceSpans))
+	assert.Equal(t, "test-span", request.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+}
+
+func TestOTLPHTTPExporterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &otlpHTTPClient{url: server.URL + defaultOTLPHTTPPath}
+	assert.Nil(t, client.Start(context.Background()))
+	err := client.UploadTraces(context.Background(), nil)
+	assert.NotNil(t, err)
+}
@@ -7,10 +7,16 @@
 package exporters
 
 import (
+	"context"
 	"io"
+	"net/url"
+	"strings"
+
 	// "log"
 	// "os"
 
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	stdout "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/exporters/zipkin"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -35,3 +41,62 @@ func NewZipkinExporter(url string, sampleRate string) (sdktrace.SpanExporter, er
 	// return zipkin.New(url, zipkin.WithLogger(logger))
 	return zipkin.New(url)
 }
+
+// NewOTLPgRPCExporter creates an exporter that sends spans to an OTLP collector over gRPC. The URL is
+// the collector endpoint, such as localhost:4317 or http://localhost:4317, and defaults to
+// localhost:4317 when it's empty.
+func NewOTLPgRPCExporter(backendUrl string, headers map[string]string, insecure bool) (sdktrace.SpanExporter, error) {
+	endpoint, _, plain, err := ParseOTLPEndpoint(backendUrl)
+	if err != nil {
+		return nil, err
+	}
+	options := []otlptracegrpc.Option{}
+	if endpoint != "" {
+		options = append(options, otlptracegrpc.WithEndpoint(endpoint))
+	}
+	if insecure || plain {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	if len(headers) > 0 {
+		options = append(options, otlptracegrpc.WithHeaders(headers))
+	}
+	return otlptracegrpc.New(context.Background(), options...)
+}
+
+// NewOTLPHTTPExporter creates an exporter that sends spans to an OTLP collector over HTTP. The URL is
+// the collector URL, such as http://localhost:4318/v1/traces, and defaults to
+// https://localhost:4318/v1/traces when it's empty.
+func NewOTLPHTTPExporter(backendUrl string, headers map[string]string, insecure bool) (sdktrace.SpanExporter, error) {
+	endpoint, path, plain, err := ParseOTLPEndpoint(backendUrl)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == "" {
+		endpoint = defaultOTLPHTTPEndpoint
+	}
+	if path == "" || path == "/" {
+		path = defaultOTLPHTTPPath
+	}
+	scheme := "https"
+	if insecure || plain {
+		scheme = "http"
+	}
+	client := &otlpHTTPClient{
+		url:     scheme + "://" + endpoint + path,
+		headers: headers,
+	}
+	return otlptrace.New(context.Background(), client)
+}
+
+// ParseOTLPEndpoint splits the URL of an OTLP collector into the endpoint (host and port) and the
+// path. A URL without a scheme is taken as an endpoint. insecure is true for http:// URLs.
+func ParseOTLPEndpoint(backendUrl string) (endpoint string, path string, insecure bool, err error) {
+	if !strings.Contains(backendUrl, "://") {
+		return backendUrl, "", false, nil
+	}
+	u, err := url.Parse(backendUrl)
+	if err != nil {
+		return "", "", false, err
+	}
+	return u.Host, u.Path, u.Scheme == "http", nil
+}
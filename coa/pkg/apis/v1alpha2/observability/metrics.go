@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package observability
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/unit"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const meterName = "symphony"
+
+var (
+	metricsLock sync.Mutex
+	metrics     *Metrics
+)
+
+// Metrics records the metrics of a Symphony host and exposes them in the Prometheus format. The
+// operation metrics come from the spans started with StartSpan: each span is counted and timed by
+// its name, which is the component that started it, and its "method" attribute.
+type Metrics struct {
+	MeterProvider *sdkmetric.MeterProvider
+	Registry      *prometheus.Registry
+
+	operations        instrument.Int64Counter
+	operationDuration instrument.Float64Histogram
+	httpRequests      instrument.Int64Counter
+	httpDuration      instrument.Float64Histogram
+	queueDepthFuncs   []func() map[string]int
+}
+
+// InitMetrics sets up the meter provider of the process. Metrics are set up once, and later calls
+// return the same metrics.
+func InitMetrics() (*Metrics, error) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	if metrics != nil {
+		return metrics, nil
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	exporter, err := otelprom.New(otelprom.WithRegisterer(registry), otelprom.WithoutScopeInfo())
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to create Prometheus exporter", v1alpha2.InternalError)
+	}
+	ret := &Metrics{
+		MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(exporter), sdkmetric.WithResource(symphonyResource())),
+		Registry:      registry,
+	}
+	meter := ret.MeterProvider.Meter(meterName)
+	if ret.operations, err = meter.Int64Counter("symphony_operations",
+		instrument.WithDescription("Number of operations of managers and providers, by component, method and status")); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to create metric", v1alpha2.InternalError)
+	}
+	if ret.operationDuration, err = meter.Float64Histogram("symphony_operation_duration",
+		instrument.WithDescription("Duration of operations of managers and providers, by component, method and status"),
+		instrument.WithUnit(string(unit.Milliseconds))); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to create metric", v1alpha2.InternalError)
+	}
+	if ret.httpRequests, err = meter.Int64Counter("symphony_http_requests",
+		instrument.WithDescription("Number of HTTP requests, by route, method and status code")); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to create metric", v1alpha2.InternalError)
+	}
+	if ret.httpDuration, err = meter.Float64Histogram("symphony_http_request_duration",
+		instrument.WithDescription("Duration of HTTP requests, by route, method and status code"),
+		instrument.WithUnit(string(unit.Milliseconds))); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to create metric", v1alpha2.InternalError)
+	}
+	if _, err = meter.Int64ObservableGauge("symphony_pubsub_queue_depth",
+		instrument.WithDescription("Number of events waiting to be handled, by topic"),
+		instrument.WithInt64Callback(ret.observeQueueDepth)); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to create metric", v1alpha2.InternalError)
+	}
+	global.SetMeterProvider(ret.MeterProvider)
+	// spans started before the metrics are set up are counted once the tracer provider exists
+	getTracerProvider().RegisterSpanProcessor(&metricsSpanProcessor{metrics: ret})
+	metrics = ret
+	return ret, nil
+}
+
+// Handler returns the HTTP handler that serves the metrics in the Prometheus format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// RecordHTTPRequest records a handled HTTP request
+func (m *Metrics) RecordHTTPRequest(ctx context.Context, route string, method string, status int, duration time.Duration) {
+	attributes := []attribute.KeyValue{
+		attribute.String("route", route),
+		attribute.String("method", method),
+		attribute.String("status", strconv.Itoa(status)),
+	}
+	m.httpRequests.Add(ctx, 1, attributes...)
+	m.httpDuration.Record(ctx, float64(duration)/float64(time.Millisecond), attributes...)
+}
+
+// RegisterQueueDepth adds a function that reports the number of events waiting in each topic
+func (m *Metrics) RegisterQueueDepth(depth func() map[string]int) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	m.queueDepthFuncs = append(m.queueDepthFuncs, depth)
+}
+
+func (m *Metrics) observeQueueDepth(ctx context.Context, observer instrument.Int64Observer) error {
+	metricsLock.Lock()
+	funcs := m.queueDepthFuncs
+	metricsLock.Unlock()
+	for _, f := range funcs {
+		for topic, depth := range f() {
+			observer.Observe(int64(depth), attribute.String("topic", topic))
+		}
+	}
+	return nil
+}
+
+// metricsSpanProcessor records the operation metrics of the spans started with StartSpan
+type metricsSpanProcessor struct {
+	metrics *Metrics
+}
+
+func (p *metricsSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+}
+
+func (p *metricsSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	// StartSpan names the tracer after the span, other spans, such as the spans of HTTP requests
+	// named after the request path, are left out
+	if s.InstrumentationScope().Name != s.Name() {
+		return
+	}
+	method := ""
+	for _, a := range s.Attributes() {
+		if a.Key == "method" {
+			method = a.Value.AsString()
+			break
+		}
+	}
+	status := "ok"
+	if s.Status().Code == codes.Error {
+		status = "error"
+	}
+	attributes := []attribute.KeyValue{
+		attribute.String("component", s.Name()),
+		attribute.String("method", method),
+		attribute.String("status", status),
+	}
+	ctx := context.Background()
+	p.metrics.operations.Add(ctx, 1, attributes...)
+	p.metrics.operationDuration.Record(ctx, float64(s.EndTime().Sub(s.StartTime()))/float64(time.Millisecond), attributes...)
+}
+
+func (p *metricsSpanProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (p *metricsSpanProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
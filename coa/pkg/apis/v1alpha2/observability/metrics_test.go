@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package observability
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	data, err := io.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestInitMetricsOnce(t *testing.T) {
+	m1, err := InitMetrics()
+	assert.Nil(t, err)
+	m2, err := InitMetrics()
+	assert.Nil(t, err)
+	assert.Same(t, m1, m2)
+}
+
+func TestOperationMetricsFromSpans(t *testing.T) {
+	m, err := InitMetrics()
+	assert.Nil(t, err)
+
+	_, span := StartSpan("Test Target Provider", context.Background(), &map[string]string{
+		"method": "Apply",
+	})
+	var spanErr error = errors.New("failed")
+	observ_utils.CloseSpanWithError(span, &spanErr)
+	_, span = StartSpan("Test Target Provider", context.Background(), &map[string]string{
+		"method": "Get",
+	})
+	spanErr = nil
+	observ_utils.CloseSpanWithError(span, &spanErr)
+
+	data := scrape(t, m)
+	assert.Contains(t, data, `symphony_operations_total{component="Test Target Provider",method="Apply",status="error"} 1`)
+	assert.Contains(t, data, `symphony_operations_total{component="Test Target Provider",method="Get",status="ok"} 1`)
+	assert.Contains(t, data, `symphony_operation_duration_milliseconds_count{component="Test Target Provider",method="Apply",status="error"} 1`)
+}
+
+func TestHTTPAndQueueDepthMetrics(t *testing.T) {
+	m, err := InitMetrics()
+	assert.Nil(t, err)
+	m.RecordHTTPRequest(context.Background(), "/v1alpha2/solutions", "GET", 200, 5*time.Millisecond)
+	m.RegisterQueueDepth(func() map[string]int {
+		return map[string]int{"test-topic": 3}
+	})
+
+	data := scrape(t, m)
+	assert.Contains(t, data, `symphony_http_requests_total{method="GET",route="/v1alpha2/solutions",status="200"} 1`)
+	assert.Contains(t, data, `symphony_pubsub_queue_depth{topic="test-topic"} 3`)
+}
@@ -8,6 +8,8 @@ package observability
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"bytes"
 	"context"
@@ -25,6 +27,11 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	tracerProviderLock sync.Mutex
+	tracerProvider     *sdktrace.TracerProvider
+)
+
 type ObservabilityConfig struct {
 	Pipelines []PipelineConfig `json:"pipelines"`
 }
@@ -33,9 +40,21 @@ type PipelineConfig struct {
 	Exporter ExporterConfig `json:"exporter"`
 }
 type ExporterConfig struct {
-	Type       string        `json:"type"`
-	BackendUrl string        `json:"backendUrl"`
-	Sampler    SamplerConfig `json:"sampler"`
+	Type       string            `json:"type"`
+	BackendUrl string            `json:"backendUrl"`
+	Sampler    SamplerConfig     `json:"sampler"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Insecure   bool              `json:"insecure,omitempty"`
+	Batch      BatchConfig       `json:"batch,omitempty"`
+}
+
+// BatchConfig configures how spans are batched before they are exported. Zero values keep the
+// defaults of OpenTelemetry.
+type BatchConfig struct {
+	MaxQueueSize       int    `json:"maxQueueSize,omitempty"`
+	MaxExportBatchSize int    `json:"maxExportBatchSize,omitempty"`
+	BatchTimeout       string `json:"batchTimeout,omitempty"`
+	ExportTimeout      string `json:"exportTimeout,omitempty"`
 }
 type ProcessorConfig struct {
 }
@@ -110,18 +129,80 @@ func (o *Observability) createExporter(config ExporterConfig) error {
 		if err != nil {
 			return err
 		}
+	case v1alpha2.TracingExporterOTLPgRPC:
+		exporter, err = exporters.NewOTLPgRPCExporter(config.BackendUrl, config.Headers, config.Insecure)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to create OTLP gRPC exporter", v1alpha2.BadConfig)
+		}
+	case v1alpha2.TracingExporterOTLPHTTP:
+		exporter, err = exporters.NewOTLPHTTPExporter(config.BackendUrl, config.Headers, config.Insecure)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to create OTLP HTTP exporter", v1alpha2.BadConfig)
+		}
 	default:
 		return v1alpha2.NewCOAError(nil, fmt.Sprintf("exporter type '%s' is not supported", config.Type), v1alpha2.BadConfig)
 	}
-	batcher := sdktrace.NewBatchSpanProcessor(exporter)
-	//otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(batcher)))
-	//res, _ := resource.New(context.TODO(), resource.WithAttributes(attribute.String("service.name", "Symphony API (PAI)")))
-	//otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(batcher), sdktrace.WithResource(res)))
-	otel.SetTracerProvider(sdktrace.NewTracerProvider(
-		sdktrace.WithSpanProcessor(batcher),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String("Symphony API"),
-		))))
+	options, err := batchOptions(config.Batch)
+	if err != nil {
+		return err
+	}
+	getTracerProvider().RegisterSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter, options...))
+	o.TracerProvider = getTracerProvider()
 	return nil
 }
+
+func batchOptions(config BatchConfig) ([]sdktrace.BatchSpanProcessorOption, error) {
+	options := []sdktrace.BatchSpanProcessorOption{}
+	if config.MaxQueueSize > 0 {
+		options = append(options, sdktrace.WithMaxQueueSize(config.MaxQueueSize))
+	}
+	if config.MaxExportBatchSize > 0 {
+		options = append(options, sdktrace.WithMaxExportBatchSize(config.MaxExportBatchSize))
+	}
+	if config.BatchTimeout != "" {
+		timeout, err := time.ParseDuration(config.BatchTimeout)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "invalid batch timeout", v1alpha2.BadConfig)
+		}
+		options = append(options, sdktrace.WithBatchTimeout(timeout))
+	}
+	if config.ExportTimeout != "" {
+		timeout, err := time.ParseDuration(config.ExportTimeout)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "invalid export timeout", v1alpha2.BadConfig)
+		}
+		options = append(options, sdktrace.WithExportTimeout(timeout))
+	}
+	return options, nil
+}
+
+// getTracerProvider returns the tracer provider of the process, which is shared by the exporters of
+// all the pipelines and by the metrics
+func getTracerProvider() *sdktrace.TracerProvider {
+	tracerProviderLock.Lock()
+	defer tracerProviderLock.Unlock()
+	if tracerProvider == nil {
+		tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithResource(symphonyResource()))
+		otel.SetTracerProvider(tracerProvider)
+	}
+	return tracerProvider
+}
+
+// Flush exports the spans that are buffered by the exporters of the process. It's called when the
+// host shuts down so that the spans of the last requests aren't lost.
+func Flush(ctx context.Context) error {
+	tracerProviderLock.Lock()
+	provider := tracerProvider
+	tracerProviderLock.Unlock()
+	if provider == nil {
+		return nil
+	}
+	return provider.ForceFlush(ctx)
+}
+
+func symphonyResource() *resource.Resource {
+	return resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String("Symphony API"),
+	)
+}
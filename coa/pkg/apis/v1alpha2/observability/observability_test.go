@@ -47,3 +47,58 @@ func TestZipkinPipeline(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+func TestOTLPgRPCPipeline(t *testing.T) {
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPgRPC,
+					BackendUrl: "http://localhost:4317",
+					Headers: map[string]string{
+						"api-key": "secret",
+					},
+					Batch: BatchConfig{
+						MaxQueueSize:       100,
+						MaxExportBatchSize: 10,
+						BatchTimeout:       "2s",
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+}
+func TestOTLPHTTPPipeline(t *testing.T) {
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPHTTP,
+					BackendUrl: "http://localhost:4318/v1/traces",
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+}
+func TestPipelineWithBadBatchTimeout(t *testing.T) {
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type: v1alpha2.TracingExporterConsole,
+					Batch: BatchConfig{
+						BatchTimeout: "soon",
+					},
+				},
+			},
+		},
+	})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
@@ -25,6 +25,10 @@ const (
 	paiFastHTTPContextKey = "paiSpanContextKey"
 )
 
+// tracePropagator carries the W3C trace context (traceparent and tracestate) and baggage across
+// sites, bindings and pub-sub events
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
 func GetTraceSamplingRate(rate string) float64 {
 	f, err := strconv.ParseFloat(rate, 64)
 	if err != nil {
@@ -53,8 +57,28 @@ func PropagateSpanContextToHttpRequestHeader(req *http.Request) {
 		return
 	}
 
-	propagator := propagation.TraceContext{}
-	propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	tracePropagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+}
+
+// PropagateSpanContextToMetadata writes the trace context of ctx, such as the traceparent key, to the
+// metadata of a request or an event
+func PropagateSpanContextToMetadata(ctx context.Context, metadata map[string]string) {
+	if ctx == nil || metadata == nil {
+		return
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		ctx = trace.ContextWithSpan(ctx, *span)
+	}
+	tracePropagator.Inject(ctx, propagation.MapCarrier(metadata))
+}
+
+// SpanContextFromMetadata returns a context that carries the trace context found in the metadata of a
+// request or an event, so that spans started with it continue the remote trace
+func SpanContextFromMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	if metadata == nil {
+		return ctx
+	}
+	return tracePropagator.Extract(ctx, propagation.MapCarrier(metadata))
 }
 
 func SpanToFastHTTPContext(ctx *fasthttp.RequestCtx, span *trace.Span) {
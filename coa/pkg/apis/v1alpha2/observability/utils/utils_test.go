@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanContextThroughMetadata(t *testing.T) {
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	metadata := map[string]string{"site": "child"}
+	PropagateSpanContextToMetadata(ctx, metadata)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", metadata["traceparent"])
+	assert.Equal(t, "child", metadata["site"])
+
+	remote := trace.SpanContextFromContext(SpanContextFromMetadata(context.Background(), metadata))
+	assert.True(t, remote.IsRemote())
+	assert.Equal(t, traceId, remote.TraceID())
+	assert.Equal(t, spanId, remote.SpanID())
+}
+
+func TestSpanContextFromEmptyMetadata(t *testing.T) {
+	ctx := SpanContextFromMetadata(context.Background(), map[string]string{})
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+	ctx = SpanContextFromMetadata(context.Background(), nil)
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}
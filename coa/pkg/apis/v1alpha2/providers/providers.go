@@ -6,9 +6,23 @@
 
 package providers
 
+import "context"
+
 type IProviderConfig interface {
 }
 
 type IProvider interface {
 	Init(config IProviderConfig) error
 }
+
+// IHealthCheckProvider is implemented by providers that can check whether their backend, such as a
+// database or a message broker, is reachable
+type IHealthCheckProvider interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// IReconfigurableProvider is implemented by providers that can apply a new configuration without being
+// created again, which lets a host reload its configuration without a restart
+type IReconfigurableProvider interface {
+	Reconfigure(config IProviderConfig) error
+}
@@ -7,21 +7,85 @@
 package memory
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
+var mLog = logger.NewLogger("coa.runtime")
+
+const (
+	DefaultNumberOfWorkers  = 100
+	DefaultMaxRetries       = 3
+	DefaultRetryBackoff     = "1s"
+	DefaultMaxRetryBackoff  = "30s"
+	DefaultDeadLetterTopic  = "dead-letter"
+	DeadLetterTopicMetadata = "deadLetter.topic"
+	DeadLetterErrorMetadata = "deadLetter.error"
+	DeadLetterRetryMetadata = "deadLetter.attempts"
+)
+
+// InMemoryPubSubProvider delivers events to the handlers subscribed to a topic in the same process.
+// Each topic has a bounded pool of workers. A handler that returns an error gets the event again
+// with an exponential backoff, and an event that still fails after the retries, or fails with a
+// BadRequest error, is published to the dead-letter topic.
 type InMemoryPubSubProvider struct {
-	Config      InMemoryPubSubConfig               `json:"config"`
-	Subscribers map[string][]v1alpha2.EventHandler `json:"subscribers"`
-	Context     *contexts.ManagerContext
+	Config  InMemoryPubSubConfig `json:"config"`
+	Context *contexts.ManagerContext
+
+	lock            sync.Mutex
+	topics          map[string]*topic
+	nextID          int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	closed          bool
+	workers         sync.WaitGroup
 }
 
 type InMemoryPubSubConfig struct {
 	Name string `json:"name"`
+	// NumberOfWorkers is the maximum number of events of a topic handled at the same time
+	NumberOfWorkers int `json:"numberOfWorkers,omitempty"`
+	// MaxRetries is the number of times an event is delivered again to a handler that fails, -1 disables retries
+	MaxRetries      int    `json:"maxRetries,omitempty"`
+	RetryBackoff    string `json:"retryBackoff,omitempty"`
+	MaxRetryBackoff string `json:"maxRetryBackoff,omitempty"`
+	// DeadLetterTopic receives the events that can't be handled, "-" disables dead-lettering
+	DeadLetterTopic string `json:"deadLetterTopic,omitempty"`
+}
+
+type topic struct {
+	name          string
+	subscriptions []*subscription
+	queue         []*delivery
+	// ordering keys of the subscriptions with an event being handled or waiting for a retry
+	busy    map[string]bool
+	workers int
+	cond    *sync.Cond
+}
+
+type subscription struct {
+	id       int
+	topic    string
+	handler  v1alpha2.EventHandler
+	provider *InMemoryPubSubProvider
+	closed   bool
+}
+
+type delivery struct {
+	subscription *subscription
+	event        v1alpha2.Event
+	key          string
+	attempt      int
 }
 
 func InMemoryPubSubConfigFromMap(properties map[string]string) (InMemoryPubSubConfig, error) {
@@ -29,6 +93,29 @@ func InMemoryPubSubConfigFromMap(properties map[string]string) (InMemoryPubSubCo
 	if v, ok := properties["name"]; ok {
 		ret.Name = v
 	}
+	if v, ok := properties["numberOfWorkers"]; ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'numberOfWorkers' setting of in-memory pub-sub provider", v1alpha2.BadConfig)
+		}
+		ret.NumberOfWorkers = n
+	}
+	if v, ok := properties["maxRetries"]; ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'maxRetries' setting of in-memory pub-sub provider", v1alpha2.BadConfig)
+		}
+		ret.MaxRetries = n
+	}
+	if v, ok := properties["retryBackoff"]; ok {
+		ret.RetryBackoff = v
+	}
+	if v, ok := properties["maxRetryBackoff"]; ok {
+		ret.MaxRetryBackoff = v
+	}
+	if v, ok := properties["deadLetterTopic"]; ok {
+		ret.DeadLetterTopic = v
+	}
 	return ret, nil
 }
 
@@ -53,31 +140,330 @@ func (i *InMemoryPubSubProvider) Init(config providers.IProviderConfig) error {
 	if err != nil {
 		return v1alpha2.NewCOAError(nil, "provided config is not a valid in-memory pub-sub provider config", v1alpha2.BadConfig)
 	}
+	if vConfig.NumberOfWorkers <= 0 {
+		vConfig.NumberOfWorkers = DefaultNumberOfWorkers
+	}
+	if vConfig.MaxRetries == 0 {
+		vConfig.MaxRetries = DefaultMaxRetries
+	}
+	if vConfig.RetryBackoff == "" {
+		vConfig.RetryBackoff = DefaultRetryBackoff
+	}
+	if vConfig.MaxRetryBackoff == "" {
+		vConfig.MaxRetryBackoff = DefaultMaxRetryBackoff
+	}
+	if vConfig.DeadLetterTopic == "" {
+		vConfig.DeadLetterTopic = DefaultDeadLetterTopic
+	}
+	retryBackoff, err := time.ParseDuration(vConfig.RetryBackoff)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid duration value in the 'retryBackoff' setting of in-memory pub-sub provider", v1alpha2.BadConfig)
+	}
+	maxRetryBackoff, err := time.ParseDuration(vConfig.MaxRetryBackoff)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid duration value in the 'maxRetryBackoff' setting of in-memory pub-sub provider", v1alpha2.BadConfig)
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
 	i.Config = vConfig
-	i.Subscribers = make(map[string][]v1alpha2.EventHandler)
+	i.retryBackoff = retryBackoff
+	i.maxRetryBackoff = maxRetryBackoff
+	// a provider initialized again keeps its subscriptions
+	if i.topics == nil {
+		i.topics = make(map[string]*topic)
+	}
+	i.closed = false
 	return nil
 }
+
 func (i *InMemoryPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
-	arr, ok := i.Subscribers[topic]
-	if ok && arr != nil {
-		for _, s := range arr {
-			go func(handler v1alpha2.EventHandler, topic string, event v1alpha2.Event) {
-				handler(topic, event)
-			}(s, topic, event)
-		}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.closed {
+		return v1alpha2.NewCOAError(nil, "in-memory pub-sub provider is shut down", v1alpha2.ServiceUnavailable)
+	}
+	t, ok := i.topics[topic]
+	if !ok || len(t.subscriptions) == 0 {
+		return nil
+	}
+	key := ""
+	if event.Metadata != nil {
+		key = event.Metadata[pubsub.OrderingKeyMetadata]
 	}
+	for _, s := range t.subscriptions {
+		t.queue = append(t.queue, &delivery{
+			subscription: s,
+			event:        event,
+			key:          key,
+		})
+	}
+	i.startWorkers(t)
+	t.cond.Broadcast()
 	return nil
 }
+
 func (i *InMemoryPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
-	arr, ok := i.Subscribers[topic]
-	if !ok || arr == nil {
-		i.Subscribers[topic] = make([]v1alpha2.EventHandler, 0)
+	_, err := i.SubscribeWithHandle(topic, handler)
+	return err
+}
+
+func (i *InMemoryPubSubProvider) SubscribeWithHandle(topicName string, handler v1alpha2.EventHandler) (pubsub.ISubscription, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.topics == nil {
+		return nil, v1alpha2.NewCOAError(nil, "in-memory pub-sub provider is not initialized", v1alpha2.BadConfig)
+	}
+	t, ok := i.topics[topicName]
+	if !ok {
+		t = &topic{
+			name: topicName,
+			busy: make(map[string]bool),
+		}
+		t.cond = sync.NewCond(&i.lock)
+		i.topics[topicName] = t
 	}
-	i.Subscribers[topic] = append(i.Subscribers[topic], handler)
+	i.nextID++
+	s := &subscription{
+		id:       i.nextID,
+		topic:    topicName,
+		handler:  handler,
+		provider: i,
+	}
+	t.subscriptions = append(t.subscriptions, s)
+	return s, nil
+}
 
+// Unsubscribe removes the subscription. Events being handled are finished, while events waiting to
+// be handled or retried are dropped.
+func (s *subscription) Unsubscribe() error {
+	i := s.provider
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	t, ok := i.topics[s.topic]
+	if !ok {
+		return nil
+	}
+	subscriptions := make([]*subscription, 0, len(t.subscriptions))
+	for _, sub := range t.subscriptions {
+		if sub != s {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+	t.subscriptions = subscriptions
+	queue := make([]*delivery, 0, len(t.queue))
+	for _, d := range t.queue {
+		if d.subscription != s {
+			queue = append(queue, d)
+		}
+	}
+	t.queue = queue
+	// wake up the workers waiting for events that are dropped
+	t.cond.Broadcast()
 	return nil
 }
 
+// Shutdown stops handling events. Events being handled are finished, until ctx is done, while events
+// waiting to be handled or retried are dropped, as the provider doesn't keep events anywhere else.
+// Publishing after a shutdown fails with a ServiceUnavailable error.
+func (i *InMemoryPubSubProvider) Shutdown(ctx context.Context) error {
+	i.lock.Lock()
+	if i.closed {
+		i.lock.Unlock()
+		return nil
+	}
+	i.closed = true
+	dropped := 0
+	for _, t := range i.topics {
+		dropped += len(t.queue)
+		t.queue = nil
+		// wake up the workers waiting for events with the same ordering key
+		t.cond.Broadcast()
+	}
+	i.lock.Unlock()
+	if dropped > 0 {
+		mLog.Infof("  P (Memory PubSub): dropped %d event(s) waiting to be handled at shutdown", dropped)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		i.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return v1alpha2.NewCOAError(ctx.Err(), "in-memory pub-sub provider didn't finish the events being handled", v1alpha2.InternalError)
+	}
+}
+
+// QueueDepth returns the number of events waiting to be handled in each topic
+func (i *InMemoryPubSubProvider) QueueDepth() map[string]int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	ret := make(map[string]int, len(i.topics))
+	for name, t := range i.topics {
+		ret[name] = len(t.queue)
+	}
+	return ret
+}
+
+// startWorkers starts the workers of a topic, up to NumberOfWorkers, while there are events to handle.
+// It's called with the lock held.
+func (i *InMemoryPubSubProvider) startWorkers(t *topic) {
+	if i.closed {
+		return
+	}
+	for t.workers < i.Config.NumberOfWorkers && t.workers < len(t.queue) {
+		t.workers++
+		i.workers.Add(1)
+		go i.worker(t)
+	}
+}
+
+// worker handles the events of a topic and stops when there are no events left
+func (i *InMemoryPubSubProvider) worker(t *topic) {
+	defer i.workers.Done()
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for {
+		d := t.next()
+		if d == nil {
+			if len(t.queue) == 0 {
+				t.workers--
+				return
+			}
+			// the remaining events wait for events with the same ordering key
+			t.cond.Wait()
+			continue
+		}
+		i.lock.Unlock()
+		err := handle(t.name, d)
+		i.lock.Lock()
+		if err == nil {
+			t.release(d)
+			continue
+		}
+		i.fail(t, d, err)
+	}
+}
+
+// next takes the first event that doesn't wait for an event with the same ordering key. It's called
+// with the lock held.
+func (t *topic) next() *delivery {
+	for n, d := range t.queue {
+		key := d.orderingKey()
+		if key != "" && t.busy[key] {
+			continue
+		}
+		t.queue = append(t.queue[:n:n], t.queue[n+1:]...)
+		if key != "" {
+			t.busy[key] = true
+		}
+		return d
+	}
+	return nil
+}
+
+// release lets the next event with the same ordering key be handled. It's called with the lock held.
+func (t *topic) release(d *delivery) {
+	if key := d.orderingKey(); key != "" {
+		delete(t.busy, key)
+		t.cond.Broadcast()
+	}
+}
+
+// fail schedules a retry of a failed event, or publishes it to the dead-letter topic when it can't be
+// handled. Events with the same ordering key wait until the retry is handled. It's called with the
+// lock held.
+func (i *InMemoryPubSubProvider) fail(t *topic, d *delivery, err error) {
+	permanent := false
+	if cErr, ok := err.(v1alpha2.COAError); ok && cErr.State == v1alpha2.BadRequest {
+		permanent = true
+	}
+	if permanent || d.attempt >= i.Config.MaxRetries || d.subscription.closed || i.closed {
+		t.release(d)
+		if d.subscription.closed || i.closed {
+			return
+		}
+		mLog.Errorf("  P (Memory PubSub): failed to handle event of topic %s after %d attempt(s): %v", t.name, d.attempt+1, err)
+		i.deadLetter(t.name, d, err)
+		return
+	}
+	backoff := i.retryBackoff << d.attempt
+	if backoff > i.maxRetryBackoff || backoff <= 0 {
+		backoff = i.maxRetryBackoff
+	}
+	mLog.Debugf("  P (Memory PubSub): failed to handle event of topic %s, retrying in %v: %v", t.name, backoff, err)
+	d.attempt++
+	time.AfterFunc(backoff, func() {
+		i.lock.Lock()
+		defer i.lock.Unlock()
+		if key := d.orderingKey(); key != "" {
+			delete(t.busy, key)
+		}
+		if d.subscription.closed || i.closed {
+			t.cond.Broadcast()
+			return
+		}
+		// retries go first so that they keep their place among the events with the same ordering key
+		t.queue = append([]*delivery{d}, t.queue...)
+		i.startWorkers(t)
+		t.cond.Broadcast()
+	})
+}
+
+// deadLetter publishes an event that can't be handled to the dead-letter topic. It's called with the
+// lock held.
+func (i *InMemoryPubSubProvider) deadLetter(topicName string, d *delivery, err error) {
+	if i.Config.DeadLetterTopic == "-" || topicName == i.Config.DeadLetterTopic {
+		return
+	}
+	t, ok := i.topics[i.Config.DeadLetterTopic]
+	if !ok || len(t.subscriptions) == 0 {
+		return
+	}
+	metadata := make(map[string]string, len(d.event.Metadata)+3)
+	for k, v := range d.event.Metadata {
+		metadata[k] = v
+	}
+	metadata[DeadLetterTopicMetadata] = topicName
+	metadata[DeadLetterErrorMetadata] = err.Error()
+	metadata[DeadLetterRetryMetadata] = strconv.Itoa(d.attempt + 1)
+	for _, s := range t.subscriptions {
+		t.queue = append(t.queue, &delivery{
+			subscription: s,
+			event: v1alpha2.Event{
+				Metadata: metadata,
+				Body:     d.event.Body,
+			},
+		})
+	}
+	i.startWorkers(t)
+	t.cond.Broadcast()
+}
+
+func (d *delivery) orderingKey() string {
+	if d.key == "" {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", d.subscription.id, d.key)
+}
+
+// handle calls the handler of an event. A handler that panics fails like a handler that returns an error.
+func handle(topic string, d *delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return d.subscription.handler(topic, d.event)
+}
+
 func toInMemoryPubSubConfig(config providers.IProviderConfig) (InMemoryPubSubConfig, error) {
 	ret := InMemoryPubSubConfig{}
 	data, err := json.Marshal(config)
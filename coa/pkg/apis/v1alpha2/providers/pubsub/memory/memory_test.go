@@ -7,9 +7,16 @@
 package memory
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -108,3 +115,315 @@ func TestClone(t *testing.T) {
 	assert.NotNil(t, pc)
 	assert.Nil(t, err)
 }
+
+func TestMemoryPubsubProviderConfigFromMapSettings(t *testing.T) {
+	config, err := InMemoryPubSubConfigFromMap(map[string]string{
+		"numberOfWorkers": "4",
+		"maxRetries":      "-1",
+		"retryBackoff":    "10ms",
+		"deadLetterTopic": "failed",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, config.NumberOfWorkers)
+	assert.Equal(t, -1, config.MaxRetries)
+	assert.Equal(t, "10ms", config.RetryBackoff)
+	assert.Equal(t, "failed", config.DeadLetterTopic)
+
+	_, err = InMemoryPubSubConfigFromMap(map[string]string{
+		"numberOfWorkers": "many",
+	})
+	assert.NotNil(t, err)
+
+	provider := InMemoryPubSubProvider{}
+	err = provider.InitWithMap(map[string]string{
+		"retryBackoff": "soon",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestConcurrentPublishSubscribe(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test"})
+	var count int32
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+				atomic.AddInt32(&count, 1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+		}()
+	}
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) == 100
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test"})
+	var count1, count2 int32
+	unsubscribable, ok := interface{}(&provider).(pubsub.IUnsubscribablePubSubProvider)
+	assert.True(t, ok)
+	sub, err := unsubscribable.SubscribeWithHandle("test", func(topic string, event v1alpha2.Event) error {
+		atomic.AddInt32(&count1, 1)
+		return nil
+	})
+	assert.Nil(t, err)
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		atomic.AddInt32(&count2, 1)
+		return nil
+	})
+	provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count1) == 1 && atomic.LoadInt32(&count2) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, sub.Unsubscribe())
+	assert.Nil(t, sub.Unsubscribe())
+	provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count2) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count1))
+}
+
+func TestRetry(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", RetryBackoff: "10ms"})
+	var attempts int32
+	sig := make(chan int, 1)
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errors.New("not yet")
+		}
+		sig <- 1
+		return nil
+	})
+	provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+	select {
+	case <-sig:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "event isn't retried")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestDeadLetter(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", MaxRetries: 2, RetryBackoff: "10ms"})
+	var attempts int32
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("always fails")
+	})
+	deadLetters := make(chan v1alpha2.Event, 1)
+	provider.Subscribe(DefaultDeadLetterTopic, func(topic string, event v1alpha2.Event) error {
+		deadLetters <- event
+		return nil
+	})
+	provider.Publish("test", v1alpha2.Event{Metadata: map[string]string{"scope": "default"}, Body: "TEST"})
+	select {
+	case event := <-deadLetters:
+		assert.Equal(t, "TEST", event.Body)
+		assert.Equal(t, "default", event.Metadata["scope"])
+		assert.Equal(t, "test", event.Metadata[DeadLetterTopicMetadata])
+		assert.Equal(t, "always fails", event.Metadata[DeadLetterErrorMetadata])
+		assert.Equal(t, "3", event.Metadata[DeadLetterRetryMetadata])
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "event isn't dead-lettered")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestDeadLetterBadRequest(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", DeadLetterTopic: "failed"})
+	var attempts int32
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		atomic.AddInt32(&attempts, 1)
+		return v1alpha2.NewCOAError(nil, "bad event", v1alpha2.BadRequest)
+	})
+	deadLetters := make(chan v1alpha2.Event, 1)
+	provider.Subscribe("failed", func(topic string, event v1alpha2.Event) error {
+		deadLetters <- event
+		return nil
+	})
+	provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+	select {
+	case event := <-deadLetters:
+		assert.Equal(t, "1", event.Metadata[DeadLetterRetryMetadata])
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "event isn't dead-lettered")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestPanickingHandler(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", MaxRetries: -1})
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		panic("handler bug")
+	})
+	deadLetters := make(chan v1alpha2.Event, 1)
+	provider.Subscribe(DefaultDeadLetterTopic, func(topic string, event v1alpha2.Event) error {
+		deadLetters <- event
+		return nil
+	})
+	provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+	select {
+	case event := <-deadLetters:
+		assert.Contains(t, event.Metadata[DeadLetterErrorMetadata], "handler bug")
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "event isn't dead-lettered")
+	}
+}
+
+func TestOrderedDelivery(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", RetryBackoff: "10ms"})
+	var lock sync.Mutex
+	received := make(map[string][]int)
+	failed := false
+	var count int32
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		lock.Lock()
+		defer lock.Unlock()
+		key := event.Metadata[pubsub.OrderingKeyMetadata]
+		n := event.Body.(int)
+		// a failed event is retried before the next events with the same key
+		if key == "a" && n == 2 && !failed {
+			failed = true
+			return errors.New("retry")
+		}
+		received[key] = append(received[key], n)
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	for n := 0; n < 10; n++ {
+		for _, key := range []string{"a", "b"} {
+			provider.Publish("test", v1alpha2.Event{
+				Metadata: map[string]string{pubsub.OrderingKeyMetadata: key},
+				Body:     n,
+			})
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) == 20
+	}, 5*time.Second, 10*time.Millisecond)
+	expected := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	assert.Equal(t, expected, received["a"])
+	assert.Equal(t, expected, received["b"])
+}
+
+func TestBoundedWorkers(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", NumberOfWorkers: 2})
+	var running, maxRunning, count int32
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		r := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	for n := 0; n < 10; n++ {
+		provider.Publish("test", v1alpha2.Event{Body: fmt.Sprintf("TEST%d", n)})
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) == 10
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestQueueDepth(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", NumberOfWorkers: 1})
+	release := make(chan int)
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		<-release
+		return nil
+	})
+	for n := 0; n < 3; n++ {
+		provider.Publish("test", v1alpha2.Event{Body: fmt.Sprintf("TEST%d", n)})
+	}
+	// one event is being handled and the others are waiting
+	assert.Eventually(t, func() bool {
+		return provider.QueueDepth()["test"] == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	close(release)
+	assert.Eventually(t, func() bool {
+		return provider.QueueDepth()["test"] == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	var _ pubsub.IQueueDepthProvider = &provider
+}
+
+func TestInitAgainKeepsSubscriptions(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	err := provider.Init(InMemoryPubSubConfig{Name: "test"})
+	assert.Nil(t, err)
+	var count int32
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	err = provider.Init(InMemoryPubSubConfig{Name: "test"})
+	assert.Nil(t, err)
+	provider.Publish("test", v1alpha2.Event{Body: "event"})
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestShutdown(t *testing.T) {
+	started := make(chan int, 3)
+	release := make(chan int)
+	var handled int32
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{Name: "test", NumberOfWorkers: 1})
+	provider.Subscribe("test", func(topic string, event v1alpha2.Event) error {
+		started <- 1
+		<-release
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		provider.Publish("test", v1alpha2.Event{Body: i})
+	}
+	<-started
+
+	// the event being handled isn't finished in time
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, provider.Shutdown(ctx))
+
+	close(release)
+	assert.Nil(t, provider.Shutdown(context.Background()))
+	provider.workers.Wait()
+	// the events waiting to be handled are dropped
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handled))
+	assert.Equal(t, 0, provider.QueueDepth()["test"])
+
+	err := provider.Publish("test", v1alpha2.Event{Body: "TEST"})
+	assert.NotNil(t, err)
+	cErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.ServiceUnavailable, cErr.State)
+}
@@ -0,0 +1,527 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	natsio "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+var mLog = logger.NewLogger("coa.runtime")
+
+const (
+	DefaultStream        = "symphony"
+	DefaultSubjectPrefix = "symphony"
+	DefaultConsumerID    = "symphony"
+	DefaultQueueDepth    = 100
+)
+
+// NatsPubSubProvider publishes events to a JetStream stream. Each topic is a subject of the stream and
+// each subscription reads the topic through a durable consumer named after ConsumerID and the topic, so
+// providers with the same ConsumerID share the events of a topic, and events published while a provider
+// is down are delivered when it comes back.
+type NatsPubSubProvider struct {
+	Config    NatsPubSubProviderConfig `json:"config"`
+	Conn      *natsio.Conn
+	JetStream jetstream.JetStream
+	Queue     chan NatsMessageWrapper
+	Ctx       context.Context
+	Cancel    context.CancelFunc
+	Context   *contexts.ManagerContext
+
+	lock          sync.Mutex
+	subscriptions []*natsSubscription
+	workers       sync.WaitGroup
+}
+
+type NatsMessageWrapper struct {
+	Topic        string
+	Message      jetstream.Msg
+	Handler      v1alpha2.EventHandler
+	Subscription *natsSubscription
+}
+
+type NatsPubSubProviderConfig struct {
+	Name string `json:"name"`
+	// Url is the NATS server URL, or a comma-separated list of server URLs
+	Url           string `json:"url"`
+	Stream        string `json:"stream,omitempty"`
+	SubjectPrefix string `json:"subjectPrefix,omitempty"`
+	ConsumerID    string `json:"consumerID,omitempty"`
+	// Replicas is the number of replicas of the stream when the provider creates it
+	Replicas        int `json:"replicas,omitempty"`
+	NumberOfWorkers int `json:"numberOfWorkers,omitempty"`
+	QueueDepth      int `json:"queueDepth,omitempty"`
+	// ProcessingTimeout is how long an event can be handled before it's delivered again
+	ProcessingTimeout time.Duration `json:"processingTimeout,omitempty"`
+	// RedeliverInterval is how long an event that failed to be handled waits before it's delivered again
+	RedeliverInterval time.Duration `json:"redeliverInterval,omitempty"`
+	// MaxDeliver is the maximum number of times an event is delivered, 0 means no limit
+	MaxDeliver         int    `json:"maxDeliver,omitempty"`
+	RequiresTLS        bool   `json:"requiresTLS,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	CACert             string `json:"caCert,omitempty"`
+	ClientCert         string `json:"clientCert,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty"`
+	User               string `json:"user,omitempty"`
+	Password           string `json:"password,omitempty"`
+	Token              string `json:"token,omitempty"`
+	CredentialsFile    string `json:"credentialsFile,omitempty"`
+	NKeySeedFile       string `json:"nkeySeedFile,omitempty"`
+}
+
+// UnmarshalJSON reads the durations of the config either as duration strings, such as "1m", or as
+// nanoseconds
+func (c *NatsPubSubProviderConfig) UnmarshalJSON(data []byte) error {
+	type config NatsPubSubProviderConfig
+	aux := struct {
+		*config
+		ProcessingTimeout json.RawMessage `json:"processingTimeout,omitempty"`
+		RedeliverInterval json.RawMessage `json:"redeliverInterval,omitempty"`
+	}{config: (*config)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	for raw, field := range map[*json.RawMessage]*time.Duration{
+		&aux.ProcessingTimeout: &c.ProcessingTimeout,
+		&aux.RedeliverInterval: &c.RedeliverInterval,
+	} {
+		if len(*raw) == 0 {
+			continue
+		}
+		d, err := utils.UnmarshalDuration(string(*raw))
+		if err != nil {
+			return err
+		}
+		*field = d
+	}
+	return nil
+}
+
+func NatsPubSubProviderConfigFromMap(properties map[string]string) (NatsPubSubProviderConfig, error) {
+	ret := NatsPubSubProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	if v, ok := properties["url"]; ok {
+		ret.Url = v
+	} else {
+		return ret, v1alpha2.NewCOAError(nil, "NATS pub-sub provider url is not set", v1alpha2.BadConfig)
+	}
+	for key, field := range map[string]*string{
+		"stream":          &ret.Stream,
+		"subjectPrefix":   &ret.SubjectPrefix,
+		"consumerID":      &ret.ConsumerID,
+		"caCert":          &ret.CACert,
+		"clientCert":      &ret.ClientCert,
+		"clientKey":       &ret.ClientKey,
+		"user":            &ret.User,
+		"password":        &ret.Password,
+		"token":           &ret.Token,
+		"credentialsFile": &ret.CredentialsFile,
+		"nkeySeedFile":    &ret.NKeySeedFile,
+	} {
+		if v, ok := properties[key]; ok {
+			*field = v
+		}
+	}
+	for key, field := range map[string]*bool{
+		"requiresTLS":        &ret.RequiresTLS,
+		"insecureSkipVerify": &ret.InsecureSkipVerify,
+	} {
+		if v, ok := properties[key]; ok && v != "" {
+			bVal, err := strconv.ParseBool(v)
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid bool value in the '%s' setting of NATS pub-sub provider", key), v1alpha2.BadConfig)
+			}
+			*field = bVal
+		}
+	}
+	for key, field := range map[string]*int{
+		"replicas":        &ret.Replicas,
+		"numberOfWorkers": &ret.NumberOfWorkers,
+		"queueDepth":      &ret.QueueDepth,
+		"maxDeliver":      &ret.MaxDeliver,
+	} {
+		if v, ok := properties[key]; ok && v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid int value in the '%s' setting of NATS pub-sub provider", key), v1alpha2.BadConfig)
+			}
+			*field = n
+		}
+	}
+	for key, field := range map[string]*time.Duration{
+		"processingTimeout": &ret.ProcessingTimeout,
+		"redeliverInterval": &ret.RedeliverInterval,
+	} {
+		if v, ok := properties[key]; ok && v != "" {
+			n, err := time.ParseDuration(v)
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid duration value in the '%s' setting of NATS pub-sub provider", key), v1alpha2.BadConfig)
+			}
+			*field = n
+		}
+	}
+	return ret, nil
+}
+
+func (v *NatsPubSubProvider) ID() string {
+	return v.Config.Name
+}
+
+func (s *NatsPubSubProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (i *NatsPubSubProvider) InitWithMap(properties map[string]string) error {
+	config, err := NatsPubSubProviderConfigFromMap(properties)
+	if err != nil {
+		mLog.Debugf("  P (NATS PubSub) : failed to initialize provider %v", err)
+		return err
+	}
+	return i.Init(config)
+}
+
+func (i *NatsPubSubProvider) Init(config providers.IProviderConfig) error {
+	vConfig, err := toNatsPubSubProviderConfig(config)
+	if err != nil {
+		return v1alpha2.NewCOAError(nil, "provided config is not a valid NATS pub-sub provider config", v1alpha2.BadConfig)
+	}
+	i.Config = vConfig
+	if i.Config.Url == "" {
+		return v1alpha2.NewCOAError(nil, "NATS url is not supplied", v1alpha2.MissingConfig)
+	}
+	options, err := i.connectOptions()
+	if err != nil {
+		return err
+	}
+	conn, err := natsio.Connect(i.Config.Url, options...)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("NATS: error connecting to %s", i.Config.Url), v1alpha2.InternalError)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return v1alpha2.NewCOAError(err, "NATS: failed to create JetStream context", v1alpha2.InternalError)
+	}
+	i.Ctx, i.Cancel = context.WithCancel(context.Background())
+	_, err = js.CreateOrUpdateStream(i.Ctx, jetstream.StreamConfig{
+		Name:     i.Config.Stream,
+		Subjects: []string{i.Config.SubjectPrefix + ".>"},
+		Replicas: i.Config.Replicas,
+	})
+	if err != nil {
+		i.Cancel()
+		conn.Close()
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("NATS: failed to create stream %s", i.Config.Stream), v1alpha2.InternalError)
+	}
+	i.Conn = conn
+	i.JetStream = js
+	i.Queue = make(chan NatsMessageWrapper, i.Config.QueueDepth)
+	for k := 0; k < i.Config.NumberOfWorkers; k++ {
+		i.workers.Add(1)
+		go i.worker()
+	}
+	return nil
+}
+
+// CheckHealth checks that the provider is connected to the NATS server
+func (i *NatsPubSubProvider) CheckHealth(ctx context.Context) error {
+	if i.Conn == nil {
+		return v1alpha2.NewCOAError(nil, "NATS: provider is not initialized", v1alpha2.InternalError)
+	}
+	if status := i.Conn.Status(); status != natsio.CONNECTED {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("NATS: connection to %s is %s", i.Config.Url, status), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (i *NatsPubSubProvider) connectOptions() ([]natsio.Option, error) {
+	options := []natsio.Option{
+		natsio.MaxReconnects(-1),
+	}
+	if i.Config.Name != "" {
+		options = append(options, natsio.Name(i.Config.Name))
+	}
+	if i.Config.RequiresTLS || i.Config.CACert != "" || i.Config.ClientCert != "" {
+		options = append(options, natsio.Secure(&tls.Config{
+			InsecureSkipVerify: i.Config.InsecureSkipVerify,
+		}))
+	}
+	if i.Config.CACert != "" {
+		options = append(options, natsio.RootCAs(i.Config.CACert))
+	}
+	if i.Config.ClientCert != "" || i.Config.ClientKey != "" {
+		if i.Config.ClientCert == "" || i.Config.ClientKey == "" {
+			return nil, v1alpha2.NewCOAError(nil, "NATS clientCert and clientKey must be set together", v1alpha2.BadConfig)
+		}
+		options = append(options, natsio.ClientCert(i.Config.ClientCert, i.Config.ClientKey))
+	}
+	if i.Config.User != "" {
+		options = append(options, natsio.UserInfo(i.Config.User, i.Config.Password))
+	}
+	if i.Config.Token != "" {
+		options = append(options, natsio.Token(i.Config.Token))
+	}
+	if i.Config.CredentialsFile != "" {
+		options = append(options, natsio.UserCredentials(i.Config.CredentialsFile))
+	}
+	if i.Config.NKeySeedFile != "" {
+		option, err := natsio.NkeyOptionFromSeed(i.Config.NKeySeedFile)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "NATS: failed to load nkey seed file", v1alpha2.BadConfig)
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+func (i *NatsPubSubProvider) worker() {
+	defer i.workers.Done()
+	for {
+		select {
+		case <-i.Ctx.Done():
+			return
+		case msg := <-i.Queue:
+			if i.Ctx.Err() != nil {
+				// deliver the event again instead of handling it while shutting down
+				msg.Message.Nak()
+				return
+			}
+			if err := i.processMessage(msg); err != nil {
+				mLog.Debugf("  P (NATS PubSub) : %v", err)
+			}
+		}
+	}
+}
+
+// processMessage acknowledges an event that is handled. An event that fails is delivered again after
+// RedeliverInterval, or after ProcessingTimeout when RedeliverInterval isn't set. An event that can't be
+// decoded, or fails with a BadRequest error, isn't delivered again.
+func (i *NatsPubSubProvider) processMessage(msg NatsMessageWrapper) error {
+	if msg.Subscription.isClosed() {
+		// leave the event to the other subscribers of the durable consumer
+		return msg.Message.Nak()
+	}
+	var evt v1alpha2.Event
+	if err := json.Unmarshal(msg.Message.Data(), &evt); err != nil {
+		msg.Message.Term()
+		return v1alpha2.NewCOAError(err, "failed to unmarshal event", v1alpha2.InternalError)
+	}
+	if err := msg.Handler(msg.Topic, evt); err != nil {
+		if cErr, ok := err.(v1alpha2.COAError); ok && cErr.State == v1alpha2.BadRequest {
+			msg.Message.Term()
+		} else if i.Config.RedeliverInterval > 0 {
+			msg.Message.NakWithDelay(i.Config.RedeliverInterval)
+		}
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to handle message of topic %s", msg.Topic), v1alpha2.InternalError)
+	}
+	if err := msg.Message.Ack(); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to acknowledge message of topic %s", msg.Topic), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (i *NatsPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
+	if i.JetStream == nil {
+		return v1alpha2.NewCOAError(nil, "NATS pub-sub provider is not initialized", v1alpha2.InternalError)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to marshal event", v1alpha2.BadRequest)
+	}
+	if _, err = i.JetStream.Publish(i.Ctx, i.subject(topic), data); err != nil {
+		mLog.Debugf("  P (NATS PubSub) : failed to publish message %v", err)
+		return v1alpha2.NewCOAError(err, "failed to publish message", v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (i *NatsPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
+	_, err := i.SubscribeWithHandle(topic, handler)
+	return err
+}
+
+// natsSubscription stops consuming the events of a topic. Events received but not handled yet aren't
+// acknowledged, so they are delivered again.
+type natsSubscription struct {
+	lock    sync.RWMutex
+	closed  bool
+	consume jetstream.ConsumeContext
+}
+
+func (s *natsSubscription) Unsubscribe() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.consume.Stop()
+	}
+	return nil
+}
+
+func (s *natsSubscription) isClosed() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.closed
+}
+
+func (i *NatsPubSubProvider) SubscribeWithHandle(topic string, handler v1alpha2.EventHandler) (pubsub.ISubscription, error) {
+	if i.JetStream == nil {
+		return nil, v1alpha2.NewCOAError(nil, "NATS pub-sub provider is not initialized", v1alpha2.InternalError)
+	}
+	consumer, err := i.JetStream.CreateOrUpdateConsumer(i.Ctx, i.Config.Stream, jetstream.ConsumerConfig{
+		Durable:       i.durableName(topic),
+		FilterSubject: i.subject(topic),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       i.Config.ProcessingTimeout,
+		MaxDeliver:    i.Config.MaxDeliver,
+		MaxAckPending: i.Config.QueueDepth,
+	})
+	if err != nil {
+		mLog.Debugf("  P (NATS PubSub) : failed to subscribe %v", err)
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to subscribe to topic %s", topic), v1alpha2.InternalError)
+	}
+	subscription := &natsSubscription{}
+	subscription.lock.Lock()
+	defer subscription.lock.Unlock()
+	subscription.consume, err = consumer.Consume(func(msg jetstream.Msg) {
+		select {
+		case i.Queue <- NatsMessageWrapper{
+			Topic:        topic,
+			Message:      msg,
+			Handler:      handler,
+			Subscription: subscription,
+		}:
+		case <-i.Ctx.Done():
+			msg.Nak()
+		}
+	}, jetstream.PullMaxMessages(i.Config.QueueDepth), jetstream.ConsumeErrHandler(func(consumeCtx jetstream.ConsumeContext, err error) {
+		mLog.Debugf("  P (NATS PubSub) : failed to consume messages of topic %s: %v", topic, err)
+	}))
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to subscribe to topic %s", topic), v1alpha2.InternalError)
+	}
+	i.lock.Lock()
+	i.subscriptions = append(i.subscriptions, subscription)
+	i.lock.Unlock()
+	return subscription, nil
+}
+
+func (i *NatsPubSubProvider) subject(topic string) string {
+	return i.Config.SubjectPrefix + "." + topic
+}
+
+// durableName returns the name of the durable consumer of a topic. Consumer names can't contain
+// whitespace, '.', '*' or '>'.
+func (i *NatsPubSubProvider) durableName(topic string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n', '/', '\\':
+			return '_'
+		}
+		return r
+	}, i.Config.ConsumerID+"-"+topic)
+}
+
+// Close stops the workers and closes the connection to the NATS server
+func (i *NatsPubSubProvider) Close() {
+	if i.Cancel != nil {
+		i.Cancel()
+	}
+	if i.Conn != nil {
+		i.Conn.Close()
+	}
+}
+
+// Shutdown stops consuming events and waits for the workers to finish the events they're handling until
+// ctx is done. Events received but not handled are nacked, so that JetStream delivers them again right
+// away, to another provider with the same ConsumerID if there is one.
+func (i *NatsPubSubProvider) Shutdown(ctx context.Context) error {
+	if i.Cancel == nil || i.Ctx.Err() != nil {
+		return nil
+	}
+	i.lock.Lock()
+	subscriptions := i.subscriptions
+	i.subscriptions = nil
+	i.lock.Unlock()
+	for _, s := range subscriptions {
+		s.Unsubscribe()
+	}
+	i.Cancel()
+
+	done := make(chan struct{})
+	go func() {
+		i.workers.Wait()
+		close(done)
+	}()
+	var ret error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		ret = v1alpha2.NewCOAError(ctx.Err(), "NATS: workers didn't finish the events being handled", v1alpha2.InternalError)
+	}
+	released := 0
+release:
+	for {
+		select {
+		case msg := <-i.Queue:
+			msg.Message.Nak()
+			released++
+		default:
+			break release
+		}
+	}
+	if released > 0 {
+		mLog.Infof("  P (NATS PubSub) : released %d event(s) at shutdown", released)
+	}
+	if err := i.Conn.Drain(); err != nil && ret == nil {
+		ret = v1alpha2.NewCOAError(err, "NATS: failed to drain the connection", v1alpha2.InternalError)
+	}
+	return ret
+}
+
+func toNatsPubSubProviderConfig(config providers.IProviderConfig) (NatsPubSubProviderConfig, error) {
+	ret := NatsPubSubProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	if ret.NumberOfWorkers <= 0 {
+		ret.NumberOfWorkers = 1
+	}
+	if ret.QueueDepth <= 0 {
+		ret.QueueDepth = DefaultQueueDepth
+	}
+	if ret.Stream == "" {
+		ret.Stream = DefaultStream
+	}
+	if ret.SubjectPrefix == "" {
+		ret.SubjectPrefix = DefaultSubjectPrefix
+	}
+	if ret.ConsumerID == "" {
+		ret.ConsumerID = DefaultConsumerID
+	}
+	return ret, err
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package nats
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// natsUrl returns the URL of an in-process test server, or of the NATS server given by TEST_NATS, for
+// example: docker run -p 4222:4222 nats -js
+func natsUrl(t *testing.T) string {
+	if url := os.Getenv("TEST_NATS"); url != "" {
+		return url
+	}
+	return startTestServer(t)
+}
+
+func newTestProvider(t *testing.T, config NatsPubSubProviderConfig) *NatsPubSubProvider {
+	config.Url = natsUrl(t)
+	if config.Stream == "" {
+		// a stream per test keeps the tests apart
+		id := uuid.New().String()
+		config.Stream = "test-" + id
+		config.SubjectPrefix = "test-" + id
+	}
+	provider := &NatsPubSubProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		provider.JetStream.DeleteStream(provider.Ctx, config.Stream)
+		provider.Close()
+	})
+	return provider
+}
+
+func TestWithEmptyConfig(t *testing.T) {
+	provider := NatsPubSubProvider{}
+	err := provider.Init(NatsPubSubProviderConfig{})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.MissingConfig, coaErr.State)
+}
+
+func TestWithDefaults(t *testing.T) {
+	provider := NatsPubSubProvider{}
+	err := provider.Init(NatsPubSubProviderConfig{
+		Name: "test",
+		Url:  "nats://127.0.0.1:1",
+	})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	// initialization fails because there is no server, but the defaults are set
+	assert.Equal(t, v1alpha2.InternalError, coaErr.State)
+	assert.Equal(t, 1, provider.Config.NumberOfWorkers)
+	assert.Equal(t, DefaultQueueDepth, provider.Config.QueueDepth)
+	assert.Equal(t, DefaultStream, provider.Config.Stream)
+	assert.Equal(t, DefaultConsumerID, provider.Config.ConsumerID)
+}
+
+func TestInitWithJsonConfig(t *testing.T) {
+	provider := NatsPubSubProvider{}
+	err := provider.Init(map[string]interface{}{
+		"url":               "nats://127.0.0.1:1",
+		"processingTimeout": "1m",
+		"redeliverInterval": 5000000000,
+	})
+	// initialization fails because there is no server, but the config is read
+	assert.NotNil(t, err)
+	assert.Equal(t, time.Minute, provider.Config.ProcessingTimeout)
+	assert.Equal(t, 5*time.Second, provider.Config.RedeliverInterval)
+
+	err = provider.Init(map[string]interface{}{
+		"url":               "nats://127.0.0.1:1",
+		"processingTimeout": "soon",
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
+func TestWithIncompleteClientCert(t *testing.T) {
+	provider := NatsPubSubProvider{}
+	err := provider.Init(NatsPubSubProviderConfig{
+		Url:        "nats://127.0.0.1:1",
+		ClientCert: "client.pem",
+	})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
+func TestNatsPubSubProviderConfigFromMap(t *testing.T) {
+	config, err := NatsPubSubProviderConfigFromMap(map[string]string{
+		"name":              "test",
+		"url":               "nats://localhost:4222",
+		"consumerID":        "symphony-api",
+		"numberOfWorkers":   "4",
+		"maxDeliver":        "5",
+		"processingTimeout": "1m",
+		"redeliverInterval": "5s",
+		"requiresTLS":       "true",
+		"credentialsFile":   "/etc/nats/user.creds",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "nats://localhost:4222", config.Url)
+	assert.Equal(t, "symphony-api", config.ConsumerID)
+	assert.Equal(t, 4, config.NumberOfWorkers)
+	assert.Equal(t, 5, config.MaxDeliver)
+	assert.Equal(t, time.Minute, config.ProcessingTimeout)
+	assert.Equal(t, 5*time.Second, config.RedeliverInterval)
+	assert.True(t, config.RequiresTLS)
+	assert.Equal(t, "/etc/nats/user.creds", config.CredentialsFile)
+
+	_, err = NatsPubSubProviderConfigFromMap(map[string]string{})
+	assert.NotNil(t, err)
+	_, err = NatsPubSubProviderConfigFromMap(map[string]string{
+		"url":        "nats://localhost:4222",
+		"maxDeliver": "five",
+	})
+	assert.NotNil(t, err)
+	_, err = NatsPubSubProviderConfigFromMap(map[string]string{
+		"url":               "nats://localhost:4222",
+		"processingTimeout": "soon",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestDurableName(t *testing.T) {
+	provider := NatsPubSubProvider{Config: NatsPubSubProviderConfig{ConsumerID: "symphony.api"}}
+	assert.Equal(t, "symphony_api-catalog-sync", provider.durableName("catalog-sync"))
+	assert.Equal(t, "symphony_api-a_b_c", provider.durableName("a.b>c"))
+}
+
+func TestBasicPubSub(t *testing.T) {
+	provider := newTestProvider(t, NatsPubSubProviderConfig{Name: "test"})
+	sig := make(chan string, 1)
+	err := provider.Subscribe("job", func(topic string, event v1alpha2.Event) error {
+		sig <- event.Body.(string)
+		return nil
+	})
+	assert.Nil(t, err)
+	err = provider.Publish("job", v1alpha2.Event{Body: "TEST"})
+	assert.Nil(t, err)
+	select {
+	case msg := <-sig:
+		assert.Equal(t, "TEST", msg)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "event isn't delivered")
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	provider := newTestProvider(t, NatsPubSubProviderConfig{
+		Name:              "test",
+		RedeliverInterval: 100 * time.Millisecond,
+	})
+	var attempts int32
+	sig := make(chan int, 1)
+	provider.Subscribe("trigger", func(topic string, event v1alpha2.Event) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errors.New("not yet")
+		}
+		sig <- 1
+		return nil
+	})
+	provider.Publish("trigger", v1alpha2.Event{Body: "TEST"})
+	select {
+	case <-sig:
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "event isn't delivered again")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestDurableConsumer(t *testing.T) {
+	// a stopped subscription unsubscribes asynchronously, so an event can still reach it and is then
+	// delivered again after ProcessingTimeout
+	provider := newTestProvider(t, NatsPubSubProviderConfig{
+		Name:              "test",
+		ProcessingTimeout: 500 * time.Millisecond,
+	})
+	sub, err := provider.SubscribeWithHandle("heartbeat", func(topic string, event v1alpha2.Event) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, sub.Unsubscribe())
+
+	// events published without subscribers are delivered to the next subscription of the consumer
+	provider.Publish("heartbeat", v1alpha2.Event{Body: "TEST"})
+	sig := make(chan string, 1)
+	provider.Subscribe("heartbeat", func(topic string, event v1alpha2.Event) error {
+		sig <- event.Body.(string)
+		return nil
+	})
+	select {
+	case msg := <-sig:
+		assert.Equal(t, "TEST", msg)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "event isn't delivered")
+	}
+}
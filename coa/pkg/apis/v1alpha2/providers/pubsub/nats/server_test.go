@@ -0,0 +1,428 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal in-process NATS server with only the JetStream subjects the provider uses:
+// STREAM.UPDATE, CONSUMER.CREATE, CONSUMER.MSG.NEXT and the acks of delivered messages. Pull requests
+// don't expire and no idle heartbeats are sent, which is fine for tests shorter than the heartbeat the
+// client checks. The tests run against a real server instead when TEST_NATS is set.
+type testServer struct {
+	listener net.Listener
+	lock     sync.Mutex
+	clients  map[*testClient]bool
+	streams  map[string]*testStream
+	closed   bool
+}
+
+type testClient struct {
+	conn  net.Conn
+	lock  sync.Mutex
+	subs  map[string]string
+	write *bufio.Writer
+}
+
+type testStream struct {
+	name      string
+	subjects  []string
+	seq       uint64
+	msgs      map[uint64]testStoredMsg
+	consumers map[string]*testConsumer
+}
+
+type testStoredMsg struct {
+	subject string
+	data    []byte
+}
+
+type testConsumer struct {
+	name       string
+	filter     string
+	ackWait    time.Duration
+	maxDeliver int
+	dseq       uint64
+	pending    []uint64
+	deliveries map[uint64]int
+	unacked    map[uint64]*time.Timer
+	requests   []*testPull
+	config     json.RawMessage
+}
+
+type testPull struct {
+	reply string
+	batch int
+}
+
+type testAPIError struct {
+	Code        int    `json:"code"`
+	ErrCode     int    `json:"err_code"`
+	Description string `json:"description"`
+}
+
+// startTestServer starts a testServer on a free local port and returns its URL
+func startTestServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start NATS test server: %v", err)
+	}
+	s := &testServer{
+		listener: listener,
+		clients:  make(map[*testClient]bool),
+		streams:  make(map[string]*testStream),
+	}
+	go s.accept()
+	t.Cleanup(s.close)
+	return "nats://" + listener.Addr().String()
+}
+
+func (s *testServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &testClient{conn: conn, subs: make(map[string]string), write: bufio.NewWriter(conn)}
+		s.lock.Lock()
+		s.clients[c] = true
+		s.lock.Unlock()
+		go s.serve(c)
+	}
+}
+
+func (s *testServer) close() {
+	s.lock.Lock()
+	s.closed = true
+	for _, stream := range s.streams {
+		for _, consumer := range stream.consumers {
+			for _, timer := range consumer.unacked {
+				timer.Stop()
+			}
+		}
+	}
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.lock.Unlock()
+	s.listener.Close()
+}
+
+func (s *testServer) serve(c *testClient) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.clients, c)
+		s.lock.Unlock()
+		c.conn.Close()
+	}()
+	port := s.listener.Addr().(*net.TCPAddr).Port
+	c.send(fmt.Sprintf("INFO {\"server_id\":\"test\",\"server_name\":\"test\",\"version\":\"2.10.0\",\"proto\":1,\"host\":\"127.0.0.1\",\"port\":%d,\"headers\":true,\"jetstream\":true,\"max_payload\":1048576}\r\n", port), nil)
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			c.send("PONG\r\n", nil)
+		case "SUB":
+			if len(args) < 3 {
+				return
+			}
+			s.lock.Lock()
+			c.subs[args[len(args)-1]] = args[1]
+			s.lock.Unlock()
+		case "UNSUB":
+			if len(args) < 2 {
+				return
+			}
+			s.lock.Lock()
+			delete(c.subs, args[1])
+			s.lock.Unlock()
+		case "PUB":
+			subject, reply, data, err := readPayload(reader, args)
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.publish(subject, reply, data)
+			s.lock.Unlock()
+		}
+	}
+}
+
+// readPayload reads the subject, reply subject and payload of PUB
+func readPayload(reader *bufio.Reader, args []string) (string, string, []byte, error) {
+	if len(args) < 3 {
+		return "", "", nil, fmt.Errorf("invalid %s", args[0])
+	}
+	subject, reply := args[1], ""
+	if len(args) == 4 {
+		reply = args[2]
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return "", "", nil, err
+	}
+	buf := make([]byte, size+2)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		return "", "", nil, err
+	}
+	return subject, reply, buf[:size], nil
+}
+
+func (c *testClient) send(line string, data []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.write.WriteString(line)
+	if data != nil {
+		c.write.Write(data)
+		c.write.WriteString("\r\n")
+	}
+	c.write.Flush()
+}
+
+// route sends a message to the matching subscriptions of all clients
+func (s *testServer) route(subject string, reply string, data []byte) bool {
+	routed := false
+	for c := range s.clients {
+		for sid, pattern := range c.subs {
+			if !subjectMatches(pattern, subject) {
+				continue
+			}
+			if reply != "" {
+				c.send(fmt.Sprintf("MSG %s %s %s %d\r\n", subject, sid, reply, len(data)), data)
+			} else {
+				c.send(fmt.Sprintf("MSG %s %s %d\r\n", subject, sid, len(data)), data)
+			}
+			routed = true
+		}
+	}
+	return routed
+}
+
+func (s *testServer) respond(reply string, response interface{}) {
+	if reply == "" {
+		return
+	}
+	data, _ := json.Marshal(response)
+	s.route(reply, "", data)
+}
+
+func (s *testServer) publish(subject string, reply string, data []byte) {
+	switch {
+	case strings.HasPrefix(subject, "$JS.API."):
+		s.handleAPI(strings.TrimPrefix(subject, "$JS.API."), reply, data)
+		return
+	case strings.HasPrefix(subject, "$JS.ACK."):
+		s.handleAck(strings.Split(strings.TrimPrefix(subject, "$JS.ACK."), "."), data)
+		return
+	}
+	s.route(subject, reply, data)
+	for _, stream := range s.streams {
+		for _, pattern := range stream.subjects {
+			if subjectMatches(pattern, subject) {
+				stream.seq++
+				stream.msgs[stream.seq] = testStoredMsg{subject: subject, data: data}
+				for _, consumer := range stream.consumers {
+					if subjectMatches(consumer.filter, subject) {
+						consumer.pending = append(consumer.pending, stream.seq)
+						s.dispatch(stream, consumer)
+					}
+				}
+				s.respond(reply, map[string]interface{}{"stream": stream.name, "seq": stream.seq})
+				return
+			}
+		}
+	}
+}
+
+func (s *testServer) handleAPI(api string, reply string, data []byte) {
+	tokens := strings.Split(api, ".")
+	switch {
+	// the client creates streams by updating them first, so an update of a missing stream creates it
+	case len(tokens) == 3 && tokens[0] == "STREAM" && tokens[1] == "UPDATE":
+		var config struct {
+			Subjects []string `json:"subjects"`
+		}
+		json.Unmarshal(data, &config)
+		stream, ok := s.streams[tokens[2]]
+		if !ok {
+			stream = &testStream{name: tokens[2], msgs: make(map[uint64]testStoredMsg), consumers: make(map[string]*testConsumer)}
+			s.streams[stream.name] = stream
+		}
+		stream.subjects = config.Subjects
+		s.respond(reply, map[string]interface{}{"config": json.RawMessage(data), "created": time.Now(), "state": map[string]interface{}{"messages": len(stream.msgs)}})
+	case len(tokens) >= 4 && tokens[0] == "CONSUMER" && tokens[1] == "CREATE":
+		var request struct {
+			Config json.RawMessage `json:"config"`
+		}
+		var config struct {
+			Durable       string        `json:"durable_name"`
+			FilterSubject string        `json:"filter_subject"`
+			AckWait       time.Duration `json:"ack_wait"`
+			MaxDeliver    int           `json:"max_deliver"`
+		}
+		json.Unmarshal(data, &request)
+		json.Unmarshal(request.Config, &config)
+		stream, ok := s.streams[tokens[2]]
+		if !ok {
+			s.respond(reply, map[string]interface{}{"error": testAPIError{Code: 404, ErrCode: 10059, Description: "stream not found"}})
+			return
+		}
+		consumer, ok := stream.consumers[tokens[3]]
+		if !ok {
+			consumer = &testConsumer{name: tokens[3], deliveries: make(map[uint64]int), unacked: make(map[uint64]*time.Timer)}
+			for seq := uint64(1); seq <= stream.seq; seq++ {
+				if msg, ok := stream.msgs[seq]; ok && (config.FilterSubject == "" || subjectMatches(config.FilterSubject, msg.subject)) {
+					consumer.pending = append(consumer.pending, seq)
+				}
+			}
+			stream.consumers[consumer.name] = consumer
+		}
+		consumer.filter = config.FilterSubject
+		if consumer.filter == "" {
+			consumer.filter = ">"
+		}
+		consumer.ackWait = config.AckWait
+		if consumer.ackWait <= 0 {
+			consumer.ackWait = 30 * time.Second
+		}
+		consumer.maxDeliver = config.MaxDeliver
+		consumer.config = request.Config
+		s.respond(reply, map[string]interface{}{"stream_name": stream.name, "name": consumer.name, "config": consumer.config, "created": time.Now()})
+	case len(tokens) == 5 && tokens[0] == "CONSUMER" && tokens[1] == "MSG" && tokens[2] == "NEXT":
+		var request struct {
+			Batch int `json:"batch"`
+		}
+		json.Unmarshal(data, &request)
+		if stream, ok := s.streams[tokens[3]]; ok {
+			if consumer, ok := stream.consumers[tokens[4]]; ok && reply != "" {
+				if request.Batch <= 0 {
+					request.Batch = 1
+				}
+				consumer.requests = append(consumer.requests, &testPull{reply: reply, batch: request.Batch})
+				s.dispatch(stream, consumer)
+			}
+		}
+	default:
+		s.respond(reply, map[string]interface{}{"error": testAPIError{Code: 400, ErrCode: 10003, Description: "not supported by the test server"}})
+	}
+}
+
+// dispatch delivers the pending messages of a consumer to its pull requests. Requests whose reply subject
+// has no subscribers anymore, such as the requests of a stopped subscription, are dropped.
+func (s *testServer) dispatch(stream *testStream, consumer *testConsumer) {
+	for len(consumer.pending) > 0 && len(consumer.requests) > 0 {
+		request := consumer.requests[0]
+		seq := consumer.pending[0]
+		msg, ok := stream.msgs[seq]
+		if !ok {
+			consumer.pending = consumer.pending[1:]
+			continue
+		}
+		consumer.dseq++
+		consumer.deliveries[seq]++
+		ack := fmt.Sprintf("$JS.ACK.%s.%s.%d.%d.%d.%d.%d", stream.name, consumer.name, consumer.deliveries[seq], seq, consumer.dseq, time.Now().UnixNano(), len(consumer.pending)-1)
+		if !s.route(request.reply, ack, msg.data) {
+			consumer.dseq--
+			consumer.deliveries[seq]--
+			consumer.requests = consumer.requests[1:]
+			continue
+		}
+		consumer.pending = consumer.pending[1:]
+		consumer.unacked[seq] = time.AfterFunc(consumer.ackWait, func() {
+			s.redeliver(stream, consumer, seq)
+		})
+		request.batch--
+		if request.batch == 0 {
+			consumer.requests = consumer.requests[1:]
+		}
+	}
+}
+
+func (s *testServer) handleAck(tokens []string, data []byte) {
+	if len(tokens) < 5 {
+		return
+	}
+	stream, ok := s.streams[tokens[0]]
+	if !ok {
+		return
+	}
+	consumer, ok := stream.consumers[tokens[1]]
+	if !ok {
+		return
+	}
+	seq, err := strconv.ParseUint(tokens[3], 10, 64)
+	if err != nil {
+		return
+	}
+	timer, ok := consumer.unacked[seq]
+	if !ok {
+		return
+	}
+	body := string(data)
+	switch {
+	case strings.HasPrefix(body, "-NAK"):
+		var delay struct {
+			Delay time.Duration `json:"delay"`
+		}
+		json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(body, "-NAK"))), &delay)
+		timer.Reset(delay.Delay)
+	default:
+		// +ACK and +TERM
+		timer.Stop()
+		delete(consumer.unacked, seq)
+	}
+}
+
+// redeliver delivers a message again when it isn't acknowledged in time or is nacked, until MaxDeliver is reached
+func (s *testServer) redeliver(stream *testStream, consumer *testConsumer, seq uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	if _, ok := consumer.unacked[seq]; !ok {
+		return
+	}
+	delete(consumer.unacked, seq)
+	if consumer.maxDeliver > 0 && consumer.deliveries[seq] >= consumer.maxDeliver {
+		return
+	}
+	consumer.pending = append([]uint64{seq}, consumer.pending...)
+	s.dispatch(stream, consumer)
+}
+
+func subjectMatches(pattern string, subject string) bool {
+	p := strings.Split(pattern, ".")
+	s := strings.Split(subject, ".")
+	for i, token := range p {
+		if token == ">" {
+			return len(s) > i
+		}
+		if i >= len(s) || (token != "*" && token != s[i]) {
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
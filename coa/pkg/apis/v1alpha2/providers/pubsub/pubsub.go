@@ -7,12 +7,45 @@
 package pubsub
 
 import (
+	"context"
+
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 )
 
+const (
+	// OrderingKeyMetadata is the event metadata that holds the ordering key of an event. Events with the
+	// same ordering key are delivered to a subscriber one at a time, in the order they're published.
+	OrderingKeyMetadata = "orderingKey"
+)
+
+// ISubscription is a handle to a subscription
+type ISubscription interface {
+	// Unsubscribe stops delivering events to the handler of the subscription
+	Unsubscribe() error
+}
+
 type IPubSubProvider interface {
 	Init(config providers.IProviderConfig) error
 	Publish(topic string, message v1alpha2.Event) error
 	Subscribe(topic string, handler v1alpha2.EventHandler) error
 }
+
+// IUnsubscribablePubSubProvider is implemented by pub-sub providers whose subscriptions can be removed.
+// SubscribeWithHandle subscribes like Subscribe and returns a handle to unsubscribe.
+type IUnsubscribablePubSubProvider interface {
+	SubscribeWithHandle(topic string, handler v1alpha2.EventHandler) (ISubscription, error)
+}
+
+// IQueueDepthProvider is implemented by pub-sub providers that can report the number of events waiting
+// to be handled in each topic
+type IQueueDepthProvider interface {
+	QueueDepth() map[string]int
+}
+
+// IShutdownProvider is implemented by pub-sub providers that can stop delivering events gracefully.
+// Shutdown stops taking new events, waits for the events being handled until ctx is done and releases
+// the events that aren't handled, so that they're delivered again where the provider allows it.
+type IShutdownProvider interface {
+	Shutdown(ctx context.Context) error
+}
@@ -13,11 +13,13 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/go-redis/redis/v7"
@@ -33,6 +35,7 @@ type RedisPubSubProvider struct {
 	Ctx         context.Context
 	Cancel      context.CancelFunc
 	Context     *contexts.ManagerContext
+	workers     sync.WaitGroup
 }
 
 type RedisMessageWrapper struct {
@@ -180,21 +183,67 @@ func (i *RedisPubSubProvider) Init(config providers.IProviderConfig) error {
 	i.Ctx, i.Cancel = context.WithCancel(context.Background())
 	i.Queue = make(chan RedisMessageWrapper, int(i.Config.QueueDepth))
 	for k := uint(0); k < uint(i.Config.NumberOfWorkers); k++ {
+		i.workers.Add(1)
 		go i.worker()
 	}
 	return nil
 }
 
+// CheckHealth checks that the Redis server is reachable
+func (i *RedisPubSubProvider) CheckHealth(ctx context.Context) error {
+	if i.Client == nil {
+		return v1alpha2.NewCOAError(nil, "redis stream: provider is not initialized", v1alpha2.InternalError)
+	}
+	if _, err := i.Client.WithContext(ctx).Ping().Result(); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("redis stream: error connecting to redis at %s", i.Config.Host), v1alpha2.InternalError)
+	}
+	return nil
+}
+
 func (i *RedisPubSubProvider) worker() {
+	defer i.workers.Done()
 	for {
 		select {
 		case <-i.Ctx.Done():
 			return
 		case msg := <-i.Queue:
+			if i.Ctx.Err() != nil {
+				// leave the message pending, to be reclaimed once the processing timeout expires
+				return
+			}
 			i.processMessage(msg)
 		}
 	}
 }
+
+// Shutdown stops reading messages and waits for the workers to finish the messages they're handling
+// until ctx is done. Messages read but not handled aren't acknowledged, so they stay pending in the
+// consumer group and are reclaimed, by this consumer or another one with the same ConsumerID, once
+// ProcessingTimeout expires.
+func (i *RedisPubSubProvider) Shutdown(ctx context.Context) error {
+	if i.Cancel == nil || i.Ctx.Err() != nil {
+		return nil
+	}
+	i.Cancel()
+	done := make(chan struct{})
+	go func() {
+		i.workers.Wait()
+		close(done)
+	}()
+	var ret error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		ret = v1alpha2.NewCOAError(ctx.Err(), "redis stream: workers didn't finish the messages being handled", v1alpha2.InternalError)
+	}
+	if len(i.Queue) > 0 {
+		mLog.Infof("  P (Redis PubSub) : left %d message(s) pending at shutdown", len(i.Queue))
+	}
+	if err := i.Client.Close(); err != nil && ret == nil {
+		ret = v1alpha2.NewCOAError(err, "redis stream: failed to close the connection", v1alpha2.InternalError)
+	}
+	return ret
+}
 func (i *RedisPubSubProvider) processMessage(msg RedisMessageWrapper) error {
 	var evt v1alpha2.Event
 	err := json.Unmarshal([]byte(msg.Message.(string)), &evt)
@@ -222,20 +271,43 @@ func (i *RedisPubSubProvider) Publish(topic string, event v1alpha2.Event) error
 	return nil
 }
 func (i *RedisPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
+	_, err := i.SubscribeWithHandle(topic, handler)
+	return err
+}
+
+// redisSubscription stops the loops that read the messages of a subscription. Messages read but not
+// handled yet aren't acknowledged, so they stay pending in the consumer group.
+type redisSubscription struct {
+	cancel context.CancelFunc
+}
+
+func (s *redisSubscription) Unsubscribe() error {
+	s.cancel()
+	return nil
+}
+
+func (i *RedisPubSubProvider) SubscribeWithHandle(topic string, handler v1alpha2.EventHandler) (pubsub.ISubscription, error) {
 	err := i.Client.XGroupCreateMkStream(topic, i.Config.ConsumerID, "0").Err()
 	//Ignore BUSYGROUP errors
 	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
 		mLog.Debugf("  P (Redis PubSub) : failed to subscribe %v", err)
-		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to subsceribe to topic %s", topic), v1alpha2.InternalError)
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to subsceribe to topic %s", topic), v1alpha2.InternalError)
 	}
-	go i.pollNewMessagesLoop(topic, handler)
-	go i.reclaimPendingMessagesLoop(topic, handler)
-	return nil
+	ctx, cancel := context.WithCancel(i.Ctx)
+	subscribed := func(topic string, event v1alpha2.Event) error {
+		if ctx.Err() != nil {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("subscription to topic %s is cancelled", topic), v1alpha2.InternalError)
+		}
+		return handler(topic, event)
+	}
+	go i.pollNewMessagesLoop(ctx, topic, subscribed)
+	go i.reclaimPendingMessagesLoop(ctx, topic, subscribed)
+	return &redisSubscription{cancel: cancel}, nil
 }
 
-func (i *RedisPubSubProvider) pollNewMessagesLoop(topic string, handler v1alpha2.EventHandler) {
+func (i *RedisPubSubProvider) pollNewMessagesLoop(ctx context.Context, topic string, handler v1alpha2.EventHandler) {
 	for {
-		if i.Ctx.Err() != nil {
+		if ctx.Err() != nil {
 			return
 		}
 		streams, err := i.Client.XReadGroup(&redis.XReadGroupArgs{
@@ -280,15 +352,16 @@ func createRedisMessageWrapper(topic string, handler v1alpha2.EventHandler, msg
 	}
 }
 
-func (i *RedisPubSubProvider) reclaimPendingMessagesLoop(topic string, handler v1alpha2.EventHandler) {
+func (i *RedisPubSubProvider) reclaimPendingMessagesLoop(ctx context.Context, topic string, handler v1alpha2.EventHandler) {
 	if i.Config.ProcessingTimeout == 0 || i.Config.RedeliverInterval == 0 {
 		return
 	}
 	i.reclaimPendingMessages(topic, handler)
 	reclaimTicker := time.NewTicker(i.Config.RedeliverInterval)
+	defer reclaimTicker.Stop()
 	for {
 		select {
-		case <-i.Ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-reclaimTicker.C:
 			i.reclaimPendingMessages(topic, handler)
@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	DefaultMaxRetries    = 3
+	DefaultRetryInterval = "1s"
+	DefaultTimeout       = "10s"
+)
+
+// HttpSinkProvider posts events as CloudEvents in the structured JSON format to a webhook. A request
+// that fails, or gets a 429 or 5xx response, is retried with an exponential backoff.
+type HttpSinkProvider struct {
+	Config     HttpSinkProviderConfig
+	Context    *contexts.ManagerContext
+	client     *http.Client
+	maxRetries int
+}
+
+type HttpSinkProviderConfig struct {
+	Name    string            `json:"name"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// MaxRetries is a pointer so that 0, which turns retries off, can be told apart from a missing setting
+	MaxRetries    *int   `json:"maxRetries,omitempty"`
+	RetryInterval string `json:"retryInterval,omitempty"`
+	Timeout       string `json:"timeout,omitempty"`
+}
+
+func HttpSinkProviderConfigFromMap(properties map[string]string) (HttpSinkProviderConfig, error) {
+	ret := HttpSinkProviderConfig{}
+	ret.Name = properties["name"]
+	ret.Url = properties["url"]
+	ret.RetryInterval = properties["retryInterval"]
+	ret.Timeout = properties["timeout"]
+	if v, ok := properties["maxRetries"]; ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'maxRetries' setting of HTTP sink provider", v1alpha2.BadConfig)
+		}
+		ret.MaxRetries = &n
+	}
+	// headers are set as header.<name> properties
+	for k, v := range properties {
+		if strings.HasPrefix(k, "header.") {
+			if ret.Headers == nil {
+				ret.Headers = make(map[string]string)
+			}
+			ret.Headers[strings.TrimPrefix(k, "header.")] = v
+		}
+	}
+	return ret, nil
+}
+
+func (i *HttpSinkProvider) InitWithMap(properties map[string]string) error {
+	config, err := HttpSinkProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *HttpSinkProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (m *HttpSinkProvider) ID() string {
+	return m.Config.Name
+}
+
+func (i *HttpSinkProvider) Init(config providers.IProviderConfig) error {
+	updateConfig, err := toHttpSinkProviderConfig(config)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "provided config is not a valid HTTP sink provider config", v1alpha2.BadConfig)
+	}
+	if updateConfig.Url == "" {
+		return v1alpha2.NewCOAError(nil, "HTTP sink provider url is not set", v1alpha2.MissingConfig)
+	}
+	maxRetries := DefaultMaxRetries
+	if updateConfig.MaxRetries != nil {
+		maxRetries = *updateConfig.MaxRetries
+	}
+	if maxRetries < 0 {
+		return v1alpha2.NewCOAError(nil, "the 'maxRetries' setting of HTTP sink provider can't be negative", v1alpha2.BadConfig)
+	}
+	if updateConfig.RetryInterval == "" {
+		updateConfig.RetryInterval = DefaultRetryInterval
+	}
+	if updateConfig.Timeout == "" {
+		updateConfig.Timeout = DefaultTimeout
+	}
+	if _, err = time.ParseDuration(updateConfig.RetryInterval); err != nil {
+		return v1alpha2.NewCOAError(err, "invalid duration value in the 'retryInterval' setting of HTTP sink provider", v1alpha2.BadConfig)
+	}
+	timeout, err := time.ParseDuration(updateConfig.Timeout)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid duration value in the 'timeout' setting of HTTP sink provider", v1alpha2.BadConfig)
+	}
+	i.Config = updateConfig
+	i.maxRetries = maxRetries
+	i.client = &http.Client{Timeout: timeout}
+	return nil
+}
+
+func toHttpSinkProviderConfig(config providers.IProviderConfig) (HttpSinkProviderConfig, error) {
+	ret := HttpSinkProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func (i *HttpSinkProvider) Send(ctx context.Context, event v1alpha2.CloudEvent) error {
+	ctx, span := observability.StartSpan("HTTP Sink Provider", ctx, &map[string]string{
+		"method": "Send",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var data []byte
+	data, err = json.Marshal(event)
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, "failed to marshal event", v1alpha2.BadRequest)
+		return err
+	}
+	interval, _ := time.ParseDuration(i.Config.RetryInterval)
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = i.post(ctx, data)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= i.maxRetries {
+			sLog.Errorf("  P (HTTP Sink): failed to send event %s after %d attempt(s): %v", event.Id, attempt+1, err)
+			return err
+		}
+		sLog.Debugf("  P (HTTP Sink): failed to send event %s, retrying: %v", event.Id, err)
+		select {
+		case <-time.After(interval << attempt):
+		case <-ctx.Done():
+			err = v1alpha2.NewCOAError(ctx.Err(), "sending event is cancelled", v1alpha2.InternalError)
+			return err
+		}
+	}
+}
+
+// post sends an event once, and returns whether a failure may succeed when it's retried
+func (i *HttpSinkProvider) post(ctx context.Context, data []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.Config.Url, bytes.NewBuffer(data))
+	if err != nil {
+		return false, v1alpha2.NewCOAError(err, "failed to create request", v1alpha2.BadConfig)
+	}
+	req.Header.Set("Content-Type", v1alpha2.CloudEventsContentType)
+	for k, v := range i.Config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return true, v1alpha2.NewCOAError(err, "failed to send event", v1alpha2.InternalError)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	message := fmt.Sprintf("failed to send event: unexpected status code %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return true, v1alpha2.NewCOAError(nil, message, v1alpha2.InternalError)
+	}
+	return false, v1alpha2.NewCOAError(nil, message, v1alpha2.BadRequest)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEmptyConfig(t *testing.T) {
+	provider := HttpSinkProvider{}
+	err := provider.Init(HttpSinkProviderConfig{})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.MissingConfig, coaErr.State)
+}
+
+func TestHttpSinkProviderConfigFromMap(t *testing.T) {
+	config, err := HttpSinkProviderConfigFromMap(map[string]string{
+		"name":                 "test",
+		"url":                  "http://localhost:8080/events",
+		"maxRetries":           "5",
+		"retryInterval":        "2s",
+		"header.Authorization": "Bearer token",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/events", config.Url)
+	assert.Equal(t, 5, *config.MaxRetries)
+	assert.Equal(t, "2s", config.RetryInterval)
+	assert.Equal(t, "Bearer token", config.Headers["Authorization"])
+
+	_, err = HttpSinkProviderConfigFromMap(map[string]string{
+		"url":        "http://localhost:8080/events",
+		"maxRetries": "many",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSend(t *testing.T) {
+	var received v1alpha2.CloudEvent
+	var contentType, auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	provider := HttpSinkProvider{}
+	err := provider.Init(HttpSinkProviderConfig{
+		Url:     ts.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	assert.Nil(t, err)
+	event := v1alpha2.Event{Body: "TEST"}.ToCloudEvent("job", "")
+	err = provider.Send(context.Background(), event)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.CloudEventsContentType, contentType)
+	assert.Equal(t, "Bearer token", auth)
+	assert.Equal(t, event.Id, received.Id)
+	assert.Equal(t, "org.eclipse.symphony.job", received.Type)
+}
+
+func TestSendRetries(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	provider := HttpSinkProvider{}
+	err := provider.Init(HttpSinkProviderConfig{
+		Url:           ts.URL,
+		RetryInterval: "10ms",
+	})
+	assert.Nil(t, err)
+	err = provider.Send(context.Background(), v1alpha2.Event{Body: "TEST"}.ToCloudEvent("job", ""))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestSendGivesUp(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	retries := 2
+	provider := HttpSinkProvider{}
+	err := provider.Init(HttpSinkProviderConfig{
+		Url:           ts.URL,
+		MaxRetries:    &retries,
+		RetryInterval: "10ms",
+	})
+	assert.Nil(t, err)
+	err = provider.Send(context.Background(), v1alpha2.Event{Body: "TEST"}.ToCloudEvent("job", ""))
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestSendNoRetryOnBadRequest(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	provider := HttpSinkProvider{}
+	err := provider.Init(HttpSinkProviderConfig{
+		Url:           ts.URL,
+		RetryInterval: "10ms",
+	})
+	assert.Nil(t, err)
+	err = provider.Send(context.Background(), v1alpha2.Event{Body: "TEST"}.ToCloudEvent("job", ""))
+	assert.True(t, v1alpha2.IsBadRequest(err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestSendWithoutRetries(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	provider := HttpSinkProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"url":           ts.URL,
+		"maxRetries":    "0",
+		"retryInterval": "10ms",
+	})
+	assert.Nil(t, err)
+	err = provider.Send(context.Background(), v1alpha2.Event{Body: "TEST"}.ToCloudEvent("job", ""))
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	err = provider.InitWithMap(map[string]string{
+		"url":        ts.URL,
+		"maxRetries": "-1",
+	})
+	assert.NotNil(t, err)
+}
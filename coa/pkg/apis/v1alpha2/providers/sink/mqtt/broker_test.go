@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is a minimal embedded MQTT 3.1.1 broker for tests, modeled on the one of the MQTT target
+// provider tests. It records the messages that are published to it, at QoS 0-2, and checks
+// username/password, but doesn't support subscriptions.
+type testBroker struct {
+	Address  string
+	Username string
+	Password string
+
+	listener  net.Listener
+	lock      sync.Mutex
+	conns     map[net.Conn]bool
+	published []publishedMessage
+}
+
+type publishedMessage struct {
+	Topic    string
+	QoS      byte
+	Retained bool
+	Payload  []byte
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := &testBroker{
+		Address:  "tcp://" + listener.Addr().String(),
+		listener: listener,
+		conns:    map[net.Conn]bool{},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+func (b *testBroker) Close() {
+	b.listener.Close()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for c := range b.conns {
+		c.Close()
+	}
+}
+
+func (b *testBroker) Published(topic string) []publishedMessage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ret := make([]publishedMessage, 0)
+	for _, m := range b.published {
+		if m.Topic == topic {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	b.lock.Lock()
+	b.conns[conn] = true
+	b.lock.Unlock()
+	defer func() {
+		b.lock.Lock()
+		delete(b.conns, conn)
+		b.lock.Unlock()
+		conn.Close()
+	}()
+	write := func(data []byte) {
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		conn.Write(data)
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case 1: // CONNECT
+			if !b.checkCredentials(body) {
+				write([]byte{0x20, 0x02, 0x00, 0x04})
+				return
+			}
+			write([]byte{0x20, 0x02, 0x00, 0x00})
+		case 3: // PUBLISH
+			qos := (header >> 1) & 0x03
+			topic, rest := readString(body)
+			if qos > 0 {
+				id := rest[:2]
+				rest = rest[2:]
+				if qos == 1 {
+					write([]byte{0x40, 0x02, id[0], id[1]})
+				} else {
+					write([]byte{0x50, 0x02, id[0], id[1]})
+				}
+			}
+			b.lock.Lock()
+			b.published = append(b.published, publishedMessage{Topic: topic, QoS: qos, Retained: header&0x01 == 1, Payload: rest})
+			b.lock.Unlock()
+		case 6: // PUBREL
+			write([]byte{0x70, 0x02, body[0], body[1]})
+		case 12: // PINGREQ
+			write([]byte{0xD0, 0x00})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func (b *testBroker) checkCredentials(body []byte) bool {
+	_, rest := readString(body) // protocol name
+	flags := rest[1]
+	rest = rest[4:]
+	_, rest = readString(rest) // client ID
+	if flags&0x04 != 0 {
+		_, rest = readString(rest) // will topic
+		_, rest = readString(rest) // will message
+	}
+	username, password := "", ""
+	if flags&0x80 != 0 {
+		username, rest = readString(rest)
+	}
+	if flags&0x40 != 0 {
+		password, _ = readString(rest)
+	}
+	return b.Username == "" || (b.Username == username && b.Password == password)
+}
+
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := 0
+	for shift := 0; ; shift += 7 {
+		if shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	return header, body, err
+}
+
+func readString(data []byte) (string, []byte) {
+	length := int(binary.BigEndian.Uint16(data))
+	return string(data[2 : 2+length]), data[2+length:]
+}
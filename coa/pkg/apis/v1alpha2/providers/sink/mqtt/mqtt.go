@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	gmqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	DefaultTimeout = "10s"
+)
+
+// MqttSinkProvider publishes events as CloudEvents in the structured JSON format to an MQTT topic. The
+// topic can contain {type} and {subject}, which are replaced with the type and the subject of the event.
+type MqttSinkProvider struct {
+	Config     MqttSinkProviderConfig
+	Context    *contexts.ManagerContext
+	MQTTClient gmqtt.Client
+	timeout    time.Duration
+}
+
+type MqttSinkProviderConfig struct {
+	Name          string `json:"name"`
+	BrokerAddress string `json:"brokerAddress"`
+	ClientID      string `json:"clientID"`
+	Topic         string `json:"topic"`
+	QoS           int    `json:"qos,omitempty"`
+	Retained      bool   `json:"retained,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Timeout       string `json:"timeout,omitempty"`
+}
+
+func MqttSinkProviderConfigFromMap(properties map[string]string) (MqttSinkProviderConfig, error) {
+	ret := MqttSinkProviderConfig{}
+	ret.Name = properties["name"]
+	ret.BrokerAddress = properties["brokerAddress"]
+	ret.ClientID = properties["clientID"]
+	ret.Topic = properties["topic"]
+	ret.Username = properties["username"]
+	ret.Password = properties["password"]
+	ret.Timeout = properties["timeout"]
+	if v, ok := properties["qos"]; ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'qos' setting of MQTT sink provider", v1alpha2.BadConfig)
+		}
+		ret.QoS = n
+	}
+	if v, ok := properties["retained"]; ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'retained' setting of MQTT sink provider", v1alpha2.BadConfig)
+		}
+		ret.Retained = b
+	}
+	return ret, nil
+}
+
+func (i *MqttSinkProvider) InitWithMap(properties map[string]string) error {
+	config, err := MqttSinkProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *MqttSinkProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (m *MqttSinkProvider) ID() string {
+	return m.Config.Name
+}
+
+func (i *MqttSinkProvider) Init(config providers.IProviderConfig) error {
+	updateConfig, err := toMqttSinkProviderConfig(config)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "provided config is not a valid MQTT sink provider config", v1alpha2.BadConfig)
+	}
+	if updateConfig.BrokerAddress == "" || updateConfig.Topic == "" {
+		return v1alpha2.NewCOAError(nil, "MQTT sink provider brokerAddress and topic are required", v1alpha2.MissingConfig)
+	}
+	if updateConfig.QoS < 0 || updateConfig.QoS > 2 {
+		return v1alpha2.NewCOAError(nil, "MQTT sink provider qos must be 0, 1 or 2", v1alpha2.BadConfig)
+	}
+	if updateConfig.Timeout == "" {
+		updateConfig.Timeout = DefaultTimeout
+	}
+	timeout, err := time.ParseDuration(updateConfig.Timeout)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid duration value in the 'timeout' setting of MQTT sink provider", v1alpha2.BadConfig)
+	}
+	i.Config = updateConfig
+	i.timeout = timeout
+
+	opts := gmqtt.NewClientOptions().AddBroker(i.Config.BrokerAddress).SetClientID(i.Config.ClientID)
+	opts.SetKeepAlive(2 * time.Second)
+	opts.SetPingTimeout(1 * time.Second)
+	opts.SetAutoReconnect(true)
+	if i.Config.Username != "" {
+		opts.SetUsername(i.Config.Username)
+		opts.SetPassword(i.Config.Password)
+	}
+	i.MQTTClient = gmqtt.NewClient(opts)
+	token := i.MQTTClient.Connect()
+	if !token.WaitTimeout(i.timeout) {
+		return v1alpha2.NewCOAError(nil, "timed out connecting to MQTT broker", v1alpha2.InternalError)
+	}
+	if token.Error() != nil {
+		return v1alpha2.NewCOAError(token.Error(), "failed to connect to MQTT broker", v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func toMqttSinkProviderConfig(config providers.IProviderConfig) (MqttSinkProviderConfig, error) {
+	ret := MqttSinkProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func (i *MqttSinkProvider) Send(ctx context.Context, event v1alpha2.CloudEvent) error {
+	_, span := observability.StartSpan("MQTT Sink Provider", ctx, &map[string]string{
+		"method": "Send",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var data []byte
+	data, err = json.Marshal(event)
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, "failed to marshal event", v1alpha2.BadRequest)
+		return err
+	}
+	topic := strings.NewReplacer("{type}", event.Type, "{subject}", event.Subject).Replace(i.Config.Topic)
+	token := i.MQTTClient.Publish(topic, byte(i.Config.QoS), i.Config.Retained, data)
+	if !token.WaitTimeout(i.timeout) {
+		err = v1alpha2.NewCOAError(nil, "timed out publishing event to MQTT broker", v1alpha2.InternalError)
+		return err
+	}
+	if token.Error() != nil {
+		err = v1alpha2.NewCOAError(token.Error(), "failed to publish event to MQTT broker", v1alpha2.InternalError)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMqttSinkProviderConfigFromMap(t *testing.T) {
+	config, err := MqttSinkProviderConfigFromMap(map[string]string{
+		"name":          "test",
+		"brokerAddress": "tcp://localhost:1883",
+		"clientID":      "symphony-sink",
+		"topic":         "symphony/{type}",
+		"qos":           "1",
+		"retained":      "true",
+		"username":      "user",
+		"password":      "secret",
+		"timeout":       "5s",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp://localhost:1883", config.BrokerAddress)
+	assert.Equal(t, "symphony-sink", config.ClientID)
+	assert.Equal(t, "symphony/{type}", config.Topic)
+	assert.Equal(t, 1, config.QoS)
+	assert.True(t, config.Retained)
+	assert.Equal(t, "user", config.Username)
+	assert.Equal(t, "secret", config.Password)
+	assert.Equal(t, "5s", config.Timeout)
+
+	_, err = MqttSinkProviderConfigFromMap(map[string]string{"qos": "one"})
+	assert.NotNil(t, err)
+	_, err = MqttSinkProviderConfigFromMap(map[string]string{"retained": "maybe"})
+	assert.NotNil(t, err)
+}
+
+func TestInitWithBadConfig(t *testing.T) {
+	provider := MqttSinkProvider{}
+	err := provider.Init(MqttSinkProviderConfig{Topic: "symphony"})
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(MqttSinkProviderConfig{BrokerAddress: "tcp://127.0.0.1:1883"})
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(MqttSinkProviderConfig{BrokerAddress: "tcp://127.0.0.1:1883", Topic: "symphony", QoS: 3})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(MqttSinkProviderConfig{BrokerAddress: "tcp://127.0.0.1:1883", Topic: "symphony", Timeout: "soon"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestInitWithWrongCredentials(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.Username = "user"
+	broker.Password = "secret"
+	provider := MqttSinkProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"brokerAddress": broker.Address,
+		"clientID":      "symphony-sink",
+		"topic":         "symphony",
+		"username":      "user",
+		"password":      "wrong",
+		"timeout":       "5s",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, err.(v1alpha2.COAError).State)
+}
+
+func TestSend(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.Username = "user"
+	broker.Password = "secret"
+	provider := MqttSinkProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name":          "test",
+		"brokerAddress": broker.Address,
+		"clientID":      "symphony-sink",
+		"topic":         "symphony/{type}/{subject}",
+		"qos":           "1",
+		"retained":      "true",
+		"username":      "user",
+		"password":      "secret",
+		"timeout":       "5s",
+	})
+	assert.Nil(t, err)
+	defer provider.MQTTClient.Disconnect(0)
+
+	event := v1alpha2.Event{
+		Metadata: v1alpha2.NewCloudEventMetadata("org.eclipse.symphony.deployment.succeeded", "instance1"),
+		Body:     "TEST",
+	}.ToCloudEvent("deployment", "https://symphony.example.com")
+	err = provider.Send(context.Background(), event)
+	assert.Nil(t, err)
+
+	topic := "symphony/org.eclipse.symphony.deployment.succeeded/instance1"
+	assert.Eventually(t, func() bool {
+		return len(broker.Published(topic)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	message := broker.Published(topic)[0]
+	assert.Equal(t, byte(1), message.QoS)
+	assert.True(t, message.Retained)
+	var received v1alpha2.CloudEvent
+	assert.Nil(t, json.Unmarshal(message.Payload, &received))
+	assert.Equal(t, event.Id, received.Id)
+	assert.Equal(t, "instance1", received.Subject)
+	assert.Equal(t, "https://symphony.example.com", received.Source)
+}
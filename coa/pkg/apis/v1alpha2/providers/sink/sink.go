@@ -0,0 +1,18 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sink
+
+import (
+	"context"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// ISinkProvider forwards Symphony events to an external system
+type ISinkProvider interface {
+	Send(ctx context.Context, event v1alpha2.CloudEvent) error
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ConfigChange is a difference between the configuration a host runs with and a new configuration
+// it's asked to reload
+type ConfigChange struct {
+	// Path locates the changed setting, such as "api.vendors[solution].managers[solution-manager].properties"
+	Path    string `json:"path"`
+	Applied bool   `json:"applied"`
+	// Reason tells why a change isn't applied. Such changes take effect once the host is restarted.
+	Reason string `json:"reason,omitempty"`
+}
+
+func AppliedChange(path string) ConfigChange {
+	return ConfigChange{Path: path, Applied: true}
+}
+
+func RejectedChange(path string, reason string) ConfigChange {
+	return ConfigChange{Path: path, Reason: reason}
+}
+
+// ConfigEqual compares two configurations by their JSON forms, so that a typed configuration equals
+// the map it was read from
+func ConfigEqual(a interface{}, b interface{}) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	return bytes.Equal(aData, bData)
+}
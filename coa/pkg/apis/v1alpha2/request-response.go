@@ -74,10 +74,17 @@ func (c COAResponse) Println() {
 
 type COAHandler func(COARequest) COAResponse
 
+// COAStreamHandler serves a watch-style request. It sends a response for every change, and returns when
+// the request context is done or when there are no more changes to watch.
+type COAStreamHandler func(request COARequest, send func(COAResponse) error) error
+
 type Endpoint struct {
 	Methods    []string
 	Version    string
 	Route      string
 	Handler    COAHandler
 	Parameters []string
+	// StreamHandler serves watch-style requests on bindings that support streaming, such as gRPC. It's
+	// optional, and bindings that don't stream use Handler.
+	StreamHandler COAStreamHandler
 }
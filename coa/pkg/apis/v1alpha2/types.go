@@ -29,6 +29,8 @@ const (
 	Conflict         State = 409
 	// InternalError = HTTP 500
 	InternalError State = 500
+	// ServiceUnavailable = HTTP 503
+	ServiceUnavailable State = 503
 	// Config errors
 	BadConfig     State = 1000
 	MissingConfig State = 1001
@@ -48,6 +50,8 @@ const (
 	Updated        State = 8004
 	Deleted        State = 8005
 	// Workflow status
+	TimedOut       State = 9992
+	Cancelled      State = 9993
 	Running        State = 9994
 	Paused         State = 9995
 	Done           State = 9996
@@ -74,6 +78,8 @@ func (s State) String() string {
 		return "Conflict"
 	case InternalError:
 		return "Internal Error"
+	case ServiceUnavailable:
+		return "Service Unavailable"
 	case BadConfig:
 		return "Bad Config"
 	case MissingConfig:
@@ -98,6 +104,10 @@ func (s State) String() string {
 		return "Updated"
 	case Deleted:
 		return "Deleted"
+	case TimedOut:
+		return "Timed Out"
+	case Cancelled:
+		return "Cancelled"
 	case Delayed:
 		return "Delayed"
 	case Untouched:
@@ -110,19 +120,24 @@ func (s State) String() string {
 }
 
 const (
-	COAMetaHeader          = "COA_META_HEADER"
-	TracingExporterConsole = "tracing.exporters.console"
-	TracingExporterZipkin  = "tracing.exporters.zipkin"
-	ProvidersState         = "providers.state"
-	ProvidersConfig        = "providers.config"
-	ProvidersSecret        = "providers.secret"
-	ProvidersReference     = "providers.reference"
-	ProvidersProbe         = "providers.probe"
-	ProvidersUploader      = "providers.uploader"
-	ProvidersReporter      = "providers.reporter"
-	ProviderQueue          = "providers.queue"
-	ProviderLedger         = "providers.ledger"
-	StatusOutput           = "__status"
-	ErrorOutput            = "__error"
-	StateOutput            = "__state"
+	COAMetaHeader           = "COA_META_HEADER"
+	TracingExporterConsole  = "tracing.exporters.console"
+	TracingExporterZipkin   = "tracing.exporters.zipkin"
+	TracingExporterOTLPgRPC = "tracing.exporters.otlpgrpc"
+	TracingExporterOTLPHTTP = "tracing.exporters.otlphttp"
+	ProvidersState          = "providers.state"
+	ProvidersConfig         = "providers.config"
+	ProvidersSecret         = "providers.secret"
+	ProvidersReference      = "providers.reference"
+	ProvidersProbe          = "providers.probe"
+	ProvidersUploader       = "providers.uploader"
+	ProvidersReporter       = "providers.reporter"
+	ProviderQueue           = "providers.queue"
+	ProviderLedger          = "providers.ledger"
+	StatusOutput            = "__status"
+	ErrorOutput             = "__error"
+	StateOutput             = "__state"
+	// AuthUserKey and AuthRolesKey are request context keys holding the authenticated user name and roles
+	AuthUserKey  = "coa.auth.user"
+	AuthRolesKey = "coa.auth.roles"
 )
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+// IReconfigurableVendor is implemented by vendors that can apply a new configuration without a restart.
+// Reconfigure applies the changes it can and returns all the changes it finds, with paths relative to
+// the vendor.
+type IReconfigurableVendor interface {
+	Reconfigure(config VendorConfig) []v1alpha2.ConfigChange
+}
+
+// Reconfigure applies new manager properties to the managers that implement managers.IReconfigurable,
+// and new provider configurations to the providers that implement providers.IReconfigurableProvider.
+// Other changes, such as adding a manager or changing the type of a provider, are returned as rejected.
+func (v *Vendor) Reconfigure(config VendorConfig) []v1alpha2.ConfigChange {
+	changes := make([]v1alpha2.ConfigChange, 0)
+	if v.running == nil {
+		return changes
+	}
+	v.lifecycle.reload.Lock()
+	defer v.lifecycle.reload.Unlock()
+	running := v.running
+
+	if !v1alpha2.ConfigEqual(config.Properties, running.Properties) {
+		changes = append(changes, v1alpha2.RejectedChange("properties", "vendor properties are read when the vendor is created"))
+	}
+	if config.LoopInterval != running.LoopInterval {
+		changes = append(changes, v1alpha2.RejectedChange("loopInterval", "the loop interval is set when the vendor is launched"))
+	}
+
+	existing := make(map[string]int, len(running.Managers))
+	for i, m := range running.Managers {
+		existing[m.Name] = i
+	}
+	found := make(map[string]bool, len(config.Managers))
+	for _, m := range config.Managers {
+		path := fmt.Sprintf("managers[%s]", m.Name)
+		found[m.Name] = true
+		i, ok := existing[m.Name]
+		if !ok {
+			changes = append(changes, v1alpha2.RejectedChange(path, "adding a manager requires a restart"))
+			continue
+		}
+		if m.Type != running.Managers[i].Type {
+			changes = append(changes, v1alpha2.RejectedChange(path+".type", "changing the type of a manager requires a restart"))
+			continue
+		}
+		changes = append(changes, v.reconfigureProviders(path, i, m)...)
+		if !v1alpha2.ConfigEqual(m.Properties, running.Managers[i].Properties) {
+			changes = append(changes, v.reconfigureManager(path, i, m))
+		}
+	}
+	for _, m := range running.Managers {
+		if !found[m.Name] {
+			changes = append(changes, v1alpha2.RejectedChange(fmt.Sprintf("managers[%s]", m.Name), "removing a manager requires a restart"))
+		}
+	}
+	return changes
+}
+
+// reconfigureManager applies new properties to the i-th manager. It's called with the reload lock held.
+func (v *Vendor) reconfigureManager(path string, i int, config managers.ManagerConfig) v1alpha2.ConfigChange {
+	path += ".properties"
+	manager, ok := v.Managers[i].(managers.IReconfigurable)
+	if !ok {
+		return v1alpha2.RejectedChange(path, fmt.Sprintf("manager type '%s' reads its properties when it's created", config.Type))
+	}
+	// the manager gets a copy, as it may keep the properties
+	properties := make(map[string]string, len(config.Properties))
+	for k, p := range config.Properties {
+		properties[k] = p
+	}
+	managerConfig := v.running.Managers[i]
+	managerConfig.Properties = properties
+	if err := manager.Reconfigure(managerConfig); err != nil {
+		return v1alpha2.RejectedChange(path, err.Error())
+	}
+	v.running.Managers[i].Properties = config.Properties
+	v.Context.Logger.Infof("V (%s): reconfigured manager '%s'", v.running.Type, config.Name)
+	return v1alpha2.AppliedChange(path)
+}
+
+// reconfigureProviders applies new provider configurations to the providers of the i-th manager. It's
+// called with the reload lock held.
+func (v *Vendor) reconfigureProviders(path string, i int, config managers.ManagerConfig) []v1alpha2.ConfigChange {
+	changes := make([]v1alpha2.ConfigChange, 0)
+	running := v.running.Managers[i].Providers
+	names := make([]string, 0, len(config.Providers)+len(running))
+	for name := range config.Providers {
+		names = append(names, name)
+	}
+	for name := range running {
+		if _, ok := config.Providers[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		providerPath := fmt.Sprintf("%s.providers[%s]", path, name)
+		newConfig, inNew := config.Providers[name]
+		oldConfig, inOld := running[name]
+		switch {
+		case !inOld:
+			changes = append(changes, v1alpha2.RejectedChange(providerPath, "adding a provider requires a restart"))
+		case !inNew:
+			changes = append(changes, v1alpha2.RejectedChange(providerPath, "removing a provider requires a restart"))
+		case newConfig.Type != oldConfig.Type:
+			changes = append(changes, v1alpha2.RejectedChange(providerPath+".type", "changing the type of a provider requires a restart"))
+		case !v1alpha2.ConfigEqual(newConfig.Config, oldConfig.Config):
+			provider, ok := v.providers[config.Name][name].(providers.IReconfigurableProvider)
+			if !ok {
+				changes = append(changes, v1alpha2.RejectedChange(providerPath+".config", fmt.Sprintf("provider type '%s' can't be reconfigured", newConfig.Type)))
+				continue
+			}
+			if err := provider.Reconfigure(newConfig.Config); err != nil {
+				changes = append(changes, v1alpha2.RejectedChange(providerPath+".config", err.Error()))
+				continue
+			}
+			running[name] = newConfig
+			v.Context.Logger.Infof("V (%s): reconfigured provider '%s' of manager '%s'", v.running.Type, name, config.Name)
+			changes = append(changes, v1alpha2.AppliedChange(providerPath+".config"))
+		}
+	}
+	return changes
+}
+
+// copyVendorConfig copies a vendor configuration through JSON, so that changes made to the original
+// while the vendor runs don't show up as differences when the configuration is reloaded
+func copyVendorConfig(config VendorConfig) *VendorConfig {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	ret := VendorConfig{}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil
+	}
+	return &ret
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+type reconfigurableManager struct {
+	testManager
+	properties map[string]string
+	err        error
+}
+
+func (m *reconfigurableManager) Reconfigure(config managers.ManagerConfig) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.properties = config.Properties
+	return nil
+}
+
+type reconfigurableManagerFactory struct {
+	manager *reconfigurableManager
+}
+
+func (f reconfigurableManagerFactory) CreateManager(config managers.ManagerConfig) (managers.IManager, error) {
+	return f.manager, nil
+}
+
+type reconfigurableProvider struct {
+	testProvider
+	config providers.IProviderConfig
+}
+
+func (p *reconfigurableProvider) Reconfigure(config providers.IProviderConfig) error {
+	p.config = config
+	return nil
+}
+
+func reloadTestConfig() VendorConfig {
+	return VendorConfig{
+		Type:         "vendors.test",
+		Route:        "test",
+		LoopInterval: 15,
+		Managers: []managers.ManagerConfig{
+			{
+				Name:       "test-manager",
+				Type:       "managers.test",
+				Properties: map[string]string{"interval": "1m"},
+				Providers: map[string]managers.ProviderConfig{
+					"test-state":     {Type: "providers.state.test", Config: map[string]interface{}{"name": "state"}},
+					"test-reference": {Type: "providers.reference.test", Config: map[string]interface{}{"name": "reference"}},
+				},
+			},
+		},
+	}
+}
+
+func initReconfigurableVendor(t *testing.T, manager *reconfigurableManager, state *testProvider, reference *reconfigurableProvider) *Vendor {
+	vendor := &Vendor{}
+	err := vendor.Init(reloadTestConfig(), []managers.IManagerFactroy{reconfigurableManagerFactory{manager: manager}}, map[string]map[string]providers.IProvider{
+		"test-manager": {"test-state": state, "test-reference": reference},
+	}, nil)
+	assert.Nil(t, err)
+	return vendor
+}
+
+func TestReconfigureUnchanged(t *testing.T) {
+	vendor := initReconfigurableVendor(t, &reconfigurableManager{}, &testProvider{}, &reconfigurableProvider{})
+	assert.Empty(t, vendor.Reconfigure(reloadTestConfig()))
+}
+
+func TestReconfigureManagerAndProvider(t *testing.T) {
+	manager := &reconfigurableManager{}
+	reference := &reconfigurableProvider{}
+	vendor := initReconfigurableVendor(t, manager, &testProvider{}, reference)
+
+	config := reloadTestConfig()
+	config.Managers[0].Properties = map[string]string{"interval": "5m"}
+	config.Managers[0].Providers["test-reference"] = managers.ProviderConfig{Type: "providers.reference.test", Config: map[string]interface{}{"name": "other"}}
+	changes := vendor.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.AppliedChange("managers[test-manager].providers[test-reference].config"),
+		v1alpha2.AppliedChange("managers[test-manager].properties"),
+	}, changes)
+	assert.Equal(t, map[string]string{"interval": "5m"}, manager.properties)
+	assert.Equal(t, map[string]interface{}{"name": "other"}, reference.config)
+
+	// the applied configuration is the one the next reload compares against
+	assert.Empty(t, vendor.Reconfigure(config))
+}
+
+func TestReconfigureRejectedChanges(t *testing.T) {
+	vendor := initReconfigurableVendor(t, &reconfigurableManager{}, &testProvider{}, &reconfigurableProvider{})
+
+	config := reloadTestConfig()
+	config.LoopInterval = 30
+	config.Managers[0].Providers["test-state"] = managers.ProviderConfig{Type: "providers.state.test", Config: map[string]interface{}{"name": "other"}}
+	config.Managers[0].Providers["test-queue"] = managers.ProviderConfig{Type: "providers.queue.test"}
+	config.Managers = append(config.Managers, managers.ManagerConfig{Name: "other-manager", Type: "managers.test"})
+	changes := vendor.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("loopInterval", "the loop interval is set when the vendor is launched"),
+		v1alpha2.RejectedChange("managers[test-manager].providers[test-queue]", "adding a provider requires a restart"),
+		v1alpha2.RejectedChange("managers[test-manager].providers[test-state].config", "provider type 'providers.state.test' can't be reconfigured"),
+		v1alpha2.RejectedChange("managers[other-manager]", "adding a manager requires a restart"),
+	}, changes)
+
+	config = reloadTestConfig()
+	config.Managers[0].Type = "managers.other"
+	changes = vendor.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("managers[test-manager].type", "changing the type of a manager requires a restart"),
+	}, changes)
+
+	config.Managers = nil
+	changes = vendor.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("managers[test-manager]", "removing a manager requires a restart"),
+	}, changes)
+}
+
+func TestReconfigureManagerFails(t *testing.T) {
+	manager := &reconfigurableManager{err: errors.New("interval is not a duration")}
+	vendor := initReconfigurableVendor(t, manager, &testProvider{}, &reconfigurableProvider{})
+
+	config := reloadTestConfig()
+	config.Managers[0].Properties = map[string]string{"interval": "soon"}
+	changes := vendor.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.RejectedChange("managers[test-manager].properties", "interval is not a duration"),
+	}, changes)
+
+	// a rejected change is found again by the next reload
+	manager.err = nil
+	changes = vendor.Reconfigure(config)
+	assert.Equal(t, []v1alpha2.ConfigChange{
+		v1alpha2.AppliedChange("managers[test-manager].properties"),
+	}, changes)
+}
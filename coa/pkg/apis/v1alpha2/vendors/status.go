@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+// HealthCheck is the result of checking a manager, a provider or a pub-sub connection
+type HealthCheck struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// ManagerStatus describes a manager of a vendor and the last run of its loop
+type ManagerStatus struct {
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Providers       map[string]string `json:"providers,omitempty"`
+	LastLoop        *time.Time        `json:"lastLoop,omitempty"`
+	PollErrors      []string          `json:"pollErrors,omitempty"`
+	ReconcileErrors []string          `json:"reconcileErrors,omitempty"`
+}
+
+// VendorStatus describes a vendor and its managers
+type VendorStatus struct {
+	Type         string          `json:"type"`
+	Route        string          `json:"route,omitempty"`
+	Info         VendorInfo      `json:"info"`
+	LoopInterval int             `json:"loopInterval,omitempty"`
+	Managers     []ManagerStatus `json:"managers,omitempty"`
+}
+
+// IStatusVendor is implemented by vendors that can check the health of their managers and providers,
+// and report the status of their managers
+type IStatusVendor interface {
+	CheckHealth(ctx context.Context, includeProviders bool) []HealthCheck
+	GetStatus() VendorStatus
+}
+
+// loopStatus keeps the results of the last run of the vendor loop for each manager
+type loopStatus struct {
+	lock    sync.RWMutex
+	results map[string]ManagerStatus
+}
+
+func newHealthCheck(name string, err error) HealthCheck {
+	check := HealthCheck{Name: name}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func errorStrings(errs []error) []string {
+	var ret []string
+	for _, err := range errs {
+		if err != nil {
+			ret = append(ret, err.Error())
+		}
+	}
+	return ret
+}
+
+func (v *Vendor) managerName(index int) string {
+	if index < len(v.Config.Managers) {
+		return v.Config.Managers[index].Name
+	}
+	return ""
+}
+
+func (v *Vendor) recordLoop(index int, pollErrors []error, reconcileErrors []error) {
+	name := v.managerName(index)
+	now := time.Now().UTC()
+	status := ManagerStatus{
+		LastLoop:        &now,
+		PollErrors:      errorStrings(pollErrors),
+		ReconcileErrors: errorStrings(reconcileErrors),
+	}
+	for _, e := range status.PollErrors {
+		v.Context.Logger.Errorf("V (%s): manager '%s' failed to poll: %s", v.Config.Type, name, e)
+	}
+	for _, e := range status.ReconcileErrors {
+		v.Context.Logger.Errorf("V (%s): manager '%s' failed to reconcile: %s", v.Config.Type, name, e)
+	}
+	if v.loops == nil {
+		return
+	}
+	v.loops.lock.Lock()
+	defer v.loops.lock.Unlock()
+	v.loops.results[name] = status
+}
+
+// CheckHealth checks the managers of the vendor. Managers that don't implement IHealthChecker pass.
+// When includeProviders is set, the providers of the managers and the pub-sub connection of the vendor
+// are checked too.
+func (v *Vendor) CheckHealth(ctx context.Context, includeProviders bool) []HealthCheck {
+	checks := make([]HealthCheck, 0)
+	for i, m := range v.Managers {
+		name := v.managerName(i)
+		var err error
+		if c, ok := m.(managers.IHealthChecker); ok {
+			err = c.CheckHealth(ctx)
+		}
+		checks = append(checks, newHealthCheck(v.Config.Type+"/"+name, err))
+		if !includeProviders {
+			continue
+		}
+		providerNames := make([]string, 0, len(v.providers[name]))
+		for k := range v.providers[name] {
+			providerNames = append(providerNames, k)
+		}
+		sort.Strings(providerNames)
+		for _, k := range providerNames {
+			if c, ok := v.providers[name][k].(providers.IHealthCheckProvider); ok {
+				checks = append(checks, newHealthCheck(v.Config.Type+"/"+name+"/"+k, c.CheckHealth(ctx)))
+			}
+		}
+	}
+	if includeProviders && v.Context != nil {
+		if c, ok := v.Context.PubsubProvider.(providers.IHealthCheckProvider); ok {
+			checks = append(checks, newHealthCheck(v.Config.Type+"/pubsub", c.CheckHealth(ctx)))
+		}
+	}
+	return checks
+}
+
+// GetStatus returns the managers of the vendor, their providers and the results of the last run of
+// the vendor loop
+func (v *Vendor) GetStatus() VendorStatus {
+	status := VendorStatus{
+		Type:         v.Config.Type,
+		Route:        v.Route,
+		LoopInterval: v.Config.LoopInterval,
+		Managers:     make([]ManagerStatus, 0, len(v.Managers)),
+	}
+	for i := range v.Managers {
+		var manager ManagerStatus
+		if v.loops != nil {
+			v.loops.lock.RLock()
+			manager = v.loops.results[v.managerName(i)]
+			v.loops.lock.RUnlock()
+		}
+		if i < len(v.Config.Managers) {
+			config := v.Config.Managers[i]
+			manager.Name = config.Name
+			manager.Type = config.Type
+			if len(config.Providers) > 0 {
+				manager.Providers = make(map[string]string)
+				for k, p := range config.Providers {
+					manager.Providers[k] = p.Type
+				}
+			}
+		}
+		status.Managers = append(status.Managers, manager)
+	}
+	return status
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+type testManager struct {
+	managers.Manager
+	healthErr error
+}
+
+func (m *testManager) CheckHealth(ctx context.Context) error {
+	return m.healthErr
+}
+
+type testManagerFactory struct {
+	manager *testManager
+}
+
+func (f testManagerFactory) CreateManager(config managers.ManagerConfig) (managers.IManager, error) {
+	return f.manager, nil
+}
+
+type testProvider struct {
+	healthErr error
+}
+
+func (p *testProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+
+func (p *testProvider) CheckHealth(ctx context.Context) error {
+	return p.healthErr
+}
+
+func initTestVendor(t *testing.T, manager *testManager, provider *testProvider) *Vendor {
+	vendor := &Vendor{}
+	err := vendor.Init(VendorConfig{
+		Type:         "vendors.test",
+		Route:        "test",
+		LoopInterval: 15,
+		Managers: []managers.ManagerConfig{
+			{
+				Name: "test-manager",
+				Type: "managers.test",
+				Providers: map[string]managers.ProviderConfig{
+					"test-state": {Type: "providers.state.test"},
+				},
+			},
+		},
+	}, []managers.IManagerFactroy{testManagerFactory{manager: manager}}, map[string]map[string]providers.IProvider{
+		"test-manager": {"test-state": provider},
+	}, nil)
+	assert.Nil(t, err)
+	return vendor
+}
+
+func TestCheckHealth(t *testing.T) {
+	provider := &testProvider{}
+	vendor := initTestVendor(t, &testManager{}, provider)
+
+	checks := vendor.CheckHealth(context.Background(), false)
+	assert.Equal(t, []HealthCheck{{Name: "vendors.test/test-manager"}}, checks)
+
+	provider.healthErr = errors.New("state store is unreachable")
+	checks = vendor.CheckHealth(context.Background(), true)
+	assert.Equal(t, []HealthCheck{
+		{Name: "vendors.test/test-manager"},
+		{Name: "vendors.test/test-manager/test-state", Error: "state store is unreachable"},
+	}, checks)
+}
+
+func TestCheckHealthOfManager(t *testing.T) {
+	vendor := initTestVendor(t, &testManager{healthErr: errors.New("not synced")}, &testProvider{})
+	checks := vendor.CheckHealth(context.Background(), false)
+	assert.Equal(t, "not synced", checks[0].Error)
+}
+
+func TestGetStatus(t *testing.T) {
+	vendor := initTestVendor(t, &testManager{}, &testProvider{})
+	status := vendor.GetStatus()
+	assert.Equal(t, "vendors.test", status.Type)
+	assert.Equal(t, 15, status.LoopInterval)
+	assert.Equal(t, 1, len(status.Managers))
+	assert.Equal(t, "test-manager", status.Managers[0].Name)
+	assert.Equal(t, "providers.state.test", status.Managers[0].Providers["test-state"])
+	assert.Nil(t, status.Managers[0].LastLoop)
+
+	vendor.recordLoop(0, []error{errors.New("poll failed"), nil}, nil)
+	status = vendor.GetStatus()
+	assert.NotNil(t, status.Managers[0].LastLoop)
+	assert.Equal(t, []string{"poll failed"}, status.Managers[0].PollErrors)
+	assert.Nil(t, status.Managers[0].ReconcileErrors)
+
+	vendor.recordLoop(0, nil, nil)
+	status = vendor.GetStatus()
+	assert.Nil(t, status.Managers[0].PollErrors)
+}
+
+func TestGetStatusWithoutInit(t *testing.T) {
+	vendor := &Vendor{Context: &contexts.VendorContext{}}
+	assert.Equal(t, 0, len(vendor.GetStatus().Managers))
+	assert.Equal(t, 0, len(vendor.CheckHealth(context.Background(), true)))
+}
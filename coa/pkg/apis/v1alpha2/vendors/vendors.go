@@ -7,9 +7,9 @@
 package vendors
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -31,6 +31,9 @@ type VendorConfig struct {
 
 type IVendor interface {
 	RunLoop(interval time.Duration) error
+	// Shutdown stops the background loop and the managers of the vendor, and waits for the work in flight
+	// until ctx is done
+	Shutdown(ctx context.Context) error
 	Init(config VendorConfig, managers []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error
 	GetEndpoints() []v1alpha2.Endpoint
 	GetInfo() VendorInfo
@@ -52,39 +55,117 @@ type VendorInfo struct {
 }
 
 type Vendor struct {
-	Managers []managers.IManager
-	Version  string
-	Route    string
-	Context  *contexts.VendorContext
-	Config   VendorConfig
+	Managers  []managers.IManager
+	Version   string
+	Route     string
+	Context   *contexts.VendorContext
+	Config    VendorConfig
+	providers map[string]map[string]providers.IProvider
+	loops     *loopStatus
+	lifecycle *lifecycle
+	running   *VendorConfig
+}
+
+// lifecycle stops the background loop of a vendor and serializes the reloads of its configuration
+type lifecycle struct {
+	lock    sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	loops   sync.WaitGroup
+	reload  sync.Mutex
 }
 
 func (v *Vendor) SetEvaluationContext(context *utils.EvaluationContext) {
 	v.Context.EvaluationContext = context
 }
+
+// RunLoop calls Poll and Reconcil on the schedulable managers every interval until the vendor is
+// shut down. A loop that's running finishes with the manager it's at before it stops.
 func (v *Vendor) RunLoop(interval time.Duration) error {
-	signalChannel := make(chan os.Signal, 1)
-	signal.Notify(signalChannel, os.Interrupt)
-	go func() {
-		<-signalChannel
-		fmt.Println("CLEANING UP")
-		os.Exit(0)
-	}()
-	for true {
-		for _, m := range v.Managers {
+	if v.lifecycle == nil {
+		return v1alpha2.NewCOAError(nil, "vendor is not initialized", v1alpha2.InternalError)
+	}
+	v.lifecycle.lock.Lock()
+	if v.lifecycle.stopped {
+		v.lifecycle.lock.Unlock()
+		return nil
+	}
+	v.lifecycle.loops.Add(1)
+	v.lifecycle.lock.Unlock()
+	defer v.lifecycle.loops.Done()
+
+	for {
+		for i, m := range v.Managers {
+			if v.isStopped() {
+				return nil
+			}
 			if c, ok := m.(managers.ISchedulable); ok {
 				if c.Enabled() {
-					c.Poll()     //TODO: report errors
-					c.Reconcil() //TODO: report errors
+					pollErrors := c.Poll()
+					reconcileErrors := c.Reconcil()
+					v.recordLoop(i, pollErrors, reconcileErrors)
 				}
 			}
 		}
-		time.Sleep(interval)
+		select {
+		case <-v.lifecycle.stop:
+			return nil
+		case <-time.After(interval):
+		}
 	}
-	return nil
+}
+
+func (v *Vendor) isStopped() bool {
+	select {
+	case <-v.lifecycle.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown stops the background loop, waits for the managers the loop is at, and then shuts down the
+// managers, which stop accepting new work and wait for the work in flight. It returns the first error
+// if the work isn't finished before ctx is done.
+func (v *Vendor) Shutdown(ctx context.Context) error {
+	if v.lifecycle == nil {
+		return nil
+	}
+	v.lifecycle.lock.Lock()
+	if !v.lifecycle.stopped {
+		v.lifecycle.stopped = true
+		close(v.lifecycle.stop)
+	}
+	v.lifecycle.lock.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		v.lifecycle.loops.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("background loop of vendor '%s' didn't stop", v.Config.Type), v1alpha2.InternalError)
+	}
+
+	var ret error
+	for _, m := range v.Managers {
+		if c, ok := m.(managers.IShutdownable); ok {
+			if err := c.Shutdown(ctx); err != nil {
+				v.Context.Logger.Errorf("V (%s): failed to shut down manager: %+v", v.Config.Type, err)
+				if ret == nil {
+					ret = err
+				}
+			}
+		}
+	}
+	return ret
 }
 
 func (v *Vendor) Init(config VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	// copied before the managers get the configuration
+	running := copyVendorConfig(config)
 	v.Context = &contexts.VendorContext{}
 	v.Context.SiteInfo = config.SiteInfo
 	err := v.Context.Init(pubsubProvider)
@@ -122,5 +203,9 @@ func (v *Vendor) Init(config VendorConfig, factories []managers.IManagerFactroy,
 	v.Version = "v1alpha2"
 	v.Route = config.Route
 	v.Config = config
+	v.running = running
+	v.providers = providers
+	v.loops = &loopStatus{results: make(map[string]ManagerStatus)}
+	v.lifecycle = &lifecycle{stop: make(chan struct{})}
 	return nil
 }
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/stretchr/testify/assert"
+)
+
+type schedulableManager struct {
+	testManager
+	polls int32
+}
+
+func (m *schedulableManager) Enabled() bool {
+	return true
+}
+func (m *schedulableManager) Poll() []error {
+	atomic.AddInt32(&m.polls, 1)
+	return nil
+}
+func (m *schedulableManager) Reconcil() []error {
+	return nil
+}
+
+func initSchedulableVendor(t *testing.T, manager *schedulableManager) *Vendor {
+	vendor := &Vendor{}
+	err := vendor.Init(VendorConfig{
+		Type:  "vendors.test",
+		Route: "test",
+		Managers: []managers.ManagerConfig{
+			{Name: "test-manager", Type: "managers.test"},
+		},
+	}, []managers.IManagerFactroy{schedulableManagerFactory{manager: manager}}, nil, nil)
+	assert.Nil(t, err)
+	return vendor
+}
+
+type schedulableManagerFactory struct {
+	manager *schedulableManager
+}
+
+func (f schedulableManagerFactory) CreateManager(config managers.ManagerConfig) (managers.IManager, error) {
+	return f.manager, nil
+}
+
+func TestRunLoopStopsOnShutdown(t *testing.T) {
+	manager := &schedulableManager{}
+	vendor := initSchedulableVendor(t, manager)
+	stopped := make(chan error)
+	go func() {
+		stopped <- vendor.RunLoop(time.Hour)
+	}()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&manager.polls) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	err := vendor.Shutdown(context.Background())
+	assert.Nil(t, err)
+	select {
+	case err = <-stopped:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("loop didn't stop")
+	}
+	// a loop started after the shutdown doesn't run
+	assert.Nil(t, vendor.RunLoop(time.Millisecond))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&manager.polls))
+}
+
+func TestShutdownWaitsForWorkInFlight(t *testing.T) {
+	manager := &schedulableManager{}
+	vendor := initSchedulableVendor(t, manager)
+	assert.Nil(t, manager.BeginWork())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := vendor.Shutdown(ctx)
+	assert.NotNil(t, err)
+
+	// the manager doesn't accept new work once it's shutting down
+	err = manager.BeginWork()
+	assert.NotNil(t, err)
+	cErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.ServiceUnavailable, cErr.State)
+
+	manager.EndWork()
+	assert.Nil(t, vendor.Shutdown(context.Background()))
+}
+
+func TestRunLoopWithoutInit(t *testing.T) {
+	vendor := &Vendor{}
+	assert.NotNil(t, vendor.RunLoop(time.Second))
+	assert.Nil(t, vendor.Shutdown(context.Background()))
+}
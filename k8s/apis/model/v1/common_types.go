@@ -73,6 +73,29 @@ type StageSpec struct {
 	Inputs          runtime.RawExtension `json:"inputs,omitempty"`
 	TriggeringStage string               `json:"triggeringStage,omitempty"`
 	Schedule        *ScheduleSpec        `json:"schedule,omitempty"`
+	Timeout         string               `json:"timeout,omitempty"`
+	Retry           *RetrySpec           `json:"retry,omitempty"`
+	OnTimeout       string               `json:"onTimeout,omitempty"`
+	OnFailure       string               `json:"onFailure,omitempty"`
+	MaxParallelism  int                  `json:"maxParallelism,omitempty"`
+	// SuccessThreshold is a count, such as "3", or a percentage, such as "80%"
+	SuccessThreshold string       `json:"successThreshold,omitempty"`
+	ForEach          *ForEachSpec `json:"foreach,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ForEachSpec struct {
+	Items          string `json:"items"`
+	ItemName       string `json:"itemName,omitempty"`
+	Parallel       bool   `json:"parallel,omitempty"`
+	MaxParallelism int    `json:"maxParallelism,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type RetrySpec struct {
+	Attempts   int    `json:"attempts,omitempty"`
+	Backoff    string `json:"backoff,omitempty"`
+	MaxBackoff string `json:"maxBackoff,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -84,6 +107,7 @@ type ActivationSpec struct {
 	// +kubebuilder:validation:Schemaless
 	Inputs     runtime.RawExtension `json:"inputs,omitempty"`
 	Generation string               `json:"generation,omitempty"`
+	Parent     string               `json:"parent,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
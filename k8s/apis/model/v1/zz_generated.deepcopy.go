@@ -117,6 +117,36 @@ func (in *ComponentSpec) DeepCopy() *ComponentSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForEachSpec) DeepCopyInto(out *ForEachSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForEachSpec.
+func (in *ForEachSpec) DeepCopy() *ForEachSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ForEachSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetrySpec) DeepCopyInto(out *RetrySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetrySpec.
+func (in *RetrySpec) DeepCopy() *RetrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RetrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScheduleSpec) DeepCopyInto(out *ScheduleSpec) {
 	*out = *in
@@ -171,6 +201,16 @@ func (in *StageSpec) DeepCopyInto(out *StageSpec) {
 		*out = new(ScheduleSpec)
 		**out = **in
 	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(RetrySpec)
+		**out = **in
+	}
+	if in.ForEach != nil {
+		in, out := &in.ForEach, &out.ForEach
+		*out = new(ForEachSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageSpec.
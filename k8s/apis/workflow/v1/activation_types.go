@@ -27,6 +27,23 @@ type ActivationStatus struct {
 	IsActive             bool                 `json:"isActive,omitempty"`
 	ActivationGeneration string               `json:"activationGeneration,omitempty"`
 	UpdateTime           string               `json:"updateTime,omitempty"`
+	ParentActivation     string               `json:"parentActivation,omitempty"`
+	ChildActivations     []string             `json:"childActivations,omitempty"`
+	History              []StageHistory       `json:"history,omitempty"`
+}
+
+// StageHistory records a run of a stage of an activation
+type StageHistory struct {
+	Stage     string `json:"stage"`
+	NextStage string `json:"nextStage,omitempty"`
+	Site      string `json:"site,omitempty"`
+	StartTime string `json:"startTime,omitempty"`
+	EndTime   string `json:"endTime,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Outputs      runtime.RawExtension `json:"outputs,omitempty"`
+	Status       v1alpha2.State       `json:"status,omitempty"`
+	ErrorMessage string               `json:"errorMessage,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -79,6 +79,18 @@ func (in *ActivationStatus) DeepCopyInto(out *ActivationStatus) {
 	*out = *in
 	in.Inputs.DeepCopyInto(&out.Inputs)
 	in.Outputs.DeepCopyInto(&out.Outputs)
+	if in.ChildActivations != nil {
+		in, out := &in.ChildActivations, &out.ChildActivations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]StageHistory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActivationStatus.
@@ -163,3 +175,19 @@ func (in *CampaignStatus) DeepCopy() *CampaignStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StageHistory) DeepCopyInto(out *StageHistory) {
+	*out = *in
+	in.Outputs.DeepCopyInto(&out.Outputs)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageHistory.
+func (in *StageHistory) DeepCopy() *StageHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(StageHistory)
+	in.DeepCopyInto(out)
+	return out
+}